@@ -0,0 +1,728 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Prefix of the versioned REST API
+const apiV1Prefix = "/api/v1"
+
+// Page sizes of the list endpoints
+const (
+	apiDefaultPageSize = 50
+	apiMaxPageSize     = 500
+)
+
+// Largest accepted request body
+const apiMaxBodyBytes = 1 << 20
+
+// Error codes of the versioned API
+const (
+	APIErrBadRequest       = "bad_request"       // Malformed JSON body
+	APIErrInvalidParameter = "invalid_parameter" // Query parameters or body fields failed validation, see fields
+	APIErrUnauthorized     = "unauthorized"      // No valid session or bearer token
+	APIErrForbidden        = "forbidden"         // Role, token scope or CSRF check failed
+	APIErrNotFound         = "not_found"
+	APIErrMethodNotAllowed = "method_not_allowed"
+	APIErrConflict         = "conflict"       // The resource is not in a state that allows the request
+	APIErrNotReady         = "not_ready"      // Database connection or binary checks have not passed
+	APIErrInternal         = "internal_error" // Server side failure, details are in the log
+)
+
+// APIError is the body of every /api/v1 response with a 4xx or 5xx status
+type APIError struct {
+	Code    string            `json:"code"`
+	Message string            `json:"message"`
+	Fields  map[string]string `json:"fields,omitempty"` // Invalid parameter or field name to problem
+}
+
+// APIErrorResponse wraps an APIError
+type APIErrorResponse struct {
+	Error APIError `json:"error"`
+}
+
+// Pagination describes the page of a list response. Lists take limit
+// (default 50, at most 500) and offset query parameters.
+type Pagination struct {
+	Limit      int  `json:"limit"`
+	Offset     int  `json:"offset"`
+	Total      int  `json:"total"`
+	NextOffset *int `json:"next_offset"` // Offset of the next page, null on the last page
+}
+
+// APICheck is the result of the connection or binary check
+type APICheck struct {
+	Status  string `json:"status"` // unknown, success, warning or failed
+	Message string `json:"message"`
+}
+
+// APIStatus is the state of the service
+type APIStatus struct {
+	Version          string   `json:"version"`
+	BackupsEnabled   bool     `json:"backups_enabled"` // Connection and binary checks passed, backups can be started
+	Connection       APICheck `json:"connection"`
+	Binaries         APICheck `json:"binaries"`
+	LastTested       string   `json:"last_tested,omitempty"`
+	SchedulerRunning bool     `json:"scheduler_running"`
+	NextScheduledRun string   `json:"next_scheduled_run,omitempty"`
+	RunningJobs      int      `json:"running_jobs"`
+	PendingJobs      int      `json:"pending_jobs"`
+}
+
+// BackupRun is one backup job: a set of databases backed up together
+type BackupRun struct {
+	JobID            string         `json:"job_id"`
+	State            string         `json:"state"` // running, completed, cancelled or interrupted
+	Mode             string         `json:"mode"`  // full, incremental or auto
+	Type             string         `json:"type"`  // full, incremental or retry
+	Policy           string         `json:"policy"`
+	RequestedBy      string         `json:"requested_by"`
+	Databases        []string       `json:"databases"`
+	DatabaseCount    int            `json:"database_count"`
+	CreatedAt        string         `json:"created_at"`
+	CompletedAt      string         `json:"completed_at,omitempty"`
+	SizeKB           int            `json:"size_kb"`
+	DiskSizeKB       int            `json:"disk_size_kb"`
+	FullCount        int            `json:"full_count"`
+	IncrementalCount int            `json:"incremental_count"`
+	FailedCount      int            `json:"failed_count"`
+	ResumedJobID     string         `json:"resumed_job_id,omitempty"` // Job that resumed this one after an interruption
+	Results          []BackupResult `json:"results,omitempty"`        // Per database, only when fetching a single run
+}
+
+// BackupResult is the backup of one database within a BackupRun
+type BackupResult struct {
+	Database    string `json:"database"`
+	Type        string `json:"type"`
+	Status      string `json:"status"`
+	Progress    int    `json:"progress"`
+	StartedAt   string `json:"started_at,omitempty"`
+	CompletedAt string `json:"completed_at,omitempty"`
+	SizeKB      int    `json:"size_kb"`
+	FilePath    string `json:"file_path,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// BackupRunFilter selects backup runs. Empty fields match everything.
+type BackupRunFilter struct {
+	State       string
+	Policy      string
+	RequestedBy string
+	Database    string // Runs that include the database
+	Limit       int
+	Offset      int
+}
+
+// StartBackupRequest is the body of POST /api/v1/backups
+type StartBackupRequest struct {
+	Mode       string   `json:"mode,omitempty"` // full, incremental or auto (default)
+	Databases  []string `json:"databases"`
+	OnConflict string   `json:"on_conflict,omitempty"` // wait (default) or skip when the databases are already being backed up
+}
+
+// StartedBackupJob is a job created by POST /api/v1/backups. Auto mode
+// creates one job for the full and one for the incremental backups.
+type StartedBackupJob struct {
+	JobID     string   `json:"job_id"`
+	Type      string   `json:"type"` // full or incremental
+	Databases []string `json:"databases"`
+	Accepted  bool     `json:"accepted"`
+	Message   string   `json:"message"`
+}
+
+// CancelBackupRequest is the optional body of POST /api/v1/backups/{job_id}/cancel
+type CancelBackupRequest struct {
+	Database string `json:"database,omitempty"` // Cancel only this database, the whole job when empty
+}
+
+// CancelBackupResult is the outcome of a cancel request
+type CancelBackupResult struct {
+	JobID           string `json:"job_id"`
+	Database        string `json:"database,omitempty"`
+	Message         string `json:"message"`
+	KilledProcesses int    `json:"killed_processes"`
+}
+
+// Response bodies. Successful responses carry the resource in data, lists
+// add pagination.
+type (
+	StatusResponse struct {
+		Data APIStatus `json:"data"`
+	}
+	BackupRunList struct {
+		Data       []BackupRun `json:"data"`
+		Pagination Pagination  `json:"pagination"`
+	}
+	BackupRunResponse struct {
+		Data BackupRun `json:"data"`
+	}
+	StartBackupResponse struct {
+		Data []StartedBackupJob `json:"data"`
+	}
+	CancelBackupResponse struct {
+		Data CancelBackupResult `json:"data"`
+	}
+	QueuedJobList struct {
+		Data       []QueuedJob `json:"data"` // Running jobs first, then pending jobs in queue order
+		Pagination Pagination  `json:"pagination"`
+	}
+	DatabaseHealthList struct {
+		Data       []DatabaseHealth `json:"data"`
+		Pagination Pagination       `json:"pagination"`
+	}
+	AuditEntryList struct {
+		Data       []AuditEntry `json:"data"`
+		Pagination Pagination   `json:"pagination"`
+	}
+)
+
+// apiParam is a query parameter of a route
+type apiParam struct {
+	Name        string
+	Type        string // OpenAPI type: string, integer or boolean
+	Description string
+}
+
+// apiRoute is one endpoint of the versioned API. The table drives both the
+// route registration and the OpenAPI document.
+type apiRoute struct {
+	Method   string
+	Path     string // ServeMux pattern path, {name} for path parameters
+	Tag      string
+	Summary  string
+	Role     string // Minimum role, empty for public endpoints
+	Query    []apiParam
+	Request  interface{} // Zero value of the request body type, nil without body
+	Optional bool        // The request body may be omitted
+	Status   int         // Status of a successful response
+	Response interface{} // Zero value of the response body type
+	Errors   []int       // Error statuses besides 401, 403 and 500
+	Handler  http.HandlerFunc
+}
+
+// Query parameters shared by the list endpoints
+var apiPageParams = []apiParam{
+	{"limit", "integer", fmt.Sprintf("Page size, default %d, at most %d", apiDefaultPageSize, apiMaxPageSize)},
+	{"offset", "integer", "Number of items to skip"},
+}
+
+// apiV1Routes returns the endpoints of the versioned API
+func apiV1Routes() []apiRoute {
+	return []apiRoute{
+		{
+			Method: "GET", Path: apiV1Prefix + "/openapi.json", Tag: "meta",
+			Summary: "OpenAPI document of this API", Status: http.StatusOK, Response: map[string]interface{}{},
+			Handler: handleAPIv1OpenAPI,
+		},
+		{
+			Method: "GET", Path: apiV1Prefix + "/status", Tag: "status", Role: RoleViewer,
+			Summary: "Service status, checks, scheduler and queue", Status: http.StatusOK, Response: StatusResponse{},
+			Handler: handleAPIv1Status,
+		},
+		{
+			Method: "GET", Path: apiV1Prefix + "/backups", Tag: "backups", Role: RoleViewer,
+			Summary: "List backup runs, newest first",
+			Query: append([]apiParam{
+				{"state", "string", "running, completed, cancelled or interrupted"},
+				{"policy", "string", "Policy name"},
+				{"requested_by", "string", "User, token or scheduler that started the run"},
+				{"database", "string", "Only runs that include this database"},
+			}, apiPageParams...),
+			Status: http.StatusOK, Response: BackupRunList{}, Errors: []int{http.StatusBadRequest},
+			Handler: handleAPIv1ListBackups,
+		},
+		{
+			Method: "POST", Path: apiV1Prefix + "/backups", Tag: "backups", Role: RoleOperator,
+			Summary: "Start a backup", Request: StartBackupRequest{},
+			Status: http.StatusAccepted, Response: StartBackupResponse{},
+			Errors:  []int{http.StatusBadRequest, http.StatusConflict, http.StatusServiceUnavailable},
+			Handler: handleAPIv1StartBackup,
+		},
+		{
+			Method: "GET", Path: apiV1Prefix + "/backups/{job_id}", Tag: "backups", Role: RoleViewer,
+			Summary: "Get a backup run with the result of every database",
+			Status:  http.StatusOK, Response: BackupRunResponse{}, Errors: []int{http.StatusNotFound},
+			Handler: handleAPIv1GetBackup,
+		},
+		{
+			Method: "POST", Path: apiV1Prefix + "/backups/{job_id}/cancel", Tag: "backups", Role: RoleOperator,
+			Summary: "Cancel a queued or running backup, or one database of it", Request: CancelBackupRequest{}, Optional: true,
+			Status: http.StatusOK, Response: CancelBackupResponse{},
+			Errors:  []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict},
+			Handler: handleAPIv1CancelBackup,
+		},
+		{
+			Method: "GET", Path: apiV1Prefix + "/jobs", Tag: "backups", Role: RoleViewer,
+			Summary: "List running and queued jobs", Query: apiPageParams,
+			Status: http.StatusOK, Response: QueuedJobList{}, Errors: []int{http.StatusBadRequest},
+			Handler: handleAPIv1ListJobs,
+		},
+		{
+			Method: "GET", Path: apiV1Prefix + "/databases/health", Tag: "databases", Role: RoleViewer,
+			Summary: "Backup freshness of every database against its RPO",
+			Query: append([]apiParam{
+				{"status", "string", "ok, warning, breached, never or unmonitored"},
+			}, apiPageParams...),
+			Status: http.StatusOK, Response: DatabaseHealthList{}, Errors: []int{http.StatusBadRequest},
+			Handler: handleAPIv1DatabaseHealth,
+		},
+		{
+			Method: "GET", Path: apiV1Prefix + "/audit", Tag: "audit", Role: RoleAdmin,
+			Summary: "List audit log entries, newest first",
+			Query: append([]apiParam{
+				{"actor", "string", "Substring of the actor"},
+				{"action", "string", "Exact action, or a group such as backup"},
+				{"target", "string", "Substring of the target"},
+				{"outcome", "string", "success or failure"},
+				{"from", "string", "First day, YYYY-MM-DD"},
+				{"to", "string", "Last day, YYYY-MM-DD"},
+			}, apiPageParams...),
+			Status: http.StatusOK, Response: AuditEntryList{}, Errors: []int{http.StatusBadRequest},
+			Handler: handleAPIv1ListAudit,
+		},
+	}
+}
+
+// setupAPIv1Routes registers the versioned API. Requests under the prefix
+// that match no route get a JSON 404 or 405.
+func setupAPIv1Routes() {
+	for _, route := range apiV1Routes() {
+		pattern := route.Method + " " + route.Path
+		if route.Role == "" {
+			http.HandleFunc(pattern, route.Handler)
+			continue
+		}
+		endpointRoles[pattern] = route.Role
+		http.HandleFunc(pattern, requireAuth(route.Handler))
+	}
+	http.HandleFunc(apiV1Prefix+"/", handleAPIv1NotFound)
+}
+
+// isAPIv1Request reports whether a request is for the versioned API, which
+// answers authentication failures with an APIError instead of a redirect
+func isAPIv1Request(r *http.Request) bool {
+	return strings.HasPrefix(r.URL.Path, apiV1Prefix+"/")
+}
+
+// writeAPIJSON writes a response body with the given status
+func writeAPIJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// writeAPIError writes an error envelope
+func writeAPIError(w http.ResponseWriter, status int, code, message string) {
+	writeAPIJSON(w, status, APIErrorResponse{Error: APIError{Code: code, Message: message}})
+}
+
+// writeAPIFieldErrors rejects a request whose parameters or fields are invalid
+func writeAPIFieldErrors(w http.ResponseWriter, fields map[string]string) {
+	writeAPIJSON(w, http.StatusBadRequest, APIErrorResponse{Error: APIError{
+		Code:    APIErrInvalidParameter,
+		Message: "Invalid request parameters",
+		Fields:  fields,
+	}})
+}
+
+// writeAPIInternalError logs a server side failure and reports it without details
+func writeAPIInternalError(w http.ResponseWriter, r *http.Request, err error) {
+	LogError("❌ [API] %s %s failed: %v", r.Method, r.URL.Path, err)
+	writeAPIError(w, http.StatusInternalServerError, APIErrInternal, "Internal server error")
+}
+
+// decodeAPIBody decodes a JSON request body, rejecting unknown fields. An
+// empty body leaves v unchanged when optional is set.
+func decodeAPIBody(r *http.Request, v interface{}, optional bool) error {
+	decoder := json.NewDecoder(io.LimitReader(r.Body, apiMaxBodyBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		if errors.Is(err, io.EOF) {
+			if optional {
+				return nil
+			}
+			return errors.New("request body is required")
+		}
+		return fmt.Errorf("invalid JSON body: %v", err)
+	}
+	return nil
+}
+
+// parseAPIPagination reads limit and offset. Invalid values are reported
+// by parameter name.
+func parseAPIPagination(r *http.Request, fields map[string]string) (int, int) {
+	limit, offset := apiDefaultPageSize, 0
+	query := r.URL.Query()
+
+	if value := query.Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > apiMaxPageSize {
+			fields["limit"] = fmt.Sprintf("must be an integer between 1 and %d", apiMaxPageSize)
+		} else {
+			limit = parsed
+		}
+	}
+	if value := query.Get("offset"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			fields["offset"] = "must be a non-negative integer"
+		} else {
+			offset = parsed
+		}
+	}
+
+	return limit, offset
+}
+
+// newPagination describes a page of a list with total items
+func newPagination(limit, offset, total int) Pagination {
+	page := Pagination{Limit: limit, Offset: offset, Total: total}
+	if offset+limit < total {
+		next := offset + limit
+		page.NextOffset = &next
+	}
+	return page
+}
+
+// pageBounds returns the slice bounds of a page of a list of n items
+func pageBounds(n, limit, offset int) (int, int) {
+	start := min(offset, n)
+	return start, min(start+limit, n)
+}
+
+// handleAPIv1NotFound answers requests under /api/v1 that match no route
+func handleAPIv1NotFound(w http.ResponseWriter, r *http.Request) {
+	var allowed []string
+	for _, route := range apiV1Routes() {
+		if apiPathMatches(route.Path, r.URL.Path) {
+			allowed = append(allowed, route.Method)
+		}
+	}
+
+	if len(allowed) > 0 {
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		writeAPIError(w, http.StatusMethodNotAllowed, APIErrMethodNotAllowed,
+			fmt.Sprintf("Method %s is not allowed, use %s", r.Method, strings.Join(allowed, " or ")))
+		return
+	}
+	writeAPIError(w, http.StatusNotFound, APIErrNotFound, "No such endpoint: "+r.URL.Path)
+}
+
+// apiPathMatches reports whether a request path matches a route path with
+// {name} parameters
+func apiPathMatches(pattern, path string) bool {
+	patternParts := strings.Split(pattern, "/")
+	pathParts := strings.Split(path, "/")
+	if len(patternParts) != len(pathParts) {
+		return false
+	}
+	for i, part := range patternParts {
+		if strings.HasPrefix(part, "{") {
+			if pathParts[i] == "" {
+				return false
+			}
+			continue
+		}
+		if part != pathParts[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// handleAPIv1OpenAPI serves the OpenAPI document generated from the route table
+func handleAPIv1OpenAPI(w http.ResponseWriter, r *http.Request) {
+	writeAPIJSON(w, http.StatusOK, buildOpenAPISpec())
+}
+
+// handleAPIv1Status returns the service status
+func handleAPIv1Status(w http.ResponseWriter, r *http.Request) {
+	pending, running := QueuedJobs()
+	status := APIStatus{
+		Version:        Version,
+		BackupsEnabled: testState.ButtonsEnabled,
+		Connection:     APICheck{Status: testState.ConnectionStatus, Message: testState.ConnectionMessage},
+		Binaries:       APICheck{Status: testState.BinaryStatus, Message: testState.BinaryMessage},
+		RunningJobs:    len(running),
+		PendingJobs:    len(pending),
+	}
+	if !testState.LastTested.IsZero() {
+		status.LastTested = testState.LastTested.Format(time.RFC3339)
+	}
+
+	scheduler := GetSchedulerStatus()
+	status.SchedulerRunning, _ = scheduler["running"].(bool)
+	if nextRun, ok := scheduler["next_run"].(time.Time); ok && !nextRun.IsZero() {
+		status.NextScheduledRun = nextRun.Format(time.RFC3339)
+	}
+
+	writeAPIJSON(w, http.StatusOK, StatusResponse{Data: status})
+}
+
+// handleAPIv1ListBackups returns a page of backup runs
+func handleAPIv1ListBackups(w http.ResponseWriter, r *http.Request) {
+	fields := map[string]string{}
+	limit, offset := parseAPIPagination(r, fields)
+	if len(fields) > 0 {
+		writeAPIFieldErrors(w, fields)
+		return
+	}
+
+	query := r.URL.Query()
+	runs, total, err := GetBackupRuns(BackupRunFilter{
+		State:       query.Get("state"),
+		Policy:      query.Get("policy"),
+		RequestedBy: query.Get("requested_by"),
+		Database:    query.Get("database"),
+		Limit:       limit,
+		Offset:      offset,
+	})
+	if err != nil {
+		writeAPIInternalError(w, r, err)
+		return
+	}
+
+	writeAPIJSON(w, http.StatusOK, BackupRunList{Data: runs, Pagination: newPagination(limit, offset, total)})
+}
+
+// handleAPIv1GetBackup returns one backup run
+func handleAPIv1GetBackup(w http.ResponseWriter, r *http.Request) {
+	jobID := r.PathValue("job_id")
+	run, err := GetBackupRun(jobID)
+	if err != nil {
+		writeAPIInternalError(w, r, err)
+		return
+	}
+	if run == nil {
+		writeAPIError(w, http.StatusNotFound, APIErrNotFound, fmt.Sprintf("Backup run %s not found", jobID))
+		return
+	}
+
+	writeAPIJSON(w, http.StatusOK, BackupRunResponse{Data: *run})
+}
+
+// handleAPIv1StartBackup starts a manual backup. Auto mode splits the
+// databases into a full and an incremental job.
+func handleAPIv1StartBackup(w http.ResponseWriter, r *http.Request) {
+	if !testState.ButtonsEnabled {
+		writeAPIError(w, http.StatusServiceUnavailable, APIErrNotReady,
+			"Database connection or binary validation failed. Please check your configuration.")
+		return
+	}
+
+	var request StartBackupRequest
+	if err := decodeAPIBody(r, &request, false); err != nil {
+		writeAPIError(w, http.StatusBadRequest, APIErrBadRequest, err.Error())
+		return
+	}
+
+	fields := map[string]string{}
+	if request.Mode == "" {
+		request.Mode = "auto"
+	}
+	if request.Mode != "auto" && request.Mode != "full" && request.Mode != "incremental" {
+		fields["mode"] = "must be full, incremental or auto"
+	}
+	if len(request.Databases) == 0 {
+		fields["databases"] = "at least one database is required"
+	}
+	for _, dbName := range request.Databases {
+		if strings.TrimSpace(dbName) == "" {
+			fields["databases"] = "database names must not be empty"
+		}
+	}
+	if request.OnConflict != "" && request.OnConflict != JobConflictWait && request.OnConflict != JobConflictSkip {
+		fields["on_conflict"] = "must be wait or skip"
+	}
+	if len(fields) > 0 {
+		writeAPIFieldErrors(w, fields)
+		return
+	}
+
+	fullDatabases, incDatabases := request.Databases, []string(nil)
+	switch request.Mode {
+	case "incremental":
+		fullDatabases, incDatabases = nil, request.Databases
+	case "auto":
+		config, err := loadConfig("config.json")
+		if err != nil {
+			writeAPIInternalError(w, r, err)
+			return
+		}
+		fullDatabases = nil
+		for _, dbName := range request.Databases {
+			if determineBackupType(dbName, config) == "full" {
+				fullDatabases = append(fullDatabases, dbName)
+			} else {
+				incDatabases = append(incDatabases, dbName)
+			}
+		}
+	}
+
+	LogInfo("API backup request received - Mode: %s, Databases: %v, RequestedBy: %s", request.Mode, request.Databases, currentUsername(r))
+
+	jobs := []StartedBackupJob{}
+	if len(fullDatabases) > 0 {
+		jobID := GenerateJobID()
+		response := StartFullBackup(BackupFullRequest{
+			JobID:       jobID,
+			Databases:   fullDatabases,
+			BackupMode:  request.Mode,
+			RequestedBy: currentUsername(r),
+			OnConflict:  request.OnConflict,
+		})
+		auditBackupStart(r, jobID, request.Mode, fullDatabases, response.Success, response.Message)
+		jobs = append(jobs, StartedBackupJob{JobID: jobID, Type: "full", Databases: fullDatabases, Accepted: response.Success, Message: response.Message})
+	}
+	if len(incDatabases) > 0 {
+		jobID := GenerateJobID()
+		response := StartIncBackup(BackupIncRequest{
+			JobID:       jobID,
+			Databases:   incDatabases,
+			BackupMode:  request.Mode,
+			RequestedBy: currentUsername(r),
+			OnConflict:  request.OnConflict,
+		})
+		auditBackupStart(r, jobID, request.Mode, incDatabases, response.Success, response.Message)
+		jobs = append(jobs, StartedBackupJob{JobID: jobID, Type: "incremental", Databases: incDatabases, Accepted: response.Success, Message: response.Message})
+	}
+
+	// Accepted when at least one job made it into the queue
+	for _, job := range jobs {
+		if job.Accepted {
+			writeAPIJSON(w, http.StatusAccepted, StartBackupResponse{Data: jobs})
+			return
+		}
+	}
+	writeAPIError(w, http.StatusConflict, APIErrConflict, jobs[0].Message)
+}
+
+// handleAPIv1CancelBackup cancels a queued or running job
+func handleAPIv1CancelBackup(w http.ResponseWriter, r *http.Request) {
+	jobID := r.PathValue("job_id")
+
+	var request CancelBackupRequest
+	if err := decodeAPIBody(r, &request, true); err != nil {
+		writeAPIError(w, http.StatusBadRequest, APIErrBadRequest, err.Error())
+		return
+	}
+
+	job := GetQueuedJob(jobID)
+	if job == nil {
+		run, err := GetBackupRun(jobID)
+		if err != nil {
+			writeAPIInternalError(w, r, err)
+			return
+		}
+		if run == nil {
+			writeAPIError(w, http.StatusNotFound, APIErrNotFound, fmt.Sprintf("Backup run %s not found", jobID))
+			return
+		}
+		writeAPIError(w, http.StatusConflict, APIErrConflict, fmt.Sprintf("Backup run %s is %s, not queued or running", jobID, run.State))
+		return
+	}
+
+	if request.Database != "" && len(job.Databases) > 0 && !slices.Contains(job.Databases, request.Database) {
+		writeAPIFieldErrors(w, map[string]string{"database": fmt.Sprintf("job %s does not back up database %s", jobID, request.Database)})
+		return
+	}
+
+	message, killedCount := cancelQueuedJob(r, job, request.Database)
+	writeAPIJSON(w, http.StatusOK, CancelBackupResponse{Data: CancelBackupResult{
+		JobID:           jobID,
+		Database:        request.Database,
+		Message:         message,
+		KilledProcesses: killedCount,
+	}})
+}
+
+// handleAPIv1ListJobs returns a page of the running and queued jobs
+func handleAPIv1ListJobs(w http.ResponseWriter, r *http.Request) {
+	fields := map[string]string{}
+	limit, offset := parseAPIPagination(r, fields)
+	if len(fields) > 0 {
+		writeAPIFieldErrors(w, fields)
+		return
+	}
+
+	pending, running := QueuedJobs()
+	jobs := append(running, pending...)
+	start, end := pageBounds(len(jobs), limit, offset)
+
+	writeAPIJSON(w, http.StatusOK, QueuedJobList{Data: jobs[start:end], Pagination: newPagination(limit, offset, len(jobs))})
+}
+
+// handleAPIv1DatabaseHealth returns a page of the database backup health
+func handleAPIv1DatabaseHealth(w http.ResponseWriter, r *http.Request) {
+	fields := map[string]string{}
+	limit, offset := parseAPIPagination(r, fields)
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", RPOStatusOK, RPOStatusWarning, RPOStatusBreached, RPOStatusNever, RPOStatusUnmonitored:
+	default:
+		fields["status"] = "must be ok, warning, breached, never or unmonitored"
+	}
+	if len(fields) > 0 {
+		writeAPIFieldErrors(w, fields)
+		return
+	}
+
+	config, err := loadConfig("config.json")
+	if err != nil {
+		writeAPIInternalError(w, r, err)
+		return
+	}
+	health, err := GetBackupHealth(config)
+	if err != nil {
+		writeAPIInternalError(w, r, err)
+		return
+	}
+
+	databases := []DatabaseHealth{}
+	for _, entry := range health {
+		if status == "" || entry.Status == status {
+			databases = append(databases, entry)
+		}
+	}
+	start, end := pageBounds(len(databases), limit, offset)
+
+	writeAPIJSON(w, http.StatusOK, DatabaseHealthList{Data: databases[start:end], Pagination: newPagination(limit, offset, len(databases))})
+}
+
+// handleAPIv1ListAudit returns a page of audit entries
+func handleAPIv1ListAudit(w http.ResponseWriter, r *http.Request) {
+	fields := map[string]string{}
+	limit, offset := parseAPIPagination(r, fields)
+	for _, name := range []string{"from", "to"} {
+		if value := r.URL.Query().Get(name); value != "" {
+			if _, err := time.Parse("2006-01-02", value); err != nil {
+				fields[name] = "must be a date, YYYY-MM-DD"
+			}
+		}
+	}
+	filter, _ := parseAuditFilter(r) // Dates were checked above
+	if outcome := filter.Outcome; outcome != "" && outcome != AuditOutcomeSuccess && outcome != AuditOutcomeFailure {
+		fields["outcome"] = "must be success or failure"
+	}
+	if len(fields) > 0 {
+		writeAPIFieldErrors(w, fields)
+		return
+	}
+
+	filter.Limit, filter.Offset = limit, offset
+	entries, total, err := GetAuditEntries(filter)
+	if err != nil {
+		writeAPIInternalError(w, r, err)
+		return
+	}
+
+	writeAPIJSON(w, http.StatusOK, AuditEntryList{Data: entries, Pagination: newPagination(limit, offset, total)})
+}
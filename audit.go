@@ -0,0 +1,393 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Audit actions, grouped by the prefix before the dot
+const (
+	AuditLogin            = "auth.login"
+	AuditLoginFailed      = "auth.login_failed"
+	AuditLogout           = "auth.logout"
+	AuditSettingsSave     = "settings.save"
+	AuditSettingsReset    = "settings.reset"
+	AuditSettingsRollback = "settings.rollback"
+	AuditBackupStart      = "backup.start"
+	AuditBackupStop       = "backup.stop"
+	AuditBackupCancel     = "backup.cancel"
+	AuditBackupRetry      = "backup.retry"
+	AuditBackupResume     = "backup.resume"
+	AuditBackupDelete     = "backup.delete"
+	AuditBackupDownload   = "backup.download"
+	AuditHistoryClear     = "history.clear"
+	AuditOptimizeStart    = "optimize.start"
+	AuditOptimizeStop     = "optimize.stop"
+	AuditLogDelete        = "log.delete"
+	AuditServiceRestart   = "service.restart"
+	AuditUserCreate       = "user.create"
+	AuditUserUpdate       = "user.update"
+	AuditUserDelete       = "user.delete"
+	AuditUserPassword     = "user.password_change"
+	AuditUserReset2FA     = "user.reset_2fa"
+	AuditUser2FAEnable    = "user.2fa_enable"
+	AuditUser2FADisable   = "user.2fa_disable"
+	AuditUser2FARecovery  = "user.2fa_recovery_codes"
+	AuditTokenCreate      = "token.create"
+	AuditTokenRevoke      = "token.revoke"
+	AuditExport           = "audit.export"
+)
+
+const (
+	AuditOutcomeSuccess  = "success"
+	AuditOutcomeFailure  = "failure"
+	auditSystemActor     = "system"
+	auditRedacted        = "[redacted]"
+	auditExportMaxRows   = 100000
+	auditDefaultPageSize = 50
+	auditMaxPageSize     = 500
+)
+
+// auditActions lists the actions offered as filters on the audit page
+var auditActions = []string{
+	AuditLogin, AuditLoginFailed, AuditLogout,
+	AuditSettingsSave, AuditSettingsReset, AuditSettingsRollback,
+	AuditBackupStart, AuditBackupStop, AuditBackupCancel, AuditBackupRetry, AuditBackupResume,
+	AuditBackupDelete, AuditBackupDownload, AuditHistoryClear,
+	AuditOptimizeStart, AuditOptimizeStop, AuditLogDelete, AuditServiceRestart,
+	AuditUserCreate, AuditUserUpdate, AuditUserDelete, AuditUserPassword, AuditUserReset2FA,
+	AuditUser2FAEnable, AuditUser2FADisable, AuditUser2FARecovery, AuditTokenCreate, AuditTokenRevoke,
+	AuditExport,
+}
+
+// AuditEntry is one row of the append-only audit_log table. Before and After
+// hold JSON, or are empty when the action has no state to compare.
+type AuditEntry struct {
+	ID        int64  `json:"id"`
+	CreatedAt string `json:"created_at"`
+	Actor     string `json:"actor"`
+	ActorIP   string `json:"actor_ip"`
+	Action    string `json:"action"`
+	Target    string `json:"target"`
+	Outcome   string `json:"outcome"`
+	Before    string `json:"before"`
+	After     string `json:"after"`
+	Details   string `json:"details"`
+}
+
+// AuditFilter selects audit entries. Empty fields match everything.
+type AuditFilter struct {
+	Actor   string // Substring of the actor
+	Action  string // Exact action, or a group such as "backup"
+	Target  string // Substring of the target
+	Outcome string
+	From    time.Time
+	To      time.Time
+	Limit   int
+	Offset  int
+}
+
+// auditValue renders a before/after value as stored in the table
+func auditValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(data)
+}
+
+// recordAudit appends a successful action of the request's user
+func recordAudit(r *http.Request, action, target string, before, after interface{}) {
+	recordAuditEntry(r, AuditEntry{
+		Action:  action,
+		Target:  target,
+		Outcome: AuditOutcomeSuccess,
+		Before:  auditValue(before),
+		After:   auditValue(after),
+	})
+}
+
+// recordAuditFailure appends a failed or refused action of the request's user
+func recordAuditFailure(r *http.Request, action, target, details string) {
+	recordAuditEntry(r, AuditEntry{
+		Action:  action,
+		Target:  target,
+		Outcome: AuditOutcomeFailure,
+		Details: details,
+	})
+}
+
+// recordAuditEntry fills in the actor and client IP from the request, when
+// there is one, and appends the entry. Without a request the actor is system.
+// Failures are logged, the audited action itself is not undone.
+func recordAuditEntry(r *http.Request, entry AuditEntry) {
+	if r != nil {
+		if entry.Actor == "" {
+			entry.Actor = currentUsername(r)
+		}
+		entry.ActorIP = clientIP(r)
+	}
+	if entry.Actor == "" {
+		entry.Actor = auditSystemActor
+	}
+	if entry.Outcome == "" {
+		entry.Outcome = AuditOutcomeSuccess
+	}
+
+	if err := InsertAuditEntry(&entry); err != nil {
+		LogError("❌ [AUDIT] Failed to record %s of %s by %s: %v", entry.Action, entry.Target, entry.Actor, err)
+	}
+}
+
+// auditSecretField reports whether a config field holds a secret whose value
+// must not be copied into the audit log
+func auditSecretField(path string) bool {
+	name := path[strings.LastIndex(path, ".")+1:]
+	for _, marker := range []string{"password", "secret", "token", "_hash"} {
+		if strings.Contains(name, marker) {
+			return true
+		}
+	}
+	// Custom webhook headers usually carry an Authorization value
+	if path == "notification.webhook.headers" || strings.HasPrefix(path, "notification.webhook.headers.") {
+		return true
+	}
+	// Webhook URLs carry their credentials in the path
+	return strings.HasSuffix(name, "webhook_url") || path == "notification.webhook.url"
+}
+
+// flattenConfig turns a config into dotted field paths and their values.
+// Lists of objects, such as policies, are flattened by index so that every
+// field of an entry is a path of its own, e.g. policies.0.slack_webhook_url.
+func flattenConfig(prefix string, value interface{}, fields map[string]interface{}) {
+	join := func(key string) string {
+		if prefix == "" {
+			return key
+		}
+		return prefix + "." + key
+	}
+
+	switch value := value.(type) {
+	case map[string]interface{}:
+		if len(value) > 0 {
+			for key, child := range value {
+				flattenConfig(join(key), child, fields)
+			}
+			return
+		}
+	case []interface{}:
+		if len(value) > 0 {
+			if _, objects := value[0].(map[string]interface{}); objects {
+				for i, child := range value {
+					flattenConfig(join(strconv.Itoa(i)), child, fields)
+				}
+				return
+			}
+		}
+	}
+	fields[prefix] = value
+}
+
+// auditConfigChanges returns the changed config fields with their old and
+// new values. Secrets are redacted, so only the fact that they changed shows.
+func auditConfigChanges(before, after *Config) (map[string]interface{}, map[string]interface{}) {
+	flatten := func(config *Config) map[string]interface{} {
+		fields := make(map[string]interface{})
+		if config == nil {
+			return fields
+		}
+		data, _ := json.Marshal(config)
+		var object interface{}
+		json.Unmarshal(data, &object)
+		flattenConfig("", object, fields)
+		return fields
+	}
+
+	oldFields, newFields := flatten(before), flatten(after)
+	changedBefore := make(map[string]interface{})
+	changedAfter := make(map[string]interface{})
+	for path := range mergeKeys(oldFields, newFields) {
+		oldValue, newValue := oldFields[path], newFields[path]
+		if reflect.DeepEqual(oldValue, newValue) {
+			continue
+		}
+		if auditSecretField(path) {
+			oldValue, newValue = auditRedacted, auditRedacted
+		}
+		changedBefore[path] = oldValue
+		changedAfter[path] = newValue
+	}
+	return changedBefore, changedAfter
+}
+
+// mergeKeys returns the union of the keys of two maps
+func mergeKeys(a, b map[string]interface{}) map[string]bool {
+	keys := make(map[string]bool, len(a)+len(b))
+	for key := range a {
+		keys[key] = true
+	}
+	for key := range b {
+		keys[key] = true
+	}
+	return keys
+}
+
+// parseAuditFilter reads the filter query parameters of the audit endpoints
+func parseAuditFilter(r *http.Request) (AuditFilter, error) {
+	query := r.URL.Query()
+	filter := AuditFilter{
+		Actor:   strings.TrimSpace(query.Get("actor")),
+		Action:  strings.TrimSpace(query.Get("action")),
+		Target:  strings.TrimSpace(query.Get("target")),
+		Outcome: query.Get("outcome"),
+	}
+
+	parseDay := func(name string) (time.Time, error) {
+		value := query.Get(name)
+		if value == "" {
+			return time.Time{}, nil
+		}
+		day, err := time.ParseInLocation("2006-01-02", value, time.Local)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid %s date %q, expected YYYY-MM-DD", name, value)
+		}
+		return day, nil
+	}
+	var err error
+	if filter.From, err = parseDay("from"); err != nil {
+		return filter, err
+	}
+	if filter.To, err = parseDay("to"); err != nil {
+		return filter, err
+	}
+	if !filter.To.IsZero() {
+		filter.To = filter.To.AddDate(0, 0, 1) // Include the whole "to" day
+	}
+
+	filter.Limit, _ = strconv.Atoi(query.Get("limit"))
+	if filter.Limit <= 0 {
+		filter.Limit = auditDefaultPageSize
+	}
+	filter.Limit = min(filter.Limit, auditMaxPageSize)
+	filter.Offset, _ = strconv.Atoi(query.Get("offset"))
+	filter.Offset = max(filter.Offset, 0)
+	return filter, nil
+}
+
+// handleAudit renders the audit log page
+func handleAudit(w http.ResponseWriter, r *http.Request) {
+	renderTemplate(w, "audit.html", map[string]interface{}{
+		"Title":   "Audit Log - MariaDB Backup Tool",
+		"Actions": auditActions,
+	})
+}
+
+// handleListAudit returns a page of audit entries, newest first
+func handleListAudit(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	filter, err := parseAuditFilter(r)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	entries, total, err := GetAuditEntries(filter)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   "Failed to get audit log: " + err.Error(),
+		})
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"entries": entries,
+		"total":   total,
+		"limit":   filter.Limit,
+		"offset":  filter.Offset,
+	})
+}
+
+// csvSafe keeps spreadsheet applications from running a cell as a formula
+func csvSafe(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// handleExportAudit downloads the filtered audit log as CSV or JSON
+func handleExportAudit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format != "csv" && format != "json" {
+		http.Error(w, "format must be csv or json", http.StatusBadRequest)
+		return
+	}
+
+	filter, err := parseAuditFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filter.Limit, filter.Offset = auditExportMaxRows, 0
+
+	entries, _, err := GetAuditEntries(filter)
+	if err != nil {
+		http.Error(w, "Failed to get audit log: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	// Oldest first reads naturally in a file
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].ID < entries[j].ID })
+
+	recordAudit(r, AuditExport, format, nil, map[string]interface{}{"rows": len(entries)})
+
+	fileName := fmt.Sprintf("audit_%s.%s", time.Now().Format("20060102_150405"), format)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", fileName))
+
+	if format == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(entries)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"id", "created_at", "actor", "actor_ip", "action", "target", "outcome", "before", "after", "details"})
+	for _, entry := range entries {
+		writer.Write([]string{
+			strconv.FormatInt(entry.ID, 10), entry.CreatedAt, csvSafe(entry.Actor), entry.ActorIP, entry.Action,
+			csvSafe(entry.Target), entry.Outcome, csvSafe(entry.Before), csvSafe(entry.After), csvSafe(entry.Details),
+		})
+	}
+	writer.Flush()
+}
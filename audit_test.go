@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestAuditConfigChangesRedactsSecrets(t *testing.T) {
+	before := &Config{
+		Policies: []BackupPolicy{
+			{Name: "nightly", Schedule: "0 2 * * *", SlackWebhookURL: "https://hooks.slack.com/services/T000/B000/policy-secret"},
+		},
+	}
+	before.Notification.Webhook.Headers = map[string]string{"Authorization": "Bearer header-secret"}
+
+	after := &Config{
+		Policies: []BackupPolicy{
+			{Name: "nightly", Schedule: "0 3 * * *", SlackWebhookURL: "https://hooks.slack.com/services/T000/B000/new-policy-secret"},
+			{Name: "hourly", Schedule: "0 * * * *", SlackWebhookURL: "https://hooks.slack.com/services/T000/B000/added-secret"},
+		},
+	}
+	after.Notification.Webhook.Headers = map[string]string{"Authorization": "Bearer new-header-secret", "X-Team": "dba"}
+
+	changedBefore, changedAfter := auditConfigChanges(before, after)
+
+	for _, changes := range []map[string]interface{}{changedBefore, changedAfter} {
+		data, _ := json.Marshal(changes)
+		if strings.Contains(string(data), "secret") {
+			t.Errorf("audit values contain a secret: %s", data)
+		}
+	}
+
+	for field, want := range map[string]interface{}{
+		"policies.0.schedule":                        "0 3 * * *",
+		"policies.0.slack_webhook_url":               auditRedacted,
+		"policies.1.name":                            "hourly",
+		"policies.1.slack_webhook_url":               auditRedacted,
+		"notification.webhook.headers.Authorization": auditRedacted,
+		"notification.webhook.headers.X-Team":        auditRedacted,
+	} {
+		if changedAfter[field] != want {
+			t.Errorf("%s = %v, want %v", field, changedAfter[field], want)
+		}
+	}
+	if _, changed := changedAfter["policies.0.name"]; changed {
+		t.Errorf("unchanged policies.0.name reported as changed")
+	}
+}
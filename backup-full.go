@@ -23,6 +23,8 @@ type BackupFullRequest struct {
 	Databases   []string `json:"databases"`
 	BackupMode  string   `json:"backup_mode"`
 	RequestedBy string   `json:"requested_by"`
+	Policy      string   `json:"policy"`
+	OnConflict  string   `json:"on_conflict"` // wait (default) or skip
 }
 
 // BackupFullResponse represents the response after starting backup
@@ -40,8 +42,8 @@ type BackupFullResponse struct {
 // StartFullBackup starts a manual full backup process
 func StartFullBackup(request BackupFullRequest) BackupFullResponse {
 	dbList := formatDatabaseList(request.Databases)
-	LogInfo("🚀 [BACKUP-START] Starting manual full backup - JobID: %s, Databases: %s, Mode: %s, RequestedBy: %s",
-		request.JobID, dbList, request.BackupMode, request.RequestedBy)
+	LogInfo("🚀 [BACKUP-START] Starting manual full backup - JobID: %s, Databases: %s, Mode: %s, RequestedBy: %s, Policy: %s",
+		request.JobID, dbList, request.BackupMode, request.RequestedBy, request.Policy)
 
 	// Reset global abort flag when starting new backup
 	ResetGlobalBackupAbort()
@@ -55,6 +57,9 @@ func StartFullBackup(request BackupFullRequest) BackupFullResponse {
 		}
 	}
 
+	// Apply per-policy overrides (compression, retention, notifications)
+	config = applyBackupPolicy(config, request.Policy)
+
 	if len(request.Databases) == 0 {
 		LogWarn("⚠️ [VALIDATION] No databases specified for backup")
 		return BackupFullResponse{
@@ -64,20 +69,40 @@ func StartFullBackup(request BackupFullRequest) BackupFullResponse {
 	}
 	LogDebug("✅ [VALIDATION] Database list validated - Count: %d", len(request.Databases))
 
-	err = CreateBackupSummary(request.JobID, request.BackupMode, len(request.Databases))
+	job := &QueuedJob{
+		JobID:       request.JobID,
+		Type:        "full",
+		BackupMode:  request.BackupMode,
+		Databases:   request.Databases,
+		RequestedBy: request.RequestedBy,
+		Policy:      request.Policy,
+		run: func() {
+			if err := CreateBackupSummary(request.JobID, request.BackupMode, "full", request.Policy, request.RequestedBy, request.Databases); err != nil {
+				LogError("❌ [SQLITE-ERROR] Failed to create backup summary: %v", err)
+				return
+			}
+			executeFullBackup(request, config)
+		},
+	}
+
+	started, err := EnqueueJob(job, request.OnConflict)
 	if err != nil {
-		LogError("❌ [SQLITE-ERROR] Failed to create backup summary: %v", err)
+		LogWarn("⚠️ [QUEUE] %v", err)
 		return BackupFullResponse{
 			Success: false,
-			Message: "Failed to create backup summary in database",
+			Message: err.Error(),
+			JobID:   request.JobID,
 		}
 	}
 
-	go executeFullBackup(request, config)
+	message := "Backup process started successfully"
+	if !started {
+		message = "Backup queued, waiting for running jobs to finish"
+	}
 
 	response := BackupFullResponse{
 		Success: true,
-		Message: "Backup process started successfully",
+		Message: message,
 		JobID:   request.JobID,
 	}
 	response.Details.BackupMode = request.BackupMode
@@ -116,20 +141,14 @@ func RetryFailedBackups(jobID string) error {
 
 	LogInfo("🔄 [RETRY] Found %d failed databases to retry", len(failedJobs))
 
-	// Get backup mode from summary
-	backupMode := summary["backup_mode"].(string)
-
-	// Reset failed jobs to running status
-	err = ResetFailedBackupJobs(jobID)
-	if err != nil {
-		return fmt.Errorf("failed to reset backup jobs: %v", err)
+	// The summary stays completed until the retry starts, so check the queue
+	// to reject a second retry of the same job
+	if GetQueuedJob(jobID) != nil {
+		return fmt.Errorf("a retry of job %s is already queued", jobID)
 	}
 
-	// Reset backup summary state to running and update failed count
-	err = ResetBackupSummaryToRunning(jobID, 0)
-	if err != nil {
-		return fmt.Errorf("failed to reset backup summary: %v", err)
-	}
+	// Get backup mode from summary
+	backupMode := summary["backup_mode"].(string)
 
 	// Extract database names from failed jobs
 	var databases []string
@@ -144,22 +163,53 @@ func RetryFailedBackups(jobID string) error {
 		return fmt.Errorf("failed to load config for retry: %v", err)
 	}
 
+	// Retry with the same policy that produced the original job
+	policy, _ := summary["policy"].(string)
+	retryConfig = applyBackupPolicy(retryConfig, policy)
+
 	// Create backup request for retry
 	retryRequest := BackupFullRequest{
 		JobID:       jobID,
 		Databases:   databases,
 		BackupMode:  backupMode,
 		RequestedBy: "retry",
+		Policy:      policy,
 	}
 
-	// Execute retry backup in a goroutine
-	go executeFullBackup(retryRequest, retryConfig)
+	// Execute retry backup through the job queue
+	job := &QueuedJob{
+		JobID:       jobID,
+		Type:        "retry",
+		BackupMode:  backupMode,
+		Databases:   databases,
+		RequestedBy: "retry",
+		Policy:      policy,
+		run: func() {
+			// Reset the failed jobs and the summary only once the retry runs, so
+			// a retry that is rejected or dropped from the queue can be retried again
+			if err := ResetFailedBackupJobs(jobID); err != nil {
+				LogError("❌ [RETRY] Failed to reset backup jobs of %s: %v", jobID, err)
+				return
+			}
+			if err := ResetBackupSummaryToRunning(jobID, 0); err != nil {
+				LogError("❌ [RETRY] Failed to reset backup summary of %s: %v", jobID, err)
+				return
+			}
+			executeFullBackup(retryRequest, retryConfig)
+		},
+	}
+	if _, err := EnqueueJob(job, JobConflictWait); err != nil {
+		return fmt.Errorf("failed to queue retry: %v", err)
+	}
 
-	LogInfo("🔄 [RETRY] Retry started for %d failed databases", len(databases))
+	LogInfo("🔄 [RETRY] Retry queued for %d failed databases", len(databases))
 	return nil
 }
 
 func executeFullBackup(request BackupFullRequest, config *Config) {
+	defer clearBackupCancellation(request.JobID)
+	notifyJobStarted(config, "full", request.JobID, request.BackupMode, request.RequestedBy, request.Policy, request.Databases)
+
 	totalSizeKB := 0
 	totalDiskSizeKB := 0
 	totalFull := 0
@@ -421,8 +471,21 @@ func executeFullBackup(request BackupFullRequest, config *Config) {
 					return
 				}
 
+				// Skip databases cancelled through /api/backup/cancel
+				if IsBackupCancelled(request.JobID, dbName) {
+					LogWarn("🛑 [WORKER-%d] Backup of %s cancelled before it started", workerID, dbName)
+					if err := CancelBackupJob(request.JobID, dbName, "Cancelled by user"); err != nil {
+						LogError("❌ [SQLITE-ERROR] Failed to update job status to cancelled for %s: %v", dbName, err)
+					}
+					<-activeProcesses
+					continue
+				}
+
 				LogDebug("💾 [BACKUP] Worker-%d: Starting mysqldump backup for database: %s", workerID, dbName)
+				backupStarted := time.Now()
 				backupResult := executeDatabaseBackup(dbName, request.JobID, config, mysqlPool)
+				RecordBackupResult(dbName, "full", backupResult.Success, IsBackupCancelled(request.JobID, dbName),
+					time.Since(backupStarted), backupResult.SizeKB)
 
 				LogDebug("🔒 [WORKER-%d]Setting job status to 'optimizing'for %s", workerID, dbName)
 				mu.Lock()
@@ -480,7 +543,12 @@ func executeFullBackup(request BackupFullRequest, config *Config) {
 	// Update MySQL restart time in summary
 	UpdateMySQLRestartTime(request.JobID, int(totalMySQLRestartTime))
 
-	CompleteBackupSummary(request.JobID, config)
+	if IsBackupCancelled(request.JobID, "") {
+		CancelBackupSummary(request.JobID)
+		go SendBackupNotifications(config, request.JobID)
+	} else {
+		CompleteBackupSummary(request.JobID, config)
+	}
 
 	LogInfo("🎉 [BACKUP-COMPLETE] Full backup job %s completed - Total: %d, Successful: %d, Failed: %d, Total Size: %d KB, Total Disk Size: %d KB",
 		request.JobID, len(request.Databases), totalFull, totalFailed, totalSizeKB, totalDiskSizeKB)
@@ -541,6 +609,30 @@ func executeDatabaseBackup(dbName, jobID string, config *Config, mysqlPool *sql.
 		}
 	}
 
+	// Pre-flight: make sure the estimated dump fits on disk before starting
+	estimatedBytes := int64(0)
+	if dbSizeBytes, err := getDatabaseSize(dbName, mysqlPool); err == nil {
+		estimatedBytes = estimateBackupSize(dbSizeBytes, config)
+		LogDebug("📏 [DISK-SPACE] Estimated backup size for %s: %s", dbName, formatBytes(estimatedBytes))
+	} else {
+		LogWarn("⚠️ [DISK-SPACE] Failed to estimate backup size for %s: %v", dbName, err)
+	}
+	if err := reserveBackupSpace(config, dbName, estimatedBytes); err != nil {
+		LogError("❌ [DISK-SPACE] %v", err)
+
+		// Update job status to failed
+		updateErr := CompleteBackupJob(jobID, dbName, false, 0, "", err.Error())
+		if updateErr != nil {
+			LogError("❌ [SQLITE-ERROR] Failed to update job status to failed for %s: %v", dbName, updateErr)
+		}
+
+		return DatabaseBackupResult{
+			Success:      false,
+			ErrorMessage: err.Error(),
+		}
+	}
+	defer releaseBackupSpace(estimatedBytes)
+
 	extension := ".sql"
 	isWindows := runtime.GOOS == "windows"
 	if !isWindows && config.Backup.CompressionLevel > 0 {
@@ -553,6 +645,7 @@ func executeDatabaseBackup(dbName, jobID string, config *Config, mysqlPool *sql.
 	tempFileName := fmt.Sprintf("temp_%s_%s%s", dbName, time.Now().Format("20060102_150405.000000"), extension)
 	tempFilePath := filepath.Join(backupDir, tempFileName)
 	cmd := buildMysqldumpCommand(dbName, tempFilePath, config)
+	setProcessGroup(cmd)
 
 	startTime := time.Now()
 	LogDebug("🚀 [EXECUTE] Starting mysqldump process for %s", dbName)
@@ -582,6 +675,7 @@ func executeDatabaseBackup(dbName, jobID string, config *Config, mysqlPool *sql.
 		}
 	}
 	LogDebug("✅ [EXECUTE] Mysqldump process started successfully (PID: %d)", cmd.Process.Pid)
+	registerBackupProcess(jobID, dbName, tempFilePath, cmd)
 
 	// Get table count for progress tracking
 	LogDebug("📏 [SIZE-INFO] Getting table count for %s", dbName)
@@ -767,10 +861,25 @@ func executeDatabaseBackup(dbName, jobID string, config *Config, mysqlPool *sql.
 	LogDebug("⏳ [EXECUTE] Waiting for mysqldump process to complete for %s", dbName)
 	err = cmd.Wait()
 	duration := time.Since(startTime)
+	unregisterBackupProcess(jobID, dbName)
 
 	// Close the stdout pipe
 	stdout.Close()
 
+	if err != nil && IsBackupCancelled(jobID, dbName) {
+		LogWarn("🛑 [CANCEL] Backup of %s in job %s cancelled after %v", dbName, jobID, duration)
+		removeTempBackupFile(tempFilePath)
+
+		if updateErr := CancelBackupJob(jobID, dbName, "Cancelled by user"); updateErr != nil {
+			LogError("❌ [SQLITE-ERROR] Failed to update job status to cancelled for %s: %v", dbName, updateErr)
+		}
+
+		return DatabaseBackupResult{
+			Success:      false,
+			ErrorMessage: "Cancelled by user",
+		}
+	}
+
 	if err != nil {
 		var errorMessage string
 		if exitError, ok := err.(*exec.ExitError); ok {
@@ -791,13 +900,7 @@ func executeDatabaseBackup(dbName, jobID string, config *Config, mysqlPool *sql.
 		LogError("❌ [EXECUTE-ERROR] Backup command failed for %s after %v: %s", dbName, duration, errorMessage)
 
 		// Clean up temporary file if backup failed
-		if tempFilePath != "" {
-			if removeErr := os.Remove(tempFilePath); removeErr != nil {
-				LogWarn("⚠️ [CLEANUP] Failed to remove temporary file %s: %v", tempFilePath, removeErr)
-			} else {
-				LogDebug("🧹 [CLEANUP] Removed temporary file: %s", tempFilePath)
-			}
-		}
+		removeTempBackupFile(tempFilePath)
 
 		// Update job as failed
 		LogDebug("💾 [SQLITE] Updating job status to failed for %s", dbName)
@@ -856,6 +959,9 @@ func executeDatabaseBackup(dbName, jobID string, config *Config, mysqlPool *sql.
 	if !backupSuccess && errorMessage == "" {
 		errorMessage = "Backup completed with unknown issues"
 	}
+	if !backupSuccess {
+		notifyVerificationFailed(config, jobID, dbName, finalFilePath, errorMessage)
+	}
 
 	// Update job status based on actual result
 	LogDebug("💾 [SQLITE] Updating job status for %s - Success: %v, Error: %s", dbName, backupSuccess, errorMessage)
@@ -1013,8 +1119,8 @@ func buildMysqldumpArgs(dbName string, config *Config) []string {
 	// Add binary path
 	args = append(args, config.Database.BinaryDump)
 
-	// Add connection arguments
-	connArgs := buildMySQLConnectionArgs(config)
+	// Add connection arguments, quoted for the sh -c command line
+	connArgs := shellQuoteArgs(buildMySQLConnectionArgs(config))
 	args = append(args, connArgs...)
 
 	// Add memory limit per process if configured
@@ -1333,6 +1439,8 @@ func restartMySQLServiceWithMonitoring(config *Config, abortBackup *bool) (bool,
 
 	if restartedService == "" {
 		LogError("❌ [MYSQL-RESTART] Failed to restart MySQL service - tried all common service names (mysql, mariadb, mysqld)")
+		notifyMySQLRestart(config, SeverityCritical, "MySQL service restart failed",
+			"Tried systemctl and service for mysql, mariadb and mysqld. Backups are aborted")
 		return false, time.Since(restartStartTime)
 	}
 
@@ -1361,6 +1469,8 @@ func restartMySQLServiceWithMonitoring(config *Config, abortBackup *bool) (bool,
 				restartedService, memoryAfter, memoryBefore)
 			restartDuration := time.Since(restartStartTime)
 			LogInfo("⏱️ [MYSQL-RESTART-TIME] MySQL restart took %v", restartDuration)
+			notifyMySQLRestart(config, SeverityWarning, fmt.Sprintf("MySQL service '%s' restarted due to high memory usage", restartedService),
+				fmt.Sprintf("Memory %.2f%% before, %.2f%% after. Restart took %s", memoryBefore, memoryAfter, formatDurationForSlack(restartDuration)))
 			return true, restartDuration
 		}
 
@@ -1372,6 +1482,8 @@ func restartMySQLServiceWithMonitoring(config *Config, abortBackup *bool) (bool,
 
 	// Service failed to start within 10 minutes
 	LogError("❌ [MYSQL-MONITOR] MySQL service '%s' failed to start within 10 minutes, aborting all backup processes", restartedService)
+	notifyMySQLRestart(config, SeverityCritical, fmt.Sprintf("MySQL service '%s' did not come back after restart", restartedService),
+		"The service was not running 10 minutes after the restart. Backups are aborted")
 	*abortBackup = true
 	return false, time.Since(restartStartTime)
 }
@@ -1559,8 +1671,8 @@ func buildOptimizeArgs(dbName string, config *Config) []string {
 	// Add binary path
 	args = append(args, config.Database.BinaryCheck)
 
-	// Add connection arguments
-	connArgs := buildMySQLConnectionArgs(config)
+	// Add connection arguments, quoted for the sh -c command line
+	connArgs := shellQuoteArgs(buildMySQLConnectionArgs(config))
 	args = append(args, connArgs...)
 
 	// Add optimization options from config
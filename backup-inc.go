@@ -22,6 +22,8 @@ type BackupIncRequest struct {
 	Databases   []string `json:"databases"`
 	BackupMode  string   `json:"backup_mode"`
 	RequestedBy string   `json:"requested_by"`
+	Policy      string   `json:"policy"`
+	OnConflict  string   `json:"on_conflict"` // wait (default) or skip
 }
 
 // BackupIncResponse represents the response after starting incremental backup
@@ -39,8 +41,8 @@ type BackupIncResponse struct {
 // StartIncBackup starts a manual incremental backup process
 func StartIncBackup(request BackupIncRequest) BackupIncResponse {
 	dbList := formatDatabaseList(request.Databases)
-	LogInfo("🚀 [INC-BACKUP-START] Starting manual incremental backup - JobID: %s, Databases: %s, Mode: %s, RequestedBy: %s",
-		request.JobID, dbList, request.BackupMode, request.RequestedBy)
+	LogInfo("🚀 [INC-BACKUP-START] Starting manual incremental backup - JobID: %s, Databases: %s, Mode: %s, RequestedBy: %s, Policy: %s",
+		request.JobID, dbList, request.BackupMode, request.RequestedBy, request.Policy)
 
 	// Reset global abort flag when starting new backup
 	ResetGlobalBackupAbort()
@@ -54,6 +56,9 @@ func StartIncBackup(request BackupIncRequest) BackupIncResponse {
 		}
 	}
 
+	// Apply per-policy overrides (compression, retention, notifications)
+	config = applyBackupPolicy(config, request.Policy)
+
 	if len(request.Databases) == 0 {
 		LogWarn("⚠️ [VALIDATION] No databases specified for incremental backup")
 		return BackupIncResponse{
@@ -70,20 +75,40 @@ func StartIncBackup(request BackupIncRequest) BackupIncResponse {
 
 	LogDebug("✅ [VALIDATION] Database list validated - Count: %d", len(request.Databases))
 
-	err = CreateBackupSummary(request.JobID, request.BackupMode, len(request.Databases))
+	job := &QueuedJob{
+		JobID:       request.JobID,
+		Type:        "incremental",
+		BackupMode:  request.BackupMode,
+		Databases:   request.Databases,
+		RequestedBy: request.RequestedBy,
+		Policy:      request.Policy,
+		run: func() {
+			if err := CreateBackupSummary(request.JobID, request.BackupMode, "incremental", request.Policy, request.RequestedBy, request.Databases); err != nil {
+				LogError("❌ [SQLITE-ERROR] Failed to create backup summary: %v", err)
+				return
+			}
+			executeIncBackup(request, config)
+		},
+	}
+
+	started, err := EnqueueJob(job, request.OnConflict)
 	if err != nil {
-		LogError("❌ [SQLITE-ERROR] Failed to create backup summary: %v", err)
+		LogWarn("⚠️ [QUEUE] %v", err)
 		return BackupIncResponse{
 			Success: false,
-			Message: "Failed to create backup summary in database",
+			Message: err.Error(),
+			JobID:   request.JobID,
 		}
 	}
 
-	go executeIncBackup(request, config)
+	message := "Incremental backup process started successfully"
+	if !started {
+		message = "Incremental backup queued, waiting for running jobs to finish"
+	}
 
 	response := BackupIncResponse{
 		Success: true,
-		Message: "Incremental backup process started successfully",
+		Message: message,
 		JobID:   request.JobID,
 	}
 	response.Details.BackupMode = request.BackupMode
@@ -101,7 +126,14 @@ func convertToFullBackup(request BackupIncRequest) BackupIncResponse {
 	fullRequest := BackupFullRequest(request)
 
 	// Start full backup
-	go StartFullBackup(fullRequest)
+	response := StartFullBackup(fullRequest)
+	if !response.Success {
+		return BackupIncResponse{
+			Success: false,
+			Message: response.Message,
+			JobID:   request.JobID,
+		}
+	}
 
 	return BackupIncResponse{
 		Success: true,
@@ -213,6 +245,9 @@ func getLatestBackupTime(dbName string, config *Config) (time.Time, string, erro
 
 // executeIncBackup executes the incremental backup process
 func executeIncBackup(request BackupIncRequest, config *Config) {
+	defer clearBackupCancellation(request.JobID)
+	notifyJobStarted(config, "incremental", request.JobID, request.BackupMode, request.RequestedBy, request.Policy, request.Databases)
+
 	totalSizeKB := 0
 	totalDiskSizeKB := 0
 	totalInc := 0
@@ -412,8 +447,21 @@ func executeIncBackup(request BackupIncRequest, config *Config) {
 					return
 				}
 
+				// Skip databases cancelled through /api/backup/cancel
+				if IsBackupCancelled(request.JobID, dbName) {
+					LogWarn("🛑 [WORKER-%d] Incremental backup of %s cancelled before it started", workerID, dbName)
+					if err := CancelBackupJob(request.JobID, dbName, "Cancelled by user"); err != nil {
+						LogError("❌ [SQLITE-ERROR] Failed to update job status to cancelled for %s: %v", dbName, err)
+					}
+					<-activeProcesses
+					continue
+				}
+
 				LogDebug("💾 [BACKUP] Worker-%d: Starting mariadb-binlog incremental backup for database: %s", workerID, dbName)
+				backupStarted := time.Now()
 				backupResult := executeIncrementalDatabaseBackup(dbName, request.JobID, config, latestBackupTime, mysqlPool)
+				RecordBackupResult(dbName, "incremental", backupResult.Success, IsBackupCancelled(request.JobID, dbName),
+					time.Since(backupStarted), backupResult.SizeKB)
 
 				LogDebug("🔒 [WORKER-%d] Setting job status to 'running' for %s", workerID, dbName)
 				mu.Lock()
@@ -466,7 +514,12 @@ func executeIncBackup(request BackupIncRequest, config *Config) {
 	// Update MySQL restart time in summary
 	UpdateMySQLRestartTime(request.JobID, int(totalMySQLRestartTime))
 
-	CompleteBackupSummary(request.JobID, config)
+	if IsBackupCancelled(request.JobID, "") {
+		CancelBackupSummary(request.JobID)
+		go SendBackupNotifications(config, request.JobID)
+	} else {
+		CompleteBackupSummary(request.JobID, config)
+	}
 
 	LogInfo("🎉 [BACKUP-COMPLETE] Incremental backup job %s completed - Total: %d, Successful: %d, Failed: %d, Total Size: %d KB, Total Disk Size: %d KB",
 		request.JobID, len(request.Databases), totalInc, totalFailed, totalSizeKB, totalDiskSizeKB)
@@ -523,6 +576,23 @@ func executeIncrementalDatabaseBackup(dbName, jobID string, config *Config, star
 		}
 	}
 
+	// Pre-flight: binlog extracts are small, only keep the minimum free space
+	if err := reserveBackupSpace(config, dbName, 0); err != nil {
+		LogError("❌ [DISK-SPACE] %v", err)
+
+		// Update job status to failed
+		updateErr := CompleteBackupJob(jobID, dbName, false, 0, "", err.Error())
+		if updateErr != nil {
+			LogError("❌ [SQLITE-ERROR] Failed to update job status to failed for %s: %v", dbName, updateErr)
+		}
+
+		return IncrementalDatabaseBackupResult{
+			Success:      false,
+			ErrorMessage: err.Error(),
+		}
+	}
+	defer releaseBackupSpace(0)
+
 	extension := ".sql"
 	isWindows := runtime.GOOS == "windows"
 	if !isWindows && config.Backup.CompressionLevel > 0 {
@@ -535,6 +605,7 @@ func executeIncrementalDatabaseBackup(dbName, jobID string, config *Config, star
 	tempFileName := fmt.Sprintf("temp_inc_%s_%s%s", dbName, time.Now().Format("20060102_150405.000000"), extension)
 	tempFilePath := filepath.Join(backupDir, tempFileName)
 	cmd := buildMariadbBinlogCommand(dbName, tempFilePath, config, startTime, mysqlPool)
+	setProcessGroup(cmd)
 
 	backupStartTime := startTime // Use the calculated start time, not current time
 	LogDebug("🚀 [EXECUTE] Starting mariadb-binlog process for %s", dbName)
@@ -565,6 +636,7 @@ func executeIncrementalDatabaseBackup(dbName, jobID string, config *Config, star
 		}
 	}
 	LogDebug("✅ [EXECUTE] Mariadb-binlog process started successfully (PID: %d)", cmd.Process.Pid)
+	registerBackupProcess(jobID, dbName, tempFilePath, cmd)
 
 	// Start monitoring progress and writing to file
 	go func() {
@@ -661,10 +733,25 @@ func executeIncrementalDatabaseBackup(dbName, jobID string, config *Config, star
 	LogDebug("⏳ [EXECUTE] Waiting for mariadb-binlog process to complete for %s", dbName)
 	err = cmd.Wait()
 	duration := time.Since(backupStartTime)
+	unregisterBackupProcess(jobID, dbName)
 
 	// Close the stdout pipe
 	stdout.Close()
 
+	if err != nil && IsBackupCancelled(jobID, dbName) {
+		LogWarn("🛑 [CANCEL] Incremental backup of %s in job %s cancelled after %v", dbName, jobID, duration)
+		removeTempBackupFile(tempFilePath)
+
+		if updateErr := CancelBackupJob(jobID, dbName, "Cancelled by user"); updateErr != nil {
+			LogError("❌ [SQLITE-ERROR] Failed to update job status to cancelled for %s: %v", dbName, updateErr)
+		}
+
+		return IncrementalDatabaseBackupResult{
+			Success:      false,
+			ErrorMessage: "Cancelled by user",
+		}
+	}
+
 	if err != nil {
 		var errorMessage string
 		if exitError, ok := err.(*exec.ExitError); ok {
@@ -683,13 +770,7 @@ func executeIncrementalDatabaseBackup(dbName, jobID string, config *Config, star
 		LogError("❌ [EXECUTE-ERROR] Incremental backup command failed for %s after %v: %s", dbName, duration, errorMessage)
 
 		// Clean up temporary file if backup failed
-		if tempFilePath != "" {
-			if removeErr := os.Remove(tempFilePath); removeErr != nil {
-				LogWarn("⚠️ [CLEANUP] Failed to remove temporary file %s: %v", tempFilePath, removeErr)
-			} else {
-				LogDebug("🧹 [CLEANUP] Removed temporary file: %s", tempFilePath)
-			}
-		}
+		removeTempBackupFile(tempFilePath)
 
 		// Update job as failed
 		LogDebug("💾 [SQLITE] Updating job status to failed for %s", dbName)
@@ -753,6 +834,9 @@ func executeIncrementalDatabaseBackup(dbName, jobID string, config *Config, star
 	if !backupSuccess && errorMessage == "" {
 		errorMessage = "Incremental backup completed with unknown issues"
 	}
+	if !backupSuccess {
+		notifyVerificationFailed(config, jobID, dbName, finalFilePath, errorMessage)
+	}
 
 	// Update job status based on actual result
 	LogDebug("💾 [SQLITE] Updating job status for %s - Success: %v, Error: %s", dbName, backupSuccess, errorMessage)
@@ -903,8 +987,8 @@ func buildMariadbBinlogArgs(dbName string, config *Config, startTime time.Time,
 	// Add binary path
 	args = append(args, config.Database.BinaryBinLog)
 
-	// Add connection arguments
-	connArgs := buildMySQLConnectionArgs(config)
+	// Add connection arguments, quoted for the sh -c command line
+	connArgs := shellQuoteArgs(buildMySQLConnectionArgs(config))
 	args = append(args, connArgs...)
 
 	// Add database filter
@@ -5,14 +5,27 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
 )
 
 type Config struct {
+	Version      int                `json:"version"` // Config file format, see configMigrations
 	Database     DatabaseConfig     `json:"database"`
 	Backup       BackupConfig       `json:"backup"`
 	Web          WebConfig          `json:"web"`
 	Logging      LoggingConfig      `json:"logging"`
 	Notification NotificationConfig `json:"notification"`
+	Policies     []BackupPolicy     `json:"policies"`
+	Secrets      SecretsConfig      `json:"secrets"`
+
+	secretRefs map[string]secretRef // Secret references as written in the file, by field path
+}
+
+// SecretsConfig controls how secret settings are written to the config file.
+// Any secret setting may also be a reference: env:NAME, file:/path, mycnf:,
+// mycnf:/path or login-path:name.
+type SecretsConfig struct {
+	EncryptAtRest bool `json:"encrypt_at_rest"` // Encrypt secrets entered in the web interface with the machine key
 }
 
 type DatabaseConfig struct {
@@ -24,18 +37,27 @@ type DatabaseConfig struct {
 	BinaryDump   string `json:"binary_dump"`
 	BinaryCheck  string `json:"binary_check"`
 	BinaryBinLog string `json:"binary_binlog"`
+	SSLMode      string `json:"ssl_mode"` // disabled, preferred, required, verify_ca or verify_identity; empty uses the client defaults
+	SSLCA        string `json:"ssl_ca"`   // CA certificate (PEM) to verify the server with
+	SSLCert      string `json:"ssl_cert"` // Client certificate (PEM)
+	SSLKey       string `json:"ssl_key"`  // Client private key (PEM)
 }
 
 type BackupConfig struct {
 	BackupDir            string   `json:"backup_dir"`
 	RetentionBackups     int      `json:"retention_backups"`
 	Parallel             int      `json:"parallel"`
+	MaxConcurrentJobs    int      `json:"max_concurrent_jobs"`
+	ScheduleConflict     string   `json:"schedule_conflict"`
 	FullBackupInterval   int      `json:"full_backup_interval"`
-	BackupIntervalHours  int      `json:"backup_interval_hours"`
-	BackupStartTime      string   `json:"backup_start_time"`
+	BackupSchedule       string   `json:"backup_schedule"`
+	MissedRunPolicy      string   `json:"missed_run_policy"`
+	BackupIntervalHours  int      `json:"backup_interval_hours,omitempty"` // Deprecated: migrated to BackupSchedule
+	BackupStartTime      string   `json:"backup_start_time,omitempty"`     // Deprecated: migrated to BackupSchedule
 	CompressionLevel     int      `json:"compression_level"`
 	NiceLevel            int      `json:"nice_level"`
 	IgnoreDbs            []string `json:"ignore_dbs"`
+	IncludeDbs           []string `json:"include_dbs"`
 	DefaultBackupMode    string   `json:"default_backup_mode"`
 	OptimizeTables       bool     `json:"optimize_tables"`
 	MaxMemoryThreshold   int      `json:"max_memory_threshold"`
@@ -44,15 +66,77 @@ type BackupConfig struct {
 	MysqldumpOptions     string   `json:"mysqldump_options"`
 	MariadbCheckOptions  string   `json:"mariadb_check_options"`
 	MariadbBinlogOptions string   `json:"mariadb_binlog_options"`
+
+	GFSRetention        GFSRetention   `json:"gfs_retention"`
+	KeepLastFulls       int            `json:"keep_last_fulls"`        // 0 = no limit
+	MaxDatabaseBackupMB int            `json:"max_database_backup_mb"` // 0 = no limit
+	MaxTotalBackupMB    int            `json:"max_total_backup_mb"`    // 0 = no limit
+	CompressionRatio    float64        `json:"compression_ratio"`      // Expected compressed dump size / database size
+	MinFreeSpaceMB      int            `json:"min_free_space_mb"`
+	LowSpaceAction      string         `json:"low_space_action"`   // refuse or prune
+	OrphanTempAction    string         `json:"orphan_temp_action"` // delete or quarantine
+	ResumeInterrupted   bool           `json:"resume_interrupted"` // Resume interrupted jobs on startup
+	ShutdownTimeout     int            `json:"shutdown_timeout"`   // Seconds to wait for running jobs on shutdown
+	RPOHours            int            `json:"rpo_hours"`          // Maximum age of the last successful backup, 0 = not monitored
+	DatabaseRPO         map[string]int `json:"database_rpo"`       // RPO hours per database pattern, overrides policies and rpo_hours
+}
+
+// GFSRetention keeps the newest full backup (with its incremental chain) of the
+// last Daily days, Weekly weeks, Monthly months and Yearly years
+type GFSRetention struct {
+	Enabled bool `json:"enabled"`
+	Daily   int  `json:"daily"`
+	Weekly  int  `json:"weekly"`
+	Monthly int  `json:"monthly"`
+	Yearly  int  `json:"yearly"`
 }
 
 type WebConfig struct {
 	Port         int    `json:"port"`
-	AuthUser     string `json:"auth_user"`
-	AuthPassHash string `json:"auth_pass_hash"`
+	AuthUser     string `json:"auth_user"`      // Initial admin, created in the users table on first start
+	AuthPassHash string `json:"auth_pass_hash"` // Password hash of the initial admin
 	SSLEnabled   bool   `json:"ssl_enabled"`
 	SSLCertFile  string `json:"ssl_cert_file"`
 	SSLKeyFile   string `json:"ssl_key_file"`
+
+	MetricsEnabled   bool   `json:"metrics_enabled"`
+	MetricsTokenHash string `json:"metrics_token_hash"` // SHA-256 of the /metrics bearer token, empty = no auth
+
+	OIDC       OIDCConfig `json:"oidc"`
+	LDAP       LDAPConfig `json:"ldap"`
+	LocalLogin string     `json:"local_login"` // all (default) or admins: with SSO on, only local admins keep password login as break-glass
+}
+
+// OIDCConfig configures login through an OpenID Connect provider using the
+// authorization code flow with PKCE
+type OIDCConfig struct {
+	Enabled       bool              `json:"enabled"`
+	IssuerURL     string            `json:"issuer_url"`
+	ClientID      string            `json:"client_id"`
+	ClientSecret  string            `json:"client_secret"`  // Empty for public clients
+	RedirectURL   string            `json:"redirect_url"`   // Empty derives https://<host>/auth/oidc/callback from the request
+	Scopes        []string          `json:"scopes"`         // Default openid, profile, email
+	UsernameClaim string            `json:"username_claim"` // Default preferred_username
+	GroupsClaim   string            `json:"groups_claim"`   // Default groups
+	GroupRoles    map[string]string `json:"group_roles"`    // Group name to role, the highest matching role wins
+	DefaultRole   string            `json:"default_role"`   // Role of users in no mapped group, empty refuses them
+}
+
+// LDAPConfig configures password login with an LDAP bind
+type LDAPConfig struct {
+	Enabled            bool              `json:"enabled"`
+	URL                string            `json:"url"` // ldap://host:389 or ldaps://host:636
+	StartTLS           bool              `json:"start_tls"`
+	InsecureSkipVerify bool              `json:"insecure_skip_verify"`
+	BindDN             string            `json:"bind_dn"` // Service account that searches users, empty searches anonymously
+	BindPassword       string            `json:"bind_password"`
+	UserBaseDN         string            `json:"user_base_dn"`
+	UserFilter         string            `json:"user_filter"`     // Default (uid={username})
+	GroupBaseDN        string            `json:"group_base_dn"`   // Empty reads the memberOf attribute of the user instead
+	GroupFilter        string            `json:"group_filter"`    // Default (member={dn}), {username} is also replaced
+	GroupAttribute     string            `json:"group_attribute"` // Attribute holding the group name, default cn
+	GroupRoles         map[string]string `json:"group_roles"`     // Group name or DN to role, the highest matching role wins
+	DefaultRole        string            `json:"default_role"`    // Role of users in no mapped group, empty refuses them
 }
 
 type LoggingConfig struct {
@@ -62,6 +146,52 @@ type LoggingConfig struct {
 
 type NotificationConfig struct {
 	SlackWebhookURL string `json:"slack_webhook_url"`
+
+	Webhook  WebhookNotifierConfig  `json:"webhook"`
+	Email    EmailNotifierConfig    `json:"email"`
+	Teams    TeamsNotifierConfig    `json:"teams"`
+	Discord  DiscordNotifierConfig  `json:"discord"`
+	Telegram TelegramNotifierConfig `json:"telegram"`
+
+	Rules        []NotificationRule `json:"rules"`         // Empty uses the default rules
+	QuietHours   string             `json:"quiet_hours"`   // e.g. 22:00-07:00, only critical events are sent
+	DedupMinutes int                `json:"dedup_minutes"` // Identical events are sent once per window, 0 uses 60
+	DigestTime   string             `json:"digest_time"`   // When digest rules send their events, default 08:00
+}
+
+// WebhookNotifierConfig posts the backup summary as JSON to any URL
+type WebhookNotifierConfig struct {
+	Enabled      bool              `json:"enabled"`
+	URL          string            `json:"url"`
+	Headers      map[string]string `json:"headers"`
+	BodyTemplate string            `json:"body_template"` // Go text/template, empty sends the default JSON body
+}
+
+type EmailNotifierConfig struct {
+	Enabled  bool     `json:"enabled"`
+	SMTPHost string   `json:"smtp_host"`
+	SMTPPort int      `json:"smtp_port"`
+	Username string   `json:"username"`
+	Password string   `json:"password"`
+	TLSMode  string   `json:"tls_mode"` // starttls (default), tls or none
+	From     string   `json:"from"`
+	To       []string `json:"to"`
+}
+
+type TeamsNotifierConfig struct {
+	Enabled    bool   `json:"enabled"`
+	WebhookURL string `json:"webhook_url"`
+}
+
+type DiscordNotifierConfig struct {
+	Enabled    bool   `json:"enabled"`
+	WebhookURL string `json:"webhook_url"`
+}
+
+type TelegramNotifierConfig struct {
+	Enabled  bool   `json:"enabled"`
+	BotToken string `json:"bot_token"`
+	ChatID   string `json:"chat_id"`
 }
 
 func loadConfig(configFile string) (*Config, error) {
@@ -75,16 +205,87 @@ func loadConfig(configFile string) (*Config, error) {
 		return nil, fmt.Errorf("failed to read config file: %v", err)
 	}
 
+	config, err := parseConfig(data)
+	if err != nil {
+		return nil, err
+	}
+
+	restrictConfigPermissions(configFile)
+	if err := resolveConfigSecrets(config, configFile); err != nil {
+		LogWarn("🔐 [SECRETS] Some secrets in %s could not be resolved: %v", configFile, err)
+	}
+
+	fileVersion := config.Version
+	applied, err := migrateConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to migrate config file: %v", err)
+	}
+	if len(applied) > 0 {
+		for _, migration := range applied {
+			LogInfo("Migrated config %s to %s", configFile, migration)
+		}
+		if backupFile, err := backupConfigFile(configFile, data, fileVersion); err != nil {
+			LogWarn("Failed to keep a copy of the config before migrating it: %v", err)
+		} else if err := saveConfig(config, configFile); err != nil {
+			LogWarn("Failed to save migrated configuration: %v", err)
+		} else {
+			LogInfo("Config %s migrated from version %d to %d, the previous file is kept as %s", configFile, fileVersion, config.Version, backupFile)
+		}
+	}
+
+	// The parsed config comes with the errors so the settings page can fix it
+	if errs := validateConfig(config); errs != nil {
+		return config, fmt.Errorf("invalid config file %s: %w", configFile, errs)
+	}
+
+	return config, nil
+}
+
+// parseConfig decodes a config file
+func parseConfig(data []byte) (*Config, error) {
 	var config Config
 	if err := json.Unmarshal(data, &config); err != nil {
 		return nil, fmt.Errorf("failed to parse config file: %v", err)
 	}
-
 	return &config, nil
 }
 
+// legacyFallbackStartTime replaces a backup_start_time that cannot be parsed
+const legacyFallbackStartTime = "00:00"
+
+// migrateLegacySchedule converts backup_start_time/backup_interval_hours into a
+// cron expression. Returns true when the config was changed. When the start
+// time cannot be parsed, the interval is kept and counted from midnight, so an
+// upgraded install still starts and keeps backing up as often as before.
+func migrateLegacySchedule(config *Config) bool {
+	if config.Backup.BackupStartTime == "" && config.Backup.BackupIntervalHours == 0 {
+		return false
+	}
+
+	if config.Backup.BackupSchedule == "" {
+		schedule, err := convertLegacySchedule(config.Backup.BackupStartTime, config.Backup.BackupIntervalHours)
+		switch {
+		case err != nil:
+			schedule, _ = convertLegacySchedule(legacyFallbackStartTime, config.Backup.BackupIntervalHours)
+			LogWarn("Could not migrate legacy schedule (start %s, every %d hours): %v - using %s instead, check backup_schedule in Settings",
+				config.Backup.BackupStartTime, config.Backup.BackupIntervalHours, err, schedule)
+		case schedule == "":
+			LogInfo("Migrated legacy schedule: scheduler was disabled (backup_interval_hours = 0)")
+		default:
+			LogInfo("Migrated legacy schedule (start %s, every %d hours) to cron expression: %s",
+				config.Backup.BackupStartTime, config.Backup.BackupIntervalHours, schedule)
+		}
+		config.Backup.BackupSchedule = schedule
+	}
+
+	config.Backup.BackupStartTime = ""
+	config.Backup.BackupIntervalHours = 0
+	return true
+}
+
 func createDefaultConfig(configFile string) (*Config, error) {
 	config := &Config{
+		Version: currentConfigVersion,
 		Database: DatabaseConfig{
 			Host:         "127.0.0.1",
 			Port:         3306,
@@ -96,14 +297,16 @@ func createDefaultConfig(configFile string) (*Config, error) {
 			BinaryBinLog: "/usr/bin/mariadb-binlog",
 		},
 		Backup: BackupConfig{
-			BackupDir:           "/etc/mariadb-backup-tool/backups",
-			RetentionBackups:    30,
-			Parallel:            8,
-			FullBackupInterval:  7,
-			BackupIntervalHours: 0,
-			BackupStartTime:     "09:00",
-			CompressionLevel:    6,
-			NiceLevel:           15,
+			BackupDir:          "/etc/mariadb-backup-tool/backups",
+			RetentionBackups:   30,
+			Parallel:           8,
+			MaxConcurrentJobs:  1,
+			ScheduleConflict:   "skip",
+			FullBackupInterval: 7,
+			BackupSchedule:     "",
+			MissedRunPolicy:    "run_once",
+			CompressionLevel:   6,
+			NiceLevel:          15,
 			IgnoreDbs: []string{
 				"information_schema",
 				"performance_schema",
@@ -118,6 +321,23 @@ func createDefaultConfig(configFile string) (*Config, error) {
 			MysqldumpOptions:     "--quick --lock-tables=false --skip-lock-tables --single-transaction --no-autocommit --net_buffer_length=16k --skip-triggers --skip-routines --skip-events --default-character-set=utf8mb4 --compact --extended-insert --compress --opt --hex-blob --disable-keys",
 			MariadbCheckOptions:  "--auto-repair --optimize",
 			MariadbBinlogOptions: "--verbose --base64-output=DECODE-ROWS --short-form",
+			GFSRetention: GFSRetention{
+				Enabled: false,
+				Daily:   7,
+				Weekly:  4,
+				Monthly: 12,
+				Yearly:  3,
+			},
+			KeepLastFulls:       0,
+			MaxDatabaseBackupMB: 0,
+			MaxTotalBackupMB:    0,
+			CompressionRatio:    0.3,
+			MinFreeSpaceMB:      1024,
+			LowSpaceAction:      "refuse",
+			OrphanTempAction:    "delete",
+			ResumeInterrupted:   false,
+			ShutdownTimeout:     60,
+			RPOHours:            0,
 		},
 		Web: WebConfig{
 			Port:         8080,
@@ -133,6 +353,12 @@ func createDefaultConfig(configFile string) (*Config, error) {
 		},
 		Notification: NotificationConfig{
 			SlackWebhookURL: "",
+			Email: EmailNotifierConfig{
+				SMTPPort: 587,
+				TLSMode:  "starttls",
+			},
+			DedupMinutes: defaultNotificationDedupMinutes,
+			DigestTime:   defaultNotificationDigestTime,
 		},
 	}
 
@@ -150,14 +376,54 @@ func saveConfig(config *Config, configFile string) error {
 		return fmt.Errorf("failed to create config directory: %v", err)
 	}
 
-	data, err := json.MarshalIndent(config, "", "  ")
+	stored, err := storedConfigSecrets(config, configFile)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(stored, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal config: %v", err)
 	}
 
-	if err := os.WriteFile(configFile, data, 0644); err != nil {
+	// Write a private temporary file and rename it, so the config is never
+	// readable by others or half written
+	file, err := os.CreateTemp(dir, ".config-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write config file: %v", err)
+	}
+	_, err = file.Write(data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(file.Name(), 0600)
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), configFile)
+	}
+	if err != nil {
+		os.Remove(file.Name())
 		return fmt.Errorf("failed to write config file: %v", err)
 	}
 
+	rememberStoredSecrets(config, stored, configFile)
 	return nil
 }
+
+// restrictConfigPermissions makes a config file readable by its owner only,
+// since it may hold secrets
+func restrictConfigPermissions(configFile string) {
+	if runtime.GOOS == "windows" {
+		return
+	}
+	info, err := os.Stat(configFile)
+	if err != nil || info.Mode().Perm()&0077 == 0 {
+		return
+	}
+	if err := os.Chmod(configFile, 0600); err != nil {
+		LogWarn("🔐 [SECRETS] %s is readable by other users and could not be restricted: %v", configFile, err)
+		return
+	}
+	LogInfo("🔐 [SECRETS] Restricted permissions of %s from %o to 600", configFile, info.Mode().Perm())
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadConfigMigratesUnparsableLegacySchedule(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.json")
+	if _, err := createDefaultConfig(configFile); err != nil {
+		t.Fatal(err)
+	}
+
+	// Turn the default config into a version 0 file with a start time that
+	// older releases accepted but cannot be converted
+	data, err := os.ReadFile(configFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var object map[string]interface{}
+	if err := json.Unmarshal(data, &object); err != nil {
+		t.Fatal(err)
+	}
+	delete(object, "version")
+	backup := object["backup"].(map[string]interface{})
+	backup["backup_schedule"] = ""
+	backup["backup_start_time"] = "9"
+	backup["backup_interval_hours"] = 6
+	if data, err = json.Marshal(object); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(configFile, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Startup refuses a config that loadConfig reports as invalid
+	config, err := loadConfig(configFile)
+	if err != nil {
+		t.Fatalf("startup would fail: %v", err)
+	}
+	if config.Version != currentConfigVersion {
+		t.Errorf("version = %d, want %d", config.Version, currentConfigVersion)
+	}
+	if want := "0 0,6,12,18 * * *"; config.Backup.BackupSchedule != want {
+		t.Errorf("backup_schedule = %q, want %q", config.Backup.BackupSchedule, want)
+	}
+	if config.Backup.BackupStartTime != "" || config.Backup.BackupIntervalHours != 0 {
+		t.Errorf("legacy fields kept: %q, %d", config.Backup.BackupStartTime, config.Backup.BackupIntervalHours)
+	}
+
+	// The original file is kept and the migrated file loads again
+	if _, err := os.Stat(configFile + ".v0.bak"); err != nil {
+		t.Errorf("no copy of the version 0 file: %v", err)
+	}
+	if _, err := loadConfig(configFile); err != nil {
+		t.Errorf("migrated config does not load: %v", err)
+	}
+}
@@ -0,0 +1,358 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// currentConfigVersion is the config file format written by this release.
+// Files without a version field are version 0.
+const currentConfigVersion = 1
+
+// configMigration upgrades a config from the previous version to Version
+type configMigration struct {
+	Version     int
+	Description string
+	Migrate     func(config *Config) error
+}
+
+// configMigrations lists every format change in order. Append a migration
+// and raise currentConfigVersion whenever a release changes the meaning or
+// layout of existing settings.
+var configMigrations = []configMigration{
+	{
+		Version:     1,
+		Description: "convert backup_start_time/backup_interval_hours to backup_schedule and store the default job limit",
+		Migrate: func(config *Config) error {
+			migrateLegacySchedule(config)
+			// Older releases ran one job at a time when max_concurrent_jobs was 0
+			if config.Backup.MaxConcurrentJobs == 0 {
+				config.Backup.MaxConcurrentJobs = 1
+			}
+			return nil
+		},
+	},
+}
+
+// migrateConfig runs the migrations newer than the version of the config and
+// returns the descriptions of the migrations applied
+func migrateConfig(config *Config) ([]string, error) {
+	if config.Version > currentConfigVersion {
+		return nil, fmt.Errorf("config version %d was written by a newer release, this release supports version %d", config.Version, currentConfigVersion)
+	}
+	if config.Version < 0 {
+		return nil, fmt.Errorf("invalid config version %d", config.Version)
+	}
+
+	var applied []string
+	for _, migration := range configMigrations {
+		if migration.Version <= config.Version {
+			continue
+		}
+		if err := migration.Migrate(config); err != nil {
+			return applied, fmt.Errorf("migration to config version %d failed: %v", migration.Version, err)
+		}
+		config.Version = migration.Version
+		applied = append(applied, fmt.Sprintf("v%d: %s", migration.Version, migration.Description))
+	}
+	return applied, nil
+}
+
+// backupConfigFile keeps a copy of the config file as it was before a
+// migration, next to it as <file>.v<version>.bak
+func backupConfigFile(configFile string, data []byte, version int) (string, error) {
+	backupFile := fmt.Sprintf("%s.v%d.bak", configFile, version)
+	if _, err := os.Stat(backupFile); err == nil {
+		return backupFile, nil
+	}
+	if err := os.WriteFile(backupFile, data, 0600); err != nil {
+		return "", err
+	}
+	return backupFile, nil
+}
+
+// ConfigErrors holds validation errors by field path, such as backup.parallel
+type ConfigErrors map[string]string
+
+func (e ConfigErrors) Error() string {
+	lines := make([]string, 0, len(e))
+	for _, field := range e.Fields() {
+		lines = append(lines, field+": "+e[field])
+	}
+	return strings.Join(lines, "; ")
+}
+
+// Fields returns the invalid field paths in sorted order
+func (e ConfigErrors) Fields() []string {
+	return sortedKeys(e, func(field string) string { return field })
+}
+
+// add records the first error of a field
+func (e ConfigErrors) add(field, format string, args ...interface{}) {
+	if _, exists := e[field]; !exists {
+		e[field] = fmt.Sprintf(format, args...)
+	}
+}
+
+// isConfigValidationError reports whether err only means the config failed
+// validation, in which case loadConfig still returns the parsed config
+func isConfigValidationError(err error) bool {
+	var configErrs ConfigErrors
+	return errors.As(err, &configErrs)
+}
+
+// Values accepted by max_memory_per_process, e.g. 256M
+var memorySizePattern = regexp.MustCompile(`^[0-9]+[KkMmGg]?$`)
+
+// validateConfig checks every setting and returns the errors by field path,
+// nil when the config is valid. Empty values that the code already defaults
+// (e.g. missed_run_policy) are accepted.
+func validateConfig(config *Config) ConfigErrors {
+	errs := ConfigErrors{}
+
+	db := config.Database
+	if db.Host == "" && db.Socket == "" {
+		errs.add("database.host", "host or socket is required")
+	}
+	if db.Port < 0 || db.Port > 65535 {
+		errs.add("database.port", "must be between 1 and 65535")
+	} else if db.Port == 0 && db.Socket == "" {
+		errs.add("database.port", "is required when no socket is set")
+	}
+	if !validSSLMode(db.SSLMode) {
+		errs.add("database.ssl_mode", "must be disabled, preferred, required, verify_ca or verify_identity")
+	}
+	if (db.SSLCert == "") != (db.SSLKey == "") {
+		errs.add("database.ssl_key", "client certificate and key must be set together")
+	}
+	if db.BinaryDump == "" {
+		errs.add("database.binary_dump", "is required")
+	}
+
+	b := config.Backup
+	if strings.TrimSpace(b.BackupDir) == "" {
+		errs.add("backup.backup_dir", "is required")
+	}
+	if b.RetentionBackups < 0 {
+		errs.add("backup.retention_backups", "must not be negative")
+	}
+	if b.Parallel < 1 {
+		errs.add("backup.parallel", "must be at least 1")
+	}
+	if b.MaxConcurrentJobs < 1 {
+		errs.add("backup.max_concurrent_jobs", "must be at least 1")
+	}
+	switch b.ScheduleConflict {
+	case "", JobConflictWait, JobConflictSkip:
+	default:
+		errs.add("backup.schedule_conflict", "must be %s or %s", JobConflictWait, JobConflictSkip)
+	}
+	if b.FullBackupInterval < 0 {
+		errs.add("backup.full_backup_interval", "must not be negative")
+	}
+	if b.BackupSchedule != "" {
+		if _, err := ParseCronSchedule(b.BackupSchedule); err != nil {
+			errs.add("backup.backup_schedule", "%v", err)
+		}
+	}
+	switch b.MissedRunPolicy {
+	case "", MissedRunOnce, MissedRunSkip, MissedRunAlert:
+	default:
+		errs.add("backup.missed_run_policy", "must be %s, %s or %s", MissedRunOnce, MissedRunSkip, MissedRunAlert)
+	}
+	if b.BackupStartTime != "" || b.BackupIntervalHours != 0 {
+		if _, err := convertLegacySchedule(b.BackupStartTime, b.BackupIntervalHours); err != nil && b.BackupSchedule == "" {
+			errs.add("backup.backup_start_time", "legacy schedule cannot be converted (%v), set backup_schedule instead", err)
+		} else {
+			errs.add("backup.backup_start_time", "backup_start_time and backup_interval_hours are no longer supported, use backup_schedule")
+		}
+	}
+	if b.CompressionLevel < 0 || b.CompressionLevel > 9 {
+		errs.add("backup.compression_level", "must be between 0 and 9")
+	}
+	if b.NiceLevel < -20 || b.NiceLevel > 19 {
+		errs.add("backup.nice_level", "must be between -20 and 19")
+	}
+	switch b.DefaultBackupMode {
+	case "auto", "full", "incremental":
+	default:
+		errs.add("backup.default_backup_mode", "must be auto, full or incremental")
+	}
+	if b.MaxMemoryThreshold < 0 || b.MaxMemoryThreshold > 100 {
+		errs.add("backup.max_memory_threshold", "must be a percentage between 0 and 100")
+	}
+	if b.MaxMemoryPerProcess != "" && !memorySizePattern.MatchString(b.MaxMemoryPerProcess) {
+		errs.add("backup.max_memory_per_process", "must be a size such as 256M")
+	}
+	if err := validateDatabasePatterns(b.IgnoreDbs); err != nil {
+		errs.add("backup.ignore_dbs", "%v", err)
+	}
+	if err := validateDatabasePatterns(b.IncludeDbs); err != nil {
+		errs.add("backup.include_dbs", "%v", err)
+	}
+	gfs := b.GFSRetention
+	if gfs.Daily < 0 || gfs.Weekly < 0 || gfs.Monthly < 0 || gfs.Yearly < 0 {
+		errs.add("backup.gfs_retention", "daily, weekly, monthly and yearly must not be negative")
+	} else if gfs.Enabled && gfs.Daily+gfs.Weekly+gfs.Monthly+gfs.Yearly == 0 {
+		errs.add("backup.gfs_retention", "at least one of daily, weekly, monthly or yearly must be greater than 0")
+	}
+	for field, value := range map[string]int{
+		"backup.keep_last_fulls":        b.KeepLastFulls,
+		"backup.max_database_backup_mb": b.MaxDatabaseBackupMB,
+		"backup.max_total_backup_mb":    b.MaxTotalBackupMB,
+		"backup.min_free_space_mb":      b.MinFreeSpaceMB,
+		"backup.shutdown_timeout":       b.ShutdownTimeout,
+		"backup.rpo_hours":              b.RPOHours,
+	} {
+		if value < 0 {
+			errs.add(field, "must not be negative")
+		}
+	}
+	if b.CompressionRatio < 0 || b.CompressionRatio > 2 {
+		errs.add("backup.compression_ratio", "must be between 0 and 2")
+	}
+	switch b.LowSpaceAction {
+	case "", LowSpaceRefuse, LowSpacePrune:
+	default:
+		errs.add("backup.low_space_action", "must be %s or %s", LowSpaceRefuse, LowSpacePrune)
+	}
+	switch b.OrphanTempAction {
+	case "", OrphanTempDelete, OrphanTempQuarantine:
+	default:
+		errs.add("backup.orphan_temp_action", "must be %s or %s", OrphanTempDelete, OrphanTempQuarantine)
+	}
+	if err := validateDatabaseRPO(b.DatabaseRPO); err != nil {
+		errs.add("backup.database_rpo", "%v", err)
+	}
+
+	web := config.Web
+	if web.Port < 1 || web.Port > 65535 {
+		errs.add("web.port", "must be between 1 and 65535")
+	}
+	if web.SSLEnabled {
+		if web.SSLCertFile == "" {
+			errs.add("web.ssl_cert_file", "is required when SSL is enabled")
+		}
+		if web.SSLKeyFile == "" {
+			errs.add("web.ssl_key_file", "is required when SSL is enabled")
+		}
+	}
+	if err := validateSSOConfig(&web); err != nil {
+		errs.add("web", "%v", err)
+	}
+
+	if strings.TrimSpace(config.Logging.LogDir) == "" {
+		errs.add("logging.log_dir", "is required")
+	}
+	if config.Logging.RetentionLogs < 0 {
+		errs.add("logging.retention_logs", "must not be negative")
+	}
+
+	if config.Notification.DedupMinutes < 0 {
+		errs.add("notification.dedup_minutes", "must not be negative")
+	}
+	if err := validateNotificationConfig(config.Notification); err != nil {
+		errs.add("notification", "%v", err)
+	}
+
+	if err := validateBackupPolicies(config.Policies); err != nil {
+		errs.add("policies", "%v", err)
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// checkConfigFiles checks that the files named by the config can be read,
+// which validateConfig leaves out since it runs on every load
+func checkConfigFiles(config *Config) ConfigErrors {
+	errs := ConfigErrors{}
+
+	files := map[string]string{
+		"database.ssl_ca":   config.Database.SSLCA,
+		"database.ssl_cert": config.Database.SSLCert,
+		"database.ssl_key":  config.Database.SSLKey,
+	}
+	if config.Web.SSLEnabled {
+		files["web.ssl_cert_file"] = config.Web.SSLCertFile
+		files["web.ssl_key_file"] = config.Web.SSLKeyFile
+	}
+	for field, path := range files {
+		if path == "" {
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			errs.add(field, "%v", err)
+		}
+	}
+
+	// Invalid modes and a certificate without key are reported by validateConfig
+	db := config.Database
+	if len(errs) == 0 && validSSLMode(db.SSLMode) && (db.SSLCert == "") == (db.SSLKey == "") &&
+		db.SSLMode != "" && db.SSLMode != SSLModeDisabled {
+		if _, err := buildDatabaseTLSConfig(config); err != nil {
+			errs.add("database.ssl_mode", "%v", err)
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// checkConfig implements --check-config: it loads, migrates and validates a
+// config file without saving it and prints the result. Returns false when
+// the file is not usable.
+func checkConfig(configFile string) bool {
+	data, err := os.ReadFile(configFile)
+	if err != nil {
+		fmt.Printf("%s: %v\n", configFile, err)
+		return false
+	}
+
+	config, err := parseConfig(data)
+	if err != nil {
+		fmt.Printf("%s: %v\n", configFile, err)
+		return false
+	}
+	if err := resolveConfigSecrets(config, configFile); err != nil {
+		fmt.Printf("Warning: some secrets could not be resolved: %v\n", err)
+	}
+
+	fileVersion := config.Version
+	applied, err := migrateConfig(config)
+	if err != nil {
+		fmt.Printf("%s: %v\n", configFile, err)
+		return false
+	}
+	if len(applied) > 0 {
+		fmt.Printf("Config version %d, the next start migrates it to version %d:\n", fileVersion, config.Version)
+		for _, migration := range applied {
+			fmt.Printf("  - %s\n", migration)
+		}
+	}
+
+	errs := validateConfig(config)
+	for field, message := range checkConfigFiles(config) {
+		if errs == nil {
+			errs = ConfigErrors{}
+		}
+		errs.add(field, "%s", message)
+	}
+	if len(errs) > 0 {
+		fmt.Printf("%s has %d invalid setting(s):\n", configFile, len(errs))
+		for _, field := range errs.Fields() {
+			fmt.Printf("  %s: %s\n", field, errs[field])
+		}
+		return false
+	}
+
+	fmt.Printf("%s is valid (config version %d)\n", configFile, config.Version)
+	return true
+}
@@ -0,0 +1,367 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// Where a configuration snapshot came from
+const (
+	ConfigSourceSave     = "save"     // Saved on the settings page
+	ConfigSourceReset    = "reset"    // Reset to the defaults
+	ConfigSourceRollback = "rollback" // Rolled back to an earlier snapshot
+	ConfigSourceFile     = "file"     // Config file changed outside the web interface, found on startup
+)
+
+const (
+	configHistoryPageSize    = 20
+	configHistoryMaxPageSize = 200
+)
+
+// ConfigSnapshot is a saved configuration. Secrets and password hashes are
+// stored as secretMask, secret references as written.
+type ConfigSnapshot struct {
+	ID            int64    `json:"id"`
+	CreatedAt     string   `json:"created_at"`
+	Author        string   `json:"author"`
+	Source        string   `json:"source"`
+	RollbackOf    int64    `json:"rollback_of,omitempty"` // Snapshot restored by a rollback
+	ChangedFields []string `json:"changed_fields"`        // Fields changed since the previous snapshot
+	Config        string   `json:"-"`                     // Redacted config JSON
+}
+
+// ConfigFieldChange is a changed config field of a snapshot diff
+type ConfigFieldChange struct {
+	Field  string      `json:"field"`
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// configSnapshotJSON returns the config as stored in the history
+func configSnapshotJSON(config *Config) (string, error) {
+	data, err := json.Marshal(redactedConfig(config))
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// diffConfigSnapshots compares two snapshot configs field by field and
+// returns the changed fields sorted by path. An empty config has no fields.
+func diffConfigSnapshots(before, after string) ([]ConfigFieldChange, error) {
+	flatten := func(data string) (map[string]interface{}, error) {
+		fields := make(map[string]interface{})
+		if data == "" {
+			return fields, nil
+		}
+		var object interface{}
+		if err := json.Unmarshal([]byte(data), &object); err != nil {
+			return nil, fmt.Errorf("invalid snapshot: %v", err)
+		}
+		flattenConfig("", object, fields)
+		return fields, nil
+	}
+
+	oldFields, err := flatten(before)
+	if err != nil {
+		return nil, err
+	}
+	newFields, err := flatten(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := []ConfigFieldChange{}
+	for path := range mergeKeys(oldFields, newFields) {
+		if !reflect.DeepEqual(oldFields[path], newFields[path]) {
+			changes = append(changes, ConfigFieldChange{Field: path, Before: oldFields[path], After: newFields[path]})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes, nil
+}
+
+// recordConfigSnapshot adds a config to the history with the fields changed
+// since the previous snapshot. Failures are logged, the saved config stays.
+func recordConfigSnapshot(config *Config, author, source string, rollbackOf int64) {
+	data, err := configSnapshotJSON(config)
+	if err != nil {
+		LogWarn("🗂️ [CONFIG-HISTORY] Failed to encode configuration snapshot: %v", err)
+		return
+	}
+
+	snapshot := &ConfigSnapshot{Author: author, Source: source, RollbackOf: rollbackOf, Config: data}
+	previous, err := GetPreviousConfigSnapshot(0)
+	if err != nil {
+		LogWarn("🗂️ [CONFIG-HISTORY] Failed to read the previous configuration snapshot: %v", err)
+	}
+	if previous != nil {
+		changes, err := diffConfigSnapshots(previous.Config, data)
+		if err != nil {
+			LogWarn("🗂️ [CONFIG-HISTORY] Failed to compare with snapshot #%d: %v", previous.ID, err)
+		}
+		for _, change := range changes {
+			snapshot.ChangedFields = append(snapshot.ChangedFields, change.Field)
+		}
+	}
+
+	id, err := InsertConfigSnapshot(snapshot)
+	if err != nil {
+		LogWarn("🗂️ [CONFIG-HISTORY] Failed to record configuration snapshot: %v", err)
+		return
+	}
+	LogDebug("🗂️ [CONFIG-HISTORY] Recorded configuration #%d (%s by %s, %d fields changed)", id, source, author, len(snapshot.ChangedFields))
+}
+
+// recordConfigFileChanges adds the config loaded on startup to the history
+// when it differs from the latest snapshot, e.g. after config.json was
+// edited by hand or on the first start
+func recordConfigFileChanges(config *Config) {
+	data, err := configSnapshotJSON(config)
+	if err != nil {
+		LogWarn("🗂️ [CONFIG-HISTORY] Failed to encode configuration snapshot: %v", err)
+		return
+	}
+	latest, err := GetPreviousConfigSnapshot(0)
+	if err != nil {
+		LogWarn("🗂️ [CONFIG-HISTORY] Failed to read the latest configuration snapshot: %v", err)
+		return
+	}
+	if latest != nil && latest.Config == data {
+		return
+	}
+	recordConfigSnapshot(config, auditSystemActor, ConfigSourceFile, 0)
+}
+
+// restoreConfigSnapshot builds the config a rollback to snapshot saves and
+// checks it like a settings save. Secrets and the initial admin are not part
+// of the history, so they keep their current values.
+func restoreConfigSnapshot(snapshot *ConfigSnapshot, current *Config) (*Config, error) {
+	restored, err := parseConfig([]byte(snapshot.Config))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := migrateConfig(restored); err != nil {
+		return nil, err
+	}
+
+	currentSecrets := configSecrets(current)
+	currentSecrets["web.metrics_token_hash"] = &current.Web.MetricsTokenHash
+	restoredSecrets := configSecrets(restored)
+	restoredSecrets["web.metrics_token_hash"] = &restored.Web.MetricsTokenHash
+	for path, field := range restoredSecrets {
+		if *field != secretMask {
+			continue
+		}
+		*field = ""
+		if value, ok := currentSecrets[path]; ok {
+			*field = *value
+		}
+	}
+	restored.secretRefs = current.secretRefs
+	restored.Web.AuthUser = current.Web.AuthUser
+	restored.Web.AuthPassHash = current.Web.AuthPassHash
+
+	// The same checks as saving the settings page
+	if errs := validateConfig(restored); errs != nil {
+		return nil, fmt.Errorf("configuration #%d is not valid in this release: %w", snapshot.ID, errs)
+	}
+	if errs := checkConfigFiles(restored); errs != nil {
+		return nil, fmt.Errorf("configuration #%d refers to files that cannot be used: %w", snapshot.ID, errs)
+	}
+	return restored, nil
+}
+
+// handleConfigHistory returns a page of configuration snapshots, newest first
+func handleConfigHistory(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit <= 0 {
+		limit = configHistoryPageSize
+	}
+	limit = min(limit, configHistoryMaxPageSize)
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+	offset = max(offset, 0)
+
+	snapshots, total, err := GetConfigSnapshots(limit, offset)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   "Failed to get configuration history: " + err.Error(),
+		})
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":   true,
+		"snapshots": snapshots,
+		"total":     total,
+		"limit":     limit,
+		"offset":    offset,
+	})
+}
+
+// handleConfigHistoryDiff compares a snapshot with the one before it, or
+// with compare=current the current config with the snapshot, which is what
+// a rollback would change
+func handleConfigHistoryDiff(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	snapshot, ok := configSnapshotFromRequest(w, r.URL.Query().Get("id"))
+	if !ok {
+		return
+	}
+
+	compare := r.URL.Query().Get("compare")
+	var before, after string
+	switch compare {
+	case "", "previous":
+		compare = "previous"
+		previous, err := GetPreviousConfigSnapshot(snapshot.ID)
+		if err != nil {
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"success": false,
+				"error":   "Failed to get the previous configuration: " + err.Error(),
+			})
+			return
+		}
+		if previous != nil {
+			before = previous.Config
+		}
+		after = snapshot.Config
+	case "current":
+		current, err := loadConfig("config.json")
+		if err != nil && !isConfigValidationError(err) {
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"success": false,
+				"error":   "Failed to load config: " + err.Error(),
+			})
+			return
+		}
+		if before, err = configSnapshotJSON(current); err != nil {
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"success": false,
+				"error":   "Failed to encode config: " + err.Error(),
+			})
+			return
+		}
+		after = snapshot.Config
+	default:
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   "compare must be previous or current",
+		})
+		return
+	}
+
+	changes, err := diffConfigSnapshots(before, after)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":  true,
+		"snapshot": snapshot,
+		"compare":  compare,
+		"changes":  changes,
+	})
+}
+
+// handleConfigRollback saves an earlier configuration snapshot as the
+// current config and reloads the scheduler
+func handleConfigRollback(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	snapshot, ok := configSnapshotFromRequest(w, r.FormValue("id"))
+	if !ok {
+		return
+	}
+	target := fmt.Sprintf("config.json#%d", snapshot.ID)
+
+	current, err := loadConfig("config.json")
+	if err != nil && !isConfigValidationError(err) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   "Failed to load config: " + err.Error(),
+		})
+		return
+	}
+
+	restored, err := restoreConfigSnapshot(snapshot, current)
+	if err == nil {
+		err = saveConfig(restored, "config.json")
+	}
+	if err != nil {
+		recordAuditFailure(r, AuditSettingsRollback, target, err.Error())
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   "Failed to roll back configuration: " + err.Error(),
+		})
+		return
+	}
+	changedBefore, changedAfter := auditConfigChanges(current, restored)
+	recordAudit(r, AuditSettingsRollback, target, changedBefore, changedAfter)
+	recordConfigSnapshot(restored, currentUsername(r), ConfigSourceRollback, snapshot.ID)
+
+	ReloadSchedulerConfig(restored)
+	ConfigureJobQueue(restored)
+	LogInfo("🗂️ [CONFIG-HISTORY] %s rolled the configuration back to #%d, scheduler configuration reloaded", currentUsername(r), snapshot.ID)
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": fmt.Sprintf("Configuration rolled back to #%d", snapshot.ID),
+	})
+}
+
+// configSnapshotFromRequest looks up the snapshot with the ID given in a
+// request and writes the error response when there is none
+func configSnapshotFromRequest(w http.ResponseWriter, value string) (*ConfigSnapshot, bool) {
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || id <= 0 {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   "Invalid configuration ID",
+		})
+		return nil, false
+	}
+
+	snapshot, err := GetConfigSnapshot(id)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   "Failed to get configuration: " + err.Error(),
+		})
+		return nil, false
+	}
+	if snapshot == nil {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   fmt.Sprintf("Configuration #%d not found", id),
+		})
+		return nil, false
+	}
+	return snapshot, true
+}
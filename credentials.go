@@ -0,0 +1,14 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// hashToken returns the SHA-256 hex digest stored in place of a random
+// secret such as an API token, session ID or recovery code. The secrets
+// have enough entropy that a plain digest cannot be reversed.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CronSchedule is a parsed standard 5-field cron expression:
+//
+//	minute hour day-of-month month day-of-week
+//
+// Supported syntax: "*", "*/n", "a", "a-b", "a-b/n", "a/n" and comma separated
+// lists of those. Month and weekday names (JAN, MON, ...) and the macros
+// @yearly, @monthly, @weekly, @daily and @hourly are accepted as well.
+type CronSchedule struct {
+	Expression string
+	minutes    map[int]bool
+	hours      map[int]bool
+	days       map[int]bool
+	months     map[int]bool
+	weekdays   map[int]bool
+	// Vixie cron semantics: when both day fields are restricted a time
+	// matches if either of them matches
+	daysRestricted     bool
+	weekdaysRestricted bool
+}
+
+// cronField describes the valid range of a single cron field
+type cronField struct {
+	name  string
+	min   int
+	max   int
+	names map[string]int
+}
+
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var cronFields = []cronField{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12, names: map[string]int{
+		"JAN": 1, "FEB": 2, "MAR": 3, "APR": 4, "MAY": 5, "JUN": 6,
+		"JUL": 7, "AUG": 8, "SEP": 9, "OCT": 10, "NOV": 11, "DEC": 12,
+	}},
+	{name: "day of week", min: 0, max: 7, names: map[string]int{
+		"SUN": 0, "MON": 1, "TUE": 2, "WED": 3, "THU": 4, "FRI": 5, "SAT": 6,
+	}},
+}
+
+// ParseCronSchedule parses a 5-field cron expression or macro
+func ParseCronSchedule(expression string) (*CronSchedule, error) {
+	expr := strings.TrimSpace(expression)
+	if expr == "" {
+		return nil, fmt.Errorf("cron expression is empty")
+	}
+
+	if macro, ok := cronMacros[strings.ToLower(expr)]; ok {
+		expr = macro
+	}
+
+	parts := strings.Fields(expr)
+	if len(parts) != 5 {
+		return nil, fmt.Errorf("cron expression must have 5 fields (minute hour day month weekday), got %d", len(parts))
+	}
+
+	sets := make([]map[int]bool, 5)
+	for i, part := range parts {
+		set, err := parseCronField(part, cronFields[i])
+		if err != nil {
+			return nil, err
+		}
+		sets[i] = set
+	}
+
+	// Sunday can be written as 0 or 7
+	if sets[4][7] {
+		sets[4][0] = true
+		delete(sets[4], 7)
+	}
+
+	return &CronSchedule{
+		Expression:         strings.TrimSpace(expression),
+		minutes:            sets[0],
+		hours:              sets[1],
+		days:               sets[2],
+		months:             sets[3],
+		weekdays:           sets[4],
+		daysRestricted:     parts[2] != "*" && !strings.HasPrefix(parts[2], "*/"),
+		weekdaysRestricted: parts[4] != "*" && !strings.HasPrefix(parts[4], "*/"),
+	}, nil
+}
+
+// parseCronField parses a single field into the set of allowed values
+func parseCronField(field string, spec cronField) (map[int]bool, error) {
+	set := make(map[int]bool)
+
+	for _, item := range strings.Split(field, ",") {
+		if item == "" {
+			return nil, fmt.Errorf("invalid %s field %q: empty list item", spec.name, field)
+		}
+
+		rangePart := item
+		step := 1
+		if idx := strings.Index(item, "/"); idx >= 0 {
+			rangePart = item[:idx]
+			var err error
+			step, err = strconv.Atoi(item[idx+1:])
+			if err != nil || step <= 0 {
+				return nil, fmt.Errorf("invalid %s step in %q", spec.name, item)
+			}
+		}
+
+		start, end := spec.min, spec.max
+		switch {
+		case rangePart == "*":
+			// full range
+		case strings.Contains(rangePart, "-"):
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if start, err = parseCronValue(bounds[0], spec); err != nil {
+				return nil, err
+			}
+			if end, err = parseCronValue(bounds[1], spec); err != nil {
+				return nil, err
+			}
+			if start > end {
+				return nil, fmt.Errorf("invalid %s range %q: start is after end", spec.name, rangePart)
+			}
+		default:
+			value, err := parseCronValue(rangePart, spec)
+			if err != nil {
+				return nil, err
+			}
+			start = value
+			// "a/n" means every n starting at a
+			if step == 1 {
+				end = value
+			}
+		}
+
+		for v := start; v <= end; v += step {
+			set[v] = true
+		}
+	}
+
+	return set, nil
+}
+
+// parseCronValue parses a number or name within the field's range
+func parseCronValue(value string, spec cronField) (int, error) {
+	if spec.names != nil {
+		if v, ok := spec.names[strings.ToUpper(value)]; ok {
+			return v, nil
+		}
+	}
+
+	v, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s value %q", spec.name, value)
+	}
+	if v < spec.min || v > spec.max {
+		return 0, fmt.Errorf("%s value %d out of range (%d-%d)", spec.name, v, spec.min, spec.max)
+	}
+	return v, nil
+}
+
+// matchesDay reports whether the date matches the day-of-month/day-of-week fields
+func (c *CronSchedule) matchesDay(t time.Time) bool {
+	dayMatch := c.days[t.Day()]
+	weekdayMatch := c.weekdays[int(t.Weekday())]
+
+	if c.daysRestricted && c.weekdaysRestricted {
+		return dayMatch || weekdayMatch
+	}
+	return dayMatch && weekdayMatch
+}
+
+// Next returns the first scheduled time strictly after t
+func (c *CronSchedule) Next(t time.Time) time.Time {
+	// Start at the next whole minute
+	next := t.Truncate(time.Minute).Add(time.Minute)
+
+	// Bound the search to 5 years so impossible dates (e.g. Feb 30) terminate
+	limit := next.AddDate(5, 0, 0)
+
+	for next.Before(limit) {
+		if !c.months[int(next.Month())] {
+			next = time.Date(next.Year(), next.Month()+1, 1, 0, 0, 0, 0, next.Location())
+			continue
+		}
+		if !c.matchesDay(next) {
+			next = time.Date(next.Year(), next.Month(), next.Day()+1, 0, 0, 0, 0, next.Location())
+			continue
+		}
+		if !c.hours[next.Hour()] {
+			next = time.Date(next.Year(), next.Month(), next.Day(), next.Hour()+1, 0, 0, 0, next.Location())
+			continue
+		}
+		if !c.minutes[next.Minute()] {
+			next = next.Add(time.Minute)
+			continue
+		}
+		return next
+	}
+
+	return time.Time{}
+}
+
+// NextN returns the next count scheduled times after t
+func (c *CronSchedule) NextN(t time.Time, count int) []time.Time {
+	var times []time.Time
+	for i := 0; i < count; i++ {
+		t = c.Next(t)
+		if t.IsZero() {
+			break
+		}
+		times = append(times, t)
+	}
+	return times
+}
+
+// convertLegacySchedule converts the old backup_start_time + backup_interval_hours
+// settings into an equivalent cron expression. The old scheduler restarted the
+// interval chain at the start time every day, so the result runs at the start
+// time and every interval after it until midnight.
+func convertLegacySchedule(startTime string, intervalHours int) (string, error) {
+	if intervalHours <= 0 {
+		return "", nil
+	}
+
+	parts := strings.Split(startTime, ":")
+	if len(parts) != 2 {
+		return "", fmt.Errorf("invalid backup_start_time %q", startTime)
+	}
+	hour, err := strconv.Atoi(parts[0])
+	if err != nil || hour < 0 || hour > 23 {
+		return "", fmt.Errorf("invalid backup_start_time %q", startTime)
+	}
+	minute, err := strconv.Atoi(parts[1])
+	if err != nil || minute < 0 || minute > 59 {
+		return "", fmt.Errorf("invalid backup_start_time %q", startTime)
+	}
+
+	var hours []int
+	h := hour
+	for ; h < 24; h += intervalHours {
+		hours = append(hours, h)
+	}
+	// The last interval of the day could spill over midnight once before the
+	// chain restarted at the start time
+	if wrapped := h - 24; wrapped < hour {
+		hours = append([]int{wrapped}, hours...)
+	}
+
+	hourStrs := make([]string, len(hours))
+	for i, h := range hours {
+		hourStrs[i] = strconv.Itoa(h)
+	}
+
+	return fmt.Sprintf("%d %s * * *", minute, strings.Join(hourStrs, ",")), nil
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Database selection patterns
+//
+// Entries in ignore_dbs and include_dbs can be:
+//   - an exact database name:      shop
+//   - a glob pattern:              tenant_*, test_?, log_[0-9]*
+//   - a regular expression in //:  /^tenant_[0-9]+$/
+//
+// When include_dbs is non-empty the tool runs in "include-only" mode and only
+// databases matching at least one include pattern are considered. The ignore
+// list is always applied afterwards, so it can carve exceptions out of an
+// include pattern (e.g. include tenant_*, ignore tenant_demo).
+
+// systemDatabases are never backed up regardless of patterns
+var systemDatabases = map[string]bool{
+	"information_schema": true,
+	"performance_schema": true,
+	"mysql":              true,
+	"sys":                true,
+}
+
+// Compiled /regex/ patterns. The preview endpoint matches patterns that were
+// never saved, so the cache is emptied once it holds patternRegexCacheSize.
+const patternRegexCacheSize = 256
+
+var patternRegexCache = make(map[string]*regexp.Regexp)
+var patternRegexMutex sync.Mutex
+
+// isRegexPattern reports whether a pattern uses the /regex/ syntax
+func isRegexPattern(pattern string) bool {
+	return len(pattern) >= 2 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/")
+}
+
+// compilePattern compiles a /regex/ pattern, caching the result
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	patternRegexMutex.Lock()
+	defer patternRegexMutex.Unlock()
+
+	if re, ok := patternRegexCache[pattern]; ok {
+		return re, nil
+	}
+
+	re, err := regexp.Compile(pattern[1 : len(pattern)-1])
+	if err != nil {
+		return nil, err
+	}
+	if len(patternRegexCache) >= patternRegexCacheSize {
+		clear(patternRegexCache)
+	}
+	patternRegexCache[pattern] = re
+	return re, nil
+}
+
+// validateDatabasePattern checks that a pattern is a valid glob or regex
+func validateDatabasePattern(pattern string) error {
+	if isRegexPattern(pattern) {
+		if _, err := regexp.Compile(pattern[1 : len(pattern)-1]); err != nil {
+			return fmt.Errorf("invalid regular expression %s: %v", pattern, err)
+		}
+		return nil
+	}
+
+	if _, err := path.Match(pattern, ""); err != nil {
+		return fmt.Errorf("invalid glob pattern %s: %v", pattern, err)
+	}
+	return nil
+}
+
+// validateDatabasePatterns validates every pattern in a list
+func validateDatabasePatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if err := validateDatabasePattern(pattern); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// matchDatabasePattern reports whether a database name matches a single pattern
+func matchDatabasePattern(pattern, dbName string) bool {
+	if pattern == dbName {
+		return true
+	}
+
+	if isRegexPattern(pattern) {
+		re, err := compilePattern(pattern)
+		if err != nil {
+			LogWarn("Invalid database pattern %s: %v", pattern, err)
+			return false
+		}
+		return re.MatchString(dbName)
+	}
+
+	matched, err := path.Match(pattern, dbName)
+	if err != nil {
+		LogWarn("Invalid database pattern %s: %v", pattern, err)
+		return false
+	}
+	return matched
+}
+
+// findMatchingPattern returns the first pattern in the list matching the database name
+func findMatchingPattern(patterns []string, dbName string) (string, bool) {
+	for _, pattern := range patterns {
+		if matchDatabasePattern(pattern, dbName) {
+			return pattern, true
+		}
+	}
+	return "", false
+}
+
+// parsePatternList splits a newline separated list from a form field into trimmed patterns
+func parsePatternList(value string) []string {
+	var patterns []string
+	for _, line := range strings.Split(value, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" {
+			patterns = append(patterns, line)
+		}
+	}
+	return patterns
+}
+
+// classifyDatabase decides whether a database is selected for backup.
+// It returns the status ("included", "ignored", "not_included" or "system")
+// together with the pattern responsible for the decision.
+func classifyDatabase(includeDbs, ignoreDbs []string, dbName string) (string, string) {
+	if systemDatabases[dbName] {
+		return "system", ""
+	}
+
+	includePattern := ""
+	if len(includeDbs) > 0 {
+		pattern, ok := findMatchingPattern(includeDbs, dbName)
+		if !ok {
+			return "not_included", ""
+		}
+		includePattern = pattern
+	}
+
+	if pattern, ok := findMatchingPattern(ignoreDbs, dbName); ok {
+		return "ignored", pattern
+	}
+
+	return "included", includePattern
+}
+
+// isDatabaseSelected reports whether a database should be backed up with the given config
+func isDatabaseSelected(config *Config, dbName string) bool {
+	status, _ := classifyDatabase(config.Backup.IncludeDbs, config.Backup.IgnoreDbs, dbName)
+	return status == "included"
+}
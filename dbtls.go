@@ -0,0 +1,228 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// TLS modes for the MariaDB connection
+const (
+	SSLModeDisabled       = "disabled"        // Never use TLS
+	SSLModePreferred      = "preferred"       // Use TLS when the server supports it, without verifying the certificate
+	SSLModeRequired       = "required"        // Require TLS, without verifying the certificate
+	SSLModeVerifyCA       = "verify_ca"       // Require TLS and a certificate signed by the CA
+	SSLModeVerifyIdentity = "verify_identity" // Also require the certificate to match the host name
+)
+
+// Name of the tls.Config registered with the MySQL driver
+const databaseTLSConfigName = "mariadb-backup-tool"
+
+// validSSLMode reports whether mode is a known TLS mode. Empty leaves TLS
+// to the driver and client tool defaults.
+func validSSLMode(mode string) bool {
+	switch mode {
+	case "", SSLModeDisabled, SSLModePreferred, SSLModeRequired, SSLModeVerifyCA, SSLModeVerifyIdentity:
+		return true
+	}
+	return false
+}
+
+// databaseUsesTLS reports whether the configured connection is a TCP
+// connection with TLS turned on. Unix socket connections stay local and
+// never use TLS.
+func databaseUsesTLS(config *Config) bool {
+	db := config.Database
+	if db.Port <= 0 || db.Host == "" {
+		return false
+	}
+	return db.SSLMode != "" && db.SSLMode != SSLModeDisabled
+}
+
+// buildDatabaseTLSConfig builds the tls.Config for the MySQL driver from the
+// database settings. It also validates the CA, certificate and key files.
+func buildDatabaseTLSConfig(config *Config) (*tls.Config, error) {
+	db := config.Database
+	if !validSSLMode(db.SSLMode) {
+		return nil, fmt.Errorf("invalid ssl_mode %q (disabled, preferred, required, verify_ca or verify_identity)", db.SSLMode)
+	}
+
+	tlsConfig := &tls.Config{}
+
+	if db.SSLCA != "" {
+		pem, err := os.ReadFile(db.SSLCA)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no PEM certificates found in CA file %s", db.SSLCA)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if db.SSLCert != "" || db.SSLKey != "" {
+		if db.SSLCert == "" || db.SSLKey == "" {
+			return nil, fmt.Errorf("client certificate and key must be set together")
+		}
+		cert, err := tls.LoadX509KeyPair(db.SSLCert, db.SSLKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	switch db.SSLMode {
+	case SSLModePreferred, SSLModeRequired:
+		tlsConfig.InsecureSkipVerify = true
+	case SSLModeVerifyCA:
+		// Check the chain ourselves, the host name is not verified
+		tlsConfig.InsecureSkipVerify = true
+		tlsConfig.VerifyPeerCertificate = verifyCertificateChain(tlsConfig.RootCAs)
+	case SSLModeVerifyIdentity:
+		tlsConfig.ServerName = db.Host
+	}
+
+	return tlsConfig, nil
+}
+
+// verifyCertificateChain verifies the server certificate against roots (the
+// system pool when nil) without checking the host name
+func verifyCertificateChain(roots *x509.CertPool) func([][]byte, [][]*x509.Certificate) error {
+	return func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		if len(rawCerts) == 0 {
+			return errors.New("server sent no certificate")
+		}
+		certs := make([]*x509.Certificate, 0, len(rawCerts))
+		for _, raw := range rawCerts {
+			cert, err := x509.ParseCertificate(raw)
+			if err != nil {
+				return err
+			}
+			certs = append(certs, cert)
+		}
+
+		opts := x509.VerifyOptions{Roots: roots, Intermediates: x509.NewCertPool()}
+		for _, cert := range certs[1:] {
+			opts.Intermediates.AddCert(cert)
+		}
+		_, err := certs[0].Verify(opts)
+		return err
+	}
+}
+
+// The TLS settings the registered tls.Config was built from, see databaseTLSKey
+var registeredDatabaseTLS = struct {
+	sync.Mutex
+	key string
+}{}
+
+// databaseTLSKey identifies the TLS settings of a config together with the
+// modification times of the certificate files, so replaced files are
+// loaded again
+func databaseTLSKey(config *Config) string {
+	db := config.Database
+	key := []string{db.SSLMode, db.Host}
+	for _, file := range []string{db.SSLCA, db.SSLCert, db.SSLKey} {
+		stamp := ""
+		if info, err := os.Stat(file); file != "" && err == nil {
+			stamp = fmt.Sprintf("%d/%d", info.ModTime().UnixNano(), info.Size())
+		}
+		key = append(key, file, stamp)
+	}
+	return strings.Join(key, "\x00")
+}
+
+// databaseTLSParams registers the tls.Config with the MySQL driver and
+// returns the DSN parameters that select it. The config is only built and
+// registered again when the TLS settings or certificate files changed.
+func databaseTLSParams(config *Config) (string, error) {
+	if config.Database.SSLMode == SSLModeDisabled {
+		return "&tls=false", nil
+	}
+	if !databaseUsesTLS(config) {
+		return "", nil
+	}
+
+	key := databaseTLSKey(config)
+	registeredDatabaseTLS.Lock()
+	defer registeredDatabaseTLS.Unlock()
+	if registeredDatabaseTLS.key != key {
+		tlsConfig, err := buildDatabaseTLSConfig(config)
+		if err != nil {
+			return "", err
+		}
+		if err := mysql.RegisterTLSConfig(databaseTLSConfigName, tlsConfig); err != nil {
+			return "", err
+		}
+		registeredDatabaseTLS.key = key
+	}
+
+	params := "&tls=" + databaseTLSConfigName
+	if config.Database.SSLMode == SSLModePreferred {
+		params += "&allowFallbackToPlaintext=true"
+	}
+	return params, nil
+}
+
+// buildDatabaseTLSArgs returns the TLS options for mariadb-dump,
+// mariadb-binlog and mariadb-check. Preferred leaves TLS to the client's
+// own default. The client tools only know one verification option, so
+// verify_ca checks the host name as well.
+func buildDatabaseTLSArgs(config *Config) []string {
+	db := config.Database
+	if db.SSLMode == SSLModeDisabled {
+		return []string{"--skip-ssl"}
+	}
+	if !databaseUsesTLS(config) || db.SSLMode == SSLModePreferred {
+		return nil
+	}
+
+	args := []string{"--ssl"}
+	if db.SSLCA != "" {
+		args = append(args, "--ssl-ca="+db.SSLCA)
+	}
+	if db.SSLCert != "" {
+		args = append(args, "--ssl-cert="+db.SSLCert)
+	}
+	if db.SSLKey != "" {
+		args = append(args, "--ssl-key="+db.SSLKey)
+	}
+	if db.SSLMode == SSLModeRequired {
+		args = append(args, "--skip-ssl-verify-server-cert")
+	} else {
+		args = append(args, "--ssl-verify-server-cert")
+	}
+	return args
+}
+
+// queryConnectionTLS returns the TLS version and cipher negotiated by the
+// server for the session, both empty when the connection is not encrypted
+func queryConnectionTLS(db *sql.DB) (version, cipher string, err error) {
+	rows, err := db.Query("SHOW SESSION STATUS WHERE Variable_name IN ('Ssl_version', 'Ssl_cipher')")
+	if err != nil {
+		return "", "", err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name, value string
+		if err := rows.Scan(&name, &value); err != nil {
+			return "", "", err
+		}
+		switch name {
+		case "Ssl_version":
+			version = value
+		case "Ssl_cipher":
+			cipher = value
+		}
+	}
+	return version, cipher, rows.Err()
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+
+	"github.com/shirou/gopsutil/v3/disk"
+)
+
+// Actions when a backup would not fit on disk
+const (
+	LowSpaceRefuse = "refuse" // Fail the database backup before it starts
+	LowSpacePrune  = "prune"  // Delete the oldest backup groups to make room
+)
+
+// Bytes reserved by backups that are currently being written, so parallel
+// workers do not all count the same free space
+var reservedBackupSpace int64
+var reservedBackupSpaceMutex sync.Mutex
+
+// estimateBackupSize estimates the dump size of a database from its on-disk size
+func estimateBackupSize(dbSizeBytes int64, config *Config) int64 {
+	ratio := 1.0
+	if runtime.GOOS != "windows" && config.Backup.CompressionLevel > 0 {
+		ratio = config.Backup.CompressionRatio
+		if ratio <= 0 {
+			ratio = 0.3
+		}
+	}
+
+	return int64(float64(dbSizeBytes) * ratio)
+}
+
+// getBackupFreeSpace returns the free bytes of the filesystem holding the backup directory
+func getBackupFreeSpace(config *Config) (int64, error) {
+	usage, err := disk.Usage(config.Backup.BackupDir)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get free space of %s: %v", config.Backup.BackupDir, err)
+	}
+
+	return int64(usage.Free), nil
+}
+
+// reserveBackupSpace checks that a backup of estimatedBytes fits on disk while
+// keeping min_free_space_mb free, and reserves the space until releaseBackupSpace.
+// With low_space_action "prune" the oldest backup groups are deleted to make room.
+func reserveBackupSpace(config *Config, dbName string, estimatedBytes int64) error {
+	reservedBackupSpaceMutex.Lock()
+	defer reservedBackupSpaceMutex.Unlock()
+
+	minFree := int64(config.Backup.MinFreeSpaceMB) * 1024 * 1024
+
+	free, err := getBackupFreeSpace(config)
+	if err != nil {
+		// Don't block backups when free space cannot be determined
+		LogWarn("⚠️ [DISK-SPACE] %v - skipping pre-flight check for %s", err, dbName)
+		return nil
+	}
+
+	available := free - reservedBackupSpace - minFree
+	if estimatedBytes > available {
+		shortfall := estimatedBytes - available
+		LogWarn("⚠️ [DISK-SPACE] Backup of %s needs ~%s but only %s is available (free %s, reserved %s, minimum free %s)",
+			dbName, formatBytes(estimatedBytes), formatBytes(max(available, 0)), formatBytes(free),
+			formatBytes(reservedBackupSpace), formatBytes(minFree))
+
+		if config.Backup.LowSpaceAction != LowSpacePrune {
+			err := fmt.Errorf("not enough disk space for backup of %s: needs ~%s, %s available",
+				dbName, formatBytes(estimatedBytes), formatBytes(max(available, 0)))
+			notifyDiskSpace(config, SeverityCritical, dbName, err.Error(), free, estimatedBytes)
+			return err
+		}
+
+		freed, err := pruneForSpace(config, shortfall)
+		if err != nil {
+			err = fmt.Errorf("not enough disk space for backup of %s and pruning failed: %v", dbName, err)
+			notifyDiskSpace(config, SeverityCritical, dbName, err.Error(), free, estimatedBytes)
+			return err
+		}
+		if freed < shortfall {
+			err = fmt.Errorf("not enough disk space for backup of %s: needs ~%s more, only %s could be pruned, no backups were deleted",
+				dbName, formatBytes(shortfall), formatBytes(freed))
+			notifyDiskSpace(config, SeverityCritical, dbName, err.Error(), free, estimatedBytes)
+			return err
+		}
+		LogInfo("🧹 [DISK-SPACE] Pruned %s of old backups to make room for %s", formatBytes(freed), dbName)
+		notifyDiskSpace(config, SeverityWarning, dbName,
+			fmt.Sprintf("Pruned %s of old backups to make room for %s", formatBytes(freed), dbName), free, estimatedBytes)
+	}
+
+	reservedBackupSpace += estimatedBytes
+	return nil
+}
+
+// notifyDiskSpace raises a disk_space_warning event for a backup that did not fit
+func notifyDiskSpace(config *Config, severity, dbName, message string, free, estimatedBytes int64) {
+	title := fmt.Sprintf("Low disk space for backup of %s", dbName)
+	if severity == SeverityCritical {
+		title = fmt.Sprintf("Backup of %s refused: not enough disk space", dbName)
+	}
+
+	NotifyEvent(config, NotificationEvent{
+		Type:      EventDiskSpaceWarning,
+		Severity:  severity,
+		Title:     title,
+		Message:   message,
+		Databases: []string{dbName},
+		Fields: []notificationField{
+			{"Backup Directory", config.Backup.BackupDir},
+			{"Free", formatBytes(free)},
+			{"Needed", formatBytes(estimatedBytes)},
+			{"Minimum Free", fmt.Sprintf("%d MB", config.Backup.MinFreeSpaceMB)},
+		},
+	})
+}
+
+// releaseBackupSpace releases space reserved by reserveBackupSpace
+func releaseBackupSpace(estimatedBytes int64) {
+	reservedBackupSpaceMutex.Lock()
+	reservedBackupSpace -= estimatedBytes
+	reservedBackupSpaceMutex.Unlock()
+}
+
+// pruneForSpace deletes the oldest retained backup groups until neededBytes are
+// freed. When all candidate groups together cannot free neededBytes nothing is
+// deleted. Returns the bytes freed, or the bytes that could have been freed.
+func pruneForSpace(config *Config, neededBytes int64) (int64, error) {
+	plans, err := planAllRetention(config, config.Backup.GFSRetention)
+	if err != nil {
+		return 0, err
+	}
+
+	// Groups already past retention go first
+	var freed int64
+	for _, decisions := range plans {
+		for _, decision := range decisions {
+			if !decision.Keep {
+				freed += groupSize(decision.Group)
+			}
+		}
+	}
+	if freed < neededBytes {
+		freed += pruneOldestGroups(plans, neededBytes-freed, "low disk space")
+	}
+	if freed < neededBytes {
+		// Deleting would not make room, so keep the backups
+		return freed, nil
+	}
+
+	var allDeletedFiles []string
+	for databaseName, decisions := range plans {
+		_, deletedFiles := deleteBackupGroups(databaseName, decisions)
+		allDeletedFiles = append(allDeletedFiles, deletedFiles...)
+	}
+	if len(allDeletedFiles) > 0 {
+		createDeletionLog(allDeletedFiles, "low_disk_space")
+		recordAuditEntry(nil, AuditEntry{Action: AuditBackupDelete, Target: "low disk space", Before: auditValue(allDeletedFiles), Details: "low_disk_space"})
+	}
+
+	return freed, nil
+}
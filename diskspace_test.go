@@ -0,0 +1,44 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestPruneForSpace(t *testing.T) {
+	dir := useTestWorkdir(t)
+	backupDir := filepath.Join(dir, "backups")
+	// 3 groups of 2 files with 1000 bytes each, the newest is never pruned
+	fulls := writeTestBackupGroups(t, backupDir, "shop", 3, 1000)
+
+	config := &Config{}
+	config.Backup.BackupDir = backupDir
+
+	freed, err := pruneForSpace(config, 5000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if freed != 4000 {
+		t.Errorf("freeable = %d, want 4000", freed)
+	}
+	if remaining := remainingBackups(t, backupDir, "shop"); len(remaining) != 6 {
+		t.Fatalf("backups deleted although the shortfall could not be covered: %v", remaining)
+	}
+
+	freed, err = pruneForSpace(config, 1500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if freed != 2000 {
+		t.Errorf("freed = %d, want 2000", freed)
+	}
+	remaining := remainingBackups(t, backupDir, "shop")
+	if len(remaining) != 4 {
+		t.Fatalf("remaining backups = %v, want the 2 newest groups", remaining)
+	}
+	for _, name := range remaining {
+		if name == fulls[2] {
+			t.Errorf("oldest group %s was not pruned", name)
+		}
+	}
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// How a new job is handled when it shares databases with a pending or running job
+const (
+	JobConflictWait = "wait" // Queue the job until the conflicting job has finished
+	JobConflictSkip = "skip" // Reject the job
+)
+
+// QueuedJob is a backup job waiting for or holding a slot in the job queue
+type QueuedJob struct {
+	JobID       string    `json:"job_id"`
+	Type        string    `json:"type"` // full, incremental, retry
+	BackupMode  string    `json:"backup_mode"`
+	Databases   []string  `json:"databases"`
+	RequestedBy string    `json:"requested_by"`
+	Policy      string    `json:"policy"`
+	State       string    `json:"state"` // pending, running
+	QueuedAt    time.Time `json:"queued_at"`
+	StartedAt   time.Time `json:"started_at"`
+	run         func()
+}
+
+// JobQueue runs backup jobs one after another (or up to maxConcurrent at a time)
+// and never runs two jobs that back up the same database at once
+type JobQueue struct {
+	mutex         sync.Mutex
+	pending       []*QueuedJob
+	running       map[string]*QueuedJob
+	maxConcurrent int
+	closed        bool
+}
+
+var jobQueue = &JobQueue{
+	running:       make(map[string]*QueuedJob),
+	maxConcurrent: 1,
+}
+
+// ConfigureJobQueue applies the concurrency limit from the configuration
+func ConfigureJobQueue(config *Config) {
+	limit := config.Backup.MaxConcurrentJobs
+	if limit <= 0 {
+		limit = 1
+	}
+
+	jobQueue.mutex.Lock()
+	jobQueue.maxConcurrent = limit
+	jobQueue.mutex.Unlock()
+
+	LogDebug("Job queue configured - max concurrent jobs: %d", limit)
+
+	// A higher limit may allow pending jobs to start
+	jobQueue.dispatch()
+}
+
+// getScheduleConflictPolicy returns how scheduled jobs handle conflicts, defaulting to skip
+func getScheduleConflictPolicy(config *Config) string {
+	if config.Backup.ScheduleConflict == JobConflictWait {
+		return JobConflictWait
+	}
+	return JobConflictSkip
+}
+
+// EnqueueJob adds a job to the queue and starts it when a slot is free.
+// Returns true when the job started immediately. With JobConflictSkip the job
+// is rejected if it shares a database with a pending or running job.
+func EnqueueJob(job *QueuedJob, onConflict string) (bool, error) {
+	jobQueue.mutex.Lock()
+	if jobQueue.closed {
+		jobQueue.mutex.Unlock()
+		return false, fmt.Errorf("job %s rejected: service is shutting down", job.JobID)
+	}
+	if onConflict == JobConflictSkip {
+		if other := jobQueue.findConflict(job, true); other != nil {
+			jobQueue.mutex.Unlock()
+			return false, fmt.Errorf("job %s skipped: %s job %s is already %s for the same databases",
+				job.JobID, other.Type, other.JobID, other.State)
+		}
+	}
+
+	job.State = "pending"
+	job.QueuedAt = time.Now()
+	jobQueue.pending = append(jobQueue.pending, job)
+	jobQueue.mutex.Unlock()
+
+	LogInfo("📥 [QUEUE] Job %s queued - Type: %s, Databases: %s, RequestedBy: %s",
+		job.JobID, job.Type, formatDatabaseList(job.Databases), job.RequestedBy)
+
+	jobQueue.dispatch()
+
+	jobQueue.mutex.Lock()
+	defer jobQueue.mutex.Unlock()
+	return job.State == "running", nil
+}
+
+// CancelPendingJobs removes all jobs that have not started yet and returns how many were removed
+func CancelPendingJobs() int {
+	jobQueue.mutex.Lock()
+	defer jobQueue.mutex.Unlock()
+
+	count := len(jobQueue.pending)
+	for _, job := range jobQueue.pending {
+		LogInfo("🗑️ [QUEUE] Pending job %s removed from queue", job.JobID)
+	}
+	jobQueue.pending = nil
+
+	return count
+}
+
+// CancelPendingJob removes a job that has not started yet. Returns false when
+// the job is not pending.
+func CancelPendingJob(jobID string) bool {
+	jobQueue.mutex.Lock()
+	defer jobQueue.mutex.Unlock()
+
+	for i, job := range jobQueue.pending {
+		if job.JobID == jobID {
+			jobQueue.pending = append(jobQueue.pending[:i], jobQueue.pending[i+1:]...)
+			LogInfo("🗑️ [QUEUE] Pending job %s removed from queue", jobID)
+			return true
+		}
+	}
+
+	return false
+}
+
+// CloseJobQueue stops accepting new jobs and drops the pending ones. Running
+// jobs keep running. Returns the number of pending jobs dropped.
+func CloseJobQueue() int {
+	jobQueue.mutex.Lock()
+	jobQueue.closed = true
+	jobQueue.mutex.Unlock()
+
+	return CancelPendingJobs()
+}
+
+// WaitForRunningJobs waits until no job is running or the timeout expires.
+// Returns true when all jobs have finished.
+func WaitForRunningJobs(timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for {
+		jobQueue.mutex.Lock()
+		running := len(jobQueue.running)
+		jobQueue.mutex.Unlock()
+
+		if running == 0 {
+			return true
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
+
+// GetQueuedJob returns a copy of a pending or running job, or nil if the job is not in the queue
+func GetQueuedJob(jobID string) *QueuedJob {
+	jobQueue.mutex.Lock()
+	defer jobQueue.mutex.Unlock()
+
+	if job, ok := jobQueue.running[jobID]; ok {
+		copied := *job
+		return &copied
+	}
+	for _, job := range jobQueue.pending {
+		if job.JobID == jobID {
+			copied := *job
+			return &copied
+		}
+	}
+
+	return nil
+}
+
+// QueuedJobs returns copies of the pending jobs in queue order and of the
+// running jobs, oldest first
+func QueuedJobs() (pending, running []QueuedJob) {
+	jobQueue.mutex.Lock()
+	defer jobQueue.mutex.Unlock()
+
+	pending = []QueuedJob{}
+	for _, job := range jobQueue.pending {
+		pending = append(pending, *job)
+	}
+
+	running = []QueuedJob{}
+	for _, job := range jobQueue.running {
+		running = append(running, *job)
+	}
+	sort.Slice(running, func(i, j int) bool {
+		return running[i].StartedAt.Before(running[j].StartedAt)
+	})
+
+	return pending, running
+}
+
+// GetJobQueueStatus returns the pending and running jobs of the queue
+func GetJobQueueStatus() map[string]interface{} {
+	pending, running := QueuedJobs()
+
+	jobQueue.mutex.Lock()
+	defer jobQueue.mutex.Unlock()
+
+	return map[string]interface{}{
+		"pending":             pending,
+		"running":             running,
+		"total_pending":       len(pending),
+		"total_running":       len(running),
+		"max_concurrent_jobs": jobQueue.maxConcurrent,
+	}
+}
+
+// dispatch starts pending jobs in order while slots are free. A job whose
+// databases overlap with a running or earlier pending job keeps waiting.
+func (q *JobQueue) dispatch() {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	var stillPending []*QueuedJob
+	for i, job := range q.pending {
+		if len(q.running) >= q.maxConcurrent {
+			stillPending = append(stillPending, q.pending[i:]...)
+			break
+		}
+
+		if q.findConflict(job, false) != nil || sharesDatabases(job, stillPending) {
+			stillPending = append(stillPending, job)
+			continue
+		}
+
+		job.State = "running"
+		job.StartedAt = time.Now()
+		q.running[job.JobID] = job
+
+		if waited := job.StartedAt.Sub(job.QueuedAt); waited >= time.Second {
+			LogInfo("▶️ [QUEUE] Job %s started after waiting %s", job.JobID, formatDuration(waited))
+		}
+
+		go q.execute(job)
+	}
+
+	q.pending = stillPending
+}
+
+// execute runs a job and frees its slot when done
+func (q *JobQueue) execute(job *QueuedJob) {
+	defer func() {
+		if r := recover(); r != nil {
+			LogError("❌ [QUEUE] Job %s panicked: %v", job.JobID, r)
+		}
+
+		q.mutex.Lock()
+		delete(q.running, job.JobID)
+		q.mutex.Unlock()
+
+		LogDebug("✅ [QUEUE] Job %s finished, slot released", job.JobID)
+		q.dispatch()
+	}()
+
+	job.run()
+}
+
+// findConflict returns a running (or, if includePending, pending) job that
+// shares a database with job. Caller must hold q.mutex.
+func (q *JobQueue) findConflict(job *QueuedJob, includePending bool) *QueuedJob {
+	for _, other := range q.running {
+		if sharesDatabases(job, []*QueuedJob{other}) {
+			return other
+		}
+	}
+
+	if includePending {
+		for _, other := range q.pending {
+			if sharesDatabases(job, []*QueuedJob{other}) {
+				return other
+			}
+		}
+	}
+
+	return nil
+}
+
+// sharesDatabases reports whether job backs up a database of any of the other jobs
+func sharesDatabases(job *QueuedJob, others []*QueuedJob) bool {
+	databases := make(map[string]bool, len(job.Databases))
+	for _, db := range job.Databases {
+		databases[db] = true
+	}
+
+	for _, other := range others {
+		for _, db := range other.Databases {
+			if databases[db] {
+				return true
+			}
+		}
+	}
+
+	return false
+}
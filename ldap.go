@@ -0,0 +1,695 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// A minimal LDAPv3 client (RFC 4511): simple bind, StartTLS and search,
+// which is all a password check with group lookup needs.
+
+const (
+	ldapTimeout        = 10 * time.Second // Per connection attempt, an authentication gets three times this
+	ldapMaxMessageSize = 16 << 20         // Larger responses are refused
+
+	ldapResultSuccess            = 0
+	ldapResultSizeLimitExceeded  = 4
+	ldapResultInvalidCredentials = 49
+	ldapStartTLSOID              = "1.3.6.1.4.1.1466.20037"
+	ldapDefaultUserFilter        = "(uid={username})"
+	ldapDefaultGroupFilter       = "(member={dn})"
+	ldapDefaultGroupAttribute    = "cn"
+	ldapScopeWholeSubtree        = 2
+	ldapDerefNever               = 0
+	ldapNoAttributes             = "1.1"
+	ldapProtocolVersion          = 3
+)
+
+// BER and LDAP protocol tags
+const (
+	ldapSimpleAuthenticationTag  = 0x80
+	ldapExtendedRequestNameTag   = 0x80
+	ldapSubstringInitialTag      = 0x80
+	ldapSubstringAnyTag          = 0x81
+	ldapSubstringFinalTag        = 0x82
+	ldapFilterPresentTag         = 0x87
+	ldapFilterAndTag             = 0xa0
+	ldapFilterOrTag              = 0xa1
+	ldapFilterNotTag             = 0xa2
+	ldapFilterEqualityTag        = 0xa3
+	ldapFilterSubstringsTag      = 0xa4
+	ldapFilterGreaterOrEqualTag  = 0xa5
+	ldapFilterLessOrEqualTag     = 0xa6
+	ldapFilterApproxMatchTag     = 0xa8
+	ldapBindRequestTag           = 0x60
+	ldapBindResponseTag          = 0x61
+	ldapUnbindRequestTag         = 0x42
+	ldapSearchRequestTag         = 0x63
+	ldapSearchResultEntryTag     = 0x64
+	ldapSearchResultDoneTag      = 0x65
+	ldapSearchResultReferenceTag = 0x73
+	ldapExtendedRequestTag       = 0x77
+	ldapExtendedResponseTag      = 0x78
+	berTagBoolean                = 0x01
+	berTagInteger                = 0x02
+	berTagOctetString            = 0x04
+	berTagEnumerated             = 0x0a
+	berTagSequence               = 0x30
+	berTagSet                    = 0x31
+	berConstructedBit            = 0x20
+)
+
+// berPacket is a decoded BER element. Constructed elements have children.
+type berPacket struct {
+	Tag      byte
+	Value    []byte
+	Children []*berPacket
+}
+
+// berEncode encodes one element with a definite length
+func berEncode(tag byte, value []byte) []byte {
+	encoded := []byte{tag}
+	if len(value) < 0x80 {
+		encoded = append(encoded, byte(len(value)))
+	} else {
+		var length []byte
+		for n := len(value); n > 0; n >>= 8 {
+			length = append([]byte{byte(n)}, length...)
+		}
+		encoded = append(encoded, 0x80|byte(len(length)))
+		encoded = append(encoded, length...)
+	}
+	return append(encoded, value...)
+}
+
+// berConstructed encodes a constructed element from already encoded children
+func berConstructed(tag byte, children ...[]byte) []byte {
+	return berEncode(tag, bytes.Join(children, nil))
+}
+
+func berString(tag byte, value string) []byte {
+	return berEncode(tag, []byte(value))
+}
+
+// berInt encodes a non-negative INTEGER or ENUMERATED
+func berInt(tag byte, value int) []byte {
+	encoded := []byte{byte(value)}
+	for value >>= 8; value > 0; value >>= 8 {
+		encoded = append([]byte{byte(value)}, encoded...)
+	}
+	if encoded[0]&0x80 != 0 {
+		encoded = append([]byte{0}, encoded...)
+	}
+	return berEncode(tag, encoded)
+}
+
+func berBool(value bool) []byte {
+	if value {
+		return berEncode(berTagBoolean, []byte{0xff})
+	}
+	return berEncode(berTagBoolean, []byte{0})
+}
+
+// berRead reads one complete element from a stream
+func berRead(reader io.Reader) (*berPacket, error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return nil, err
+	}
+
+	length := int(header[1])
+	if length&0x80 != 0 {
+		size := length &^ 0x80
+		if size == 0 || size > 4 {
+			return nil, fmt.Errorf("unsupported BER length encoding")
+		}
+		lengthBytes := make([]byte, size)
+		if _, err := io.ReadFull(reader, lengthBytes); err != nil {
+			return nil, err
+		}
+		length = 0
+		for _, b := range lengthBytes {
+			length = length<<8 | int(b)
+		}
+	}
+	if length > ldapMaxMessageSize {
+		return nil, fmt.Errorf("LDAP message of %d bytes is too large", length)
+	}
+
+	value := make([]byte, length)
+	if _, err := io.ReadFull(reader, value); err != nil {
+		return nil, err
+	}
+	return berParse(header[0], value)
+}
+
+// berParse builds a packet and, for constructed tags, its children
+func berParse(tag byte, value []byte) (*berPacket, error) {
+	packet := &berPacket{Tag: tag, Value: value}
+	if tag&berConstructedBit == 0 {
+		return packet, nil
+	}
+
+	reader := bytes.NewReader(value)
+	for reader.Len() > 0 {
+		child, err := berRead(reader)
+		if err != nil {
+			return nil, fmt.Errorf("malformed BER element: %v", err)
+		}
+		packet.Children = append(packet.Children, child)
+	}
+	return packet, nil
+}
+
+// int decodes an INTEGER or ENUMERATED value
+func (p *berPacket) int() (int, error) {
+	if len(p.Value) == 0 || len(p.Value) > 4 {
+		return 0, fmt.Errorf("invalid BER integer")
+	}
+	value := int(int8(p.Value[0]))
+	for _, b := range p.Value[1:] {
+		value = value<<8 | int(b)
+	}
+	return value, nil
+}
+
+// ldapResultError is a non-success LDAP result code
+type ldapResultError struct {
+	Code    int
+	Message string
+}
+
+func (e *ldapResultError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("LDAP result code %d", e.Code)
+	}
+	return fmt.Sprintf("LDAP result code %d: %s", e.Code, e.Message)
+}
+
+// ldapResult checks the LDAPResult at the start of a response operation
+func ldapResult(op *berPacket) error {
+	if len(op.Children) < 3 {
+		return fmt.Errorf("malformed LDAP result")
+	}
+	code, err := op.Children[0].int()
+	if err != nil {
+		return err
+	}
+	if code == ldapResultSuccess {
+		return nil
+	}
+	return &ldapResultError{Code: code, Message: string(op.Children[2].Value)}
+}
+
+// ldapEntry is a search result. Attribute names are lower-cased.
+type ldapEntry struct {
+	DN         string
+	Attributes map[string][]string
+}
+
+type ldapConn struct {
+	conn      net.Conn
+	reader    *bufio.Reader
+	messageID int
+}
+
+// ldapDial connects to the configured server, upgrading with StartTLS when set
+func ldapDial(config *LDAPConfig) (*ldapConn, error) {
+	server, err := url.Parse(config.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid LDAP URL: %v", err)
+	}
+
+	address := server.Host
+	if server.Port() == "" {
+		port := "389"
+		if server.Scheme == "ldaps" {
+			port = "636"
+		}
+		address = net.JoinHostPort(server.Hostname(), port)
+	}
+
+	tlsConfig := &tls.Config{
+		ServerName:         server.Hostname(),
+		InsecureSkipVerify: config.InsecureSkipVerify,
+		MinVersion:         tls.VersionTLS12,
+	}
+
+	dialer := &net.Dialer{Timeout: ldapTimeout}
+	var conn net.Conn
+	if server.Scheme == "ldaps" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", address, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", address)
+	}
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Now().Add(3 * ldapTimeout))
+
+	c := &ldapConn{conn: conn, reader: bufio.NewReader(conn)}
+	if config.StartTLS {
+		if err := c.startTLS(tlsConfig); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("StartTLS failed: %v", err)
+		}
+	}
+	return c, nil
+}
+
+// send wraps an operation in an LDAPMessage and returns its message ID
+func (c *ldapConn) send(op []byte) (int, error) {
+	c.messageID++
+	message := berConstructed(berTagSequence, berInt(berTagInteger, c.messageID), op)
+	_, err := c.conn.Write(message)
+	return c.messageID, err
+}
+
+// receive returns the operation of the next response to a message ID
+func (c *ldapConn) receive(messageID int) (*berPacket, error) {
+	for {
+		packet, err := berRead(c.reader)
+		if err != nil {
+			return nil, err
+		}
+		if packet.Tag != berTagSequence || len(packet.Children) < 2 {
+			return nil, fmt.Errorf("malformed LDAP message")
+		}
+		id, err := packet.Children[0].int()
+		if err != nil {
+			return nil, err
+		}
+		if id == 0 {
+			// Unsolicited notification, sent before the server drops the connection
+			return nil, fmt.Errorf("server closed the connection: %v", ldapResult(packet.Children[1]))
+		}
+		if id == messageID {
+			return packet.Children[1], nil
+		}
+	}
+}
+
+func (c *ldapConn) startTLS(tlsConfig *tls.Config) error {
+	id, err := c.send(berConstructed(ldapExtendedRequestTag, berString(ldapExtendedRequestNameTag, ldapStartTLSOID)))
+	if err != nil {
+		return err
+	}
+	op, err := c.receive(id)
+	if err != nil {
+		return err
+	}
+	if op.Tag != ldapExtendedResponseTag {
+		return fmt.Errorf("unexpected response to StartTLS")
+	}
+	if err := ldapResult(op); err != nil {
+		return err
+	}
+
+	tlsConn := tls.Client(c.conn, tlsConfig)
+	if err := tlsConn.Handshake(); err != nil {
+		return err
+	}
+	c.conn = tlsConn
+	c.reader = bufio.NewReader(tlsConn)
+	return nil
+}
+
+// bind performs a simple bind
+func (c *ldapConn) bind(dn, password string) error {
+	id, err := c.send(berConstructed(ldapBindRequestTag,
+		berInt(berTagInteger, ldapProtocolVersion),
+		berString(berTagOctetString, dn),
+		berString(ldapSimpleAuthenticationTag, password)))
+	if err != nil {
+		return err
+	}
+	op, err := c.receive(id)
+	if err != nil {
+		return err
+	}
+	if op.Tag != ldapBindResponseTag {
+		return fmt.Errorf("unexpected response to bind")
+	}
+	return ldapResult(op)
+}
+
+// search runs a subtree search. Hitting sizeLimit is not an error, the
+// entries received so far are returned.
+func (c *ldapConn) search(baseDN, filter string, attributes []string, sizeLimit int) ([]ldapEntry, error) {
+	encodedFilter, err := parseLDAPFilter(filter)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter %s: %v", filter, err)
+	}
+
+	var encodedAttributes [][]byte
+	for _, attribute := range attributes {
+		encodedAttributes = append(encodedAttributes, berString(berTagOctetString, attribute))
+	}
+
+	id, err := c.send(berConstructed(ldapSearchRequestTag,
+		berString(berTagOctetString, baseDN),
+		berInt(berTagEnumerated, ldapScopeWholeSubtree),
+		berInt(berTagEnumerated, ldapDerefNever),
+		berInt(berTagInteger, sizeLimit),
+		berInt(berTagInteger, int(ldapTimeout.Seconds())),
+		berBool(false),
+		encodedFilter,
+		berConstructed(berTagSequence, encodedAttributes...)))
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []ldapEntry
+	for {
+		op, err := c.receive(id)
+		if err != nil {
+			return nil, err
+		}
+
+		switch op.Tag {
+		case ldapSearchResultEntryTag:
+			entry, err := parseLDAPEntry(op)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, entry)
+		case ldapSearchResultReferenceTag:
+			// Referrals to other servers are not followed
+		case ldapSearchResultDoneTag:
+			err := ldapResult(op)
+			var resultErr *ldapResultError
+			if errors.As(err, &resultErr) && resultErr.Code == ldapResultSizeLimitExceeded {
+				err = nil
+			}
+			return entries, err
+		default:
+			return nil, fmt.Errorf("unexpected response to search")
+		}
+	}
+}
+
+// close sends an unbind and closes the connection
+func (c *ldapConn) close() {
+	c.send(berEncode(ldapUnbindRequestTag, nil))
+	c.conn.Close()
+}
+
+// parseLDAPEntry decodes a SearchResultEntry
+func parseLDAPEntry(op *berPacket) (ldapEntry, error) {
+	if len(op.Children) < 2 {
+		return ldapEntry{}, fmt.Errorf("malformed search result entry")
+	}
+
+	entry := ldapEntry{DN: string(op.Children[0].Value), Attributes: make(map[string][]string)}
+	for _, attribute := range op.Children[1].Children {
+		if len(attribute.Children) < 2 {
+			return ldapEntry{}, fmt.Errorf("malformed attribute in %s", entry.DN)
+		}
+		name := strings.ToLower(string(attribute.Children[0].Value))
+		for _, value := range attribute.Children[1].Children {
+			entry.Attributes[name] = append(entry.Attributes[name], string(value.Value))
+		}
+	}
+	return entry, nil
+}
+
+// escapeLDAPFilterValue escapes a value for use inside a filter (RFC 4515)
+func escapeLDAPFilterValue(value string) string {
+	var escaped strings.Builder
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; c {
+		case '*', '(', ')', '\\', 0:
+			fmt.Fprintf(&escaped, "\\%02x", c)
+		default:
+			escaped.WriteByte(c)
+		}
+	}
+	return escaped.String()
+}
+
+// unescapeLDAPFilterValue decodes the \XX escapes of a filter value
+func unescapeLDAPFilterValue(value string) (string, error) {
+	if !strings.Contains(value, "\\") {
+		return value, nil
+	}
+
+	var decoded []byte
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' {
+			decoded = append(decoded, value[i])
+			continue
+		}
+		if i+2 >= len(value) {
+			return "", fmt.Errorf("incomplete escape in %q", value)
+		}
+		b, err := hex.DecodeString(value[i+1 : i+3])
+		if err != nil {
+			return "", fmt.Errorf("invalid escape in %q", value)
+		}
+		decoded = append(decoded, b[0])
+		i += 2
+	}
+	return string(decoded), nil
+}
+
+// parseLDAPFilter encodes a string filter (RFC 4515) for a search request.
+// Extensible match filters are not supported.
+func parseLDAPFilter(filter string) ([]byte, error) {
+	encoded, rest, err := parseLDAPFilterItem(strings.TrimSpace(filter))
+	if err != nil {
+		return nil, err
+	}
+	if rest != "" {
+		return nil, fmt.Errorf("unexpected %q after filter", rest)
+	}
+	return encoded, nil
+}
+
+// parseLDAPFilterItem encodes the parenthesized filter at the start of s and
+// returns the remaining input
+func parseLDAPFilterItem(s string) ([]byte, string, error) {
+	if !strings.HasPrefix(s, "(") || len(s) < 2 {
+		return nil, "", fmt.Errorf("filter must start with (")
+	}
+	s = s[1:]
+
+	switch s[0] {
+	case '&', '|':
+		operator := s[0]
+		tag := byte(ldapFilterAndTag)
+		if operator == '|' {
+			tag = ldapFilterOrTag
+		}
+		s = s[1:]
+		var parts [][]byte
+		for strings.HasPrefix(s, "(") {
+			part, rest, err := parseLDAPFilterItem(s)
+			if err != nil {
+				return nil, "", err
+			}
+			parts = append(parts, part)
+			s = rest
+		}
+		if len(parts) == 0 || !strings.HasPrefix(s, ")") {
+			return nil, "", fmt.Errorf("invalid %c filter", operator)
+		}
+		return berConstructed(tag, parts...), s[1:], nil
+
+	case '!':
+		part, rest, err := parseLDAPFilterItem(s[1:])
+		if err != nil {
+			return nil, "", err
+		}
+		if !strings.HasPrefix(rest, ")") {
+			return nil, "", fmt.Errorf("invalid ! filter")
+		}
+		return berConstructed(ldapFilterNotTag, part), rest[1:], nil
+	}
+
+	end := strings.IndexByte(s, ')')
+	if end < 0 {
+		return nil, "", fmt.Errorf("missing ) in filter")
+	}
+	encoded, err := encodeLDAPFilterItem(s[:end])
+	return encoded, s[end+1:], err
+}
+
+// encodeLDAPFilterItem encodes a single attribute comparison such as uid=jane
+func encodeLDAPFilterItem(item string) ([]byte, error) {
+	separator := strings.IndexByte(item, '=')
+	if separator <= 0 {
+		return nil, fmt.Errorf("invalid filter item %q", item)
+	}
+
+	attribute, value := item[:separator], item[separator+1:]
+	tag := byte(ldapFilterEqualityTag)
+	switch attribute[len(attribute)-1] {
+	case '>':
+		tag, attribute = ldapFilterGreaterOrEqualTag, attribute[:len(attribute)-1]
+	case '<':
+		tag, attribute = ldapFilterLessOrEqualTag, attribute[:len(attribute)-1]
+	case '~':
+		tag, attribute = ldapFilterApproxMatchTag, attribute[:len(attribute)-1]
+	case ':':
+		return nil, fmt.Errorf("extensible match filters are not supported")
+	}
+	if attribute == "" || strings.ContainsAny(attribute, "()*\\ ") {
+		return nil, fmt.Errorf("invalid attribute in filter item %q", item)
+	}
+
+	if tag == ldapFilterEqualityTag && value == "*" {
+		return berString(ldapFilterPresentTag, attribute), nil
+	}
+
+	if tag == ldapFilterEqualityTag && strings.Contains(value, "*") {
+		parts := strings.Split(value, "*")
+		var substrings [][]byte
+		for i, part := range parts {
+			if part == "" {
+				continue
+			}
+			decoded, err := unescapeLDAPFilterValue(part)
+			if err != nil {
+				return nil, err
+			}
+			partTag := byte(ldapSubstringAnyTag)
+			if i == 0 {
+				partTag = ldapSubstringInitialTag
+			} else if i == len(parts)-1 {
+				partTag = ldapSubstringFinalTag
+			}
+			substrings = append(substrings, berString(partTag, decoded))
+		}
+		return berConstructed(ldapFilterSubstringsTag,
+			berString(berTagOctetString, attribute),
+			berConstructed(berTagSequence, substrings...)), nil
+	}
+
+	decoded, err := unescapeLDAPFilterValue(value)
+	if err != nil {
+		return nil, err
+	}
+	return berConstructed(tag, berString(berTagOctetString, attribute), berString(berTagOctetString, decoded)), nil
+}
+
+// ldapUserFilter returns the user search filter for a username
+func ldapUserFilter(config *LDAPConfig, username string) string {
+	filter := config.UserFilter
+	if filter == "" {
+		filter = ldapDefaultUserFilter
+	}
+	return strings.ReplaceAll(filter, "{username}", escapeLDAPFilterValue(username))
+}
+
+// ldapGroupFilter returns the group search filter for a user
+func ldapGroupFilter(config *LDAPConfig, userDN, username string) string {
+	filter := config.GroupFilter
+	if filter == "" {
+		filter = ldapDefaultGroupFilter
+	}
+	filter = strings.ReplaceAll(filter, "{dn}", escapeLDAPFilterValue(userDN))
+	return strings.ReplaceAll(filter, "{username}", escapeLDAPFilterValue(username))
+}
+
+// ldapFirstRDNValue returns the value of the first RDN of a DN, such as dba
+// for cn=dba,ou=groups,dc=example,dc=com
+func ldapFirstRDNValue(dn string) string {
+	end := len(dn)
+	for i := 0; i < len(dn); i++ {
+		if dn[i] == '\\' {
+			i++
+		} else if dn[i] == ',' {
+			end = i
+			break
+		}
+	}
+	rdn := dn[:end]
+	if separator := strings.IndexByte(rdn, '='); separator >= 0 {
+		return strings.TrimSpace(rdn[separator+1:])
+	}
+	return ""
+}
+
+// ldapAuthenticate checks a password with a bind as the user found by the
+// user filter and returns the user's groups, as DNs and names. A wrong
+// username or password returns errInvalidLogin.
+func ldapAuthenticate(config *LDAPConfig, username, password string) ([]string, error) {
+	// An empty password would be an unauthenticated bind, which servers accept
+	if username == "" || password == "" {
+		return nil, errInvalidLogin
+	}
+
+	conn, err := ldapDial(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to LDAP server: %v", err)
+	}
+	defer conn.close()
+
+	if config.BindDN != "" {
+		if err := conn.bind(config.BindDN, config.BindPassword); err != nil {
+			return nil, fmt.Errorf("LDAP service account bind failed: %v", err)
+		}
+	}
+
+	attributes := []string{"memberOf"}
+	if config.GroupBaseDN != "" {
+		attributes = []string{ldapNoAttributes}
+	}
+	entries, err := conn.search(config.UserBaseDN, ldapUserFilter(config, username), attributes, 2)
+	if err != nil {
+		return nil, fmt.Errorf("LDAP user search failed: %v", err)
+	}
+	switch len(entries) {
+	case 0:
+		return nil, errInvalidLogin
+	case 1:
+	default:
+		return nil, fmt.Errorf("LDAP user filter matches more than one entry for %s", username)
+	}
+	user := entries[0]
+
+	if err := conn.bind(user.DN, password); err != nil {
+		var resultErr *ldapResultError
+		if errors.As(err, &resultErr) && resultErr.Code == ldapResultInvalidCredentials {
+			return nil, errInvalidLogin
+		}
+		return nil, fmt.Errorf("LDAP bind as %s failed: %v", user.DN, err)
+	}
+
+	var groups []string
+	if config.GroupBaseDN == "" {
+		for _, dn := range user.Attributes["memberof"] {
+			groups = append(groups, dn, ldapFirstRDNValue(dn))
+		}
+		return groups, nil
+	}
+
+	if config.BindDN != "" {
+		if err := conn.bind(config.BindDN, config.BindPassword); err != nil {
+			return nil, fmt.Errorf("LDAP service account bind failed: %v", err)
+		}
+	}
+
+	attribute := config.GroupAttribute
+	if attribute == "" {
+		attribute = ldapDefaultGroupAttribute
+	}
+	groupEntries, err := conn.search(config.GroupBaseDN, ldapGroupFilter(config, user.DN, username), []string{attribute}, 0)
+	if err != nil {
+		return nil, fmt.Errorf("LDAP group search failed: %v", err)
+	}
+	for _, entry := range groupEntries {
+		groups = append(groups, entry.DN)
+		groups = append(groups, entry.Attributes[strings.ToLower(attribute)]...)
+	}
+	return groups, nil
+}
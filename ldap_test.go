@@ -0,0 +1,440 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const (
+	testLDAPServiceDN       = "cn=backup-tool,ou=services,dc=example,dc=com"
+	testLDAPServicePassword = "service-secret"
+)
+
+// testLDAPServer is an in-process LDAPv3 server answering simple binds and
+// subtree searches from a fixed directory. It records the binds and the
+// equality assertions of the search filters it received.
+type testLDAPServer struct {
+	listener  net.Listener
+	entries   map[string]map[string][]string // DN to lower-cased attributes
+	passwords map[string]string              // DN to password
+
+	mutex      sync.Mutex
+	binds      []string
+	assertions []string
+}
+
+func newTestLDAPServer(t *testing.T) *testLDAPServer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := &testLDAPServer{
+		listener: listener,
+		entries: map[string]map[string][]string{
+			"uid=jane,ou=people,dc=example,dc=com": {
+				"objectclass": {"person"},
+				"uid":         {"jane"},
+				"memberof":    {"cn=dba-admins,ou=groups,dc=example,dc=com", "cn=staff,ou=groups,dc=example,dc=com"},
+			},
+			"uid=bob,ou=people,dc=example,dc=com": {
+				"objectclass": {"person"},
+				"uid":         {"bob"},
+			},
+			"uid=jane*)(uid=*,ou=people,dc=example,dc=com": {
+				"objectclass": {"person"},
+				"uid":         {"jane*)(uid=*"},
+			},
+			"cn=dba,ou=groups,dc=example,dc=com": {
+				"objectclass": {"groupOfNames"},
+				"cn":          {"dba"},
+				"member":      {"uid=jane,ou=people,dc=example,dc=com", "uid=bob,ou=people,dc=example,dc=com"},
+			},
+			"cn=auditors,ou=groups,dc=example,dc=com": {
+				"objectclass": {"groupOfNames"},
+				"cn":          {"auditors"},
+				"member":      {"uid=bob,ou=people,dc=example,dc=com"},
+			},
+		},
+		passwords: map[string]string{
+			testLDAPServiceDN:                              testLDAPServicePassword,
+			"uid=jane,ou=people,dc=example,dc=com":         "jane-password",
+			"uid=bob,ou=people,dc=example,dc=com":          "bob-password",
+			"uid=jane*)(uid=*,ou=people,dc=example,dc=com": "tricky-password",
+		},
+	}
+	go server.serve()
+	t.Cleanup(func() { listener.Close() })
+	return server
+}
+
+func (s *testLDAPServer) url() string {
+	return "ldap://" + s.listener.Addr().String()
+}
+
+func (s *testLDAPServer) config() *LDAPConfig {
+	return &LDAPConfig{
+		Enabled:      true,
+		URL:          s.url(),
+		BindDN:       testLDAPServiceDN,
+		BindPassword: testLDAPServicePassword,
+		UserBaseDN:   "ou=people,dc=example,dc=com",
+		UserFilter:   "(&(objectClass=person)(uid={username}))",
+	}
+}
+
+func (s *testLDAPServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *testLDAPServer) handle(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+
+	for {
+		packet, err := berRead(reader)
+		if err != nil || len(packet.Children) < 2 {
+			return
+		}
+		id, _ := packet.Children[0].int()
+		op := packet.Children[1]
+		reply := func(response []byte) {
+			conn.Write(berConstructed(berTagSequence, berInt(berTagInteger, id), response))
+		}
+		result := func(tag byte, code int, message string) {
+			reply(berConstructed(tag, berInt(berTagEnumerated, code), berString(berTagOctetString, ""), berString(berTagOctetString, message)))
+		}
+
+		switch op.Tag {
+		case ldapUnbindRequestTag:
+			return
+
+		case ldapBindRequestTag:
+			dn, password := string(op.Children[1].Value), string(op.Children[2].Value)
+			s.mutex.Lock()
+			s.binds = append(s.binds, dn)
+			s.mutex.Unlock()
+			if expected, ok := s.passwords[dn]; ok && password != "" && password == expected {
+				result(ldapBindResponseTag, ldapResultSuccess, "")
+			} else {
+				result(ldapBindResponseTag, ldapResultInvalidCredentials, "invalid credentials")
+			}
+
+		case ldapSearchRequestTag:
+			baseDN := strings.ToLower(string(op.Children[0].Value))
+			filter := op.Children[6]
+			var attributes []string
+			for _, attribute := range op.Children[7].Children {
+				attributes = append(attributes, strings.ToLower(string(attribute.Value)))
+			}
+
+			for dn, entry := range s.entries {
+				if !strings.HasSuffix(strings.ToLower(dn), ","+baseDN) || !s.matches(filter, entry) {
+					continue
+				}
+				var encoded [][]byte
+				for _, name := range attributes {
+					var values [][]byte
+					for _, value := range entry[name] {
+						values = append(values, berString(berTagOctetString, value))
+					}
+					if len(values) > 0 {
+						encoded = append(encoded, berConstructed(berTagSequence,
+							berString(berTagOctetString, name), berConstructed(berTagSet, values...)))
+					}
+				}
+				reply(berConstructed(ldapSearchResultEntryTag,
+					berString(berTagOctetString, dn), berConstructed(berTagSequence, encoded...)))
+			}
+			result(ldapSearchResultDoneTag, ldapResultSuccess, "")
+
+		default:
+			result(op.Tag+1, 2, "unsupported operation")
+		}
+	}
+}
+
+// matches evaluates the and, or, not, equality, presence and substring filters
+func (s *testLDAPServer) matches(filter *berPacket, entry map[string][]string) bool {
+	switch filter.Tag {
+	case ldapFilterAndTag:
+		for _, child := range filter.Children {
+			if !s.matches(child, entry) {
+				return false
+			}
+		}
+		return true
+	case ldapFilterOrTag:
+		for _, child := range filter.Children {
+			if s.matches(child, entry) {
+				return true
+			}
+		}
+		return false
+	case ldapFilterNotTag:
+		return !s.matches(filter.Children[0], entry)
+	case ldapFilterPresentTag:
+		return len(entry[strings.ToLower(string(filter.Value))]) > 0
+	case ldapFilterEqualityTag:
+		attribute, value := strings.ToLower(string(filter.Children[0].Value)), string(filter.Children[1].Value)
+		s.mutex.Lock()
+		s.assertions = append(s.assertions, attribute+"="+value)
+		s.mutex.Unlock()
+		for _, candidate := range entry[attribute] {
+			if strings.EqualFold(candidate, value) {
+				return true
+			}
+		}
+	case ldapFilterSubstringsTag:
+		attribute := strings.ToLower(string(filter.Children[0].Value))
+		for _, candidate := range entry[attribute] {
+			if matchesSubstrings(strings.ToLower(candidate), filter.Children[1].Children) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func matchesSubstrings(value string, parts []*berPacket) bool {
+	for _, part := range parts {
+		substring := strings.ToLower(string(part.Value))
+		switch part.Tag {
+		case ldapSubstringInitialTag:
+			if !strings.HasPrefix(value, substring) {
+				return false
+			}
+			value = value[len(substring):]
+		case ldapSubstringFinalTag:
+			return strings.HasSuffix(value, substring)
+		default:
+			index := strings.Index(value, substring)
+			if index < 0 {
+				return false
+			}
+			value = value[index+len(substring):]
+		}
+	}
+	return true
+}
+
+func (s *testLDAPServer) recorded() (binds, assertions []string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]string(nil), s.binds...), append([]string(nil), s.assertions...)
+}
+
+func TestLDAPAuthenticateMemberOf(t *testing.T) {
+	server := newTestLDAPServer(t)
+
+	groups, err := ldapAuthenticate(server.config(), "jane", "jane-password")
+	if err != nil {
+		t.Fatalf("login failed: %v", err)
+	}
+	want := "cn=dba-admins,ou=groups,dc=example,dc=com,dba-admins,cn=staff,ou=groups,dc=example,dc=com,staff"
+	if strings.Join(groups, ",") != want {
+		t.Errorf("groups = %v", groups)
+	}
+
+	binds, _ := server.recorded()
+	if strings.Join(binds, "|") != testLDAPServiceDN+"|uid=jane,ou=people,dc=example,dc=com" {
+		t.Errorf("binds = %v, want the service account then the user", binds)
+	}
+}
+
+func TestLDAPAuthenticateGroupSearch(t *testing.T) {
+	server := newTestLDAPServer(t)
+	config := server.config()
+	config.GroupBaseDN = "ou=groups,dc=example,dc=com"
+
+	groups, err := ldapAuthenticate(config, "bob", "bob-password")
+	if err != nil {
+		t.Fatalf("login failed: %v", err)
+	}
+	found := map[string]bool{}
+	for _, group := range groups {
+		found[group] = true
+	}
+	for _, group := range []string{"dba", "auditors", "cn=dba,ou=groups,dc=example,dc=com"} {
+		if !found[group] {
+			t.Errorf("groups %v are missing %s", groups, group)
+		}
+	}
+	if found["dba-admins"] {
+		t.Errorf("groups %v include a group bob is not a member of", groups)
+	}
+
+	// The group search runs as the service account again
+	binds, _ := server.recorded()
+	if len(binds) != 3 || binds[2] != testLDAPServiceDN {
+		t.Errorf("binds = %v", binds)
+	}
+	if role := mapGroupsToRole(groups, map[string]string{"auditors": RoleViewer, "dba": RoleOperator}, ""); role != RoleOperator {
+		t.Errorf("role = %q, want %q", role, RoleOperator)
+	}
+}
+
+func TestLDAPAuthenticateInvalidCredentials(t *testing.T) {
+	server := newTestLDAPServer(t)
+
+	tests := []struct {
+		name     string
+		username string
+		password string
+	}{
+		{"wrong password", "jane", "wrong"},
+		{"unknown user", "nobody", "jane-password"},
+		{"empty password", "jane", ""},
+		{"empty username", "", "jane-password"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := ldapAuthenticate(server.config(), test.username, test.password); !errors.Is(err, errInvalidLogin) {
+				t.Fatalf("err = %v, want errInvalidLogin", err)
+			}
+		})
+	}
+
+	// Only the wrong password reaches the server as a bind of jane, an empty
+	// password would be an anonymous bind that servers accept
+	binds, _ := server.recorded()
+	janeBinds := 0
+	for _, dn := range binds {
+		if dn == "uid=jane,ou=people,dc=example,dc=com" {
+			janeBinds++
+		}
+	}
+	if janeBinds != 1 {
+		t.Errorf("binds = %v, want one bind as jane", binds)
+	}
+}
+
+func TestLDAPAuthenticateServiceAccountFailure(t *testing.T) {
+	server := newTestLDAPServer(t)
+	config := server.config()
+	config.BindPassword = "wrong"
+
+	_, err := ldapAuthenticate(config, "jane", "jane-password")
+	if err == nil || errors.Is(err, errInvalidLogin) || !strings.Contains(err.Error(), "service account") {
+		t.Fatalf("err = %v, want a service account bind error", err)
+	}
+}
+
+func TestLDAPAuthenticateEscapesUsername(t *testing.T) {
+	server := newTestLDAPServer(t)
+
+	// Unescaped, the filter would become (uid=jane*)(uid=*) and match jane
+	groups, err := ldapAuthenticate(server.config(), "jane*)(uid=*", "jane-password")
+	if !errors.Is(err, errInvalidLogin) {
+		t.Fatalf("err = %v, groups = %v, want errInvalidLogin", err, groups)
+	}
+	binds, assertions := server.recorded()
+	for _, dn := range binds {
+		if dn == "uid=jane,ou=people,dc=example,dc=com" {
+			t.Fatalf("bound as jane with a crafted username: %v", binds)
+		}
+	}
+	if !strings.Contains(strings.Join(assertions, "|"), "uid=jane*)(uid=*") {
+		t.Errorf("assertions = %v, want the literal username", assertions)
+	}
+
+	// The same name is an ordinary user when it exists
+	if _, err := ldapAuthenticate(server.config(), "jane*)(uid=*", "tricky-password"); err != nil {
+		t.Fatalf("login with special characters failed: %v", err)
+	}
+}
+
+func TestLDAPFilterEscaping(t *testing.T) {
+	config := &LDAPConfig{UserFilter: "(&(objectClass=person)(uid={username}))"}
+	got := ldapUserFilter(config, `a*b(c)d\e`+"\x00")
+	want := `(&(objectClass=person)(uid=a\2ab\28c\29d\5ce\00))`
+	if got != want {
+		t.Fatalf("ldapUserFilter = %s, want %s", got, want)
+	}
+	if got := ldapGroupFilter(&LDAPConfig{}, "cn=x(y),dc=example", "x"); got != `(member=cn=x\28y\29,dc=example)` {
+		t.Errorf("ldapGroupFilter = %s", got)
+	}
+
+	encoded, err := parseLDAPFilter(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	packet, err := berParse(encoded[0], encoded[2:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	uid := packet.Children[1]
+	if uid.Tag != ldapFilterEqualityTag || string(uid.Children[1].Value) != "a*b(c)d\\e\x00" {
+		t.Errorf("decoded assertion = %q", uid.Children[1].Value)
+	}
+}
+
+func TestParseLDAPFilter(t *testing.T) {
+	valid := []string{
+		"(uid=jane)",
+		"(&(objectClass=person)(|(uid=jane)(mail=jane@example.com)))",
+		"(!(uid=bob))",
+		"(cn=*)",
+		"(cn=ja*n*e)",
+		"(uidNumber>=1000)",
+	}
+	for _, filter := range valid {
+		if _, err := parseLDAPFilter(filter); err != nil {
+			t.Errorf("%s: %v", filter, err)
+		}
+	}
+
+	invalid := []string{
+		"uid=jane",
+		"(uid=jane",
+		"(uid=jane))",
+		"(&)",
+		"(=jane)",
+		"(uid:dn:=jane)",
+		`(uid=\zz)`,
+		`(uid=jane\2)`,
+	}
+	for _, filter := range invalid {
+		if _, err := parseLDAPFilter(filter); err == nil {
+			t.Errorf("%s: accepted", filter)
+		}
+	}
+}
+
+func TestBerRoundTrip(t *testing.T) {
+	long := strings.Repeat("x", 70000)
+	encoded := berConstructed(berTagSequence, berInt(berTagInteger, 300), berString(berTagOctetString, long), berBool(true))
+
+	packet, err := berRead(bufio.NewReader(strings.NewReader(string(encoded))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(packet.Children) != 3 {
+		t.Fatalf("children = %d", len(packet.Children))
+	}
+	if value, _ := packet.Children[0].int(); value != 300 {
+		t.Errorf("integer = %d", value)
+	}
+	if string(packet.Children[1].Value) != long {
+		t.Errorf("long string of %d bytes decoded as %d bytes", len(long), len(packet.Children[1].Value))
+	}
+
+	// Lengths beyond ldapMaxMessageSize are refused before allocating
+	if _, err := berRead(strings.NewReader("\x30\x84\x7f\xff\xff\xff")); err == nil {
+		t.Error("oversized length accepted")
+	}
+	if _, err := berRead(strings.NewReader("\x30\x05\x02\x01")); err == nil {
+		t.Error("truncated element accepted")
+	}
+}
@@ -282,6 +282,8 @@ func createDeletionLog(deletedFiles []string, reason string) {
 	if len(deletedFiles) == 0 {
 		return
 	}
+	RecordDeletedBackups(reason, len(deletedFiles))
+	notifyDeletedBackups(deletedFiles, reason)
 
 	// Create log filename with timestamp: mbt-deleted-YYYYMMDD_HHMMSS.log
 	timestamp := time.Now().Format("20060102_150405")
@@ -406,6 +408,8 @@ func ShutdownLogger() {
 		if appLogger.writeTicker != nil {
 			appLogger.writeTicker.Stop()
 		}
+		// The writer flushes on stop, flush again for entries logged in between
+		appLogger.flushBuffer()
 	}
 }
 
@@ -6,8 +6,6 @@ import (
 	"log"
 	"net/http"
 	"os"
-
-	"golang.org/x/crypto/bcrypt"
 )
 
 // Version will be injected at build time via -ldflags
@@ -16,7 +14,10 @@ var Version = "dev"
 func main() {
 	configFile := flag.String("config", "config.json", "Path to configuration file")
 	sqliteFile := flag.String("sqlite", "app.db", "Path to SQLite database file")
-	setPassword := flag.String("set-password", "", "Set new password for web interface authentication")
+	setPassword := flag.String("set-password", "", "Set new password for a web interface user")
+	setPasswordUser := flag.String("user", "", "User for --set-password and --reset-2fa (default: auth_user from config), created as admin when missing")
+	resetTwoFactor := flag.Bool("reset-2fa", false, "Turn off two-factor authentication of a web interface user")
+	checkConfigFile := flag.Bool("check-config", false, "Validate the configuration file, list invalid settings and exit")
 	showVersion := flag.Bool("version", false, "Show version information")
 	showHelp := flag.Bool("help", false, "Show help information")
 	debugMode := flag.Bool("debug", false, "Show console window (Windows only)")
@@ -38,7 +39,10 @@ func main() {
 		fmt.Println("Examples:")
 		fmt.Println("  mariadb-backup-tool                                    # Start with default settings")
 		fmt.Println("  mariadb-backup-tool --config /etc/mbt/config.json      # Use custom config file")
-		fmt.Println("  mariadb-backup-tool --set-password newpassword        # Set new web interface password")
+		fmt.Println("  mariadb-backup-tool --set-password newpassword        # Set new password of the initial admin")
+		fmt.Println("  mariadb-backup-tool --set-password pw --user alice    # Set new password of user alice")
+		fmt.Println("  mariadb-backup-tool --reset-2fa --user alice           # Turn off 2FA of user alice")
+		fmt.Println("  mariadb-backup-tool --check-config --config cfg.json   # Validate a config file")
 		fmt.Println("  mariadb-backup-tool --version                          # Show version information")
 		fmt.Println("  mariadb-backup-tool --debug                            # Show console window (Windows only)")
 		os.Exit(0)
@@ -50,18 +54,37 @@ func main() {
 		os.Exit(0)
 	}
 
+	// Handle config validation
+	if *checkConfigFile {
+		if !checkConfig(*configFile) {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
 	// Handle password setting
 	if *setPassword != "" {
-		if err := setNewPassword(*configFile, *setPassword); err != nil {
+		if err := setNewPassword(*configFile, *sqliteFile, *setPasswordUser, *setPassword); err != nil {
 			log.Fatalf("Failed to set password: %v", err)
 		}
 		fmt.Println("Password updated successfully!")
+		if !*resetTwoFactor {
+			os.Exit(0)
+		}
+	}
+
+	// Handle two-factor reset
+	if *resetTwoFactor {
+		if err := resetUserTwoFactor(*configFile, *sqliteFile, *setPasswordUser); err != nil {
+			log.Fatalf("Failed to reset two-factor authentication: %v", err)
+		}
+		fmt.Println("Two-factor authentication turned off, log in with the password and enroll again")
 		os.Exit(0)
 	}
 
 	config, err := loadConfig(*configFile)
 	if err != nil {
-		log.Fatalf("Failed to load config: %v", err)
+		log.Fatalf("Failed to load config: %v (run with --check-config for details)", err)
 	}
 
 	if err := InitializeLogger(config); err != nil {
@@ -81,10 +104,18 @@ func main() {
 	}
 	LogInfo("SQLite database initialized successfully")
 
+	if err := EnsureInitialAdmin(config); err != nil {
+		LogError("Failed to create initial admin: %v", err)
+	}
+	recordConfigFileChanges(config)
+
+	ConfigureJobQueue(config)
+	ReconcileInterruptedBackups(config)
 	go autoTestConnectionsOnStart(config)
 	go startSystemMetricsBroadcaster()
 	go startJobsBroadcaster()
 	go StartScheduler(config)
+	go StartNotificationMonitor()
 	setupRoutes(config)
 
 	// Start system tray on Windows
@@ -94,33 +125,105 @@ func main() {
 	LogInfo("Starting MariaDB Backup Tool on http://localhost%s", addr)
 	LogDebug("Web server configuration - Port: %d, SSL: %v", config.Web.Port, config.Web.SSLEnabled)
 
-	if err := http.ListenAndServe(addr, nil); err != nil {
+	server := &http.Server{Addr: addr}
+
+	// Shut down gracefully on SIGINT/SIGTERM
+	shutdownDone := make(chan struct{})
+	go func() {
+		waitForShutdownSignal()
+		// Settings may have changed since startup
+		if latest, err := loadConfig(*configFile); err == nil {
+			config = latest
+		}
+		GracefulShutdown(server, config)
+		close(shutdownDone)
+	}()
+
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 		LogError("Server failed to start: %v", err)
 		log.Fatalf("Server failed to start: %v", err)
 	}
+	<-shutdownDone
 }
 
-// setNewPassword hashes a new password and updates the config file
-func setNewPassword(configFile, newPassword string) error {
-	// Load existing config
+// setNewPassword sets the password of a web user in the SQLite users table.
+// The user defaults to auth_user and is created as an admin when missing, so
+// this also recovers access when every admin is locked out.
+func setNewPassword(configFile, sqliteFile, username, newPassword string) error {
+	// Account recovery must keep working while the config has invalid settings
 	config, err := loadConfig(configFile)
-	if err != nil {
+	if err != nil && !isConfigValidationError(err) {
 		return fmt.Errorf("failed to load config: %v", err)
 	}
 
-	// Hash the new password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err := InitDB(sqliteFile); err != nil {
+		return fmt.Errorf("failed to open SQLite: %v", err)
+	}
+	defer CloseDB()
+
+	if err := EnsureInitialAdmin(config); err != nil {
+		return err
+	}
+
+	if username == "" {
+		username = config.Web.AuthUser
+	}
+	if len(newPassword) < minPasswordLength {
+		return fmt.Errorf("password must be at least %d characters", minPasswordLength)
+	}
+
+	passwordHash, err := hashPassword(newPassword)
 	if err != nil {
 		return fmt.Errorf("failed to hash password: %v", err)
 	}
 
-	// Update the password hash in config
-	config.Web.AuthPassHash = string(hashedPassword)
+	user, err := GetUser(username)
+	if err != nil {
+		return fmt.Errorf("failed to look up user %s: %v", username, err)
+	}
+	if user == nil {
+		if err := validateUserInput(username, RoleAdmin, newPassword); err != nil {
+			return err
+		}
+		return CreateUser(username, passwordHash, RoleAdmin)
+	}
+	if !user.IsLocal() {
+		return fmt.Errorf("user %s is a %s account, choose another --user for a local admin", user.Username, user.AuthSource)
+	}
+
+	if err := SetUserPassword(user.Username, passwordHash); err != nil {
+		return err
+	}
+	// Re-enable the account so a locked out admin can log in again
+	return UpdateUser(user.Username, user.Role, false)
+}
+
+// resetUserTwoFactor turns off TOTP two-factor authentication of a web user
+// who lost their authenticator and recovery codes. The user (default: the
+// initial admin from config) can log in with the password alone afterwards.
+func resetUserTwoFactor(configFile, sqliteFile, username string) error {
+	// Account recovery must keep working while the config has invalid settings
+	config, err := loadConfig(configFile)
+	if err != nil && !isConfigValidationError(err) {
+		return fmt.Errorf("failed to load config: %v", err)
+	}
+
+	if err := InitDB(sqliteFile); err != nil {
+		return fmt.Errorf("failed to open SQLite: %v", err)
+	}
+	defer CloseDB()
+
+	if username == "" {
+		username = config.Web.AuthUser
+	}
 
-	// Save the updated config
-	if err := saveConfig(config, configFile); err != nil {
-		return fmt.Errorf("failed to save config: %v", err)
+	user, err := GetUser(username)
+	if err != nil {
+		return fmt.Errorf("failed to look up user %s: %v", username, err)
+	}
+	if user == nil {
+		return fmt.Errorf("user %s does not exist", username)
 	}
 
-	return nil
+	return DisableUserTOTP(user.Username)
 }
@@ -0,0 +1,335 @@
+package main
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/disk"
+)
+
+// Histogram buckets for backup durations (seconds) and backup sizes (bytes)
+var backupDurationBuckets = []float64{10, 30, 60, 300, 900, 1800, 3600, 7200, 14400, 28800}
+var backupSizeBuckets = []float64{1 << 20, 10 << 20, 100 << 20, 1 << 30, 10 << 30, 100 << 30}
+
+// histogram is a cumulative Prometheus histogram
+type histogram struct {
+	buckets []float64
+	counts  []uint64
+	sum     float64
+	count   uint64
+}
+
+func newHistogram(buckets []float64) *histogram {
+	return &histogram{buckets: buckets, counts: make([]uint64, len(buckets))}
+}
+
+func (h *histogram) observe(value float64) {
+	for i, bound := range h.buckets {
+		if value <= bound {
+			h.counts[i]++
+		}
+	}
+	h.sum += value
+	h.count++
+}
+
+// metricKey identifies a database and backup type (full or incremental)
+type metricKey struct {
+	database   string
+	backupType string
+}
+
+// BackupMetrics holds the counters and histograms recorded since startup
+type BackupMetrics struct {
+	mutex        sync.Mutex
+	runs         map[[2]string]uint64 // backup type, result
+	failures     map[metricKey]uint64
+	durations    map[string]*histogram // by backup type
+	sizes        map[string]*histogram // by backup type
+	lastDuration map[metricKey]float64
+	lastSize     map[metricKey]float64
+	deletedFiles map[string]uint64 // by deletion reason
+}
+
+var backupMetrics = &BackupMetrics{
+	runs:         make(map[[2]string]uint64),
+	failures:     make(map[metricKey]uint64),
+	durations:    make(map[string]*histogram),
+	sizes:        make(map[string]*histogram),
+	lastDuration: make(map[metricKey]float64),
+	lastSize:     make(map[metricKey]float64),
+	deletedFiles: make(map[string]uint64),
+}
+
+// RecordBackupResult records the outcome of one database backup
+func RecordBackupResult(databaseName, backupType string, success, cancelled bool, duration time.Duration, sizeKB int) {
+	backupMetrics.mutex.Lock()
+	defer backupMetrics.mutex.Unlock()
+
+	result := "success"
+	switch {
+	case cancelled:
+		result = "cancelled"
+	case !success:
+		result = "failed"
+	}
+	backupMetrics.runs[[2]string{backupType, result}]++
+
+	key := metricKey{databaseName, backupType}
+	if !success {
+		if !cancelled {
+			backupMetrics.failures[key]++
+		}
+		return
+	}
+
+	if backupMetrics.durations[backupType] == nil {
+		backupMetrics.durations[backupType] = newHistogram(backupDurationBuckets)
+		backupMetrics.sizes[backupType] = newHistogram(backupSizeBuckets)
+	}
+	sizeBytes := float64(sizeKB) * 1024
+	backupMetrics.durations[backupType].observe(duration.Seconds())
+	backupMetrics.sizes[backupType].observe(sizeBytes)
+	backupMetrics.lastDuration[key] = duration.Seconds()
+	backupMetrics.lastSize[key] = sizeBytes
+}
+
+// RecordDeletedBackups counts backup files deleted for a reason (retention_cleanup, low_disk_space, manual_deletion)
+func RecordDeletedBackups(reason string, count int) {
+	backupMetrics.mutex.Lock()
+	backupMetrics.deletedFiles[reason] += uint64(count)
+	backupMetrics.mutex.Unlock()
+}
+
+// hashMetricsToken returns the SHA-256 hex digest stored for a metrics bearer token
+func hashMetricsToken(token string) string {
+	return hashToken(token)
+}
+
+// checkMetricsAuth validates the bearer token when one is configured
+func checkMetricsAuth(r *http.Request, config *Config) bool {
+	if config.Web.MetricsTokenHash == "" {
+		return true
+	}
+
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(hashMetricsToken(token)), []byte(config.Web.MetricsTokenHash)) == 1
+}
+
+// handleMetrics serves Prometheus metrics. It does not use the session login;
+// when metrics_token_hash is set, scrapers must send the token as a bearer token.
+func handleMetrics(w http.ResponseWriter, r *http.Request) {
+	config, err := loadConfig("config.json")
+	if err != nil {
+		http.Error(w, "Failed to load configuration", http.StatusInternalServerError)
+		return
+	}
+
+	if !config.Web.MetricsEnabled {
+		http.NotFound(w, r)
+		return
+	}
+
+	if !checkMetricsAuth(r, config) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="metrics"`)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	writeMetrics(w, config)
+}
+
+// metricsWriter writes the Prometheus text exposition format
+type metricsWriter struct {
+	w io.Writer
+}
+
+func (m *metricsWriter) header(name, metricType, help string) {
+	fmt.Fprintf(m.w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, metricType)
+}
+
+// sample writes one sample; labels are name/value pairs
+func (m *metricsWriter) sample(name string, value float64, labels ...string) {
+	fmt.Fprintf(m.w, "%s%s %v\n", name, formatMetricLabels(labels), value)
+}
+
+func (m *metricsWriter) histogram(name string, h *histogram, labels ...string) {
+	for i, bound := range h.buckets {
+		m.sample(name+"_bucket", float64(h.counts[i]), append(labels, "le", fmt.Sprintf("%v", bound))...)
+	}
+	m.sample(name+"_bucket", float64(h.count), append(labels, "le", "+Inf")...)
+	m.sample(name+"_sum", h.sum, labels...)
+	m.sample(name+"_count", float64(h.count), labels...)
+}
+
+// formatMetricLabels formats name/value pairs as {name="value",...}
+func formatMetricLabels(labels []string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	escaper := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	parts := make([]string, 0, len(labels)/2)
+	for i := 0; i+1 < len(labels); i += 2 {
+		parts = append(parts, fmt.Sprintf(`%s="%s"`, labels[i], escaper.Replace(labels[i+1])))
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// writeMetrics writes all metrics
+func writeMetrics(w io.Writer, config *Config) {
+	m := &metricsWriter{w: w}
+
+	m.header("mariadb_backup_info", "gauge", "Version of the MariaDB Backup Tool")
+	m.sample("mariadb_backup_info", 1, "version", Version)
+
+	// Last successful backups from SQLite, so they survive restarts
+	m.header("mariadb_backup_last_success_timestamp_seconds", "gauge", "Unix time of the last successful backup per database and type")
+	if lastBackups, err := GetLastSuccessfulBackups(); err != nil {
+		LogWarn("Failed to get last successful backups for metrics: %v", err)
+	} else {
+		for _, backup := range lastBackups {
+			m.sample("mariadb_backup_last_success_timestamp_seconds", float64(backup["completed_at"].(int64)),
+				"database", backup["database_name"].(string), "type", backup["backup_type"].(string))
+		}
+	}
+
+	writeBackupMetrics(m)
+
+	queue := GetJobQueueStatus()
+	m.header("mariadb_backup_jobs_running", "gauge", "Backup jobs currently running")
+	m.sample("mariadb_backup_jobs_running", float64(queue["total_running"].(int)))
+	m.header("mariadb_backup_jobs_pending", "gauge", "Backup jobs waiting in the job queue")
+	m.sample("mariadb_backup_jobs_pending", float64(queue["total_pending"].(int)))
+	m.header("mariadb_backup_jobs_max_concurrent", "gauge", "Maximum number of backup jobs running at once")
+	m.sample("mariadb_backup_jobs_max_concurrent", float64(queue["max_concurrent_jobs"].(int)))
+	m.header("mariadb_backup_processes_running", "gauge", "Dump and binlog processes currently running")
+	m.sample("mariadb_backup_processes_running", float64(len(GetBackupProcesses())))
+
+	writeBackupDirMetrics(m, config)
+
+	dbStats := GetDatabaseMetrics()
+	sqliteCounters := []struct{ name, key, help string }{
+		{"mariadb_backup_sqlite_operations_total", "total_operations", "SQLite operations executed"},
+		{"mariadb_backup_sqlite_failed_operations_total", "failed_operations", "SQLite operations that failed"},
+		{"mariadb_backup_sqlite_retried_operations_total", "retry_operations", "SQLite operations retried after lock contention"},
+		{"mariadb_backup_sqlite_lock_contention_total", "lock_contention_ops", "SQLite operations that hit a locked database"},
+		{"mariadb_backup_sqlite_batch_operations_total", "batch_operations", "SQLite batched progress and status updates"},
+	}
+	for _, counter := range sqliteCounters {
+		m.header(counter.name, "counter", counter.help)
+		m.sample(counter.name, float64(dbStats[counter.key].(int64)))
+	}
+}
+
+// writeBackupMetrics writes the counters and histograms recorded since startup
+func writeBackupMetrics(m *metricsWriter) {
+	backupMetrics.mutex.Lock()
+	defer backupMetrics.mutex.Unlock()
+
+	m.header("mariadb_backup_runs_total", "counter", "Database backups by type and result since startup")
+	for _, key := range sortedKeys(backupMetrics.runs, func(k [2]string) string { return k[0] + "/" + k[1] }) {
+		m.sample("mariadb_backup_runs_total", float64(backupMetrics.runs[key]), "type", key[0], "result", key[1])
+	}
+
+	m.header("mariadb_backup_failures_total", "counter", "Failed database backups since startup")
+	for _, key := range sortedKeys(backupMetrics.failures, metricKeyString) {
+		m.sample("mariadb_backup_failures_total", float64(backupMetrics.failures[key]), "database", key.database, "type", key.backupType)
+	}
+
+	m.header("mariadb_backup_duration_seconds", "histogram", "Duration of successful database backups")
+	for _, backupType := range sortedKeys(backupMetrics.durations, func(k string) string { return k }) {
+		m.histogram("mariadb_backup_duration_seconds", backupMetrics.durations[backupType], "type", backupType)
+	}
+
+	m.header("mariadb_backup_size_bytes", "histogram", "Size of successful database backups")
+	for _, backupType := range sortedKeys(backupMetrics.sizes, func(k string) string { return k }) {
+		m.histogram("mariadb_backup_size_bytes", backupMetrics.sizes[backupType], "type", backupType)
+	}
+
+	m.header("mariadb_backup_last_duration_seconds", "gauge", "Duration of the last successful backup per database and type")
+	for _, key := range sortedKeys(backupMetrics.lastDuration, metricKeyString) {
+		m.sample("mariadb_backup_last_duration_seconds", backupMetrics.lastDuration[key], "database", key.database, "type", key.backupType)
+	}
+
+	m.header("mariadb_backup_last_size_bytes", "gauge", "Size of the last successful backup per database and type")
+	for _, key := range sortedKeys(backupMetrics.lastSize, metricKeyString) {
+		m.sample("mariadb_backup_last_size_bytes", backupMetrics.lastSize[key], "database", key.database, "type", key.backupType)
+	}
+
+	m.header("mariadb_backup_deleted_files_total", "counter", "Backup files deleted since startup by reason")
+	for _, reason := range sortedKeys(backupMetrics.deletedFiles, func(k string) string { return k }) {
+		m.sample("mariadb_backup_deleted_files_total", float64(backupMetrics.deletedFiles[reason]), "reason", reason)
+	}
+}
+
+// writeBackupDirMetrics writes the filesystem usage of the backup directory and
+// the size of each database's backups
+func writeBackupDirMetrics(m *metricsWriter, config *Config) {
+	backupDir := config.Backup.BackupDir
+
+	if usage, err := disk.Usage(backupDir); err == nil {
+		m.header("mariadb_backup_dir_filesystem_size_bytes", "gauge", "Size of the filesystem holding the backup directory")
+		m.sample("mariadb_backup_dir_filesystem_size_bytes", float64(usage.Total))
+		m.header("mariadb_backup_dir_filesystem_free_bytes", "gauge", "Free space on the filesystem holding the backup directory")
+		m.sample("mariadb_backup_dir_filesystem_free_bytes", float64(usage.Free))
+		m.header("mariadb_backup_dir_filesystem_used_bytes", "gauge", "Used space on the filesystem holding the backup directory")
+		m.sample("mariadb_backup_dir_filesystem_used_bytes", float64(usage.Used))
+	}
+
+	entries, err := os.ReadDir(backupDir)
+	if err != nil {
+		return
+	}
+
+	m.header("mariadb_backup_dir_database_bytes", "gauge", "Size of the backup files of each database")
+	var total int64
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		var size int64
+		filepath.WalkDir(filepath.Join(backupDir, entry.Name()), func(path string, d fs.DirEntry, err error) error {
+			if err == nil && !d.IsDir() {
+				if info, err := d.Info(); err == nil {
+					size += info.Size()
+				}
+			}
+			return nil
+		})
+		total += size
+		m.sample("mariadb_backup_dir_database_bytes", float64(size), "database", entry.Name())
+	}
+
+	m.header("mariadb_backup_dir_bytes", "gauge", "Size of all backup files")
+	m.sample("mariadb_backup_dir_bytes", float64(total))
+}
+
+// metricKeyString orders metric keys by database, then type
+func metricKeyString(k metricKey) string {
+	return k.database + "/" + k.backupType
+}
+
+// sortedKeys returns the keys of a map ordered by their string form
+func sortedKeys[K comparable, V any](values map[K]V, key func(K) string) []K {
+	keys := make([]K, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(a, b int) bool { return key(keys[a]) < key(keys[b]) })
+	return keys
+}
@@ -0,0 +1,480 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Notification event types
+const (
+	EventJobStarted         = "job_started"
+	EventJobSucceeded       = "job_succeeded"
+	EventJobFailed          = "job_failed"
+	EventJobCancelled       = "job_cancelled"
+	EventRPOBreached        = "rpo_breached"        // Last successful backup of a database is older than its RPO
+	EventRetentionDeleted   = "retention_deleted"   // Backup files removed by retention, pruning or by hand
+	EventVerificationFailed = "verification_failed" // The dump finished but its backup file is missing or unusable
+	EventDiskSpaceWarning   = "disk_space_warning"
+	EventMySQLRestart       = "mysql_restart"
+	EventMissedRun          = "missed_run"
+)
+
+var notificationEventTypes = []string{
+	EventJobStarted, EventJobSucceeded, EventJobFailed, EventJobCancelled, EventRPOBreached,
+	EventRetentionDeleted, EventVerificationFailed, EventDiskSpaceWarning, EventMySQLRestart, EventMissedRun,
+}
+
+// Notification severities, in increasing order
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+var severityLevels = map[string]int{SeverityInfo: 0, SeverityWarning: 1, SeverityCritical: 2}
+
+// NotificationEvent is something that happened and may be sent to notification channels
+type NotificationEvent struct {
+	Type      string
+	Severity  string
+	Title     string
+	Message   string
+	JobID     string
+	Databases []string
+	Fields    []notificationField
+	Summary   *BackupSummary // Set for job_succeeded, job_failed and job_cancelled
+	Time      time.Time
+	Test      bool
+}
+
+// NotificationRule routes matching events to notification channels
+type NotificationRule struct {
+	Name        string   `json:"name"`
+	Events      []string `json:"events"`       // Empty matches all events
+	MinSeverity string   `json:"min_severity"` // info (default), warning or critical
+	Databases   []string `json:"databases"`    // Database patterns, empty matches all events
+	Channels    []string `json:"channels"`     // Empty sends to every configured channel
+	Digest      bool     `json:"digest"`       // Collect events and send them once a day at digest_time
+}
+
+// Rules used when none are configured: every job summary, as before rules
+// existed, plus all warning and critical events
+var defaultNotificationRules = []NotificationRule{
+	{Name: "job-summaries", Events: []string{EventJobSucceeded, EventJobFailed, EventJobCancelled}},
+	{Name: "alerts", MinSeverity: SeverityWarning},
+}
+
+// Defaults for unset notification settings
+const (
+	defaultNotificationDedupMinutes = 60
+	defaultNotificationDigestTime   = "08:00"
+)
+
+// Most events listed in one digest message
+const maxDigestEvents = 50
+
+// Last time an event was sent to a channel, by channel and dedup key
+var sentNotifications = make(map[string]time.Time)
+var sentNotificationsMutex sync.Mutex
+
+// Events waiting for the daily digest, by channel
+var digestEvents = make(map[string][]NotificationEvent)
+var digestEventsMutex sync.Mutex
+
+// matches reports whether an event is selected by the rule
+func (rule NotificationRule) matches(event NotificationEvent) bool {
+	if len(rule.Events) > 0 && !slices.Contains(rule.Events, event.Type) {
+		return false
+	}
+
+	if severityLevels[event.Severity] < severityLevels[rule.MinSeverity] {
+		return false
+	}
+
+	if len(rule.Databases) > 0 {
+		for _, databaseName := range event.Databases {
+			if _, ok := findMatchingPattern(rule.Databases, databaseName); ok {
+				return true
+			}
+		}
+		return false
+	}
+
+	return true
+}
+
+// NotifyEvent routes an event through the notification rules and sends it to
+// the selected channels in the background. Events below critical are dropped
+// during quiet hours, and repeats within dedup_minutes are sent once. A nil
+// config loads config.json.
+func NotifyEvent(config *Config, event NotificationEvent) {
+	if config == nil {
+		var err error
+		config, err = loadConfig("config.json")
+		if err != nil {
+			LogError("❌ [NOTIFY] Failed to load config for %s notification: %v", event.Type, err)
+			return
+		}
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	rules := config.Notification.Rules
+	if len(rules) == 0 {
+		rules = defaultNotificationRules
+	}
+
+	immediate := make(map[string]bool)
+	digest := make(map[string]bool)
+	for _, rule := range rules {
+		if !rule.matches(event) {
+			continue
+		}
+		channels := rule.Channels
+		if len(channels) == 0 {
+			channels = notifierNames
+		}
+		for _, channel := range channels {
+			if rule.Digest {
+				digest[channel] = true
+			} else {
+				immediate[channel] = true
+			}
+		}
+	}
+	if len(immediate) == 0 && len(digest) == 0 {
+		LogDebug("No notification rule matches %s event: %s", event.Type, event.Title)
+		return
+	}
+
+	quiet := event.Severity != SeverityCritical && isQuietHours(config.Notification.QuietHours, event.Time)
+
+	for _, channel := range notifierNames {
+		notifier := newNotifier(config.Notification, channel)
+		if notifier == nil {
+			continue
+		}
+
+		if immediate[channel] {
+			if quiet {
+				LogDebug("Quiet hours, not sending %s notification: %s", channel, event.Title)
+				continue
+			}
+			if isDuplicateNotification(config, channel, event) {
+				LogDebug("Duplicate %s notification suppressed: %s", channel, event.Title)
+				continue
+			}
+			go deliverNotification(notifier, event)
+		} else if digest[channel] {
+			digestEventsMutex.Lock()
+			digestEvents[channel] = append(digestEvents[channel], event)
+			digestEventsMutex.Unlock()
+		}
+	}
+}
+
+// deliverNotification sends an event through one notifier and logs the result
+func deliverNotification(notifier Notifier, event NotificationEvent) {
+	if err := notifier.Send(event); err != nil {
+		LogError("❌ [NOTIFY] Failed to send %s notification (%s): %v", notifier.Name(), event.Title, err)
+		return
+	}
+	LogInfo("📢 [NOTIFY] %s notification sent: %s", notifier.Name(), event.Title)
+}
+
+// getNotificationDedupKey identifies repeats of the same event
+func getNotificationDedupKey(event NotificationEvent) string {
+	return strings.Join([]string{event.Type, event.Severity, event.JobID, strings.Join(event.Databases, ","), event.Title}, "|")
+}
+
+// isDuplicateNotification reports whether the same event was sent to the
+// channel within the dedup window, and records it otherwise
+func isDuplicateNotification(config *Config, channel string, event NotificationEvent) bool {
+	minutes := config.Notification.DedupMinutes
+	if minutes <= 0 {
+		minutes = defaultNotificationDedupMinutes
+	}
+	window := time.Duration(minutes) * time.Minute
+
+	sentNotificationsMutex.Lock()
+	defer sentNotificationsMutex.Unlock()
+
+	key := channel + "|" + getNotificationDedupKey(event)
+	if lastSent, ok := sentNotifications[key]; ok && event.Time.Sub(lastSent) < window {
+		return true
+	}
+	sentNotifications[key] = event.Time
+
+	// Forget entries that can no longer suppress anything
+	for sentKey, sentAt := range sentNotifications {
+		if event.Time.Sub(sentAt) >= window {
+			delete(sentNotifications, sentKey)
+		}
+	}
+	return false
+}
+
+// parseClockTime parses HH:MM into minutes after midnight
+func parseClockTime(value string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(value))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// parseQuietHours parses a "22:00-07:00" range, which may wrap past midnight
+func parseQuietHours(value string) (int, int, error) {
+	startValue, endValue, ok := strings.Cut(value, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid quiet hours %q, expected HH:MM-HH:MM", value)
+	}
+	start, err := parseClockTime(startValue)
+	if err != nil {
+		return 0, 0, err
+	}
+	end, err := parseClockTime(endValue)
+	if err != nil {
+		return 0, 0, err
+	}
+	return start, end, nil
+}
+
+// isQuietHours reports whether t falls in the quiet hours range. An empty or
+// invalid range is never quiet.
+func isQuietHours(value string, t time.Time) bool {
+	if strings.TrimSpace(value) == "" {
+		return false
+	}
+	start, end, err := parseQuietHours(value)
+	if err != nil || start == end {
+		return false
+	}
+
+	minute := t.Hour()*60 + t.Minute()
+	if start < end {
+		return minute >= start && minute < end
+	}
+	return minute >= start || minute < end
+}
+
+// validateNotificationRules checks rule events, severities and channels and the quiet hours and digest time
+func validateNotificationRules(config NotificationConfig) error {
+	for i, rule := range config.Rules {
+		name := rule.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", i+1)
+		}
+		for _, eventType := range rule.Events {
+			if !slices.Contains(notificationEventTypes, eventType) {
+				return fmt.Errorf("rule %s: unknown event %q", name, eventType)
+			}
+		}
+		if _, ok := severityLevels[rule.MinSeverity]; rule.MinSeverity != "" && !ok {
+			return fmt.Errorf("rule %s: unknown severity %q (use info, warning or critical)", name, rule.MinSeverity)
+		}
+		for _, channel := range rule.Channels {
+			if !slices.Contains(notifierNames, channel) {
+				return fmt.Errorf("rule %s: unknown channel %q", name, channel)
+			}
+		}
+		if err := validateDatabasePatterns(rule.Databases); err != nil {
+			return fmt.Errorf("rule %s: %v", name, err)
+		}
+	}
+
+	if strings.TrimSpace(config.QuietHours) != "" {
+		if _, _, err := parseQuietHours(config.QuietHours); err != nil {
+			return err
+		}
+	}
+	if config.DigestTime != "" {
+		if _, err := parseClockTime(config.DigestTime); err != nil {
+			return fmt.Errorf("invalid digest time: %v", err)
+		}
+	}
+	if config.DedupMinutes < 0 {
+		return fmt.Errorf("dedup minutes cannot be negative")
+	}
+
+	return nil
+}
+
+// StartNotificationMonitor runs the RPO check and sends the daily digest.
+// It runs independently of the scheduler, so a scheduler that stopped
+// starting backups still raises RPO alerts. Settings are reloaded from
+// config.json on every check.
+func StartNotificationMonitor() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	var lastRPOCheck time.Time
+	var lastDigestDay string
+	for now := range ticker.C {
+		config, err := loadConfig("config.json")
+		if err != nil {
+			LogError("❌ [NOTIFY] Failed to load config for notification monitor: %v", err)
+			continue
+		}
+
+		if now.Sub(lastRPOCheck) >= rpoCheckInterval {
+			lastRPOCheck = now
+			checkRPOBreaches(config)
+		}
+
+		digestTime := config.Notification.DigestTime
+		if digestTime == "" {
+			digestTime = defaultNotificationDigestTime
+		}
+		digestMinute, err := parseClockTime(digestTime)
+		if err != nil {
+			continue
+		}
+		today := now.Format("2006-01-02")
+		if lastDigestDay != today && now.Hour()*60+now.Minute() >= digestMinute {
+			lastDigestDay = today
+			sendNotificationDigests(config)
+		}
+	}
+}
+
+// sendNotificationDigests sends the collected digest events, one message per channel
+func sendNotificationDigests(config *Config) {
+	digestEventsMutex.Lock()
+	pending := digestEvents
+	digestEvents = make(map[string][]NotificationEvent)
+	digestEventsMutex.Unlock()
+
+	channels := make([]string, 0, len(pending))
+	for channel := range pending {
+		channels = append(channels, channel)
+	}
+	sort.Strings(channels)
+
+	for _, channel := range channels {
+		events := pending[channel]
+		notifier := newNotifier(config.Notification, channel)
+		if notifier == nil || len(events) == 0 {
+			continue
+		}
+
+		severity := SeverityInfo
+		var fields []notificationField
+		for i, event := range events {
+			if severityLevels[event.Severity] > severityLevels[severity] {
+				severity = event.Severity
+			}
+			if i < maxDigestEvents {
+				fields = append(fields, notificationField{event.Time.Format("01-02 15:04"), event.Title})
+			}
+		}
+		if len(events) > maxDigestEvents {
+			fields = append(fields, notificationField{"More", fmt.Sprintf("%d more events", len(events)-maxDigestEvents)})
+		}
+
+		go deliverNotification(notifier, NotificationEvent{
+			Type:     "digest",
+			Severity: severity,
+			Title:    fmt.Sprintf("MariaDB Backup digest: %d events", len(events)),
+			Message:  fmt.Sprintf("Events since %s", events[0].Time.Format("2006-01-02 15:04")),
+			Fields:   fields,
+			Time:     time.Now(),
+		})
+	}
+}
+
+// notifyJobStarted raises the job_started event of a backup job
+func notifyJobStarted(config *Config, jobType, jobID, backupMode, requestedBy, policy string, databases []string) {
+	fields := []notificationField{
+		{"Backup Job", jobID},
+		{"Mode", backupMode},
+		{"Databases", formatDatabaseList(databases)},
+	}
+	if requestedBy != "" {
+		fields = append(fields, notificationField{"Requested By", requestedBy})
+	}
+	if policy != "" {
+		fields = append(fields, notificationField{"Policy", policy})
+	}
+
+	NotifyEvent(config, NotificationEvent{
+		Type:      EventJobStarted,
+		Severity:  SeverityInfo,
+		Title:     fmt.Sprintf("MariaDB %s backup started", jobType),
+		Message:   fmt.Sprintf("Backing up %d databases", len(databases)),
+		JobID:     jobID,
+		Databases: databases,
+		Fields:    fields,
+	})
+}
+
+// notifyVerificationFailed raises a verification_failed event when a dump
+// finished but its backup file could not be renamed or read
+func notifyVerificationFailed(config *Config, jobID, dbName, filePath, errorMessage string) {
+	NotifyEvent(config, NotificationEvent{
+		Type:      EventVerificationFailed,
+		Severity:  SeverityCritical,
+		Title:     fmt.Sprintf("Backup file check failed for %s", dbName),
+		Message:   errorMessage,
+		JobID:     jobID,
+		Databases: []string{dbName},
+		Fields: []notificationField{
+			{"Database", dbName},
+			{"Backup Job", jobID},
+			{"File", filePath},
+		},
+	})
+}
+
+// notifyMySQLRestart raises a mysql_restart event
+func notifyMySQLRestart(config *Config, severity, title, message string) {
+	NotifyEvent(config, NotificationEvent{
+		Type:     EventMySQLRestart,
+		Severity: severity,
+		Title:    title,
+		Message:  message,
+		Fields: []notificationField{
+			{"Memory Threshold", fmt.Sprintf("%d%%", config.Backup.MaxMemoryThreshold)},
+		},
+	})
+}
+
+// notifyDeletedBackups raises a retention_deleted event for removed backup files
+func notifyDeletedBackups(deletedFiles []string, reason string) {
+	var databases []string
+	for _, filePath := range deletedFiles {
+		databaseName := filepath.Base(filepath.Dir(filePath))
+		if !slices.Contains(databases, databaseName) {
+			databases = append(databases, databaseName)
+		}
+	}
+
+	NotifyEvent(nil, NotificationEvent{
+		Type:      EventRetentionDeleted,
+		Severity:  SeverityInfo,
+		Title:     fmt.Sprintf("Deleted %d backup files (%s)", len(deletedFiles), reason),
+		Message:   fmt.Sprintf("Backup files of %s were removed", formatDatabaseList(databases)),
+		Databases: databases,
+		Fields: []notificationField{
+			{"Reason", reason},
+			{"Files", fmt.Sprintf("%d", len(deletedFiles))},
+		},
+	})
+}
+
+// getSeverityEmoji returns the emoji shown in front of event titles
+func getSeverityEmoji(severity string) string {
+	switch severity {
+	case SeverityCritical:
+		return "🚨"
+	case SeverityWarning:
+		return "⚠️"
+	}
+	return "ℹ️"
+}
@@ -0,0 +1,727 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"net/url"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Notifier delivers notification events to one notification channel
+type Notifier interface {
+	Name() string
+	Send(event NotificationEvent) error
+}
+
+// Notification channel names, as used by the send test endpoint
+const (
+	NotifierSlack    = "slack"
+	NotifierWebhook  = "webhook"
+	NotifierEmail    = "email"
+	NotifierTeams    = "teams"
+	NotifierDiscord  = "discord"
+	NotifierTelegram = "telegram"
+)
+
+var notifierNames = []string{NotifierSlack, NotifierWebhook, NotifierEmail, NotifierTeams, NotifierDiscord, NotifierTelegram}
+
+// Timeout for delivering a single notification
+const notificationTimeout = 15 * time.Second
+
+var notificationClient = &http.Client{Timeout: notificationTimeout}
+
+// newNotifier returns the notifier of a channel, or nil if the channel is not configured
+func newNotifier(config NotificationConfig, name string) Notifier {
+	switch name {
+	case NotifierSlack:
+		if config.SlackWebhookURL != "" {
+			return &SlackNotifier{WebhookURL: config.SlackWebhookURL}
+		}
+	case NotifierWebhook:
+		if config.Webhook.Enabled && config.Webhook.URL != "" {
+			return &WebhookNotifier{Config: config.Webhook}
+		}
+	case NotifierEmail:
+		if config.Email.Enabled && config.Email.SMTPHost != "" && len(config.Email.To) > 0 {
+			return &EmailNotifier{Config: config.Email}
+		}
+	case NotifierTeams:
+		if config.Teams.Enabled && config.Teams.WebhookURL != "" {
+			return &TeamsNotifier{WebhookURL: config.Teams.WebhookURL}
+		}
+	case NotifierDiscord:
+		if config.Discord.Enabled && config.Discord.WebhookURL != "" {
+			return &DiscordNotifier{WebhookURL: config.Discord.WebhookURL}
+		}
+	case NotifierTelegram:
+		if config.Telegram.Enabled && config.Telegram.BotToken != "" && config.Telegram.ChatID != "" {
+			return &TelegramNotifier{Config: config.Telegram}
+		}
+	}
+	return nil
+}
+
+// GetNotifiers returns the notifiers of all configured channels
+func GetNotifiers(config NotificationConfig) []Notifier {
+	var notifiers []Notifier
+	for _, name := range notifierNames {
+		if notifier := newNotifier(config, name); notifier != nil {
+			notifiers = append(notifiers, notifier)
+		}
+	}
+	return notifiers
+}
+
+// SendBackupNotifications raises the job_succeeded, job_failed or job_cancelled
+// event of a finished backup job
+func SendBackupNotifications(cfg *Config, jobID string) {
+	summary, err := LoadBackupSummary(jobID)
+	if err != nil {
+		LogError("❌ [NOTIFY] Failed to load backup summary for job %s: %v", jobID, err)
+		return
+	}
+
+	NotifyEvent(cfg, newJobFinishedEvent(summary))
+}
+
+// newJobFinishedEvent builds the notification event of a backup summary
+func newJobFinishedEvent(summary BackupSummary) NotificationEvent {
+	event := NotificationEvent{
+		Type:      EventJobSucceeded,
+		Severity:  SeverityInfo,
+		Title:     getNotificationTitle(summary),
+		Message:   fmt.Sprintf("Backup completed in %s", formatDurationForSlack(summary.Duration)),
+		JobID:     summary.JobID,
+		Databases: summary.Databases,
+		Fields:    getNotificationFields(summary),
+		Summary:   &summary,
+		Test:      summary.Test,
+	}
+
+	if summary.State == "cancelled" {
+		event.Type = EventJobCancelled
+		event.Severity = SeverityWarning
+		event.Title = "MariaDB Backup 🛑 Cancelled"
+		event.Message = "Backup job cancelled"
+	} else if summary.TotalFailed > 0 {
+		event.Type = EventJobFailed
+		event.Severity = SeverityWarning
+		if summary.TotalFailed == summary.TotalDBCount {
+			event.Severity = SeverityCritical
+		}
+		event.Message = fmt.Sprintf("%d of %d databases failed", summary.TotalFailed, summary.TotalDBCount)
+	}
+
+	return event
+}
+
+// SendTestNotification sends a sample backup summary through one channel
+func SendTestNotification(config NotificationConfig, name string) error {
+	notifier := newNotifier(config, name)
+	if notifier == nil {
+		return fmt.Errorf("%s notifications are not enabled or not fully configured", name)
+	}
+
+	completedAt := time.Now()
+	return notifier.Send(newJobFinishedEvent(BackupSummary{
+		JobID:        "test-notification",
+		BackupMode:   "full",
+		TotalDBCount: 1,
+		TotalFull:    1,
+		TotalSizeKB:  1024,
+		CreatedAt:    completedAt.Add(-time.Minute),
+		CompletedAt:  completedAt,
+		Duration:     time.Minute,
+		State:        "completed",
+		Test:         true,
+	}))
+}
+
+// validateNotificationConfig checks that enabled channels have their required settings
+func validateNotificationConfig(config NotificationConfig) error {
+	if config.Webhook.Enabled {
+		if config.Webhook.URL == "" {
+			return fmt.Errorf("webhook URL is required")
+		}
+		if strings.TrimSpace(config.Webhook.BodyTemplate) != "" {
+			if _, err := template.New("webhook").Funcs(webhookTemplateFuncs).Parse(config.Webhook.BodyTemplate); err != nil {
+				return fmt.Errorf("invalid webhook body template: %v", err)
+			}
+		}
+	}
+
+	if config.Email.Enabled {
+		if config.Email.SMTPHost == "" {
+			return fmt.Errorf("SMTP host is required")
+		}
+		if config.Email.SMTPPort < 0 || config.Email.SMTPPort > 65535 {
+			return fmt.Errorf("invalid SMTP port %d", config.Email.SMTPPort)
+		}
+		switch config.Email.TLSMode {
+		case "", "starttls", "tls", "none":
+		default:
+			return fmt.Errorf("invalid SMTP TLS mode %q (use starttls, tls or none)", config.Email.TLSMode)
+		}
+		if len(config.Email.To) == 0 {
+			return fmt.Errorf("at least one email recipient is required")
+		}
+		if config.Email.From == "" && config.Email.Username == "" {
+			return fmt.Errorf("email sender (from) is required")
+		}
+	}
+
+	if config.Teams.Enabled && config.Teams.WebhookURL == "" {
+		return fmt.Errorf("Teams webhook URL is required")
+	}
+	if config.Discord.Enabled && config.Discord.WebhookURL == "" {
+		return fmt.Errorf("Discord webhook URL is required")
+	}
+	if config.Telegram.Enabled && (config.Telegram.BotToken == "" || config.Telegram.ChatID == "") {
+		return fmt.Errorf("Telegram bot token and chat ID are required")
+	}
+
+	return validateNotificationRules(config)
+}
+
+// parseWebhookHeaders parses "Name: Value" lines from a form field
+func parseWebhookHeaders(value string) (map[string]string, error) {
+	headers := map[string]string{}
+	for _, line := range strings.Split(value, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		name, headerValue, ok := strings.Cut(line, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("expected \"Name: Value\", got %q", line)
+		}
+		headers[strings.TrimSpace(name)] = strings.TrimSpace(headerValue)
+	}
+	return headers, nil
+}
+
+// parseEmailRecipients splits a comma, semicolon or newline separated address list
+func parseEmailRecipients(value string) []string {
+	return strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ';' || r == '\n' || r == '\r' || r == ' '
+	})
+}
+
+// LoadBackupSummary builds a BackupSummary from the backup_summary row of a job
+func LoadBackupSummary(jobID string) (BackupSummary, error) {
+	summary, err := GetBackupSummaryByJobID(jobID)
+	if err != nil {
+		return BackupSummary{}, fmt.Errorf("failed to get backup summary: %v", err)
+	}
+
+	if summary == nil {
+		return BackupSummary{}, fmt.Errorf("backup summary not found for job %s", jobID)
+	}
+
+	backupSummary := BackupSummary{
+		JobID:            summary["job_id"].(string),
+		BackupMode:       summary["backup_mode"].(string),
+		TotalDBCount:     summary["total_db_count"].(int),
+		TotalFull:        summary["total_full"].(int),
+		TotalIncremental: summary["total_incremental"].(int),
+		TotalFailed:      summary["total_failed"].(int),
+		TotalSizeKB:      summary["total_size_kb"].(int),
+		MysqlRestartTime: summary["mysql_restart_time"].(int),
+	}
+	backupSummary.Policy, _ = summary["policy"].(string)
+	backupSummary.State, _ = summary["state"].(string)
+	if databases, _ := summary["databases"].(string); databases != "" {
+		backupSummary.Databases = strings.Split(databases, ",")
+	}
+
+	failedJobs, err := GetFailedBackupJobsByJobID(jobID)
+	if err != nil {
+		LogWarn("⚠️ [NOTIFY] Failed to get failed databases of job %s: %v", jobID, err)
+	}
+	for _, job := range failedJobs {
+		backupSummary.FailedDatabases = append(backupSummary.FailedDatabases, job["database_name"].(string))
+	}
+
+	// Parse timestamps
+	if createdAtStr, ok := summary["created_at"].(string); ok {
+		if createdAt, err := time.Parse("2006-01-02 15:04:05", createdAtStr); err == nil {
+			backupSummary.CreatedAt = createdAt
+		}
+	}
+	if completedAtStr, ok := summary["completed_at"].(string); ok && completedAtStr != "" {
+		if completedAt, err := time.Parse("2006-01-02 15:04:05", completedAtStr); err == nil {
+			backupSummary.CompletedAt = completedAt
+			backupSummary.Duration = completedAt.Sub(backupSummary.CreatedAt)
+		}
+	}
+
+	return backupSummary, nil
+}
+
+// notificationField is a name/value pair shown in a notification
+type notificationField struct {
+	Name  string
+	Value string
+}
+
+// getNotificationStatus returns success, partial or failed
+func getNotificationStatus(summary BackupSummary) string {
+	if summary.TotalFailed == 0 {
+		return "success"
+	} else if summary.TotalFailed == summary.TotalDBCount {
+		return "failed"
+	}
+	return "partial"
+}
+
+// getNotificationTitle returns the headline of a backup summary notification
+func getNotificationTitle(summary BackupSummary) string {
+	title := fmt.Sprintf("MariaDB Backup %s", getBackupStatusEmoji(summary.TotalFailed, summary.TotalDBCount))
+	if summary.Test {
+		title = "[TEST] " + title
+	}
+	return title
+}
+
+// getSuccessRate returns the percentage of databases backed up successfully
+func getSuccessRate(summary BackupSummary) float64 {
+	if summary.TotalDBCount == 0 {
+		return 0
+	}
+	return float64(summary.TotalDBCount-summary.TotalFailed) / float64(summary.TotalDBCount) * 100
+}
+
+// getNotificationFields returns the summary details shown by all channels
+func getNotificationFields(summary BackupSummary) []notificationField {
+	fields := []notificationField{
+		{"Backup Job", summary.JobID},
+		{"Mode", summary.BackupMode},
+	}
+	if summary.Policy != "" {
+		fields = append(fields, notificationField{"Policy", summary.Policy})
+	}
+	fields = append(fields,
+		notificationField{"Total Databases", strconv.Itoa(summary.TotalDBCount)},
+		notificationField{"Full Backups", strconv.Itoa(summary.TotalFull)},
+		notificationField{"Incremental Backups", strconv.Itoa(summary.TotalIncremental)},
+		notificationField{"Failed", strconv.Itoa(summary.TotalFailed)},
+		notificationField{"Total Size", formatFileSize(summary.TotalSizeKB)},
+		notificationField{"Success Rate", fmt.Sprintf("%.1f%%", getSuccessRate(summary))},
+		notificationField{"Duration", formatDurationForSlack(summary.Duration)},
+	)
+	if summary.MysqlRestartTime > 0 {
+		restartDuration := time.Duration(summary.MysqlRestartTime) * time.Second
+		fields = append(fields, notificationField{"MySQL Restart Time", formatDurationForSlack(restartDuration)})
+	}
+	if len(summary.FailedDatabases) > 0 {
+		fields = append(fields, notificationField{"Failed Databases", formatDatabaseList(summary.FailedDatabases)})
+	}
+	return fields
+}
+
+// postNotificationJSON posts a JSON payload to a notification endpoint
+func postNotificationJSON(channel, endpoint string, payload interface{}) error {
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s message: %v", channel, err)
+	}
+
+	req, err := http.NewRequest("POST", endpoint, bytes.NewReader(jsonData))
+	if err != nil {
+		return fmt.Errorf("invalid %s URL", channel)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	return doNotificationRequest(channel, req)
+}
+
+// doNotificationRequest sends a request and fails on non-2xx responses. The
+// URL is left out of errors since webhook URLs and bot tokens are secrets.
+func doNotificationRequest(channel string, req *http.Request) error {
+	resp, err := notificationClient.Do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("failed to send %s notification: %v", channel, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s returned status %d: %s", channel, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	return nil
+}
+
+// SlackNotifier sends events as Slack attachments
+type SlackNotifier struct {
+	WebhookURL string
+}
+
+func (n *SlackNotifier) Name() string { return "Slack" }
+
+func (n *SlackNotifier) Send(event NotificationEvent) error {
+	if event.Summary != nil {
+		return SendSlackNotification(n.WebhookURL, *event.Summary)
+	}
+	return SendSlackEvent(n.WebhookURL, event)
+}
+
+// WebhookNotifier posts events as JSON, optionally rendered from a template
+type WebhookNotifier struct {
+	Config WebhookNotifierConfig
+}
+
+// webhookPayload is the default webhook body and the data of body templates.
+// The job fields are only set for job_succeeded, job_failed and job_cancelled.
+type webhookPayload struct {
+	Event               string            `json:"event"`
+	Severity            string            `json:"severity"`
+	Test                bool              `json:"test"`
+	Title               string            `json:"title"`
+	Message             string            `json:"message"`
+	Time                time.Time         `json:"time"`
+	Databases           []string          `json:"databases"`
+	Fields              map[string]string `json:"fields"`
+	Status              string            `json:"status,omitempty"`
+	JobID               string            `json:"job_id,omitempty"`
+	BackupMode          string            `json:"backup_mode,omitempty"`
+	Policy              string            `json:"policy,omitempty"`
+	TotalDatabases      int               `json:"total_databases,omitempty"`
+	TotalFull           int               `json:"total_full,omitempty"`
+	TotalIncremental    int               `json:"total_incremental,omitempty"`
+	TotalFailed         int               `json:"total_failed,omitempty"`
+	FailedDatabases     []string          `json:"failed_databases,omitempty"`
+	TotalSizeBytes      int64             `json:"total_size_bytes,omitempty"`
+	TotalSize           string            `json:"total_size,omitempty"`
+	SuccessRate         float64           `json:"success_rate,omitempty"`
+	DurationSeconds     float64           `json:"duration_seconds,omitempty"`
+	Duration            string            `json:"duration,omitempty"`
+	MysqlRestartSeconds int               `json:"mysql_restart_seconds,omitempty"`
+	StartedAt           *time.Time        `json:"started_at,omitempty"`
+	CompletedAt         *time.Time        `json:"completed_at,omitempty"`
+}
+
+// webhookTemplateFuncs are available in webhook body templates. json encodes a
+// value as a JSON literal, e.g. {"text": {{json .Title}}}.
+var webhookTemplateFuncs = template.FuncMap{
+	"json": func(value interface{}) (string, error) {
+		data, err := json.Marshal(value)
+		return string(data), err
+	},
+}
+
+func (n *WebhookNotifier) Name() string { return "Webhook" }
+
+func (n *WebhookNotifier) Send(event NotificationEvent) error {
+	payload := webhookPayload{
+		Event:     event.Type,
+		Severity:  event.Severity,
+		Test:      event.Test,
+		Title:     event.Title,
+		Message:   event.Message,
+		Time:      event.Time,
+		Databases: event.Databases,
+		Fields:    make(map[string]string),
+		JobID:     event.JobID,
+	}
+	for _, field := range event.Fields {
+		payload.Fields[field.Name] = field.Value
+	}
+	if summary := event.Summary; summary != nil {
+		payload.Status = getNotificationStatus(*summary)
+		payload.BackupMode = summary.BackupMode
+		payload.Policy = summary.Policy
+		payload.TotalDatabases = summary.TotalDBCount
+		payload.TotalFull = summary.TotalFull
+		payload.TotalIncremental = summary.TotalIncremental
+		payload.TotalFailed = summary.TotalFailed
+		payload.FailedDatabases = summary.FailedDatabases
+		payload.TotalSizeBytes = int64(summary.TotalSizeKB) * 1024
+		payload.TotalSize = formatFileSize(summary.TotalSizeKB)
+		payload.SuccessRate = getSuccessRate(*summary)
+		payload.DurationSeconds = summary.Duration.Seconds()
+		payload.Duration = formatDurationForSlack(summary.Duration)
+		payload.MysqlRestartSeconds = summary.MysqlRestartTime
+		payload.StartedAt = &summary.CreatedAt
+		payload.CompletedAt = &summary.CompletedAt
+	}
+
+	var body []byte
+	if strings.TrimSpace(n.Config.BodyTemplate) == "" {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return fmt.Errorf("failed to marshal webhook body: %v", err)
+		}
+		body = data
+	} else {
+		tmpl, err := template.New("webhook").Funcs(webhookTemplateFuncs).Parse(n.Config.BodyTemplate)
+		if err != nil {
+			return fmt.Errorf("invalid webhook body template: %v", err)
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, payload); err != nil {
+			return fmt.Errorf("failed to render webhook body template: %v", err)
+		}
+		body = buf.Bytes()
+	}
+
+	req, err := http.NewRequest("POST", n.Config.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("invalid webhook URL")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range n.Config.Headers {
+		req.Header.Set(name, value)
+	}
+
+	return doNotificationRequest("webhook", req)
+}
+
+// EmailNotifier sends events as plain text emails over SMTP
+type EmailNotifier struct {
+	Config EmailNotifierConfig
+}
+
+func (n *EmailNotifier) Name() string { return "Email" }
+
+func (n *EmailNotifier) Send(event NotificationEvent) error {
+	var body strings.Builder
+	body.WriteString(event.Title + "\r\n\r\n")
+	if event.Message != "" {
+		body.WriteString(event.Message + "\r\n\r\n")
+	}
+	for _, field := range event.Fields {
+		fmt.Fprintf(&body, "%s: %s\r\n", field.Name, field.Value)
+	}
+	fmt.Fprintf(&body, "Time: %s\r\n", event.Time.Format("2006-01-02 15:04:05"))
+
+	from := n.Config.From
+	if from == "" {
+		from = n.Config.Username
+	}
+
+	subject := fmt.Sprintf("[%s] %s", strings.ToUpper(event.Severity), event.Title)
+	if event.JobID != "" {
+		subject += " - " + event.JobID
+	}
+
+	var message strings.Builder
+	fmt.Fprintf(&message, "From: %s\r\n", from)
+	fmt.Fprintf(&message, "To: %s\r\n", strings.Join(n.Config.To, ", "))
+	fmt.Fprintf(&message, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&message, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	message.WriteString("MIME-Version: 1.0\r\n")
+	message.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	message.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	message.WriteString(body.String())
+
+	return sendSMTPMail(n.Config, from, []byte(message.String()))
+}
+
+// sendSMTPMail delivers a message with implicit TLS, STARTTLS or no encryption.
+// Authentication is only attempted when a username is set.
+func sendSMTPMail(config EmailNotifierConfig, from string, message []byte) error {
+	port := config.SMTPPort
+	if port == 0 {
+		port = 587
+	}
+	addr := net.JoinHostPort(config.SMTPHost, strconv.Itoa(port))
+	tlsConfig := &tls.Config{ServerName: config.SMTPHost}
+	dialer := &net.Dialer{Timeout: notificationTimeout}
+
+	var conn net.Conn
+	var err error
+	if config.TLSMode == "tls" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP server %s: %v", addr, err)
+	}
+	conn.SetDeadline(time.Now().Add(notificationTimeout))
+
+	client, err := smtp.NewClient(conn, config.SMTPHost)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to start SMTP session: %v", err)
+	}
+	defer client.Close()
+
+	if config.TLSMode == "" || config.TLSMode == "starttls" {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("SMTP server %s does not support STARTTLS", addr)
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("STARTTLS failed: %v", err)
+		}
+	}
+
+	if config.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", config.Username, config.Password, config.SMTPHost)); err != nil {
+			return fmt.Errorf("SMTP authentication failed: %v", err)
+		}
+	}
+
+	if err := client.Mail(from); err != nil {
+		return fmt.Errorf("SMTP MAIL FROM failed: %v", err)
+	}
+	for _, recipient := range config.To {
+		if err := client.Rcpt(recipient); err != nil {
+			return fmt.Errorf("SMTP RCPT TO %s failed: %v", recipient, err)
+		}
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("SMTP DATA failed: %v", err)
+	}
+	if _, err := writer.Write(message); err != nil {
+		writer.Close()
+		return fmt.Errorf("failed to write email: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("failed to send email: %v", err)
+	}
+
+	return client.Quit()
+}
+
+// TeamsNotifier posts events as Adaptive Cards to a Teams incoming webhook or workflow
+type TeamsNotifier struct {
+	WebhookURL string
+}
+
+func (n *TeamsNotifier) Name() string { return "Teams" }
+
+func (n *TeamsNotifier) Send(event NotificationEvent) error {
+	color := "Good"
+	switch event.Severity {
+	case SeverityCritical:
+		color = "Attention"
+	case SeverityWarning:
+		color = "Warning"
+	}
+
+	body := []map[string]interface{}{
+		{
+			"type":   "TextBlock",
+			"text":   event.Title,
+			"weight": "Bolder",
+			"size":   "Medium",
+			"color":  color,
+			"wrap":   true,
+		},
+	}
+	if event.Message != "" {
+		body = append(body, map[string]interface{}{
+			"type": "TextBlock",
+			"text": event.Message,
+			"wrap": true,
+		})
+	}
+	if len(event.Fields) > 0 {
+		facts := []map[string]string{}
+		for _, field := range event.Fields {
+			facts = append(facts, map[string]string{"title": field.Name, "value": field.Value})
+		}
+		body = append(body, map[string]interface{}{
+			"type":  "FactSet",
+			"facts": facts,
+		})
+	}
+
+	card := map[string]interface{}{
+		"type": "message",
+		"attachments": []map[string]interface{}{
+			{
+				"contentType": "application/vnd.microsoft.card.adaptive",
+				"content": map[string]interface{}{
+					"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+					"type":    "AdaptiveCard",
+					"version": "1.4",
+					"body":    body,
+				},
+			},
+		},
+	}
+
+	return postNotificationJSON("Teams", n.WebhookURL, card)
+}
+
+// DiscordNotifier posts events as Discord embeds
+type DiscordNotifier struct {
+	WebhookURL string
+}
+
+func (n *DiscordNotifier) Name() string { return "Discord" }
+
+func (n *DiscordNotifier) Send(event NotificationEvent) error {
+	color := 0x2eb886 // green
+	switch event.Severity {
+	case SeverityCritical:
+		color = 0xa30200 // red
+	case SeverityWarning:
+		color = 0xdaa038 // yellow
+	}
+
+	fields := []map[string]interface{}{}
+	for _, field := range event.Fields {
+		fields = append(fields, map[string]interface{}{"name": field.Name, "value": field.Value, "inline": true})
+	}
+
+	embed := map[string]interface{}{
+		"title":       event.Title,
+		"description": event.Message,
+		"color":       color,
+		"fields":      fields,
+	}
+	if !event.Time.IsZero() {
+		embed["timestamp"] = event.Time.Format(time.RFC3339)
+	}
+
+	return postNotificationJSON("Discord", n.WebhookURL, map[string]interface{}{
+		"embeds": []map[string]interface{}{embed},
+	})
+}
+
+// TelegramNotifier sends events through a Telegram bot
+type TelegramNotifier struct {
+	Config TelegramNotifierConfig
+}
+
+func (n *TelegramNotifier) Name() string { return "Telegram" }
+
+func (n *TelegramNotifier) Send(event NotificationEvent) error {
+	var text strings.Builder
+	text.WriteString("<b>" + html.EscapeString(event.Title) + "</b>\n")
+	if event.Message != "" {
+		text.WriteString(html.EscapeString(event.Message) + "\n")
+	}
+	for _, field := range event.Fields {
+		fmt.Fprintf(&text, "%s: <code>%s</code>\n", html.EscapeString(field.Name), html.EscapeString(field.Value))
+	}
+
+	endpoint := "https://api.telegram.org/bot" + n.Config.BotToken + "/sendMessage"
+	return postNotificationJSON("Telegram", endpoint, map[string]interface{}{
+		"chat_id":    n.Config.ChatID,
+		"text":       text.String(),
+		"parse_mode": "HTML",
+	})
+}
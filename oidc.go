@@ -0,0 +1,622 @@
+package main
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OpenID Connect login with the authorization code flow and PKCE (S256).
+// ID tokens are verified against the provider's JWKS, RS* and ES* only.
+const (
+	oidcLoginPath            = "/auth/oidc/login"
+	oidcCallbackPath         = "/auth/oidc/callback"
+	oidcLoginTTL             = 10 * time.Minute // Time allowed at the provider's login page
+	oidcLoginCookie          = "oidc_login"
+	oidcDiscoveryTTL         = time.Hour
+	oidcKeysRefreshInterval  = time.Minute // Minimum time between JWKS fetches for unknown key IDs
+	oidcClockSkew            = time.Minute
+	oidcDefaultUsernameClaim = "preferred_username"
+	oidcDefaultGroupsClaim   = "groups"
+	oidcMaxResponseSize      = 1 << 20
+)
+
+var oidcDefaultScopes = []string{"openid", "profile", "email"}
+
+var oidcHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+// oidcProvider is the discovery document of an issuer with its signing keys
+type oidcProvider struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+
+	discoveredAt  time.Time
+	mutex         sync.Mutex
+	keys          map[string]crypto.PublicKey
+	keysFetchedAt time.Time
+}
+
+var (
+	oidcProviders      = make(map[string]*oidcProvider)
+	oidcProvidersMutex sync.Mutex
+)
+
+// oidcGetJSON fetches a JSON document from the provider
+func oidcGetJSON(endpoint string, target interface{}) error {
+	resp, err := oidcHTTPClient.Get(endpoint)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s returned %s", endpoint, resp.Status)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, oidcMaxResponseSize)).Decode(target)
+}
+
+// discoverOIDCProvider returns the cached provider metadata of an issuer,
+// fetching /.well-known/openid-configuration when missing or stale
+func discoverOIDCProvider(issuerURL string) (*oidcProvider, error) {
+	issuer := strings.TrimSuffix(issuerURL, "/")
+
+	oidcProvidersMutex.Lock()
+	defer oidcProvidersMutex.Unlock()
+
+	if provider := oidcProviders[issuer]; provider != nil && time.Since(provider.discoveredAt) < oidcDiscoveryTTL {
+		return provider, nil
+	}
+
+	provider := &oidcProvider{}
+	if err := oidcGetJSON(issuer+"/.well-known/openid-configuration", provider); err != nil {
+		return nil, fmt.Errorf("OIDC discovery failed: %v", err)
+	}
+	if strings.TrimSuffix(provider.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("OIDC discovery returned issuer %s, expected %s", provider.Issuer, issuer)
+	}
+	if provider.AuthorizationEndpoint == "" || provider.TokenEndpoint == "" || provider.JWKSURI == "" {
+		return nil, fmt.Errorf("OIDC discovery document of %s is missing endpoints", issuer)
+	}
+
+	provider.discoveredAt = time.Now()
+	oidcProviders[issuer] = provider
+	return provider, nil
+}
+
+// jsonWebKey is a public key of a JWKS document (RFC 7517)
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// publicKey decodes an RSA or EC key
+func (k *jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	decode := func(value string) ([]byte, error) {
+		return base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+	}
+
+	switch k.Kty {
+	case "RSA":
+		n, err := decode(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode(k.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return nil, fmt.Errorf("invalid RSA exponent")
+		}
+		exponent := 0
+		for _, b := range e {
+			exponent = exponent<<8 | int(b)
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: exponent}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		var ecdhCurve ecdh.Curve
+		switch k.Crv {
+		case "P-256":
+			curve, ecdhCurve = elliptic.P256(), ecdh.P256()
+		case "P-384":
+			curve, ecdhCurve = elliptic.P384(), ecdh.P384()
+		case "P-521":
+			curve, ecdhCurve = elliptic.P521(), ecdh.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %s", k.Crv)
+		}
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode(k.Y)
+		if err != nil {
+			return nil, err
+		}
+
+		// Reject points that are not on the curve
+		size := (curve.Params().BitSize + 7) / 8
+		if len(x) != size || len(y) != size {
+			return nil, fmt.Errorf("invalid EC point size")
+		}
+		if _, err := ecdhCurve.NewPublicKey(append(append([]byte{4}, x...), y...)); err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported key type %s", k.Kty)
+}
+
+// key returns the signing key with an ID, fetching the JWKS when the ID is
+// unknown so key rotation at the provider is picked up
+func (p *oidcProvider) key(kid string) (crypto.PublicKey, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+	if time.Since(p.keysFetchedAt) < oidcKeysRefreshInterval {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	var document struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	p.keysFetchedAt = time.Now()
+	if err := oidcGetJSON(p.JWKSURI, &document); err != nil {
+		return nil, fmt.Errorf("failed to fetch signing keys: %v", err)
+	}
+
+	p.keys = make(map[string]crypto.PublicKey)
+	for _, jwk := range document.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			LogWarn("⚠️ [AUTH] Skipping OIDC signing key %q: %v", jwk.Kid, err)
+			continue
+		}
+		p.keys[jwk.Kid] = key
+	}
+
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+	// Tokens without a key ID are accepted when the provider has a single key
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// verifyJWTSignature checks a JWS signature for the RS* and ES* algorithms
+func verifyJWTSignature(algorithm string, key crypto.PublicKey, signed, signature []byte) error {
+	var hash crypto.Hash
+	switch algorithm[2:] {
+	case "256":
+		hash = crypto.SHA256
+	case "384":
+		hash = crypto.SHA384
+	case "512":
+		hash = crypto.SHA512
+	default:
+		return fmt.Errorf("unsupported algorithm %s", algorithm)
+	}
+
+	var digest []byte
+	switch hash {
+	case crypto.SHA256:
+		sum := sha256.Sum256(signed)
+		digest = sum[:]
+	case crypto.SHA384:
+		sum := sha512.Sum384(signed)
+		digest = sum[:]
+	default:
+		sum := sha512.Sum512(signed)
+		digest = sum[:]
+	}
+
+	switch algorithm[:2] {
+	case "RS":
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("algorithm %s does not match the key type", algorithm)
+		}
+		return rsa.VerifyPKCS1v15(rsaKey, hash, digest, signature)
+
+	case "ES":
+		ecKey, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("algorithm %s does not match the key type", algorithm)
+		}
+		size := (ecKey.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return fmt.Errorf("invalid signature length")
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(ecKey, digest, r, s) {
+			return fmt.Errorf("invalid signature")
+		}
+		return nil
+	}
+
+	return fmt.Errorf("unsupported algorithm %s", algorithm)
+}
+
+// verifyIDToken checks the signature, issuer, audience, lifetime and nonce
+// of an ID token and returns its claims
+func verifyIDToken(provider *oidcProvider, config *OIDCConfig, rawToken, nonce string) (map[string]interface{}, error) {
+	parts := strings.Split(rawToken, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("ID token is not a signed JWT")
+	}
+
+	headerJSON, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid ID token header: %v", err)
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := json.Unmarshal(headerJSON, &header); err != nil {
+		return nil, fmt.Errorf("invalid ID token header: %v", err)
+	}
+	if len(header.Alg) != 5 || (header.Alg[:2] != "RS" && header.Alg[:2] != "ES") {
+		return nil, fmt.Errorf("unsupported ID token algorithm %q", header.Alg)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid ID token signature: %v", err)
+	}
+	key, err := provider.key(header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyJWTSignature(header.Alg, key, []byte(parts[0]+"."+parts[1]), signature); err != nil {
+		return nil, fmt.Errorf("ID token signature check failed: %v", err)
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid ID token payload: %v", err)
+	}
+	var claims map[string]interface{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("invalid ID token payload: %v", err)
+	}
+
+	if issuer, _ := claims["iss"].(string); strings.TrimSuffix(issuer, "/") != strings.TrimSuffix(provider.Issuer, "/") {
+		return nil, fmt.Errorf("ID token issuer %q does not match %s", issuer, provider.Issuer)
+	}
+
+	audiences := claimStrings(claims, "aud")
+	audienceOK := false
+	for _, audience := range audiences {
+		if audience == config.ClientID {
+			audienceOK = true
+		}
+	}
+	if !audienceOK {
+		return nil, fmt.Errorf("ID token is not issued for client %s", config.ClientID)
+	}
+	if azp, ok := claims["azp"].(string); ok && len(audiences) > 1 && azp != config.ClientID {
+		return nil, fmt.Errorf("ID token is authorized for another client")
+	}
+
+	now := time.Now()
+	expires, ok := claims["exp"].(float64)
+	if !ok || now.After(time.Unix(int64(expires), 0).Add(oidcClockSkew)) {
+		return nil, fmt.Errorf("ID token has expired")
+	}
+	if notBefore, ok := claims["nbf"].(float64); ok && now.Add(oidcClockSkew).Before(time.Unix(int64(notBefore), 0)) {
+		return nil, fmt.Errorf("ID token is not valid yet")
+	}
+	if tokenNonce, _ := claims["nonce"].(string); tokenNonce != nonce {
+		return nil, fmt.Errorf("ID token nonce does not match the login")
+	}
+
+	return claims, nil
+}
+
+// claimValue returns a claim by name. Dotted names such as
+// realm_access.roles walk into nested objects.
+func claimValue(claims map[string]interface{}, name string) interface{} {
+	if value, ok := claims[name]; ok {
+		return value
+	}
+	var current interface{} = claims
+	for _, part := range strings.Split(name, ".") {
+		object, ok := current.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		current = object[part]
+	}
+	return current
+}
+
+// claimStrings returns a string or string array claim as a list
+func claimStrings(claims map[string]interface{}, name string) []string {
+	switch value := claimValue(claims, name).(type) {
+	case string:
+		return []string{value}
+	case []interface{}:
+		var values []string
+		for _, item := range value {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// Logins sent to the provider and waiting for its callback. They live in
+// memory only, keyed by the hash of the state parameter.
+type oidcPendingLogin struct {
+	Nonce        string
+	CodeVerifier string
+	RedirectURL  string
+	Expires      time.Time
+}
+
+var (
+	oidcPendingLogins      = make(map[string]*oidcPendingLogin)
+	oidcPendingLoginsMutex sync.Mutex
+)
+
+// oidcRedirectURL returns the configured callback URL, or the one of the
+// host the browser used
+func oidcRedirectURL(config *OIDCConfig, r *http.Request) string {
+	if config.RedirectURL != "" {
+		return config.RedirectURL
+	}
+	scheme := "http"
+	if isSecureRequest(r) {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + oidcCallbackPath
+}
+
+func setOIDCLoginCookie(w http.ResponseWriter, r *http.Request, value string, maxAge int) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcLoginCookie,
+		Value:    value,
+		Path:     "/auth/oidc",
+		HttpOnly: true,
+		Secure:   isSecureRequest(r),
+		SameSite: http.SameSiteLaxMode, // The callback is a cross-site redirect from the provider
+		MaxAge:   maxAge,
+	})
+}
+
+// handleOIDCLogin sends the browser to the provider's authorization endpoint
+func handleOIDCLogin(w http.ResponseWriter, r *http.Request) {
+	config, err := loadConfig("config.json")
+	if err != nil || !config.Web.OIDC.Enabled {
+		http.NotFound(w, r)
+		return
+	}
+	oidc := &config.Web.OIDC
+
+	provider, err := discoverOIDCProvider(oidc.IssuerURL)
+	if err != nil {
+		LogError("❌ [AUTH] %v", err)
+		w.WriteHeader(http.StatusBadGateway)
+		renderLoginPage(w, "Single sign-on is unavailable, try again later", false)
+		return
+	}
+
+	var state, nonce, codeVerifier string
+	for _, value := range []*string{&state, &nonce, &codeVerifier} {
+		if *value, err = generateSecureToken(32); err != nil {
+			http.Error(w, "Failed to start login", http.StatusInternalServerError)
+			return
+		}
+	}
+	challenge := sha256.Sum256([]byte(codeVerifier))
+	redirectURL := oidcRedirectURL(oidc, r)
+
+	now := time.Now()
+	oidcPendingLoginsMutex.Lock()
+	for key, login := range oidcPendingLogins {
+		if now.After(login.Expires) {
+			delete(oidcPendingLogins, key)
+		}
+	}
+	oidcPendingLogins[hashToken(state)] = &oidcPendingLogin{
+		Nonce:        nonce,
+		CodeVerifier: codeVerifier,
+		RedirectURL:  redirectURL,
+		Expires:      now.Add(oidcLoginTTL),
+	}
+	oidcPendingLoginsMutex.Unlock()
+
+	authURL, err := url.Parse(provider.AuthorizationEndpoint)
+	if err != nil {
+		LogError("❌ [AUTH] Invalid OIDC authorization endpoint %s: %v", provider.AuthorizationEndpoint, err)
+		http.Error(w, "Failed to start login", http.StatusInternalServerError)
+		return
+	}
+	scopes := oidc.Scopes
+	if len(scopes) == 0 {
+		scopes = oidcDefaultScopes
+	}
+	query := authURL.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", oidc.ClientID)
+	query.Set("redirect_uri", redirectURL)
+	query.Set("scope", strings.Join(scopes, " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	query.Set("code_challenge_method", "S256")
+	authURL.RawQuery = query.Encode()
+
+	setOIDCLoginCookie(w, r, state, int(oidcLoginTTL.Seconds()))
+	http.Redirect(w, r, authURL.String(), http.StatusFound)
+}
+
+// takeOIDCLogin returns and removes the pending login of a callback. The
+// state parameter must match the cookie set when the login started.
+func takeOIDCLogin(r *http.Request) *oidcPendingLogin {
+	cookie, err := r.Cookie(oidcLoginCookie)
+	state := r.URL.Query().Get("state")
+	if err != nil || state == "" || cookie.Value != state {
+		return nil
+	}
+	key := hashToken(state)
+
+	oidcPendingLoginsMutex.Lock()
+	defer oidcPendingLoginsMutex.Unlock()
+
+	login := oidcPendingLogins[key]
+	delete(oidcPendingLogins, key)
+	if login == nil || time.Now().After(login.Expires) {
+		return nil
+	}
+	return login
+}
+
+// exchangeOIDCCode redeems an authorization code for the ID token
+func exchangeOIDCCode(provider *oidcProvider, config *OIDCConfig, code string, login *oidcPendingLogin) (string, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {login.RedirectURL},
+		"code_verifier": {login.CodeVerifier},
+		"client_id":     {config.ClientID},
+	}
+	req, err := http.NewRequest("POST", provider.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if config.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(config.ClientID), url.QueryEscape(config.ClientSecret))
+	}
+
+	resp, err := oidcHTTPClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var tokens struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, oidcMaxResponseSize)).Decode(&tokens); err != nil {
+		return "", fmt.Errorf("token endpoint returned %s", resp.Status)
+	}
+	if resp.StatusCode != http.StatusOK || tokens.Error != "" {
+		return "", fmt.Errorf("token endpoint returned %s: %s %s", resp.Status, tokens.Error, tokens.ErrorDescription)
+	}
+	if tokens.IDToken == "" {
+		return "", fmt.Errorf("token response has no id_token, is the openid scope granted?")
+	}
+	return tokens.IDToken, nil
+}
+
+// handleOIDCCallback finishes a login at the provider: it redeems the code,
+// verifies the ID token, maps the groups to a role and starts the session
+func handleOIDCCallback(w http.ResponseWriter, r *http.Request) {
+	config, err := loadConfig("config.json")
+	if err != nil || !config.Web.OIDC.Enabled {
+		http.NotFound(w, r)
+		return
+	}
+	oidc := &config.Web.OIDC
+	ip := clientIP(r)
+
+	login := takeOIDCLogin(r)
+	setOIDCLoginCookie(w, r, "", -1)
+	if login == nil {
+		renderLoginPage(w, "Your login has expired, sign in again", false)
+		return
+	}
+
+	if providerError := r.URL.Query().Get("error"); providerError != "" {
+		LogWarn("🔒 [AUTH] OIDC login from %s failed at the provider: %s %s", ip, providerError, r.URL.Query().Get("error_description"))
+		renderLoginPage(w, "Single sign-on was cancelled or failed", false)
+		return
+	}
+
+	provider, err := discoverOIDCProvider(oidc.IssuerURL)
+	var idToken string
+	if err == nil {
+		idToken, err = exchangeOIDCCode(provider, oidc, r.URL.Query().Get("code"), login)
+	}
+	var claims map[string]interface{}
+	if err == nil {
+		claims, err = verifyIDToken(provider, oidc, idToken, login.Nonce)
+	}
+	if err != nil {
+		LogError("❌ [AUTH] OIDC login from %s failed: %v", ip, err)
+		renderLoginPage(w, "Single sign-on failed, try again or contact an administrator", false)
+		return
+	}
+
+	usernameClaim := oidc.UsernameClaim
+	if usernameClaim == "" {
+		usernameClaim = oidcDefaultUsernameClaim
+	}
+	groupsClaim := oidc.GroupsClaim
+	if groupsClaim == "" {
+		groupsClaim = oidcDefaultGroupsClaim
+	}
+
+	username := ""
+	if values := claimStrings(claims, usernameClaim); len(values) > 0 {
+		username = values[0]
+	}
+	if username == "" {
+		LogError("❌ [AUTH] OIDC login from %s failed: ID token has no %s claim", ip, usernameClaim)
+		renderLoginPage(w, "Single sign-on failed, try again or contact an administrator", false)
+		return
+	}
+
+	role := mapGroupsToRole(claimStrings(claims, groupsClaim), oidc.GroupRoles, oidc.DefaultRole)
+	user, err := provisionSSOUser(username, AuthSourceOIDC, role)
+	if err != nil {
+		LogWarn("🔒 [AUTH] Refused OIDC login of %s from %s: %v", username, ip, err)
+		recordAuditEntry(r, AuditEntry{Actor: username, Action: AuditLoginFailed, Target: username, Outcome: AuditOutcomeFailure, Details: "OIDC: " + err.Error()})
+		w.WriteHeader(http.StatusForbidden)
+		renderLoginPage(w, "Your account is not allowed to use this tool", false)
+		return
+	}
+
+	beginLogin(w, r, user, ip)
+}
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	testOIDCClientID     = "backup-tool"
+	testOIDCClientSecret = "client-secret"
+	testOIDCCode         = "test-code"
+	testOIDCVerifier     = "test-verifier"
+	testOIDCNonce        = "test-nonce"
+)
+
+// testOIDCIssuer is an in-process provider serving discovery, JWKS and the
+// token endpoint. The token endpoint returns idToken for testOIDCCode.
+type testOIDCIssuer struct {
+	server  *httptest.Server
+	rsaKey  *rsa.PrivateKey
+	ecKey   *ecdsa.PrivateKey
+	idToken string
+}
+
+func newTestOIDCIssuer(t *testing.T) *testOIDCIssuer {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer := &testOIDCIssuer{rsaKey: rsaKey, ecKey: ecKey}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 issuer.server.URL,
+			"authorization_endpoint": issuer.server.URL + "/auth",
+			"token_endpoint":         issuer.server.URL + "/token",
+			"jwks_uri":               issuer.server.URL + "/keys",
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		encode := base64.RawURLEncoding.EncodeToString
+		size := (ecKey.Curve.Params().BitSize + 7) / 8
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{
+				{"kty": "RSA", "kid": "rsa-key", "use": "sig", "n": encode(rsaKey.N.Bytes()), "e": encode(big.NewInt(int64(rsaKey.E)).Bytes())},
+				{"kty": "EC", "kid": "ec-key", "use": "sig", "crv": "P-256", "x": encode(ecKey.X.FillBytes(make([]byte, size))), "y": encode(ecKey.Y.FillBytes(make([]byte, size)))},
+				{"kty": "RSA", "kid": "enc-key", "use": "enc", "n": encode(rsaKey.N.Bytes()), "e": "AQAB"},
+			},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		clientID, secret, _ := r.BasicAuth()
+		switch {
+		case r.Method != "POST" || r.FormValue("grant_type") != "authorization_code":
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "unsupported_grant_type"})
+		case clientID != testOIDCClientID || secret != testOIDCClientSecret:
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_client"})
+		case r.FormValue("code") != testOIDCCode || r.FormValue("code_verifier") != testOIDCVerifier:
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+		default:
+			json.NewEncoder(w).Encode(map[string]string{"access_token": "access", "token_type": "Bearer", "id_token": issuer.idToken})
+		}
+	})
+	issuer.server = httptest.NewServer(mux)
+	t.Cleanup(issuer.server.Close)
+	return issuer
+}
+
+func (i *testOIDCIssuer) config() *OIDCConfig {
+	return &OIDCConfig{Enabled: true, IssuerURL: i.server.URL, ClientID: testOIDCClientID, ClientSecret: testOIDCClientSecret}
+}
+
+// claims returns the claims of a valid ID token
+func (i *testOIDCIssuer) claims() map[string]interface{} {
+	now := time.Now()
+	return map[string]interface{}{
+		"iss":                i.server.URL,
+		"sub":                "user-1",
+		"aud":                testOIDCClientID,
+		"exp":                now.Add(5 * time.Minute).Unix(),
+		"iat":                now.Unix(),
+		"nonce":              testOIDCNonce,
+		"preferred_username": "jane",
+		"groups":             []string{"dba", "staff"},
+		"realm_access":       map[string]interface{}{"roles": []string{"backup-admin"}},
+	}
+}
+
+// signTestJWT signs claims with RS256 for RSA keys and ES256 for EC keys
+func signTestJWT(t *testing.T, key crypto.Signer, kid string, claims map[string]interface{}) string {
+	t.Helper()
+	header := map[string]string{"typ": "JWT", "kid": kid, "alg": "RS256"}
+	if _, ok := key.(*ecdsa.PrivateKey); ok {
+		header["alg"] = "ES256"
+	}
+	headerJSON, _ := json.Marshal(header)
+	claimsJSON, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(headerJSON) + "." + base64.RawURLEncoding.EncodeToString(claimsJSON)
+	digest := sha256.Sum256([]byte(signed))
+
+	var signature []byte
+	switch key := key.(type) {
+	case *rsa.PrivateKey:
+		var err error
+		if signature, err = rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:]); err != nil {
+			t.Fatal(err)
+		}
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		signature = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestOIDCCodeExchangeAndVerify(t *testing.T) {
+	issuer := newTestOIDCIssuer(t)
+	config := issuer.config()
+
+	provider, err := discoverOIDCProvider(issuer.server.URL + "/")
+	if err != nil {
+		t.Fatalf("discovery failed: %v", err)
+	}
+	if provider.TokenEndpoint != issuer.server.URL+"/token" {
+		t.Fatalf("token endpoint = %s", provider.TokenEndpoint)
+	}
+
+	issuer.idToken = signTestJWT(t, issuer.rsaKey, "rsa-key", issuer.claims())
+	login := &oidcPendingLogin{Nonce: testOIDCNonce, CodeVerifier: testOIDCVerifier, RedirectURL: "http://localhost/auth/oidc/callback"}
+	idToken, err := exchangeOIDCCode(provider, config, testOIDCCode, login)
+	if err != nil {
+		t.Fatalf("code exchange failed: %v", err)
+	}
+
+	claims, err := verifyIDToken(provider, config, idToken, testOIDCNonce)
+	if err != nil {
+		t.Fatalf("valid ID token rejected: %v", err)
+	}
+	if got := claimStrings(claims, "preferred_username"); len(got) != 1 || got[0] != "jane" {
+		t.Errorf("preferred_username = %v", got)
+	}
+	if got := claimStrings(claims, "groups"); strings.Join(got, ",") != "dba,staff" {
+		t.Errorf("groups = %v", got)
+	}
+	if got := claimStrings(claims, "realm_access.roles"); strings.Join(got, ",") != "backup-admin" {
+		t.Errorf("realm_access.roles = %v", got)
+	}
+
+	if _, err := exchangeOIDCCode(provider, config, "other-code", login); err == nil || !strings.Contains(err.Error(), "invalid_grant") {
+		t.Errorf("exchange of an unknown code: err = %v, want invalid_grant", err)
+	}
+	wrongSecret := *config
+	wrongSecret.ClientSecret = "wrong"
+	if _, err := exchangeOIDCCode(provider, &wrongSecret, testOIDCCode, login); err == nil || !strings.Contains(err.Error(), "invalid_client") {
+		t.Errorf("exchange with a wrong client secret: err = %v, want invalid_client", err)
+	}
+}
+
+func TestOIDCVerifyIDTokenES256(t *testing.T) {
+	issuer := newTestOIDCIssuer(t)
+	provider, err := discoverOIDCProvider(issuer.server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token := signTestJWT(t, issuer.ecKey, "ec-key", issuer.claims())
+	if _, err := verifyIDToken(provider, issuer.config(), token, testOIDCNonce); err != nil {
+		t.Fatalf("valid ES256 ID token rejected: %v", err)
+	}
+}
+
+func TestOIDCVerifyIDTokenRejects(t *testing.T) {
+	issuer := newTestOIDCIssuer(t)
+	provider, err := discoverOIDCProvider(issuer.server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		key    crypto.Signer
+		kid    string
+		modify func(claims map[string]interface{})
+		nonce  string
+		want   string
+	}{
+		{name: "bad signature", key: otherKey, kid: "rsa-key", want: "signature check failed"},
+		{name: "key of another type", key: issuer.ecKey, kid: "rsa-key", want: "does not match the key type"},
+		{name: "unknown kid", key: issuer.rsaKey, kid: "rotated-away", want: "unknown signing key"},
+		{name: "encryption key", key: issuer.rsaKey, kid: "enc-key", want: "unknown signing key"},
+		{name: "wrong issuer", key: issuer.rsaKey, kid: "rsa-key", want: "issuer",
+			modify: func(claims map[string]interface{}) { claims["iss"] = "https://evil.example.com" }},
+		{name: "wrong audience", key: issuer.rsaKey, kid: "rsa-key", want: "not issued for client",
+			modify: func(claims map[string]interface{}) { claims["aud"] = "another-client" }},
+		{name: "authorized for another client", key: issuer.rsaKey, kid: "rsa-key", want: "another client",
+			modify: func(claims map[string]interface{}) {
+				claims["aud"] = []string{testOIDCClientID, "another-client"}
+				claims["azp"] = "another-client"
+			}},
+		{name: "wrong nonce", key: issuer.rsaKey, kid: "rsa-key", nonce: "other-nonce", want: "nonce"},
+		{name: "missing nonce", key: issuer.rsaKey, kid: "rsa-key", want: "nonce",
+			modify: func(claims map[string]interface{}) { delete(claims, "nonce") }},
+		{name: "expired", key: issuer.rsaKey, kid: "rsa-key", want: "expired",
+			modify: func(claims map[string]interface{}) {
+				claims["exp"] = time.Now().Add(-oidcClockSkew - time.Minute).Unix()
+			}},
+		{name: "missing expiry", key: issuer.rsaKey, kid: "rsa-key", want: "expired",
+			modify: func(claims map[string]interface{}) { delete(claims, "exp") }},
+		{name: "not valid yet", key: issuer.rsaKey, kid: "rsa-key", want: "not valid yet",
+			modify: func(claims map[string]interface{}) {
+				claims["nbf"] = time.Now().Add(oidcClockSkew + time.Minute).Unix()
+			}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			claims := issuer.claims()
+			if test.modify != nil {
+				test.modify(claims)
+			}
+			nonce := testOIDCNonce
+			if test.nonce != "" {
+				nonce = test.nonce
+			}
+
+			token := signTestJWT(t, test.key, test.kid, claims)
+			_, err := verifyIDToken(provider, issuer.config(), token, nonce)
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Fatalf("err = %v, want an error containing %q", err, test.want)
+			}
+		})
+	}
+}
+
+func TestOIDCVerifyIDTokenMalformed(t *testing.T) {
+	issuer := newTestOIDCIssuer(t)
+	provider, err := discoverOIDCProvider(issuer.server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	valid := signTestJWT(t, issuer.rsaKey, "rsa-key", issuer.claims())
+	parts := strings.Split(valid, ".")
+	noneHeader := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","kid":"rsa-key"}`))
+	hsHeader := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","kid":"rsa-key"}`))
+
+	for name, token := range map[string]string{
+		"not a JWT":       "abc.def",
+		"alg none":        noneHeader + "." + parts[1] + ".",
+		"alg HS256":       hsHeader + "." + parts[1] + "." + parts[2],
+		"swapped payload": parts[0] + "." + base64.RawURLEncoding.EncodeToString([]byte(`{"iss":"x"}`)) + "." + parts[2],
+	} {
+		if _, err := verifyIDToken(provider, issuer.config(), token, testOIDCNonce); err == nil {
+			t.Errorf("%s: token accepted", name)
+		}
+	}
+}
+
+func TestOIDCDiscoveryIssuerMismatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 "https://other.example.com",
+			"authorization_endpoint": "https://other.example.com/auth",
+			"token_endpoint":         "https://other.example.com/token",
+			"jwks_uri":               "https://other.example.com/keys",
+		})
+	}))
+	defer server.Close()
+
+	if _, err := discoverOIDCProvider(server.URL); err == nil || !strings.Contains(err.Error(), "expected") {
+		t.Fatalf("err = %v, want an issuer mismatch", err)
+	}
+}
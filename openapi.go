@@ -0,0 +1,211 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// buildOpenAPISpec generates the OpenAPI 3.0 document of the versioned API
+// from the route table and the Go types of the request and response bodies
+func buildOpenAPISpec() map[string]interface{} {
+	schemas := map[string]interface{}{}
+	errorSchema := openAPISchema(reflect.TypeOf(APIErrorResponse{}), schemas)
+	paths := map[string]map[string]interface{}{}
+
+	for _, route := range apiV1Routes() {
+		operation := map[string]interface{}{
+			"summary":     route.Summary,
+			"operationId": openAPIOperationID(route),
+			"tags":        []string{route.Tag},
+		}
+
+		var parameters []interface{}
+		for _, part := range strings.Split(route.Path, "/") {
+			if strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}") {
+				parameters = append(parameters, map[string]interface{}{
+					"name":     strings.Trim(part, "{}"),
+					"in":       "path",
+					"required": true,
+					"schema":   map[string]interface{}{"type": "string"},
+				})
+			}
+		}
+		for _, param := range route.Query {
+			parameters = append(parameters, map[string]interface{}{
+				"name":        param.Name,
+				"in":          "query",
+				"description": param.Description,
+				"schema":      map[string]interface{}{"type": param.Type},
+			})
+		}
+		if len(parameters) > 0 {
+			operation["parameters"] = parameters
+		}
+
+		if route.Request != nil {
+			operation["requestBody"] = map[string]interface{}{
+				"required": !route.Optional,
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{
+						"schema": openAPISchema(reflect.TypeOf(route.Request), schemas),
+					},
+				},
+			}
+		}
+
+		responses := map[string]interface{}{
+			strconv.Itoa(route.Status): map[string]interface{}{
+				"description": http.StatusText(route.Status),
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{
+						"schema": openAPISchema(reflect.TypeOf(route.Response), schemas),
+					},
+				},
+			},
+		}
+		errorStatuses := append([]int{}, route.Errors...)
+		if route.Role != "" {
+			errorStatuses = append(errorStatuses, http.StatusUnauthorized, http.StatusForbidden, http.StatusInternalServerError)
+			scope := getEndpointScope(route.Method + " " + route.Path)
+			operation["description"] = fmt.Sprintf("Requires the %s role. API tokens need the %s scope.", route.Role, scope)
+		} else {
+			operation["security"] = []interface{}{}
+		}
+		for _, status := range errorStatuses {
+			responses[strconv.Itoa(status)] = map[string]interface{}{
+				"description": http.StatusText(status),
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{"schema": errorSchema},
+				},
+			}
+		}
+		operation["responses"] = responses
+
+		if paths[route.Path] == nil {
+			paths[route.Path] = map[string]interface{}{}
+		}
+		paths[route.Path][strings.ToLower(route.Method)] = operation
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":       "MariaDB Backup Tool API",
+			"version":     Version,
+			"description": "Versioned REST API. Errors use the APIErrorResponse envelope, lists take limit and offset and return pagination.",
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": schemas,
+			"securitySchemes": map[string]interface{}{
+				"bearerAuth": map[string]interface{}{
+					"type":        "http",
+					"scheme":      "bearer",
+					"description": "API token created on the Users page",
+				},
+				"sessionCookie": map[string]interface{}{
+					"type":        "apiKey",
+					"in":          "cookie",
+					"name":        sessionCookieName,
+					"description": "Web interface session, state changing requests also need the X-CSRF-Token header",
+				},
+			},
+		},
+		"security": []interface{}{
+			map[string]interface{}{"bearerAuth": []string{}},
+			map[string]interface{}{"sessionCookie": []string{}},
+		},
+	}
+}
+
+// openAPIOperationID derives an operation ID such as getBackupsJobId from a route
+func openAPIOperationID(route apiRoute) string {
+	id := strings.ToLower(route.Method)
+	for _, part := range strings.FieldsFunc(strings.TrimPrefix(route.Path, apiV1Prefix), func(c rune) bool {
+		return c == '/' || c == '{' || c == '}' || c == '_' || c == '.' || c == '-'
+	}) {
+		id += strings.ToUpper(part[:1]) + part[1:]
+	}
+	return id
+}
+
+// openAPISchema returns the schema of a Go type. Named structs are added to
+// schemas and referenced; fields without omitempty are required.
+func openAPISchema(t reflect.Type, schemas map[string]interface{}) map[string]interface{} {
+	if t == reflect.TypeOf(time.Time{}) {
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		schema := openAPISchema(t.Elem(), schemas)
+		if _, isRef := schema["$ref"]; isRef {
+			return map[string]interface{}{"allOf": []interface{}{schema}, "nullable": true}
+		}
+		schema["nullable"] = true
+		return schema
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int64, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "format": "int64"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": openAPISchema(t.Elem(), schemas)}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": openAPISchema(t.Elem(), schemas)}
+	case reflect.Struct:
+		return openAPIStructSchema(t, schemas)
+	}
+
+	// interface{} and anything else accepts any value
+	return map[string]interface{}{}
+}
+
+// openAPIStructSchema describes a struct by its JSON field names
+func openAPIStructSchema(t reflect.Type, schemas map[string]interface{}) map[string]interface{} {
+	name := t.Name()
+	if name != "" {
+		ref := map[string]interface{}{"$ref": "#/components/schemas/" + name}
+		if _, seen := schemas[name]; seen {
+			return ref
+		}
+		schemas[name] = nil // Placeholder while the fields are described
+	}
+
+	properties := map[string]interface{}{}
+	var required []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if !field.IsExported() || tag == "-" {
+			continue
+		}
+		fieldName, options, _ := strings.Cut(tag, ",")
+		if fieldName == "" {
+			fieldName = field.Name
+		}
+		properties[fieldName] = openAPISchema(field.Type, schemas)
+		if !strings.Contains(options, "omitempty") {
+			required = append(required, fieldName)
+		}
+	}
+
+	schema := map[string]interface{}{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	if name == "" {
+		return schema
+	}
+	schemas[name] = schema
+	return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+}
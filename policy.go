@@ -0,0 +1,205 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// DefaultPolicyName is the implicit policy built from the top-level backup settings
+const DefaultPolicyName = "default"
+
+// BackupPolicy is a named schedule with its own database selection and backup
+// settings. Zero values (and a nil compression level) inherit the matching
+// top-level setting from BackupConfig/NotificationConfig.
+type BackupPolicy struct {
+	Name               string   `json:"name"`
+	Enabled            bool     `json:"enabled"`
+	Schedule           string   `json:"schedule"`
+	Mode               string   `json:"mode"`
+	IncludeDbs         []string `json:"include_dbs"`
+	IgnoreDbs          []string `json:"ignore_dbs"`
+	CompressionLevel   *int     `json:"compression_level,omitempty"`
+	RetentionDays      int      `json:"retention_days"`
+	FullBackupInterval int      `json:"full_backup_interval"`
+	SlackWebhookURL    string   `json:"slack_webhook_url"`
+	RPOHours           int      `json:"rpo_hours"`
+}
+
+var policyNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// getDefaultPolicy builds the implicit policy from the top-level backup settings
+func getDefaultPolicy(config *Config) BackupPolicy {
+	compressionLevel := config.Backup.CompressionLevel
+	return BackupPolicy{
+		Name:               DefaultPolicyName,
+		Enabled:            config.Backup.BackupSchedule != "",
+		Schedule:           config.Backup.BackupSchedule,
+		Mode:               config.Backup.DefaultBackupMode,
+		IncludeDbs:         config.Backup.IncludeDbs,
+		IgnoreDbs:          config.Backup.IgnoreDbs,
+		CompressionLevel:   &compressionLevel,
+		RetentionDays:      config.Backup.RetentionBackups,
+		FullBackupInterval: config.Backup.FullBackupInterval,
+		SlackWebhookURL:    config.Notification.SlackWebhookURL,
+		RPOHours:           config.Backup.RPOHours,
+	}
+}
+
+// getBackupPolicies returns the default policy followed by all named policies
+func getBackupPolicies(config *Config) []BackupPolicy {
+	policies := []BackupPolicy{getDefaultPolicy(config)}
+	policies = append(policies, config.Policies...)
+	return policies
+}
+
+// findBackupPolicy looks up a policy by name, including the default policy
+func findBackupPolicy(config *Config, name string) (BackupPolicy, bool) {
+	if name == "" || name == DefaultPolicyName {
+		return getDefaultPolicy(config), true
+	}
+
+	for _, policy := range config.Policies {
+		if policy.Name == name {
+			return policy, true
+		}
+	}
+
+	return BackupPolicy{}, false
+}
+
+// applyBackupPolicy returns a copy of config with the policy's settings applied.
+// Unknown policy names leave the configuration unchanged.
+func applyBackupPolicy(config *Config, policyName string) *Config {
+	if policyName == "" || policyName == DefaultPolicyName {
+		return config
+	}
+
+	policy, ok := findBackupPolicy(config, policyName)
+	if !ok {
+		LogWarn("Backup policy %s not found, using global settings", policyName)
+		return config
+	}
+
+	applied := *config
+	applied.Backup.IncludeDbs = policy.IncludeDbs
+	applied.Backup.IgnoreDbs = policy.IgnoreDbs
+	if policy.Mode != "" {
+		applied.Backup.DefaultBackupMode = policy.Mode
+	}
+	if policy.CompressionLevel != nil {
+		applied.Backup.CompressionLevel = *policy.CompressionLevel
+	}
+	if policy.RetentionDays > 0 {
+		applied.Backup.RetentionBackups = policy.RetentionDays
+	}
+	if policy.FullBackupInterval > 0 {
+		applied.Backup.FullBackupInterval = policy.FullBackupInterval
+	}
+	if policy.SlackWebhookURL != "" {
+		applied.Notification.SlackWebhookURL = policy.SlackWebhookURL
+	}
+
+	return &applied
+}
+
+// policySelectsDatabase reports whether a policy's include/ignore patterns select a database
+func policySelectsDatabase(policy BackupPolicy, dbName string) bool {
+	status, _ := classifyDatabase(policy.IncludeDbs, policy.IgnoreDbs, dbName)
+	return status == "included"
+}
+
+// isDatabaseSelectedByAnyPolicy reports whether the default policy or an
+// enabled named policy backs up the database
+func isDatabaseSelectedByAnyPolicy(config *Config, dbName string) bool {
+	for _, policy := range getBackupPolicies(config) {
+		if policy.Name != DefaultPolicyName && !policy.Enabled {
+			continue
+		}
+		if policySelectsDatabase(policy, dbName) {
+			return true
+		}
+	}
+	return false
+}
+
+// getRetentionDaysForDatabase returns the longest retention of all policies that
+// back up the database, so one policy never prunes files another still needs.
+// Databases not selected by any policy fall back to the global retention.
+func getRetentionDaysForDatabase(config *Config, dbName string) int {
+	retentionDays := 0
+	matched := false
+
+	for _, policy := range getBackupPolicies(config) {
+		if policy.Name != DefaultPolicyName && !policy.Enabled {
+			continue
+		}
+		if !policySelectsDatabase(policy, dbName) {
+			continue
+		}
+
+		days := policy.RetentionDays
+		if days <= 0 {
+			days = config.Backup.RetentionBackups
+		}
+		if !matched || days > retentionDays {
+			retentionDays = days
+		}
+		matched = true
+	}
+
+	if !matched {
+		return config.Backup.RetentionBackups
+	}
+
+	return retentionDays
+}
+
+// validateBackupPolicies checks names, schedules, modes and patterns of all named policies
+func validateBackupPolicies(policies []BackupPolicy) error {
+	seen := make(map[string]bool)
+
+	for _, policy := range policies {
+		name := policy.Name
+		if !policyNamePattern.MatchString(name) {
+			return fmt.Errorf("invalid policy name %q: use letters, digits, '-', '_' or '.'", name)
+		}
+		if strings.EqualFold(name, DefaultPolicyName) {
+			return fmt.Errorf("policy name %q is reserved", name)
+		}
+		if seen[name] {
+			return fmt.Errorf("duplicate policy name %q", name)
+		}
+		seen[name] = true
+
+		if policy.Schedule != "" {
+			if _, err := ParseCronSchedule(policy.Schedule); err != nil {
+				return fmt.Errorf("policy %s: invalid schedule: %v", name, err)
+			}
+		} else if policy.Enabled {
+			return fmt.Errorf("policy %s: schedule is required when the policy is enabled", name)
+		}
+
+		switch policy.Mode {
+		case "auto", "full", "incremental":
+		default:
+			return fmt.Errorf("policy %s: invalid mode %q (auto, full or incremental)", name, policy.Mode)
+		}
+
+		if policy.CompressionLevel != nil && (*policy.CompressionLevel < 0 || *policy.CompressionLevel > 9) {
+			return fmt.Errorf("policy %s: compression level must be between 0 and 9", name)
+		}
+		if policy.RetentionDays < 0 || policy.FullBackupInterval < 0 || policy.RPOHours < 0 {
+			return fmt.Errorf("policy %s: retention, full backup interval and RPO cannot be negative", name)
+		}
+
+		if err := validateDatabasePatterns(policy.IncludeDbs); err != nil {
+			return fmt.Errorf("policy %s: include databases: %v", name, err)
+		}
+		if err := validateDatabasePatterns(policy.IgnoreDbs); err != nil {
+			return fmt.Errorf("policy %s: ignore databases: %v", name, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupProcess is the dump or binlog command of one database in a running job
+type backupProcess struct {
+	JobID        string
+	DatabaseName string
+	TempFilePath string
+	StartedAt    time.Time
+	cmd          *exec.Cmd
+}
+
+// Running backup processes keyed by job and database, and the jobs (database "")
+// and databases that were cancelled and must not start
+var backupProcesses = make(map[string]*backupProcess)
+var cancelledBackups = make(map[string]bool)
+var backupProcessesMutex sync.Mutex
+
+// backupProcessKey returns the registry key of a database in a job
+func backupProcessKey(jobID, dbName string) string {
+	return jobID + "/" + dbName
+}
+
+// registerBackupProcess tracks a started backup command so it can be cancelled.
+// A command started right after its database was cancelled is killed at once.
+func registerBackupProcess(jobID, dbName, tempFilePath string, cmd *exec.Cmd) {
+	backupProcessesMutex.Lock()
+	defer backupProcessesMutex.Unlock()
+
+	backupProcesses[backupProcessKey(jobID, dbName)] = &backupProcess{
+		JobID:        jobID,
+		DatabaseName: dbName,
+		TempFilePath: tempFilePath,
+		StartedAt:    time.Now(),
+		cmd:          cmd,
+	}
+
+	if cancelledBackups[backupProcessKey(jobID, "")] || cancelledBackups[backupProcessKey(jobID, dbName)] {
+		LogWarn("⚠️ [CANCEL] Backup of %s in job %s was cancelled while starting, killing it", dbName, jobID)
+		if err := killProcessGroup(cmd); err != nil {
+			LogError("❌ [CANCEL] Failed to kill backup process of %s in job %s: %v", dbName, jobID, err)
+		}
+	}
+}
+
+// unregisterBackupProcess stops tracking a backup command once it has exited
+func unregisterBackupProcess(jobID, dbName string) {
+	backupProcessesMutex.Lock()
+	delete(backupProcesses, backupProcessKey(jobID, dbName))
+	backupProcessesMutex.Unlock()
+}
+
+// backupProcessRunning reports whether cmd is still registered, i.e. has not
+// been waited for yet
+func backupProcessRunning(cmd *exec.Cmd) bool {
+	backupProcessesMutex.Lock()
+	defer backupProcessesMutex.Unlock()
+
+	for _, process := range backupProcesses {
+		if process.cmd == cmd {
+			return true
+		}
+	}
+	return false
+}
+
+// IsBackupCancelled reports whether a job, or one database of it, was cancelled
+func IsBackupCancelled(jobID, dbName string) bool {
+	backupProcessesMutex.Lock()
+	defer backupProcessesMutex.Unlock()
+
+	return cancelledBackups[backupProcessKey(jobID, "")] || cancelledBackups[backupProcessKey(jobID, dbName)]
+}
+
+// CancelBackup cancels a job or, when dbName is set, a single database of a job.
+// Running commands are killed together with their child processes; databases
+// that have not started yet are skipped by the workers. Returns the number of
+// processes killed.
+func CancelBackup(jobID, dbName string) int {
+	backupProcessesMutex.Lock()
+	defer backupProcessesMutex.Unlock()
+
+	cancelledBackups[backupProcessKey(jobID, dbName)] = true
+
+	killed := 0
+	for _, process := range backupProcesses {
+		if process.JobID != jobID || (dbName != "" && process.DatabaseName != dbName) {
+			continue
+		}
+
+		LogInfo("🛑 [CANCEL] Killing backup process of %s in job %s (PID: %d)",
+			process.DatabaseName, jobID, process.cmd.Process.Pid)
+		if err := killProcessGroup(process.cmd); err != nil {
+			LogError("❌ [CANCEL] Failed to kill backup process of %s in job %s: %v", process.DatabaseName, jobID, err)
+			continue
+		}
+		killed++
+	}
+
+	return killed
+}
+
+// CancelAllBackups cancels every job that has a running backup process and
+// returns the number of processes killed
+func CancelAllBackups() int {
+	backupProcessesMutex.Lock()
+	jobIDs := make(map[string]bool)
+	for _, process := range backupProcesses {
+		jobIDs[process.JobID] = true
+	}
+	backupProcessesMutex.Unlock()
+
+	killed := 0
+	for jobID := range jobIDs {
+		killed += CancelBackup(jobID, "")
+	}
+
+	return killed
+}
+
+// clearBackupCancellation forgets the cancellations of a finished job
+func clearBackupCancellation(jobID string) {
+	backupProcessesMutex.Lock()
+	defer backupProcessesMutex.Unlock()
+
+	for key := range cancelledBackups {
+		if strings.HasPrefix(key, jobID+"/") {
+			delete(cancelledBackups, key)
+		}
+	}
+}
+
+// GetBackupProcesses returns the running backup processes
+func GetBackupProcesses() []map[string]interface{} {
+	backupProcessesMutex.Lock()
+	defer backupProcessesMutex.Unlock()
+
+	processes := []map[string]interface{}{}
+	for _, process := range backupProcesses {
+		processes = append(processes, map[string]interface{}{
+			"job_id":        process.JobID,
+			"database_name": process.DatabaseName,
+			"pid":           process.cmd.Process.Pid,
+			"started_at":    process.StartedAt.Format("2006-01-02 15:04:05"),
+		})
+	}
+
+	return processes
+}
+
+// removeTempBackupFile deletes the partial output of a failed or cancelled backup
+func removeTempBackupFile(tempFilePath string) {
+	if tempFilePath == "" {
+		return
+	}
+
+	if removeErr := os.Remove(tempFilePath); removeErr != nil && !os.IsNotExist(removeErr) {
+		LogWarn("⚠️ [CLEANUP] Failed to remove temporary file %s: %v", tempFilePath, removeErr)
+	} else {
+		LogDebug("🧹 [CLEANUP] Removed temporary file: %s", tempFilePath)
+	}
+}
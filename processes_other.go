@@ -0,0 +1,44 @@
+//go:build !windows
+
+package main
+
+import (
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// Time a cancelled process group gets to exit after SIGTERM before it is killed
+const processKillGracePeriod = 5 * time.Second
+
+// setProcessGroup starts the command in its own process group so that the
+// shell, mysqldump/mariadb-binlog and gzip can be signalled together
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// killProcessGroup sends SIGTERM to the command's process group and SIGKILL
+// if the command is still running after the grace period. Once the command
+// has been waited for, its process group ID may already belong to another
+// group, so no SIGKILL is sent then.
+func killProcessGroup(cmd *exec.Cmd) error {
+	if cmd.Process == nil {
+		return nil
+	}
+
+	pgid := cmd.Process.Pid
+	if err := syscall.Kill(-pgid, syscall.SIGTERM); err != nil && err != syscall.ESRCH {
+		return err
+	}
+
+	time.AfterFunc(processKillGracePeriod, func() {
+		if !backupProcessRunning(cmd) {
+			return
+		}
+		if err := syscall.Kill(-pgid, syscall.SIGKILL); err == nil {
+			LogWarn("⚠️ [CANCEL] Process group %d did not exit after SIGTERM, sent SIGKILL", pgid)
+		}
+	})
+
+	return nil
+}
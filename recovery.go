@@ -0,0 +1,180 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// What happens to temp_* files left behind by backups that never finished
+const (
+	OrphanTempDelete     = "delete"     // Remove the partial file
+	OrphanTempQuarantine = "quarantine" // Move it to <backup_dir>/<db>/quarantine for inspection
+)
+
+// Directory inside a database's backup directory that holds quarantined temp files
+const quarantineDirName = "quarantine"
+
+// ReconcileInterruptedBackups cleans up after a crash or kill of the service.
+// Backups still marked running are set to interrupted, orphaned temp files are
+// removed or quarantined and, with resume_interrupted enabled, the databases of
+// interrupted jobs that did not finish are queued again. Must run before the
+// scheduler starts new jobs.
+func ReconcileInterruptedBackups(config *Config) {
+	jobIDs, databaseCount, err := MarkInterruptedBackups()
+	if err != nil {
+		LogError("❌ [RECOVERY] Failed to mark interrupted backups: %v", err)
+	} else if len(jobIDs) > 0 || databaseCount > 0 {
+		LogWarn("⚠️ [RECOVERY] Marked %d database backups in %d jobs as interrupted", databaseCount, len(jobIDs))
+	}
+
+	if count, err := cleanupOrphanTempFiles(config); err != nil {
+		LogError("❌ [RECOVERY] Failed to clean up orphaned temp files: %v", err)
+	} else if count > 0 {
+		LogWarn("⚠️ [RECOVERY] Cleaned up %d orphaned temp files (%s)", count, getOrphanTempAction(config))
+	}
+
+	if !config.Backup.ResumeInterrupted {
+		if len(jobIDs) > 0 {
+			LogInfo("ℹ️ [RECOVERY] Interrupted jobs can be resumed from the dashboard")
+		}
+		return
+	}
+
+	for _, jobID := range jobIDs {
+		newJobID, err := ResumeInterruptedBackup(jobID)
+		if err != nil {
+			LogWarn("⚠️ [RECOVERY] Not resuming job %s: %v", jobID, err)
+			continue
+		}
+		LogInfo("🔁 [RECOVERY] Interrupted job %s resumed as job %s", jobID, newJobID)
+	}
+}
+
+// getOrphanTempAction returns the configured orphan temp file action, defaulting to delete
+func getOrphanTempAction(config *Config) string {
+	if config.Backup.OrphanTempAction == OrphanTempQuarantine {
+		return OrphanTempQuarantine
+	}
+	return OrphanTempDelete
+}
+
+// cleanupOrphanTempFiles deletes or quarantines temp_* files in every database
+// backup directory. Only safe while no backup is running.
+func cleanupOrphanTempFiles(config *Config) (int, error) {
+	backupDir := config.Backup.BackupDir
+	entries, err := os.ReadDir(backupDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to read backup directory: %v", err)
+	}
+
+	action := getOrphanTempAction(config)
+	count := 0
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		databaseDir := filepath.Join(backupDir, entry.Name())
+		matches, err := filepath.Glob(filepath.Join(databaseDir, "temp_*"))
+		if err != nil {
+			LogWarn("⚠️ [RECOVERY] Failed to search temp files in %s: %v", databaseDir, err)
+			continue
+		}
+
+		for _, tempFilePath := range matches {
+			if action == OrphanTempQuarantine {
+				quarantineDir := filepath.Join(databaseDir, quarantineDirName)
+				if err := os.MkdirAll(quarantineDir, 0755); err != nil {
+					LogError("❌ [RECOVERY] Failed to create quarantine directory %s: %v", quarantineDir, err)
+					continue
+				}
+				quarantinePath := filepath.Join(quarantineDir, filepath.Base(tempFilePath))
+				if err := os.Rename(tempFilePath, quarantinePath); err != nil {
+					LogError("❌ [RECOVERY] Failed to quarantine %s: %v", tempFilePath, err)
+					continue
+				}
+				LogInfo("📦 [RECOVERY] Quarantined orphaned temp file %s", quarantinePath)
+			} else {
+				if err := os.Remove(tempFilePath); err != nil {
+					LogError("❌ [RECOVERY] Failed to remove %s: %v", tempFilePath, err)
+					continue
+				}
+				LogInfo("🧹 [RECOVERY] Removed orphaned temp file %s", tempFilePath)
+			}
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// ResumeInterruptedBackup queues a new job for the databases of an interrupted
+// job that have no successful backup, with the same type, mode and policy.
+// Returns the new job ID.
+func ResumeInterruptedBackup(jobID string) (string, error) {
+	summary, err := GetBackupSummaryByJobID(jobID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get backup summary: %v", err)
+	}
+	if summary == nil {
+		return "", fmt.Errorf("backup summary not found for job_id: %s", jobID)
+	}
+	if summary["state"].(string) != "interrupted" {
+		return "", fmt.Errorf("backup job %s was not interrupted", jobID)
+	}
+	if resumedJobID, _ := summary["resumed_job_id"].(string); resumedJobID != "" {
+		return "", fmt.Errorf("backup job %s was already resumed as job %s", jobID, resumedJobID)
+	}
+
+	jobType, databases, err := GetUnfinishedDatabases(jobID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get unfinished databases: %v", err)
+	}
+	if len(databases) == 0 {
+		return "", fmt.Errorf("all databases of job %s were backed up", jobID)
+	}
+
+	backupMode := summary["backup_mode"].(string)
+	policy, _ := summary["policy"].(string)
+	newJobID := GenerateJobID()
+
+	LogInfo("🔁 [RESUME] Resuming %s job %s for %d databases as job %s: %s",
+		jobType, jobID, len(databases), newJobID, formatDatabaseList(databases))
+
+	var success bool
+	var message string
+	if jobType == "incremental" {
+		response := StartIncBackup(BackupIncRequest{
+			JobID:       newJobID,
+			Databases:   databases,
+			BackupMode:  backupMode,
+			RequestedBy: "resume",
+			Policy:      policy,
+			OnConflict:  JobConflictWait,
+		})
+		success, message = response.Success, response.Message
+	} else {
+		response := StartFullBackup(BackupFullRequest{
+			JobID:       newJobID,
+			Databases:   databases,
+			BackupMode:  backupMode,
+			RequestedBy: "resume",
+			Policy:      policy,
+			OnConflict:  JobConflictWait,
+		})
+		success, message = response.Success, response.Message
+	}
+	if !success {
+		return "", fmt.Errorf("failed to start resumed job: %s", message)
+	}
+
+	if err := SetResumedJobID(jobID, newJobID); err != nil {
+		LogError("❌ [SQLITE-ERROR] Failed to record resumed job for %s: %v", jobID, err)
+	}
+
+	return newJobID, nil
+}
@@ -0,0 +1,404 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// retentionDecision tells whether a backup group (a full backup and its
+// incremental chain) is kept, and which rules keep or prune it
+type retentionDecision struct {
+	Group   map[string]interface{}
+	Keep    bool
+	Reasons []string
+}
+
+// gfsRule keeps the newest group of each of the last Count periods
+type gfsRule struct {
+	name   string
+	count  int
+	period func(t time.Time) string
+}
+
+// planRetention decides which backup groups of a database are kept. With GFS
+// retention enabled the GFS rules apply, otherwise groups whose full backup is
+// older than the database's retention period are pruned. The count and size
+// limits are applied on top of either.
+func planRetention(config *Config, databaseName string, groups []map[string]interface{}, gfs GFSRetention) []retentionDecision {
+	var decisions []retentionDecision
+	if gfs.Enabled {
+		decisions = selectGFSGroups(groups, gfs)
+	} else {
+		decisions = selectGroupsByAge(groups, getRetentionDaysForDatabase(config, databaseName))
+	}
+
+	applyRetentionLimits(decisions, config.Backup.KeepLastFulls, int64(config.Backup.MaxDatabaseBackupMB)*1024*1024)
+
+	return decisions
+}
+
+// selectGroupsByAge keeps groups whose full backup is newer than retentionDays
+func selectGroupsByAge(groups []map[string]interface{}, retentionDays int) []retentionDecision {
+	decisions := make([]retentionDecision, len(groups))
+	for i, group := range groups {
+		decisions[i] = retentionDecision{Group: group, Keep: true}
+
+		if retentionDays <= 0 {
+			decisions[i].Reasons = []string{"retention disabled"}
+			continue
+		}
+
+		cutoffDate := time.Now().AddDate(0, 0, -retentionDays)
+		if groupStartTime(group).Before(cutoffDate) {
+			decisions[i].Keep = false
+			decisions[i].Reasons = []string{fmt.Sprintf("older than %d days", retentionDays)}
+		} else {
+			decisions[i].Reasons = []string{fmt.Sprintf("within %d days", retentionDays)}
+		}
+	}
+
+	return decisions
+}
+
+// selectGFSGroups applies grandfather-father-son retention to backup groups.
+// For every rule the newest group of each period (day, ISO week, month, year)
+// is kept until the rule's count of periods is reached. The newest group is
+// always kept because new incremental backups are appended to it.
+func selectGFSGroups(groups []map[string]interface{}, gfs GFSRetention) []retentionDecision {
+	decisions := make([]retentionDecision, len(groups))
+	for i, group := range groups {
+		decisions[i] = retentionDecision{Group: group}
+	}
+
+	order := newestFirst(decisions)
+
+	rules := []gfsRule{
+		{name: "daily", count: gfs.Daily, period: func(t time.Time) string { return t.Format("2006-01-02") }},
+		{name: "weekly", count: gfs.Weekly, period: func(t time.Time) string {
+			year, week := t.ISOWeek()
+			return fmt.Sprintf("%d-W%02d", year, week)
+		}},
+		{name: "monthly", count: gfs.Monthly, period: func(t time.Time) string { return t.Format("2006-01") }},
+		{name: "yearly", count: gfs.Yearly, period: func(t time.Time) string { return t.Format("2006") }},
+	}
+
+	var slots []string
+	for _, rule := range rules {
+		if rule.count <= 0 {
+			continue
+		}
+		slots = append(slots, rule.name)
+
+		seen := make(map[string]bool)
+		for _, i := range order {
+			if len(seen) >= rule.count {
+				break
+			}
+
+			period := rule.period(groupStartTime(groups[i]))
+			if seen[period] {
+				continue
+			}
+			seen[period] = true
+
+			decisions[i].Keep = true
+			decisions[i].Reasons = append(decisions[i].Reasons, fmt.Sprintf("%s %s", rule.name, period))
+		}
+	}
+
+	if len(order) > 0 && !decisions[order[0]].Keep {
+		decisions[order[0]].Keep = true
+		decisions[order[0]].Reasons = append(decisions[order[0]].Reasons, "latest")
+	}
+
+	pruneReason := "outside GFS slots"
+	if len(slots) > 0 {
+		pruneReason = fmt.Sprintf("outside GFS %s slots", strings.Join(slots, "/"))
+	}
+	for i := range decisions {
+		if !decisions[i].Keep {
+			decisions[i].Reasons = []string{pruneReason}
+		}
+	}
+
+	return decisions
+}
+
+// applyRetentionLimits prunes kept groups beyond the newest keepLast groups and,
+// walking newest first, the groups that no longer fit into maxBytes. The newest
+// group is never pruned.
+func applyRetentionLimits(decisions []retentionDecision, keepLast int, maxBytes int64) {
+	if keepLast <= 0 && maxBytes <= 0 {
+		return
+	}
+
+	keptCount := 0
+	var keptBytes int64
+	for n, i := range newestFirst(decisions) {
+		if !decisions[i].Keep {
+			continue
+		}
+
+		size := groupSize(decisions[i].Group)
+		switch {
+		case n == 0:
+			// Newest group
+		case keepLast > 0 && keptCount >= keepLast:
+			pruneDecision(&decisions[i], fmt.Sprintf("beyond last %d fulls", keepLast))
+			continue
+		case maxBytes > 0 && keptBytes+size > maxBytes:
+			pruneDecision(&decisions[i], fmt.Sprintf("over database budget of %s", formatBytes(maxBytes)))
+			continue
+		}
+
+		keptCount++
+		keptBytes += size
+	}
+}
+
+// pruneOldestGroups prunes kept groups across all databases, oldest first, until
+// at least neededBytes are freed. The newest group of every database is never
+// pruned. Returns the number of bytes freed.
+func pruneOldestGroups(plans map[string][]retentionDecision, neededBytes int64, reason string) int64 {
+	type candidate struct {
+		decision *retentionDecision
+		time     time.Time
+	}
+
+	var candidates []candidate
+	for _, decisions := range plans {
+		order := newestFirst(decisions)
+		for n, i := range order {
+			if n == 0 || !decisions[i].Keep {
+				continue
+			}
+			candidates = append(candidates, candidate{&decisions[i], groupStartTime(decisions[i].Group)})
+		}
+	}
+
+	sort.Slice(candidates, func(a, b int) bool {
+		return candidates[a].time.Before(candidates[b].time)
+	})
+
+	var freed int64
+	for _, c := range candidates {
+		if freed >= neededBytes {
+			break
+		}
+		pruneDecision(c.decision, reason)
+		freed += groupSize(c.decision.Group)
+	}
+
+	return freed
+}
+
+// applyTotalSizeBudget prunes the oldest groups until all kept groups fit into maxBytes
+func applyTotalSizeBudget(plans map[string][]retentionDecision, maxBytes int64) {
+	if maxBytes <= 0 {
+		return
+	}
+
+	var total int64
+	for _, decisions := range plans {
+		for _, decision := range decisions {
+			if decision.Keep {
+				total += groupSize(decision.Group)
+			}
+		}
+	}
+
+	if total > maxBytes {
+		pruneOldestGroups(plans, total-maxBytes, fmt.Sprintf("over total budget of %s", formatBytes(maxBytes)))
+	}
+}
+
+// planAllRetention builds the retention decisions of every database in the backup
+// directory, including the total size budget
+func planAllRetention(config *Config, gfs GFSRetention) (map[string][]retentionDecision, error) {
+	backupDir := config.Backup.BackupDir
+	entries, err := os.ReadDir(backupDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup directory: %v", err)
+	}
+
+	plans := make(map[string][]retentionDecision)
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		databaseName := entry.Name()
+		allFiles, err := getAllBackupFiles(filepath.Join(backupDir, databaseName), databaseName)
+		if err != nil {
+			LogError("Failed to list backup files for %s: %v", databaseName, err)
+			continue
+		}
+		if len(allFiles) == 0 {
+			continue
+		}
+
+		// Group files by full/incremental relationships (same logic as GetDatabaseBackupFiles)
+		plans[databaseName] = planRetention(config, databaseName, groupBackupFiles(allFiles), gfs)
+	}
+
+	applyTotalSizeBudget(plans, int64(config.Backup.MaxTotalBackupMB)*1024*1024)
+
+	return plans, nil
+}
+
+// pruneDecision marks a group as pruned, dropping the reasons it was kept for
+func pruneDecision(decision *retentionDecision, reason string) {
+	decision.Keep = false
+	decision.Reasons = []string{reason}
+}
+
+// newestFirst returns the indexes of decisions ordered by group time, newest first
+func newestFirst(decisions []retentionDecision) []int {
+	order := make([]int, len(decisions))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return groupStartTime(decisions[order[a]].Group).After(groupStartTime(decisions[order[b]].Group))
+	})
+	return order
+}
+
+// groupStartTime returns the timestamp of a group's full backup
+func groupStartTime(group map[string]interface{}) time.Time {
+	if t, ok := group["group_start_time"].(time.Time); ok {
+		return t
+	}
+	return time.Time{}
+}
+
+// groupSize returns the size in bytes of a group's full and incremental backups
+func groupSize(group map[string]interface{}) int64 {
+	var size int64
+	if fullBackup, ok := group["full_backup"].(map[string]interface{}); ok {
+		size, _ = fullBackup["file_size"].(int64)
+	}
+	if incrementalBackups, ok := group["incremental_backups"].([]map[string]interface{}); ok {
+		for _, incBackup := range incrementalBackups {
+			incSize, _ := incBackup["file_size"].(int64)
+			size += incSize
+		}
+	}
+	return size
+}
+
+// formatBytes converts bytes to a human readable size
+func formatBytes(bytes int64) string {
+	return formatFileSize(int(bytes / 1024))
+}
+
+// deleteBackupGroups removes the files of every group that is not kept
+func deleteBackupGroups(databaseName string, decisions []retentionDecision) (int, []string) {
+	deletedCount := 0
+	var deletedFiles []string
+
+	for _, decision := range decisions {
+		if decision.Keep {
+			continue
+		}
+
+		group := decision.Group
+
+		// Delete full backup
+		fullBackup := group["full_backup"].(map[string]interface{})
+		fullPath := fullBackup["file_path"].(string)
+		if err := os.Remove(fullPath); err != nil {
+			LogError("Failed to delete full backup %s: %v", fullPath, err)
+		} else {
+			deletedCount++
+			deletedFiles = append(deletedFiles, fullPath)
+			LogDebug("Deleted full backup: %s", fullPath)
+		}
+
+		// Delete all incremental backups in this group
+		incrementalBackups := group["incremental_backups"].([]map[string]interface{})
+		for _, incBackup := range incrementalBackups {
+			incPath := incBackup["file_path"].(string)
+			if err := os.Remove(incPath); err != nil {
+				LogError("Failed to delete incremental backup %s: %v", incPath, err)
+			} else {
+				deletedCount++
+				deletedFiles = append(deletedFiles, incPath)
+				LogDebug("Deleted incremental backup: %s", incPath)
+			}
+		}
+
+		reason := "not retained"
+		if len(decision.Reasons) > 0 {
+			reason = strings.Join(decision.Reasons, ", ")
+		}
+		LogInfo("Deleted backup group for %s (1 full + %d incremental backups, %s)",
+			databaseName, len(incrementalBackups), reason)
+	}
+
+	return deletedCount, deletedFiles
+}
+
+// PreviewRetention returns, per database, which backup groups the retention
+// settings would keep or prune without deleting anything
+func PreviewRetention(config *Config, gfs GFSRetention, databaseFilter string) ([]map[string]interface{}, error) {
+	plans, err := planAllRetention(config, gfs)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(plans))
+	for name := range plans {
+		if databaseFilter == "" || name == databaseFilter {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	databases := []map[string]interface{}{}
+	for _, databaseName := range names {
+		groups := []map[string]interface{}{}
+		kept, pruned := 0, 0
+		var prunedSize int64
+		for _, decision := range plans[databaseName] {
+			fullBackup := decision.Group["full_backup"].(map[string]interface{})
+			incrementalBackups := decision.Group["incremental_backups"].([]map[string]interface{})
+			size := groupSize(decision.Group)
+
+			if decision.Keep {
+				kept++
+			} else {
+				pruned++
+				prunedSize += size
+			}
+
+			reasons := decision.Reasons
+			if reasons == nil {
+				reasons = []string{}
+			}
+
+			groups = append(groups, map[string]interface{}{
+				"full_backup":       fullBackup["file_name"],
+				"timestamp":         groupStartTime(decision.Group).Format("2006-01-02 15:04:05"),
+				"incremental_count": len(incrementalBackups),
+				"total_size":        size,
+				"keep":              decision.Keep,
+				"reasons":           reasons,
+			})
+		}
+
+		databases = append(databases, map[string]interface{}{
+			"database":    databaseName,
+			"groups":      groups,
+			"kept":        kept,
+			"pruned":      pruned,
+			"pruned_size": prunedSize,
+		})
+	}
+
+	return databases, nil
+}
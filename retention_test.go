@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// The SQLite database shared by the tests. InitDB starts queue workers that
+// stay running, so it is opened once per test binary.
+var testDBOnce sync.Once
+var testDBErr error
+
+// useTestWorkdir runs the test in a temporary working directory, so config.json
+// and deletion logs stay out of the tree, and opens the test database
+func useTestWorkdir(t *testing.T) string {
+	t.Helper()
+	testDBOnce.Do(func() {
+		var dbDir string
+		if dbDir, testDBErr = os.MkdirTemp("", "mbt-test"); testDBErr == nil {
+			testDBErr = InitDB(filepath.Join(dbDir, "app.db"))
+		}
+	})
+	if testDBErr != nil {
+		t.Fatal(testDBErr)
+	}
+
+	dir := t.TempDir()
+	previous, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(previous) })
+	return dir
+}
+
+// writeTestBackupGroups writes one full and one incremental backup of
+// databaseName per day, starting today at noon and going back days-1 days.
+// Returns the full backup file names, newest first.
+func writeTestBackupGroups(t *testing.T, backupDir, databaseName string, days int, size int) []string {
+	t.Helper()
+	databaseDir := filepath.Join(backupDir, databaseName)
+	if err := os.MkdirAll(databaseDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	today := time.Now()
+	noon := time.Date(today.Year(), today.Month(), today.Day(), 12, 0, 0, 0, time.Local)
+	var fulls []string
+	for day := 0; day < days; day++ {
+		start := noon.AddDate(0, 0, -day)
+		full := fmt.Sprintf("full_%s_%s.sql", databaseName, start.Format("20060102_150405"))
+		inc := fmt.Sprintf("inc_%s_%s.sql", databaseName, start.Add(time.Hour).Format("20060102_150405"))
+		for _, name := range []string{full, inc} {
+			if err := os.WriteFile(filepath.Join(databaseDir, name), make([]byte, size), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		fulls = append(fulls, full)
+	}
+	return fulls
+}
+
+// remainingBackups returns the backup file names left in a database directory
+func remainingBackups(t *testing.T, backupDir, databaseName string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(filepath.Join(backupDir, databaseName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names
+}
+
+func TestCleanupOldBackupsGFS(t *testing.T) {
+	dir := useTestWorkdir(t)
+	backupDir := filepath.Join(dir, "backups")
+	fulls := writeTestBackupGroups(t, backupDir, "shop", 4, 1024)
+
+	config := &Config{}
+	config.Backup.BackupDir = backupDir
+	config.Backup.GFSRetention = GFSRetention{Enabled: true, Daily: 2}
+
+	preview, err := PreviewRetention(config, config.Backup.GFSRetention, "shop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, group := range preview[0]["groups"].([]map[string]interface{}) {
+		if reasons := group["reasons"].([]string); len(reasons) == 0 {
+			t.Errorf("group %s has no reason", group["full_backup"])
+		}
+	}
+
+	if err := CleanupOldBackups(config); err != nil {
+		t.Fatalf("cleanup failed: %v", err)
+	}
+
+	remaining := remainingBackups(t, backupDir, "shop")
+	if len(remaining) != 4 {
+		t.Fatalf("remaining backups = %v, want the 2 newest groups", remaining)
+	}
+	for i, full := range fulls {
+		if kept := slices.Contains(remaining, full); kept != (i < 2) {
+			t.Errorf("%s kept = %v", full, kept)
+		}
+	}
+}
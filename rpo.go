@@ -0,0 +1,230 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RPO health of a database
+const (
+	RPOStatusOK          = "ok"       // Last successful backup is within the RPO
+	RPOStatusWarning     = "warning"  // More than rpoWarningRatio of the RPO has passed
+	RPOStatusBreached    = "breached" // Last successful backup is older than the RPO
+	RPOStatusNever       = "never"    // No successful backup at all
+	RPOStatusUnmonitored = "no_rpo"   // No RPO configured for the database
+)
+
+// Order of the health table, most urgent first
+var rpoStatusOrder = map[string]int{
+	RPOStatusBreached:    0,
+	RPOStatusNever:       1,
+	RPOStatusWarning:     2,
+	RPOStatusOK:          3,
+	RPOStatusUnmonitored: 4,
+}
+
+// Share of the RPO after which a database is shown as warning
+const rpoWarningRatio = 0.8
+
+// How often the RPO check runs
+const rpoCheckInterval = 15 * time.Minute
+
+// DatabaseHealth is the freshness of a database's last successful backup
+// measured against its RPO
+type DatabaseHealth struct {
+	Database        string `json:"database"`
+	Status          string `json:"status"`
+	RPOHours        int    `json:"rpo_hours"`
+	RPOSource       string `json:"rpo_source"` // database:<pattern>, policy:<name> or global
+	LastSuccess     string `json:"last_success"`
+	LastSuccessType string `json:"last_success_type"`
+	AgeSeconds      int64  `json:"age_seconds"`
+	LastStatus      string `json:"last_status"` // Status of the most recent attempt
+	LastError       string `json:"last_error"`
+}
+
+// getRPOForDatabase returns the RPO in hours of a database and where it comes
+// from. The strictest matching database_rpo pattern wins, then the strictest
+// RPO of the enabled policies backing the database up, then rpo_hours.
+func getRPOForDatabase(config *Config, dbName string) (int, string) {
+	hours, source := 0, ""
+	patterns := sortedKeys(config.Backup.DatabaseRPO, func(pattern string) string { return pattern })
+	for _, pattern := range patterns {
+		patternHours := config.Backup.DatabaseRPO[pattern]
+		if patternHours <= 0 || !matchDatabasePattern(pattern, dbName) {
+			continue
+		}
+		if hours == 0 || patternHours < hours {
+			hours, source = patternHours, "database:"+pattern
+		}
+	}
+	if hours > 0 {
+		return hours, source
+	}
+
+	for _, policy := range config.Policies {
+		if !policy.Enabled || policy.RPOHours <= 0 || !policySelectsDatabase(policy, dbName) {
+			continue
+		}
+		if hours == 0 || policy.RPOHours < hours {
+			hours, source = policy.RPOHours, "policy:"+policy.Name
+		}
+	}
+	if hours > 0 {
+		return hours, source
+	}
+
+	if config.Backup.RPOHours > 0 {
+		return config.Backup.RPOHours, "global"
+	}
+	return 0, ""
+}
+
+// GetBackupHealth computes the RPO health of every database backed up by a
+// policy. Databases come from MySQL, or from the backup history when MySQL
+// cannot be reached, so the check keeps working while the server is down.
+func GetBackupHealth(config *Config) ([]DatabaseHealth, error) {
+	freshness, err := GetDatabaseFreshness()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get backup freshness: %v", err)
+	}
+
+	history := make(map[string]map[string]interface{})
+	for _, entry := range freshness {
+		history[entry["database_name"].(string)] = entry
+	}
+
+	databases, err := listServerDatabases(config)
+	if err != nil {
+		LogWarn("⚠️ [RPO] Failed to list databases from MySQL, using backup history: %v", err)
+		databases = sortedKeys(history, func(name string) string { return name })
+	}
+
+	now := time.Now()
+	var health []DatabaseHealth
+	for _, databaseName := range databases {
+		if !isDatabaseSelectedByAnyPolicy(config, databaseName) {
+			continue
+		}
+
+		entry := DatabaseHealth{Database: databaseName}
+		entry.RPOHours, entry.RPOSource = getRPOForDatabase(config, databaseName)
+
+		var lastSuccess int64
+		if record, ok := history[databaseName]; ok {
+			lastSuccess = record["last_success"].(int64)
+			entry.LastStatus = record["last_status"].(string)
+			entry.LastError = record["last_error"].(string)
+			if lastSuccess > 0 {
+				completedAt := time.Unix(lastSuccess, 0)
+				entry.LastSuccess = completedAt.Format("2006-01-02 15:04:05")
+				entry.AgeSeconds = int64(now.Sub(completedAt).Seconds())
+				entry.LastSuccessType = "full"
+				if strings.HasSuffix(record["last_success_type"].(string), "inc") {
+					entry.LastSuccessType = "incremental"
+				}
+			}
+		}
+
+		rpo := time.Duration(entry.RPOHours) * time.Hour
+		age := time.Duration(entry.AgeSeconds) * time.Second
+		switch {
+		case entry.RPOHours <= 0:
+			entry.Status = RPOStatusUnmonitored
+		case lastSuccess == 0:
+			entry.Status = RPOStatusNever
+		case age > rpo:
+			entry.Status = RPOStatusBreached
+		case age.Seconds() >= rpo.Seconds()*rpoWarningRatio:
+			entry.Status = RPOStatusWarning
+		default:
+			entry.Status = RPOStatusOK
+		}
+
+		health = append(health, entry)
+	}
+
+	sort.SliceStable(health, func(a, b int) bool {
+		return rpoStatusOrder[health[a].Status] < rpoStatusOrder[health[b].Status]
+	})
+
+	return health, nil
+}
+
+// checkRPOBreaches raises a critical rpo_breached event for every database
+// whose last successful backup is older than its RPO, or that was never
+// backed up. The dedup window repeats the alert until a backup succeeds.
+func checkRPOBreaches(config *Config) {
+	health, err := GetBackupHealth(config)
+	if err != nil {
+		LogError("❌ [RPO] Failed to check backup freshness: %v", err)
+		return
+	}
+
+	for _, entry := range health {
+		if entry.Status != RPOStatusBreached && entry.Status != RPOStatusNever {
+			continue
+		}
+
+		fields := []notificationField{
+			{"Database", entry.Database},
+			{"RPO", fmt.Sprintf("%dh (%s)", entry.RPOHours, entry.RPOSource)},
+		}
+		message := fmt.Sprintf("%s has never been backed up successfully", entry.Database)
+		if entry.Status == RPOStatusBreached {
+			age := formatDurationForSlack(time.Duration(entry.AgeSeconds) * time.Second)
+			message = fmt.Sprintf("Last successful backup of %s finished %s ago", entry.Database, age)
+			fields = append(fields, notificationField{"Last Success", entry.LastSuccess}, notificationField{"Age", age})
+		}
+		if entry.LastStatus != "" && entry.LastStatus != "done" {
+			fields = append(fields, notificationField{"Last Attempt", entry.LastStatus})
+		}
+		if entry.LastError != "" {
+			fields = append(fields, notificationField{"Last Error", entry.LastError})
+		}
+
+		LogWarn("⚠️ [RPO] %s breached its RPO of %dh: %s", entry.Database, entry.RPOHours, message)
+		NotifyEvent(config, NotificationEvent{
+			Type:      EventRPOBreached,
+			Severity:  SeverityCritical,
+			Title:     fmt.Sprintf("RPO of %dh breached for %s", entry.RPOHours, entry.Database),
+			Message:   message,
+			Databases: []string{entry.Database},
+			Fields:    fields,
+		})
+	}
+}
+
+// validateDatabaseRPO checks the patterns and hours of database_rpo
+func validateDatabaseRPO(rpo map[string]int) error {
+	for _, pattern := range sortedKeys(rpo, func(pattern string) string { return pattern }) {
+		if err := validateDatabasePattern(pattern); err != nil {
+			return err
+		}
+		if rpo[pattern] <= 0 {
+			return fmt.Errorf("RPO of %s must be a positive number of hours", pattern)
+		}
+	}
+	return nil
+}
+
+// parseDatabaseRPO parses "pattern = hours" lines from a form field
+func parseDatabaseRPO(value string) (map[string]int, error) {
+	rpo := make(map[string]int)
+	for _, line := range parsePatternList(value) {
+		separator := strings.LastIndex(line, "=")
+		if separator <= 0 {
+			return nil, fmt.Errorf("invalid line %q, expected pattern = hours", line)
+		}
+		pattern := strings.TrimSpace(line[:separator])
+		hours, err := strconv.Atoi(strings.TrimSpace(line[separator+1:]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid hours in line %q", line)
+		}
+		rpo[pattern] = hours
+	}
+	return rpo, validateDatabaseRPO(rpo)
+}
@@ -5,8 +5,8 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
-	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -17,60 +17,46 @@ type Scheduler struct {
 	isRunning bool
 	lastRun   time.Time
 	nextRun   time.Time
+	schedules map[string]*policySchedule
+	mutex     sync.RWMutex
 }
 
 var backupScheduler *Scheduler
 
-// CalculateNextBackupTime calculates the next scheduled backup time based on start time and interval
-// This function is shared between scheduler and UI to ensure consistency
-func CalculateNextBackupTime(startTime string, intervalHours int) string {
-	now := time.Now()
-	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+// Ways to handle scheduled runs missed while the service was down
+const (
+	MissedRunOnce  = "run_once" // Start one catch-up backup immediately
+	MissedRunSkip  = "skip"     // Wait for the next scheduled run
+	MissedRunAlert = "alert"    // Notify without starting a backup
+)
 
-	// Parse start time (format: "HH:MM")
-	startTimeParts := strings.Split(startTime, ":")
-	if len(startTimeParts) != 2 {
-		return ""
-	}
+// maxMissedRunScan bounds how many missed runs are counted for a single policy
+const maxMissedRunScan = 100000
 
-	startHour, err := strconv.Atoi(startTimeParts[0])
-	if err != nil {
-		return ""
-	}
-	startMinute, err := strconv.Atoi(startTimeParts[1])
-	if err != nil {
-		return ""
+// CalculateNextBackupTimes returns the next count run times of a cron schedule
+// This function is shared between scheduler and UI to ensure consistency
+func CalculateNextBackupTimes(schedule string, count int) ([]time.Time, error) {
+	if strings.TrimSpace(schedule) == "" {
+		return nil, nil // Empty schedule means the scheduler is disabled
 	}
 
-	// Create start datetime for today
-	startDateTime := today.Add(time.Duration(startHour)*time.Hour + time.Duration(startMinute)*time.Minute)
-
-	// Calculate next run time
-	var nextRun time.Time
-
-	// Handle zero interval case (scheduler disabled)
-	if intervalHours == 0 {
-		return "" // Return empty string to indicate scheduler is disabled
+	cronSchedule, err := ParseCronSchedule(schedule)
+	if err != nil {
+		return nil, err
 	}
 
-	// If start time hasn't passed today, next run is today's start time
-	if startDateTime.After(now) {
-		nextRun = startDateTime
-	} else {
-		// Calculate how many intervals have passed since start time today
-		timeDiff := now.Sub(startDateTime)
-		intervalsPassed := int(timeDiff.Hours()) / intervalHours
-
-		// Next run is the next interval after the last completed one
-		nextRun = startDateTime.Add(time.Duration(intervalsPassed+1) * time.Duration(intervalHours) * time.Hour)
+	return cronSchedule.NextN(time.Now(), count), nil
+}
 
-		// If the next run is still in the past (shouldn't happen), add another interval
-		if nextRun.Before(now) || nextRun.Equal(now) {
-			nextRun = nextRun.Add(time.Duration(intervalHours) * time.Hour)
-		}
+// CalculateNextBackupTime calculates the next scheduled backup time for a cron schedule
+// Returns an empty string when the scheduler is disabled or the schedule is invalid
+func CalculateNextBackupTime(schedule string) string {
+	times, err := CalculateNextBackupTimes(schedule, 1)
+	if err != nil || len(times) == 0 {
+		return ""
 	}
 
-	return nextRun.Format("2006-01-02 15:04:05")
+	return times[0].Format("2006-01-02 15:04:05")
 }
 
 // formatDatabaseList formats a list of databases for log messages
@@ -89,6 +75,13 @@ func formatDatabaseList(databases []string) string {
 	return fmt.Sprintf("%s and %d more", strings.Join(databases[:showCount], " "), len(databases)-showCount)
 }
 
+// policySchedule tracks the run times of a single backup policy
+type policySchedule struct {
+	policy  BackupPolicy
+	lastRun time.Time
+	nextRun time.Time
+}
+
 // StartScheduler starts the backup scheduler
 func StartScheduler(config *Config) {
 	if backupScheduler != nil && backupScheduler.isRunning {
@@ -96,32 +89,42 @@ func StartScheduler(config *Config) {
 		return
 	}
 
-	// Check if scheduler should be disabled (interval = 0)
-	if config.Backup.BackupIntervalHours == 0 {
-		LogInfo("Scheduler disabled - backup_interval_hours is set to 0")
-		backupScheduler = &Scheduler{
-			config:    config,
-			stopChan:  make(chan bool),
-			isRunning: false,
-		}
-		return
-	}
-
 	backupScheduler = &Scheduler{
 		config:    config,
 		stopChan:  make(chan bool),
-		isRunning: true,
+		schedules: make(map[string]*policySchedule),
 	}
 
+	// Check if scheduler should be disabled (no policy has a schedule)
+	if countActivePolicies(config) == 0 {
+		LogInfo("Scheduler disabled - no backup policy has a schedule")
+		return
+	}
+
+	backupScheduler.isRunning = true
+
 	LogInfo("Starting backup scheduler...")
-	LogInfo("Schedule configuration - Start time: %s, Interval: %d hours, Mode: %s",
-		config.Backup.BackupStartTime,
-		config.Backup.BackupIntervalHours,
-		config.Backup.DefaultBackupMode)
+	for _, policy := range getBackupPolicies(config) {
+		if policy.Enabled && policy.Schedule != "" {
+			LogInfo("Schedule configuration - Policy: %s, Cron: %s, Mode: %s",
+				policy.Name, policy.Schedule, policy.Mode)
+		}
+	}
 
 	go backupScheduler.run()
 }
 
+// countActivePolicies returns the number of enabled policies with a schedule
+func countActivePolicies(config *Config) int {
+	count := 0
+	for _, policy := range getBackupPolicies(config) {
+		if policy.Enabled && policy.Schedule != "" {
+			count++
+		}
+	}
+	return count
+}
+
 // StopScheduler stops the backup scheduler
 func StopScheduler() {
 	if backupScheduler == nil || !backupScheduler.isRunning {
@@ -148,15 +151,35 @@ func GetSchedulerStatus() map[string]interface{} {
 		}
 	}
 
+	backupScheduler.mutex.RLock()
+	defer backupScheduler.mutex.RUnlock()
+
+	policies := []map[string]interface{}{}
+	for _, policy := range getBackupPolicies(backupScheduler.config) {
+		entry := map[string]interface{}{
+			"name":     policy.Name,
+			"enabled":  policy.Enabled,
+			"schedule": policy.Schedule,
+			"mode":     policy.Mode,
+		}
+		if state, ok := backupScheduler.schedules[policy.Name]; ok {
+			entry["last_run"] = state.lastRun
+			entry["next_run"] = state.nextRun
+		}
+		policies = append(policies, entry)
+	}
+
 	return map[string]interface{}{
 		"running":  backupScheduler.isRunning,
 		"last_run": backupScheduler.lastRun,
 		"next_run": backupScheduler.nextRun,
 		"config": map[string]interface{}{
-			"start_time":     backupScheduler.config.Backup.BackupStartTime,
-			"interval_hours": backupScheduler.config.Backup.BackupIntervalHours,
-			"default_mode":   backupScheduler.config.Backup.DefaultBackupMode,
+			"schedule":          backupScheduler.config.Backup.BackupSchedule,
+			"default_mode":      backupScheduler.config.Backup.DefaultBackupMode,
+			"active_policies":   countActivePolicies(backupScheduler.config),
+			"missed_run_policy": getMissedRunPolicy(backupScheduler.config),
 		},
+		"policies": policies,
 	}
 }
 
@@ -164,9 +187,13 @@ func GetSchedulerStatus() map[string]interface{} {
 func (s *Scheduler) run() {
 	LogInfo("Backup scheduler started successfully")
 
-	// Calculate initial next run time
-	s.nextRun = s.calculateNextRunTime()
-	LogInfo("Next scheduled backup: %s", s.nextRun.Format("2006-01-02 15:04:05"))
+	// Calculate initial next run times
+	s.mutex.Lock()
+	s.refreshSchedules()
+	s.mutex.Unlock()
+
+	// Handle runs that were due while the service was down
+	s.catchUpMissedRuns()
 
 	// Create a ticker that checks every minute
 	ticker := time.NewTicker(1 * time.Minute)
@@ -183,75 +210,252 @@ func (s *Scheduler) run() {
 	}
 }
 
-// checkAndTriggerBackup checks if it's time to run a backup and triggers it if needed
+// refreshSchedules rebuilds the per-policy schedule state from the current config,
+// keeping the last run time of policies that still exist. Caller must hold s.mutex.
+func (s *Scheduler) refreshSchedules() {
+	schedules := make(map[string]*policySchedule)
+
+	for _, policy := range getBackupPolicies(s.config) {
+		if !policy.Enabled || policy.Schedule == "" {
+			continue
+		}
+
+		state := &policySchedule{policy: policy}
+		if previous, ok := s.schedules[policy.Name]; ok {
+			state.lastRun = previous.lastRun
+		}
+		state.nextRun = calculatePolicyNextRun(policy)
+		schedules[policy.Name] = state
+
+		LogInfo("Next scheduled backup for policy %s: %s", policy.Name, state.nextRun.Format("2006-01-02 15:04:05"))
+	}
+
+	s.schedules = schedules
+	s.updateNextRun()
+}
+
+// updateNextRun sets nextRun to the earliest run time over all policies. Caller must hold s.mutex.
+func (s *Scheduler) updateNextRun() {
+	s.nextRun = time.Time{}
+	for _, state := range s.schedules {
+		if s.nextRun.IsZero() || state.nextRun.Before(s.nextRun) {
+			s.nextRun = state.nextRun
+		}
+	}
+}
+
+// checkAndTriggerBackup checks every policy and triggers the ones that are due
 func (s *Scheduler) checkAndTriggerBackup() {
 	now := time.Now()
 
-	// Check if it's time for the next backup
-	if now.After(s.nextRun) || now.Equal(s.nextRun) {
-		LogInfo("Scheduled backup time reached: %s", s.nextRun.Format("2006-01-02 15:04:05"))
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, state := range s.schedules {
+		// Check if it's time for the next backup of this policy
+		if now.Before(state.nextRun) {
+			continue
+		}
+
+		LogInfo("Scheduled backup time reached for policy %s: %s", state.policy.Name, state.nextRun.Format("2006-01-02 15:04:05"))
+
+		// Policies run concurrently
+		go s.triggerScheduledBackup(s.config, state.policy)
 
-		// Trigger the backup
-		s.triggerScheduledBackup()
+		// Remember the slot so a restart can detect runs missed after it
+		go saveScheduleRun(state.policy, state.nextRun)
 
 		// Update last run time
+		state.lastRun = now
 		s.lastRun = now
 
 		// Calculate next run time
-		if s.config.Backup.BackupIntervalHours == 0 {
-			// For zero interval, schedule next run in 1 minute
-			s.nextRun = now.Add(1 * time.Minute)
-		} else {
-			s.nextRun = s.calculateNextRunTime()
+		state.nextRun = calculatePolicyNextRun(state.policy)
+		LogInfo("Next scheduled backup for policy %s: %s", state.policy.Name, state.nextRun.Format("2006-01-02 15:04:05"))
+	}
+
+	s.updateNextRun()
+}
+
+// catchUpMissedRuns compares the last persisted run of every policy with its
+// schedule and handles the runs missed since then according to missed_run_policy
+func (s *Scheduler) catchUpMissedRuns() {
+	now := time.Now()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	action := getMissedRunPolicy(s.config)
+
+	for _, state := range s.schedules {
+		policy := state.policy
+
+		lastRun, err := GetScheduleLastRun(policy.Name)
+		if err != nil {
+			LogError("Failed to load last scheduled run for policy %s: %v", policy.Name, err)
+			continue
+		}
+
+		// Nothing can have been missed before a schedule was tracked
+		if lastRun.IsZero() {
+			saveScheduleRun(policy, now)
+			continue
+		}
+		state.lastRun = lastRun
+
+		missedCount, firstMissed, lastMissed, err := findMissedRuns(policy.Schedule, lastRun, now)
+		if err != nil {
+			LogError("Failed to check missed runs for policy %s: %v", policy.Name, err)
+			continue
+		}
+		if missedCount == 0 {
+			continue
+		}
+
+		LogWarn("Detected %d missed scheduled run(s) for policy %s (%s - %s)", missedCount, policy.Name,
+			firstMissed.Format("2006-01-02 15:04:05"), lastMissed.Format("2006-01-02 15:04:05"))
+
+		var message string
+		switch action {
+		case MissedRunSkip:
+			message = "Skipped, waiting for the next scheduled run"
+		case MissedRunAlert:
+			message = "Alert sent, no catch-up backup started"
+			policyConfig := applyBackupPolicy(s.config, policy.Name)
+			if len(GetNotifiers(policyConfig.Notification)) == 0 {
+				message = "Alert logged (no notification channel configured), no catch-up backup started"
+			}
+			NotifyEvent(policyConfig, NotificationEvent{
+				Type:     EventMissedRun,
+				Severity: SeverityWarning,
+				Title:    fmt.Sprintf("Missed scheduled backups for policy %s", policy.Name),
+				Message:  fmt.Sprintf("%d scheduled run(s) were missed while the backup service was not running. No catch-up backup was started.", missedCount),
+				Fields: []notificationField{
+					{"Schedule", policy.Schedule},
+					{"Missed Runs", fmt.Sprintf("%d", missedCount)},
+					{"First Missed", firstMissed.Format("2006-01-02 15:04:05")},
+					{"Last Missed", lastMissed.Format("2006-01-02 15:04:05")},
+				},
+			})
+		default:
+			message = "Catch-up backup started"
+			go s.triggerScheduledBackup(s.config, policy)
+			state.lastRun = now
+			s.lastRun = now
+		}
+
+		LogInfo("Missed runs of policy %s handled (%s): %s", policy.Name, action, message)
+
+		if err := RecordMissedRuns(policy.Name, policy.Schedule, missedCount, firstMissed, lastMissed, action, message); err != nil {
+			LogError("Failed to record missed runs for policy %s: %v", policy.Name, err)
+		}
+
+		saveScheduleRun(policy, lastMissed)
+	}
+}
+
+// findMissedRuns counts the scheduled runs after lastRun up to now and returns the first and last of them
+func findMissedRuns(schedule string, lastRun, now time.Time) (int, time.Time, time.Time, error) {
+	cronSchedule, err := ParseCronSchedule(schedule)
+	if err != nil {
+		return 0, time.Time{}, time.Time{}, err
+	}
+
+	var firstMissed, lastMissed time.Time
+	count := 0
+	for next := cronSchedule.Next(lastRun); !next.IsZero() && !next.After(now); next = cronSchedule.Next(next) {
+		if count == 0 {
+			firstMissed = next
+		}
+		lastMissed = next
+		count++
+		if count >= maxMissedRunScan {
+			break
 		}
-		LogInfo("Next scheduled backup: %s", s.nextRun.Format("2006-01-02 15:04:05"))
+	}
+
+	return count, firstMissed, lastMissed, nil
+}
+
+// getMissedRunPolicy returns the configured missed run handling, defaulting to run_once
+func getMissedRunPolicy(config *Config) string {
+	switch config.Backup.MissedRunPolicy {
+	case MissedRunSkip, MissedRunAlert:
+		return config.Backup.MissedRunPolicy
+	default:
+		return MissedRunOnce
 	}
 }
 
-// triggerScheduledBackup triggers a scheduled backup
-func (s *Scheduler) triggerScheduledBackup() {
-	LogInfo("Triggering scheduled backup...")
+// saveScheduleRun persists the last scheduled run of a policy
+func saveScheduleRun(policy BackupPolicy, scheduledAt time.Time) {
+	if err := SaveScheduleLastRun(policy.Name, policy.Schedule, scheduledAt); err != nil {
+		LogError("Failed to save last scheduled run for policy %s: %v", policy.Name, err)
+	}
+}
+
+// trackNewSchedules starts tracking policies that were added, enabled or given a new
+// schedule, so the time before the change is not reported as missed. Caller must hold s.mutex.
+func (s *Scheduler) trackNewSchedules(previous map[string]*policySchedule) {
+	now := time.Now()
+	for _, policy := range getBackupPolicies(s.config) {
+		if !policy.Enabled || policy.Schedule == "" {
+			continue
+		}
+		if state, ok := previous[policy.Name]; ok && state.policy.Schedule == policy.Schedule {
+			continue
+		}
+		saveScheduleRun(policy, now)
+	}
+}
+
+// triggerScheduledBackup triggers a scheduled backup for a policy. The caller
+// passes s.config while holding s.mutex, since a reload replaces it.
+func (s *Scheduler) triggerScheduledBackup(schedulerConfig *Config, policy BackupPolicy) {
+	LogInfo("Triggering scheduled backup for policy %s...", policy.Name)
 
 	// Reset global abort flag when starting scheduled backups
 	ResetGlobalBackupAbort()
 
-	// Get all databases (excluding ignored ones)
-	databases, err := getDatabases(s.config)
+	config := applyBackupPolicy(schedulerConfig, policy.Name)
+
+	// Get all databases selected by the policy
+	databases, err := getDatabases(config)
 	if err != nil {
-		LogError("Failed to get databases for scheduled backup: %v", err)
+		LogError("Failed to get databases for scheduled backup (policy %s): %v", policy.Name, err)
 		return
 	}
 
-	// Filter out ignored databases
+	// Filter databases with the policy's include/ignore patterns
 	validDatabases := []string{}
 	for _, db := range databases {
-		if !s.isDatabaseIgnored(db) {
+		if policySelectsDatabase(policy, db) {
 			validDatabases = append(validDatabases, db)
 		}
 	}
 
 	if len(validDatabases) == 0 {
-		LogWarn("No databases available for scheduled backup")
+		LogWarn("No databases available for scheduled backup (policy %s)", policy.Name)
 		return
 	}
 
 	dbList := formatDatabaseList(validDatabases)
-	LogInfo("Scheduled backup will process %d database: %s", len(validDatabases), dbList)
+	LogInfo("Scheduled backup (policy %s) will process %d database: %s", policy.Name, len(validDatabases), dbList)
 
 	// Generate job ID
 	jobID := GenerateJobID()
-	LogInfo("Scheduled backup job ID: %s", jobID)
+	LogInfo("Scheduled backup job ID: %s (policy %s)", jobID, policy.Name)
 
 	// Route to appropriate backup function based on mode
-	switch s.config.Backup.DefaultBackupMode {
+	switch policy.Mode {
 	case "full":
-		s.triggerFullBackup(jobID, validDatabases)
+		s.triggerFullBackup(jobID, validDatabases, policy.Name, config)
 	case "incremental":
-		s.triggerIncrementalBackup(jobID, validDatabases)
+		s.triggerIncrementalBackup(jobID, validDatabases, policy.Name, config)
 	case "auto":
-		s.triggerAutoBackup(jobID, validDatabases)
+		s.triggerAutoBackup(validDatabases, policy.Name, config)
 	default:
-		LogError("Unknown backup mode: %s", s.config.Backup.DefaultBackupMode)
+		LogError("Unknown backup mode for policy %s: %s", policy.Name, policy.Mode)
 	}
 
 	// Run backup cleanup after scheduled backup
@@ -259,14 +463,14 @@ func (s *Scheduler) triggerScheduledBackup() {
 		// Wait a bit to ensure backup is complete
 		time.Sleep(30 * time.Second)
 		LogInfo("Running scheduled backup cleanup...")
-		if err := CleanupOldBackups(s.config); err != nil {
+		if err := CleanupOldBackups(schedulerConfig); err != nil {
 			LogError("Scheduled backup cleanup failed: %v", err)
 		}
 	}()
 }
 
 // triggerFullBackup triggers a full backup
-func (s *Scheduler) triggerFullBackup(jobID string, databases []string) {
+func (s *Scheduler) triggerFullBackup(jobID string, databases []string, policyName string, config *Config) {
 	LogInfo("Starting scheduled full backup for %d databases", len(databases))
 
 	request := BackupFullRequest{
@@ -274,13 +478,15 @@ func (s *Scheduler) triggerFullBackup(jobID string, databases []string) {
 		Databases:   databases,
 		BackupMode:  "scheduled-full",
 		RequestedBy: "scheduler",
+		Policy:      policyName,
+		OnConflict:  getScheduleConflictPolicy(config),
 	}
 
 	go StartFullBackup(request)
 }
 
 // triggerIncrementalBackup triggers an incremental backup
-func (s *Scheduler) triggerIncrementalBackup(jobID string, databases []string) {
+func (s *Scheduler) triggerIncrementalBackup(jobID string, databases []string, policyName string, config *Config) {
 	LogInfo("Starting scheduled incremental backup for %d databases", len(databases))
 
 	request := BackupIncRequest{
@@ -288,13 +494,15 @@ func (s *Scheduler) triggerIncrementalBackup(jobID string, databases []string) {
 		Databases:   databases,
 		BackupMode:  "scheduled-inc",
 		RequestedBy: "scheduler",
+		Policy:      policyName,
+		OnConflict:  getScheduleConflictPolicy(config),
 	}
 
 	go StartIncBackup(request)
 }
 
 // triggerAutoBackup triggers an auto backup (determines full vs incremental)
-func (s *Scheduler) triggerAutoBackup(jobID string, databases []string) {
+func (s *Scheduler) triggerAutoBackup(databases []string, policyName string, config *Config) {
 	LogInfo("Starting scheduled auto backup for %d databases", len(databases))
 
 	// Analyze each database to determine backup type
@@ -302,7 +510,7 @@ func (s *Scheduler) triggerAutoBackup(jobID string, databases []string) {
 	incBackupDBs := []string{}
 
 	for _, dbName := range databases {
-		backupType := determineBackupType(dbName, s.config)
+		backupType := determineBackupType(dbName, config)
 		if backupType == "full" {
 			fullBackupDBs = append(fullBackupDBs, dbName)
 		} else {
@@ -321,6 +529,8 @@ func (s *Scheduler) triggerAutoBackup(jobID string, databases []string) {
 			Databases:   fullBackupDBs,
 			BackupMode:  "scheduled-auto-full",
 			RequestedBy: "scheduler",
+			Policy:      policyName,
+			OnConflict:  getScheduleConflictPolicy(config),
 		}
 
 		go StartFullBackup(fullRequest)
@@ -335,6 +545,8 @@ func (s *Scheduler) triggerAutoBackup(jobID string, databases []string) {
 			Databases:   incBackupDBs,
 			BackupMode:  "scheduled-auto-inc",
 			RequestedBy: "scheduler",
+			Policy:      policyName,
+			OnConflict:  getScheduleConflictPolicy(config),
 		}
 
 		go StartIncBackup(incRequest)
@@ -342,34 +554,21 @@ func (s *Scheduler) triggerAutoBackup(jobID string, databases []string) {
 	}
 }
 
-// calculateNextRunTime calculates the next scheduled backup time
-func (s *Scheduler) calculateNextRunTime() time.Time {
+// calculatePolicyNextRun calculates the next scheduled backup time of a policy
+func calculatePolicyNextRun(policy BackupPolicy) time.Time {
 	// Use the shared calculation function
-	nextRunStr := CalculateNextBackupTime(s.config.Backup.BackupStartTime, s.config.Backup.BackupIntervalHours)
-
-	// If scheduler is disabled (interval = 0), return a far future time
-	if nextRunStr == "" {
-		return time.Now().Add(24 * 365 * time.Hour) // 1 year from now
-	}
-
-	// Parse the result back to time.Time using local timezone
-	nextRun, err := time.ParseInLocation("2006-01-02 15:04:05", nextRunStr, time.Local)
+	times, err := CalculateNextBackupTimes(policy.Schedule, 1)
 	if err != nil {
-		LogError("Failed to parse next run time: %v", err)
-		return time.Now().Add(1 * time.Hour) // Fallback
+		LogError("Invalid schedule %q for policy %s: %v", policy.Schedule, policy.Name, err)
+		return time.Now().Add(24 * 365 * time.Hour) // Effectively disabled
 	}
 
-	return nextRun
-}
-
-// isDatabaseIgnored checks if a database should be ignored
-func (s *Scheduler) isDatabaseIgnored(dbName string) bool {
-	for _, ignoredDB := range s.config.Backup.IgnoreDbs {
-		if dbName == ignoredDB {
-			return true
-		}
+	// If the schedule never fires, return a far future time
+	if len(times) == 0 {
+		return time.Now().Add(24 * 365 * time.Hour) // 1 year from now
 	}
-	return false
+
+	return times[0]
 }
 
 // ReloadSchedulerConfig reloads the scheduler configuration
@@ -379,49 +578,47 @@ func ReloadSchedulerConfig(config *Config) {
 	}
 
 	LogInfo("Reloading scheduler configuration...")
+	backupScheduler.mutex.Lock()
 	backupScheduler.config = config
+	backupScheduler.trackNewSchedules(backupScheduler.schedules)
 
-	// Recalculate next run time with new config
-	backupScheduler.nextRun = backupScheduler.calculateNextRunTime()
+	// Start the scheduler loop if it was disabled and now has a schedule
+	if !backupScheduler.isRunning && countActivePolicies(config) > 0 {
+		backupScheduler.isRunning = true
+		backupScheduler.mutex.Unlock()
+		go backupScheduler.run()
+		return
+	}
+
+	// Recalculate next run times with new config
+	backupScheduler.refreshSchedules()
+	backupScheduler.mutex.Unlock()
 	LogInfo("Scheduler configuration reloaded. Next backup: %s",
 		backupScheduler.nextRun.Format("2006-01-02 15:04:05"))
 }
 
-// CleanupOldBackups removes backup files older than the retention period
+// CleanupOldBackups removes backup groups that are no longer retained. With GFS
+// retention enabled the GFS rules decide; otherwise each database uses the longest
+// retention of the policies that back it up. Count and size budgets apply on top.
 func CleanupOldBackups(config *Config) error {
-	retentionDays := config.Backup.RetentionBackups
-	if retentionDays <= 0 {
-		LogInfo("Backup retention disabled (retention_days = %d)", retentionDays)
-		return nil // No cleanup needed
+	gfs := config.Backup.GFSRetention
+	if gfs.Enabled {
+		LogInfo("Starting backup cleanup - GFS retention: %d daily, %d weekly, %d monthly, %d yearly",
+			gfs.Daily, gfs.Weekly, gfs.Monthly, gfs.Yearly)
+	} else {
+		LogInfo("Starting backup cleanup - default retention period: %d days", config.Backup.RetentionBackups)
 	}
 
-	LogInfo("Starting backup cleanup - retention period: %d days", retentionDays)
-	cutoffDate := time.Now().AddDate(0, 0, -retentionDays)
-
-	// Get all databases from backup directory
-	backupDir := config.Backup.BackupDir
-	entries, err := os.ReadDir(backupDir)
+	plans, err := planAllRetention(config, gfs)
 	if err != nil {
-		return fmt.Errorf("failed to read backup directory: %v", err)
+		return err
 	}
 
 	var allDeletedFiles []string
 	totalDeletedFiles := 0
 
-	for _, entry := range entries {
-		if !entry.IsDir() {
-			continue
-		}
-
-		databaseName := entry.Name()
-		databaseDir := filepath.Join(backupDir, databaseName)
-
-		// Cleanup backups for this database
-		deletedCount, deletedFiles, err := cleanupDatabaseBackups(databaseName, databaseDir, cutoffDate)
-		if err != nil {
-			LogError("Failed to cleanup backups for database %s: %v", databaseName, err)
-			continue
-		}
+	for databaseName, decisions := range plans {
+		deletedCount, deletedFiles := deleteBackupGroups(databaseName, decisions)
 
 		totalDeletedFiles += deletedCount
 		allDeletedFiles = append(allDeletedFiles, deletedFiles...)
@@ -431,72 +628,17 @@ func CleanupOldBackups(config *Config) error {
 	}
 
 	if totalDeletedFiles > 0 {
-		LogInfo("Backup cleanup completed - removed %d files older than %d days", totalDeletedFiles, retentionDays)
+		LogInfo("Backup cleanup completed - removed %d files past their retention", totalDeletedFiles)
 		// Create deletion log
 		createDeletionLog(allDeletedFiles, "retention_cleanup")
+		recordAuditEntry(nil, AuditEntry{Action: AuditBackupDelete, Target: "retention", Before: auditValue(allDeletedFiles), Details: "retention_cleanup"})
 	} else {
-		LogInfo("No backup files found older than %d days", retentionDays)
+		LogInfo("No backup files found past their retention")
 	}
 
 	return nil
 }
 
-// cleanupDatabaseBackups cleans up old backup files for a specific database
-func cleanupDatabaseBackups(databaseName, databaseDir string, cutoffDate time.Time) (int, []string, error) {
-	// Get all backup files for this database
-	allFiles, err := getAllBackupFiles(databaseDir, databaseName)
-	if err != nil {
-		return 0, nil, err
-	}
-
-	if len(allFiles) == 0 {
-		return 0, nil, nil
-	}
-
-	// Group files by full/incremental relationships (same logic as GetDatabaseBackupFiles)
-	groups := groupBackupFiles(allFiles)
-
-	deletedCount := 0
-	var deletedFiles []string
-
-	// Check each group and delete if the full backup is older than cutoff
-	for _, group := range groups {
-		fullBackup := group["full_backup"].(map[string]interface{})
-		fullBackupTime := fullBackup["timestamp"].(time.Time)
-
-		// If the full backup is older than cutoff, delete the entire group
-		if fullBackupTime.Before(cutoffDate) {
-			// Delete full backup
-			fullPath := fullBackup["file_path"].(string)
-			if err := os.Remove(fullPath); err != nil {
-				LogError("Failed to delete full backup %s: %v", fullPath, err)
-			} else {
-				deletedCount++
-				deletedFiles = append(deletedFiles, fullPath)
-				LogDebug("Deleted full backup: %s", fullPath)
-			}
-
-			// Delete all incremental backups in this group
-			incrementalBackups := group["incremental_backups"].([]map[string]interface{})
-			for _, incBackup := range incrementalBackups {
-				incPath := incBackup["file_path"].(string)
-				if err := os.Remove(incPath); err != nil {
-					LogError("Failed to delete incremental backup %s: %v", incPath, err)
-				} else {
-					deletedCount++
-					deletedFiles = append(deletedFiles, incPath)
-					LogDebug("Deleted incremental backup: %s", incPath)
-				}
-			}
-
-			LogInfo("Deleted backup group for %s (1 full + %d incremental backups)",
-				databaseName, len(incrementalBackups))
-		}
-	}
-
-	return deletedCount, deletedFiles, nil
-}
-
 // getAllBackupFiles gets all backup files for a database directory
 func getAllBackupFiles(databaseDir, databaseName string) ([]map[string]interface{}, error) {
 	var allFiles []map[string]interface{}
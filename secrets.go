@@ -0,0 +1,547 @@
+package main
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Secret settings may hold a reference instead of the secret itself
+const (
+	secretRefEnv       = "env:"        // env:NAME reads an environment variable
+	secretRefFile      = "file:"       // file:/path reads a file, without the trailing newline
+	secretRefMyCnf     = "mycnf:"      // mycnf: or mycnf:/path reads password from the [client] section of ~/.my.cnf
+	secretRefLoginPath = "login-path:" // login-path:name reads password from ~/.mylogin.cnf (mysql_config_editor)
+	secretRefEncrypted = "enc:v1:"     // Encrypted with the machine key
+)
+
+const (
+	secretMask        = "********" // Shown in the web interface instead of a stored secret
+	secretKeyEnv      = "MBT_SECRET_KEY"
+	secretKeyFileName = "secret.key" // Machine key, next to the config file
+)
+
+// secretRef is a secret setting as written in the config file and its
+// resolved value
+type secretRef struct {
+	Raw   string
+	Value string
+}
+
+// configSecrets returns the secret settings of a config by field path
+func configSecrets(config *Config) map[string]*string {
+	secrets := map[string]*string{
+		"database.password":                &config.Database.Password,
+		"web.oidc.client_secret":           &config.Web.OIDC.ClientSecret,
+		"web.ldap.bind_password":           &config.Web.LDAP.BindPassword,
+		"notification.slack_webhook_url":   &config.Notification.SlackWebhookURL,
+		"notification.webhook.url":         &config.Notification.Webhook.URL,
+		"notification.email.password":      &config.Notification.Email.Password,
+		"notification.teams.webhook_url":   &config.Notification.Teams.WebhookURL,
+		"notification.discord.webhook_url": &config.Notification.Discord.WebhookURL,
+		"notification.telegram.bot_token":  &config.Notification.Telegram.BotToken,
+	}
+	for i := range config.Policies {
+		secrets[fmt.Sprintf("policies[%s].slack_webhook_url", config.Policies[i].Name)] = &config.Policies[i].SlackWebhookURL
+	}
+	return secrets
+}
+
+// isSecretRef reports whether a setting is a reference to be resolved
+func isSecretRef(value string) bool {
+	for _, prefix := range []string{secretRefEnv, secretRefFile, secretRefMyCnf, secretRefLoginPath, secretRefEncrypted} {
+		if strings.HasPrefix(value, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveSecret returns the secret a reference points to
+func resolveSecret(value, configFile string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, secretRefEnv):
+		name := strings.TrimPrefix(value, secretRefEnv)
+		secret, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return secret, nil
+
+	case strings.HasPrefix(value, secretRefFile):
+		data, err := os.ReadFile(strings.TrimPrefix(value, secretRefFile))
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+
+	case strings.HasPrefix(value, secretRefMyCnf):
+		path := strings.TrimPrefix(value, secretRefMyCnf)
+		if path == "" {
+			home, err := homeDir()
+			if err != nil {
+				return "", err
+			}
+			path = filepath.Join(home, ".my.cnf")
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		return optionFilePassword(parseOptionFile(data), path, "client", "client-server", "client-mariadb", "mariadb-client")
+
+	case strings.HasPrefix(value, secretRefLoginPath):
+		name := strings.TrimPrefix(value, secretRefLoginPath)
+		if name == "" {
+			name = "client"
+		}
+		path := os.Getenv("MYSQL_TEST_LOGIN_FILE")
+		if path == "" {
+			home, err := homeDir()
+			if err != nil {
+				return "", err
+			}
+			path = filepath.Join(home, ".mylogin.cnf")
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		plain, err := decodeLoginPathFile(data)
+		if err != nil {
+			return "", fmt.Errorf("%s: %v", path, err)
+		}
+		return optionFilePassword(parseOptionFile(plain), path, name)
+
+	case strings.HasPrefix(value, secretRefEncrypted):
+		return decryptSecret(value, configFile)
+	}
+	return value, nil
+}
+
+// resolveConfigSecrets replaces the secret references of a config with their
+// values and remembers the references, so saving the config writes them back
+// unchanged. A reference that cannot be resolved leaves the setting empty.
+func resolveConfigSecrets(config *Config, configFile string) error {
+	if config.secretRefs == nil {
+		config.secretRefs = make(map[string]secretRef)
+	}
+
+	var errs []error
+	for path, field := range configSecrets(config) {
+		if !isSecretRef(*field) {
+			continue
+		}
+		raw := *field
+		value, err := resolveSecret(raw, configFile)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", path, err))
+		}
+		config.secretRefs[path] = secretRef{Raw: raw, Value: value}
+		*field = value
+	}
+	return errors.Join(errs...)
+}
+
+// storedConfigSecrets returns a copy of a config as it is written to disk:
+// unchanged secrets keep their reference, new ones are encrypted when
+// encrypt_at_rest is on
+func storedConfigSecrets(config *Config, configFile string) (*Config, error) {
+	stored := *config
+	stored.Policies = append([]BackupPolicy(nil), config.Policies...)
+
+	for path, field := range configSecrets(&stored) {
+		value := *field
+		if ref, ok := config.secretRefs[path]; ok && ref.Value == value {
+			// Encrypted values are decrypted again once encryption is turned off
+			if !strings.HasPrefix(ref.Raw, secretRefEncrypted) || config.Secrets.EncryptAtRest {
+				*field = ref.Raw
+				continue
+			}
+		}
+		if value == "" || isSecretRef(value) || !config.Secrets.EncryptAtRest {
+			continue
+		}
+		encrypted, err := encryptSecret(value, configFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt %s: %v", path, err)
+		}
+		*field = encrypted
+	}
+	return &stored, nil
+}
+
+// rememberStoredSecrets records the references just written for a config
+// and resolves references that were entered as settings, so the config in
+// memory holds the secrets and saves back to the same file content
+func rememberStoredSecrets(config, stored *Config, configFile string) {
+	storedFields := configSecrets(stored)
+	refs := make(map[string]secretRef)
+	for path, field := range configSecrets(config) {
+		raw := *storedFields[path]
+		if !isSecretRef(raw) {
+			continue
+		}
+		value := *field
+		if value == raw {
+			var err error
+			if value, err = resolveSecret(raw, configFile); err != nil {
+				LogWarn("🔐 [SECRETS] Failed to resolve %s: %v", path, err)
+			}
+			*field = value
+		}
+		refs[path] = secretRef{Raw: raw, Value: value}
+	}
+	config.secretRefs = refs
+}
+
+// redactedConfig returns a copy of a config that is safe to send to the
+// browser. Stored secrets and hashes become secretMask, references other
+// than encrypted values are shown as written.
+func redactedConfig(config *Config) *Config {
+	redacted := *config
+	redacted.Policies = append([]BackupPolicy(nil), config.Policies...)
+
+	fields := configSecrets(&redacted)
+	fields["web.auth_pass_hash"] = &redacted.Web.AuthPassHash
+	fields["web.metrics_token_hash"] = &redacted.Web.MetricsTokenHash
+	for path, field := range fields {
+		ref, isRef := config.secretRefs[path]
+		switch {
+		case isRef && strings.HasPrefix(ref.Raw, secretRefEncrypted):
+			*field = secretMask
+		case isRef:
+			*field = ref.Raw
+		case *field != "":
+			*field = secretMask
+		}
+	}
+	return &redacted
+}
+
+// formSecret reads a secret input of the settings form. The page shows stored
+// secrets as secretMask, which keeps the stored value.
+func formSecret(r *http.Request, name, stored string) string {
+	value := r.FormValue(name)
+	if value == secretMask {
+		return stored
+	}
+	return value
+}
+
+// keepPolicySecrets restores the masked Slack webhooks of policies sent by
+// the settings form from the stored policies with the same name
+func keepPolicySecrets(policies, stored []BackupPolicy) {
+	for i := range policies {
+		if policies[i].SlackWebhookURL != secretMask {
+			continue
+		}
+		policies[i].SlackWebhookURL = ""
+		for _, existing := range stored {
+			if existing.Name == policies[i].Name {
+				policies[i].SlackWebhookURL = existing.SlackWebhookURL
+				break
+			}
+		}
+	}
+}
+
+// homeDir returns the home directory of the service user. Services started
+// by systemd may have no $HOME.
+func homeDir() (string, error) {
+	if home, err := os.UserHomeDir(); err == nil {
+		return home, nil
+	}
+	current, err := user.Current()
+	if err != nil {
+		return "", fmt.Errorf("cannot find the home directory: %v", err)
+	}
+	return current.HomeDir, nil
+}
+
+// parseOptionFile parses a MariaDB option file into lower-case sections and
+// options. Dashes and underscores in option names are the same.
+func parseOptionFile(data []byte) map[string]map[string]string {
+	sections := make(map[string]map[string]string)
+	section := ""
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' || line[0] == ';' || line[0] == '!' {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.ToLower(strings.TrimSpace(line[1 : len(line)-1]))
+			continue
+		}
+
+		name, value, _ := strings.Cut(line, "=")
+		name = strings.ReplaceAll(strings.ToLower(strings.TrimSpace(name)), "_", "-")
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && strings.LastIndexByte(value, value[0]) > 0 {
+			value = unescapeOptionValue(value[1:strings.LastIndexByte(value, value[0])])
+		} else if comment := strings.IndexByte(value, '#'); comment >= 0 {
+			value = strings.TrimSpace(value[:comment])
+		}
+
+		if sections[section] == nil {
+			sections[section] = make(map[string]string)
+		}
+		sections[section][name] = value
+	}
+	return sections
+}
+
+// unescapeOptionValue resolves the escape sequences of a quoted option value
+func unescapeOptionValue(value string) string {
+	var result strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' || i+1 == len(value) {
+			result.WriteByte(value[i])
+			continue
+		}
+		i++
+		switch value[i] {
+		case 'n':
+			result.WriteByte('\n')
+		case 't':
+			result.WriteByte('\t')
+		case 'r':
+			result.WriteByte('\r')
+		case 'b':
+			result.WriteByte('\b')
+		case 's':
+			result.WriteByte(' ')
+		default:
+			result.WriteByte(value[i])
+		}
+	}
+	return result.String()
+}
+
+// optionFilePassword returns the password option of the last of the sections
+// that sets it
+func optionFilePassword(sections map[string]map[string]string, path string, names ...string) (string, error) {
+	password, found := "", false
+	for _, name := range names {
+		if value, ok := sections[name]["password"]; ok {
+			password, found = value, true
+		}
+	}
+	if !found {
+		return "", fmt.Errorf("no password in [%s] of %s", strings.Join(names, "], ["), path)
+	}
+	return password, nil
+}
+
+// decodeLoginPathFile decrypts a .mylogin.cnf written by mysql_config_editor:
+// 4 unused bytes, a 20 byte key folded into an AES-128 key, then AES-ECB
+// encrypted lines, each preceded by its length
+func decodeLoginPathFile(data []byte) ([]byte, error) {
+	if len(data) < 24 {
+		return nil, fmt.Errorf("file is too short")
+	}
+	key := make([]byte, 16)
+	for i, b := range data[4:24] {
+		key[i%16] ^= b
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	var plain bytes.Buffer
+	for rest := data[24:]; len(rest) > 0; {
+		if len(rest) < 4 {
+			return nil, fmt.Errorf("truncated line length")
+		}
+		length := int(binary.LittleEndian.Uint32(rest))
+		rest = rest[4:]
+		if length == 0 || length > len(rest) || length%aes.BlockSize != 0 {
+			return nil, fmt.Errorf("invalid line length %d", length)
+		}
+		line := make([]byte, length)
+		for offset := 0; offset < length; offset += aes.BlockSize {
+			block.Decrypt(line[offset:], rest[offset:offset+aes.BlockSize])
+		}
+		rest = rest[length:]
+
+		padding := int(line[length-1])
+		if padding == 0 || padding > aes.BlockSize {
+			return nil, fmt.Errorf("invalid padding")
+		}
+		plain.Write(line[:length-padding])
+	}
+	return plain.Bytes(), nil
+}
+
+var secretKeys = struct {
+	sync.Mutex
+	byPath map[string][]byte
+}{byPath: make(map[string][]byte)}
+
+// loadSecretKey returns the 256-bit machine key. It comes from
+// MBT_SECRET_KEY, or from secret.key next to the config file, which is
+// created on first use when create is set.
+func loadSecretKey(configFile string, create bool) ([]byte, error) {
+	if encoded := os.Getenv(secretKeyEnv); encoded != "" {
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil || len(key) != 32 {
+			return nil, fmt.Errorf("%s must be 32 bytes in base64", secretKeyEnv)
+		}
+		return key, nil
+	}
+
+	path := filepath.Join(filepath.Dir(configFile), secretKeyFileName)
+	secretKeys.Lock()
+	defer secretKeys.Unlock()
+	if key, ok := secretKeys.byPath[path]; ok {
+		return key, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err == nil {
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+		if err != nil || len(key) != 32 {
+			return nil, fmt.Errorf("%s does not hold a 32 byte key in base64", path)
+		}
+		secretKeys.byPath[path] = key
+		return key, nil
+	}
+	if !os.IsNotExist(err) || !create {
+		return nil, fmt.Errorf("failed to read machine key: %v", err)
+	}
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create machine key: %v", err)
+	}
+	_, err = file.WriteString(base64.StdEncoding.EncodeToString(key) + "\n")
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to write machine key: %v", err)
+	}
+	LogInfo("🔐 [SECRETS] Created machine key %s, back it up together with the config", path)
+	secretKeys.byPath[path] = key
+	return key, nil
+}
+
+// encryptSecret encrypts a secret with AES-256-GCM and the machine key
+func encryptSecret(value, configFile string) (string, error) {
+	key, err := loadSecretKey(configFile, true)
+	if err != nil {
+		return "", err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(value), nil)
+	return secretRefEncrypted + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// decryptSecret decrypts a value written by encryptSecret
+func decryptSecret(value, configFile string) (string, error) {
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, secretRefEncrypted))
+	if err != nil {
+		return "", fmt.Errorf("invalid encrypted value: %v", err)
+	}
+	key, err := loadSecretKey(configFile, false)
+	if err != nil {
+		return "", err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", fmt.Errorf("invalid encrypted value")
+	}
+	plain, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("cannot decrypt, the machine key does not match")
+	}
+	return string(plain), nil
+}
+
+var clientOptionFiles = struct {
+	sync.Mutex
+	dir      string
+	password string
+	path     string
+	count    int
+}{}
+
+// clientOptionFile returns an option file that gives the database password
+// to mariadb-dump, mariadb-check and mariadb-binlog, so it never shows up in
+// process lists the way -p<password> does. The file lives in a private
+// directory that is removed on shutdown.
+func clientOptionFile(password string) (string, error) {
+	files := &clientOptionFiles
+	files.Lock()
+	defer files.Unlock()
+
+	if files.path != "" && files.password == password {
+		if _, err := os.Stat(files.path); err == nil {
+			return files.path, nil
+		}
+	}
+	if _, err := os.Stat(files.dir); files.dir == "" || err != nil {
+		dir, err := os.MkdirTemp("", "mariadb-backup-tool-")
+		if err != nil {
+			return "", err
+		}
+		files.dir, files.path = dir, ""
+	}
+
+	// A new file for a new password. The old one stays until shutdown, since a
+	// tool that was started but has not opened it yet still needs it.
+	files.count++
+	path := filepath.Join(files.dir, fmt.Sprintf("client-%d.cnf", files.count))
+	escaped := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`).Replace(password)
+	if err := os.WriteFile(path, []byte("[client]\npassword=\""+escaped+"\"\n"), 0600); err != nil {
+		return "", err
+	}
+	files.password, files.path = password, path
+	return path, nil
+}
+
+// removeClientOptionFiles deletes the option files written for the client tools
+func removeClientOptionFiles() {
+	files := &clientOptionFiles
+	files.Lock()
+	defer files.Unlock()
+
+	if files.dir != "" {
+		os.RemoveAll(files.dir)
+		files.dir, files.password, files.path = "", "", ""
+	}
+}
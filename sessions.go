@@ -0,0 +1,345 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	sessionCookieName = "session_id"
+	csrfCookieName    = "csrf_token"   // Readable by the page scripts, which echo it back
+	csrfHeaderName    = "X-CSRF-Token" // Sent by fetch calls, plain forms post a csrf_token field
+)
+
+var (
+	sessionTimeout        = 24 * time.Hour     // Idle time after which a session ends
+	sessionMaxLifetime    = 7 * 24 * time.Hour // Absolute session lifetime, even when active
+	sessionRotateInterval = 15 * time.Minute   // How often a session gets a new ID
+	sessionRotateGrace    = time.Minute        // How long a replaced ID stays valid for requests in flight
+	sessionTouchInterval  = time.Minute        // Minimum time between last-seen updates
+)
+
+// WebSession is a logged in browser session stored in the web_sessions
+// table. Only the SHA-256 hash of the session ID is stored.
+type WebSession struct {
+	IDHash       string
+	Username     string
+	CSRFToken    string
+	CreatedAt    time.Time
+	LastSeenAt   time.Time
+	RotatedAt    time.Time
+	SupersededAt time.Time // Set when the session was rotated to a new ID
+	ClientIP     string
+	UserAgent    string
+}
+
+// isExpired reports whether the session can no longer be used
+func (s *WebSession) isExpired(now time.Time) bool {
+	if !s.SupersededAt.IsZero() && now.Sub(s.SupersededAt) > sessionRotateGrace {
+		return true
+	}
+	return now.Sub(s.LastSeenAt) > sessionTimeout || now.Sub(s.CreatedAt) > sessionMaxLifetime
+}
+
+// generateSecureToken returns n random bytes, hex encoded
+func generateSecureToken(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// isSecureRequest reports whether the browser reached us over HTTPS, directly
+// or through a TLS terminating proxy
+func isSecureRequest(r *http.Request) bool {
+	return r.TLS != nil || strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https")
+}
+
+// setSessionCookies sends the session and CSRF cookies. maxAge -1 clears them.
+func setSessionCookies(w http.ResponseWriter, r *http.Request, sessionID, csrfToken string, maxAge int) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    sessionID,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   isSecureRequest(r),
+		SameSite: http.SameSiteLaxMode, // Lax so links from notifications still open the dashboard
+		MaxAge:   maxAge,
+	})
+	http.SetCookie(w, &http.Cookie{
+		Name:     csrfCookieName,
+		Value:    csrfToken,
+		Path:     "/",
+		Secure:   isSecureRequest(r),
+		SameSite: http.SameSiteStrictMode,
+		MaxAge:   maxAge,
+	})
+}
+
+// startSession creates a session for the user and sets its cookies. Any
+// session presented with the login request is discarded first, so an ID
+// planted before login is never promoted.
+func startSession(w http.ResponseWriter, r *http.Request, username string) error {
+	if cookie, err := r.Cookie(sessionCookieName); err == nil {
+		DeleteWebSession(hashToken(cookie.Value))
+	}
+
+	sessionID, err := generateSecureToken(32)
+	if err != nil {
+		return err
+	}
+	csrfToken, err := generateSecureToken(32)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	session := &WebSession{
+		IDHash:     hashToken(sessionID),
+		Username:   username,
+		CSRFToken:  csrfToken,
+		CreatedAt:  now,
+		LastSeenAt: now,
+		RotatedAt:  now,
+		ClientIP:   clientIP(r),
+		UserAgent:  r.UserAgent(),
+	}
+	if err := CreateWebSession(session); err != nil {
+		return err
+	}
+
+	if err := PurgeExpiredWebSessions(now); err != nil {
+		LogWarn("⚠️ [AUTH] Failed to purge expired sessions: %v", err)
+	}
+
+	setSessionCookies(w, r, sessionID, csrfToken, int(sessionTimeout.Seconds()))
+	return nil
+}
+
+// lookupSession returns the valid session for a session ID, or nil
+func lookupSession(sessionID string) *WebSession {
+	session, err := GetWebSession(hashToken(sessionID))
+	if err != nil {
+		LogError("❌ [AUTH] Failed to look up session: %v", err)
+		return nil
+	}
+	if session == nil {
+		return nil
+	}
+	if session.isExpired(time.Now()) {
+		DeleteWebSession(session.IDHash)
+		return nil
+	}
+	return session
+}
+
+// refreshSession records activity and, once the rotation interval has
+// passed, moves the session to a new ID. The old ID keeps working for
+// sessionRotateGrace so parallel requests from the same page do not fail.
+// WebSocket upgrades are skipped because their response headers are not ours.
+func refreshSession(w http.ResponseWriter, r *http.Request, session *WebSession) {
+	now := time.Now()
+	isUpgrade := strings.EqualFold(r.Header.Get("Upgrade"), "websocket")
+
+	if session.SupersededAt.IsZero() && !isUpgrade && now.Sub(session.RotatedAt) > sessionRotateInterval {
+		sessionID, err := generateSecureToken(32)
+		if err == nil {
+			err = RotateWebSession(session.IDHash, hashToken(sessionID), now)
+		}
+		if err != nil {
+			LogWarn("⚠️ [AUTH] Failed to rotate session of %s: %v", session.Username, err)
+			return
+		}
+		setSessionCookies(w, r, sessionID, session.CSRFToken, int(sessionTimeout.Seconds()))
+		return
+	}
+
+	if now.Sub(session.LastSeenAt) > sessionTouchInterval {
+		if err := TouchWebSession(session.IDHash, now); err != nil {
+			LogWarn("⚠️ [AUTH] Failed to update session of %s: %v", session.Username, err)
+		}
+	}
+}
+
+// endSession removes the session behind a session ID
+func endSession(sessionID string) {
+	if err := DeleteWebSession(hashToken(sessionID)); err != nil {
+		LogWarn("⚠️ [AUTH] Failed to delete session: %v", err)
+	}
+}
+
+// endUserSessions logs a user out of every browser session
+func endUserSessions(username string) {
+	if err := DeleteUserWebSessions(username); err != nil {
+		LogWarn("⚠️ [AUTH] Failed to end sessions of %s: %v", username, err)
+	}
+}
+
+// isSafeMethod reports whether a request method cannot change state
+func isSafeMethod(method string) bool {
+	return method == "GET" || method == "HEAD" || method == "OPTIONS"
+}
+
+// checkCSRF validates the CSRF token of a state-changing session request
+func checkCSRF(r *http.Request, session *WebSession) bool {
+	token := r.Header.Get(csrfHeaderName)
+	if token == "" {
+		token = r.PostFormValue("csrf_token")
+	}
+	return token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(session.CSRFToken)) == 1
+}
+
+// denyCSRF rejects a request with a missing or wrong CSRF token
+func denyCSRF(w http.ResponseWriter, r *http.Request, user *User) {
+	LogWarn("🔒 [AUTH] Rejected %s %s from %s (%s): missing or invalid CSRF token", r.Method, r.URL.Path, user.Username, clientIP(r))
+
+	if isAPIv1Request(r) {
+		writeAPIError(w, http.StatusForbidden, APIErrForbidden, "Invalid or missing CSRF token, send the X-CSRF-Token header")
+		return
+	}
+	if strings.HasPrefix(r.URL.Path, "/api/") {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   "Invalid or missing CSRF token, reload the page and try again",
+		})
+		return
+	}
+
+	http.Error(w, "Forbidden: invalid or missing CSRF token", http.StatusForbidden)
+}
+
+// checkSameOrigin rejects cross-site WebSocket connections. Requests
+// without an Origin header do not come from a browser page.
+func checkSameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	host := strings.TrimPrefix(strings.TrimPrefix(origin, "https://"), "http://")
+	return strings.EqualFold(host, r.Host)
+}
+
+// Login throttling: failures are counted per client IP and per username
+// within loginFailureWindow. Reaching a limit locks that IP or username out
+// for loginLockoutDuration, even for correct passwords.
+const (
+	loginFailureWindow   = 15 * time.Minute
+	loginLockoutDuration = 15 * time.Minute
+	loginMaxUserFailures = 5
+	loginMaxIPFailures   = 20
+)
+
+type loginFailures struct {
+	Count       int
+	WindowStart time.Time
+	LockedUntil time.Time
+}
+
+type loginThrottle struct {
+	mutex  sync.Mutex
+	byIP   map[string]*loginFailures
+	byUser map[string]*loginFailures
+}
+
+var loginLimiter = &loginThrottle{
+	byIP:   make(map[string]*loginFailures),
+	byUser: make(map[string]*loginFailures),
+}
+
+// lockedFor returns how long the IP or username is still locked out
+func (t *loginThrottle) lockedFor(ip, username string) time.Duration {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	now := time.Now()
+	var remaining time.Duration
+	for _, entry := range []*loginFailures{t.byIP[ip], t.byUser[strings.ToLower(username)]} {
+		if entry != nil && entry.LockedUntil.After(now) && entry.LockedUntil.Sub(now) > remaining {
+			remaining = entry.LockedUntil.Sub(now)
+		}
+	}
+	return remaining
+}
+
+// recordFailure counts a failed login and returns what got locked out by it
+func (t *loginThrottle) recordFailure(ip, username string) (ipLocked, userLocked bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	now := time.Now()
+	count := func(entries map[string]*loginFailures, key string, limit int) bool {
+		entry := entries[key]
+		if entry == nil || now.Sub(entry.WindowStart) > loginFailureWindow {
+			entry = &loginFailures{WindowStart: now}
+			entries[key] = entry
+		}
+		entry.Count++
+		if entry.Count >= limit && !entry.LockedUntil.After(now) {
+			entry.LockedUntil = now.Add(loginLockoutDuration)
+			return true
+		}
+		return false
+	}
+
+	ipLocked = count(t.byIP, ip, loginMaxIPFailures)
+	userLocked = username != "" && count(t.byUser, strings.ToLower(username), loginMaxUserFailures)
+	t.prune(now)
+	return ipLocked, userLocked
+}
+
+// recordSuccess clears the failures of a username after a successful login
+func (t *loginThrottle) recordSuccess(username string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	delete(t.byUser, strings.ToLower(username))
+}
+
+// prune drops entries whose window and lockout are over. Caller holds the mutex.
+func (t *loginThrottle) prune(now time.Time) {
+	for _, entries := range []map[string]*loginFailures{t.byIP, t.byUser} {
+		for key, entry := range entries {
+			if now.Sub(entry.WindowStart) > loginFailureWindow && !entry.LockedUntil.After(now) {
+				delete(entries, key)
+			}
+		}
+	}
+}
+
+// recordFailedLogin counts, logs and audits a failed password or second factor
+func recordFailedLogin(r *http.Request, username, reason string) {
+	ip := clientIP(r)
+	ipLocked, userLocked := loginLimiter.recordFailure(ip, username)
+	LogWarn("🔒 [AUTH] Failed login for %s from %s", username, ip)
+	recordAuditEntry(r, AuditEntry{
+		Actor:   username,
+		Action:  AuditLoginFailed,
+		Target:  username,
+		Outcome: AuditOutcomeFailure,
+		Details: reason,
+	})
+	if ipLocked {
+		LogWarn("🔒 [AUTH] Locked out %s for %v after %d failed logins", ip, loginLockoutDuration, loginMaxIPFailures)
+	}
+	if userLocked {
+		LogWarn("🔒 [AUTH] Locked out user %s for %v after %d failed logins", username, loginLockoutDuration, loginMaxUserFailures)
+	}
+}
+
+// formatLockout describes a remaining lockout for the login page
+func formatLockout(remaining time.Duration) string {
+	minutes := int(remaining.Minutes()) + 1
+	if minutes == 1 {
+		return "Too many failed login attempts. Try again in 1 minute"
+	}
+	return fmt.Sprintf("Too many failed login attempts. Try again in %d minutes", minutes)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// Time cancelled jobs get to record their state after their processes are killed
+const shutdownCancelTimeout = 15 * time.Second
+
+// waitForShutdownSignal blocks until SIGINT or SIGTERM is received. A second
+// signal while shutting down exits immediately.
+func waitForShutdownSignal() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	sig := <-signals
+	LogInfo("🛑 [SHUTDOWN] Received %v, shutting down gracefully (send again to force exit)", sig)
+
+	go func() {
+		sig := <-signals
+		LogWarn("⚠️ [SHUTDOWN] Received %v again, exiting immediately", sig)
+		ShutdownLogger()
+		os.Exit(1)
+	}()
+}
+
+// GracefulShutdown stops the scheduler and the job queue, waits up to
+// shutdown_timeout seconds for running backups and cancels the rest, then
+// closes WebSocket clients and the web server and flushes SQLite and the log.
+func GracefulShutdown(server *http.Server, config *Config) {
+	StopScheduler()
+
+	if dropped := CloseJobQueue(); dropped > 0 {
+		LogInfo("🛑 [SHUTDOWN] Dropped %d queued jobs", dropped)
+	}
+
+	timeout := time.Duration(config.Backup.ShutdownTimeout) * time.Second
+	if !WaitForRunningJobs(0) {
+		LogInfo("⏳ [SHUTDOWN] Waiting up to %s for running backups to finish", formatDuration(timeout))
+	}
+
+	if !WaitForRunningJobs(timeout) {
+		LogWarn("⚠️ [SHUTDOWN] Backups still running after %s, cancelling them", formatDuration(timeout))
+		SignalGlobalBackupAbort()
+		killed := CancelAllBackups()
+		LogInfo("🛑 [SHUTDOWN] Killed %d backup processes", killed)
+
+		if !WaitForRunningJobs(shutdownCancelTimeout) {
+			LogWarn("⚠️ [SHUTDOWN] Some jobs did not stop in time, they will be marked interrupted on next startup")
+		}
+	}
+
+	CloseAllWebSockets()
+	removeClientOptionFiles()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		LogWarn("⚠️ [SHUTDOWN] Web server shutdown: %v", err)
+	}
+
+	FlushDatabaseQueues(5 * time.Second)
+	if err := CloseDB(); err != nil {
+		LogWarn("⚠️ [SHUTDOWN] Failed to close SQLite: %v", err)
+	}
+
+	LogInfo("👋 [SHUTDOWN] Shutdown complete")
+	ShutdownLogger()
+}
@@ -30,7 +30,7 @@ type SlackField struct {
 	Short bool   `json:"short"`
 }
 
-// BackupSummary represents the backup summary data for Slack notification
+// BackupSummary represents the backup summary data sent to notification channels
 type BackupSummary struct {
 	JobID            string
 	BackupMode       string
@@ -43,6 +43,12 @@ type BackupSummary struct {
 	CreatedAt        time.Time
 	CompletedAt      time.Time
 	Duration         time.Duration
+
+	Policy          string
+	State           string // completed or cancelled
+	Databases       []string
+	FailedDatabases []string
+	Test            bool // Sample summary sent by the notification test button
 }
 
 // SendSlackNotification sends a backup summary notification to Slack
@@ -94,7 +100,7 @@ func SendSlackNotification(webhookURL string, summary BackupSummary) error {
 
 	// Create Slack message
 	message := SlackMessage{
-		Text: fmt.Sprintf("🗄️ *MariaDB Backup %s*", getBackupStatusEmoji(summary.TotalFailed, summary.TotalDBCount)),
+		Text: fmt.Sprintf("🗄️ *%s*", getNotificationTitle(summary)),
 		Attachments: []SlackAttachment{
 			{
 				Color:     color,
@@ -156,6 +162,45 @@ func SendSlackNotification(webhookURL string, summary BackupSummary) error {
 		})
 	}
 
+	return postSlackMessage(webhookURL, message)
+}
+
+// SendSlackEvent sends a notification event other than a job summary to Slack
+func SendSlackEvent(webhookURL string, event NotificationEvent) error {
+	color := "good"
+	switch event.Severity {
+	case SeverityWarning:
+		color = "warning"
+	case SeverityCritical:
+		color = "danger"
+	}
+
+	var fields []SlackField
+	for _, field := range event.Fields {
+		fields = append(fields, SlackField{
+			Title: field.Name,
+			Value: field.Value,
+			Short: true,
+		})
+	}
+
+	message := SlackMessage{
+		Text: fmt.Sprintf("%s *%s*", getSeverityEmoji(event.Severity), event.Title),
+		Attachments: []SlackAttachment{
+			{
+				Color:     color,
+				Text:      event.Message,
+				Timestamp: event.Time.Unix(),
+				Fields:    fields,
+			},
+		},
+	}
+
+	return postSlackMessage(webhookURL, message)
+}
+
+// postSlackMessage sends a message to a Slack webhook
+func postSlackMessage(webhookURL string, message SlackMessage) error {
 	// Convert to JSON
 	jsonData, err := json.Marshal(message)
 	if err != nil {
@@ -173,7 +218,6 @@ func SendSlackNotification(webhookURL string, summary BackupSummary) error {
 		return fmt.Errorf("Slack webhook returned status %d", resp.StatusCode)
 	}
 
-	LogInfo("📢 Slack notification sent successfully for backup job %s", summary.JobID)
 	return nil
 }
 
@@ -213,48 +257,3 @@ func getBackupStatusEmoji(failed, total int) string {
 		return "⚠️ Completed with Issues"
 	}
 }
-
-// SendSlackNotificationFromSummary sends Slack notification using backup summary data from database
-func SendSlackNotificationFromSummary(webhookURL, jobID string) error {
-	if webhookURL == "" {
-		LogDebug("Slack webhook URL not configured, skipping notification")
-		return nil
-	}
-
-	// Get backup summary from database
-	summary, err := GetBackupSummaryByJobID(jobID)
-	if err != nil {
-		return fmt.Errorf("failed to get backup summary: %v", err)
-	}
-
-	if summary == nil {
-		return fmt.Errorf("backup summary not found for job %s", jobID)
-	}
-
-	// Convert database summary to Slack summary
-	slackSummary := BackupSummary{
-		JobID:            summary["job_id"].(string),
-		BackupMode:       summary["backup_mode"].(string),
-		TotalDBCount:     summary["total_db_count"].(int),
-		TotalFull:        summary["total_full"].(int),
-		TotalIncremental: summary["total_incremental"].(int),
-		TotalFailed:      summary["total_failed"].(int),
-		TotalSizeKB:      summary["total_size_kb"].(int),
-		MysqlRestartTime: summary["mysql_restart_time"].(int),
-	}
-
-	// Parse timestamps
-	if createdAtStr, ok := summary["created_at"].(string); ok {
-		if createdAt, err := time.Parse("2006-01-02 15:04:05", createdAtStr); err == nil {
-			slackSummary.CreatedAt = createdAt
-		}
-	}
-	if completedAtStr, ok := summary["completed_at"].(string); ok && completedAtStr != "" {
-		if completedAt, err := time.Parse("2006-01-02 15:04:05", completedAtStr); err == nil {
-			slackSummary.CompletedAt = completedAt
-			slackSummary.Duration = completedAt.Sub(slackSummary.CreatedAt)
-		}
-	}
-
-	return SendSlackNotification(webhookURL, slackSummary)
-}
@@ -42,6 +42,10 @@ type StatusUpdate struct {
 var statusUpdateQueue chan StatusUpdate
 var statusBatchTimeout = 1 * time.Second //persistant update
 
+// Flush requests for the batch workers, closed by the worker once written
+var progressFlushQueue = make(chan chan struct{})
+var statusFlushQueue = make(chan chan struct{})
+
 // Database operation metrics
 type DatabaseMetrics struct {
 	TotalOperations   int64
@@ -134,6 +138,33 @@ func startQueueWorker() {
 	}()
 }
 
+// FlushDatabaseQueues writes all queued operations, progress and status updates,
+// waiting at most timeout for each queue
+func FlushDatabaseQueues(timeout time.Duration) {
+	flush := func(name string, request func(done chan struct{})) {
+		done := make(chan struct{})
+		go request(done)
+		select {
+		case <-done:
+		case <-time.After(timeout):
+			LogWarn("Timed out flushing SQLite %s queue", name)
+		}
+	}
+
+	flush("status", func(done chan struct{}) { statusFlushQueue <- done })
+	flush("progress", func(done chan struct{}) { progressFlushQueue <- done })
+	// Operations run in order, so the marker runs after everything queued before it
+	flush("operation", func(done chan struct{}) { operationQueue <- func() { close(done) } })
+}
+
+// CloseDB closes the SQLite database
+func CloseDB() error {
+	if db == nil {
+		return nil
+	}
+	return db.Close()
+}
+
 // executeWithRetry executes a database operation with exponential backoff and jitter
 func executeWithRetry(operation func() error, operationName string, maxRetries int) error {
 	if maxRetries <= 0 {
@@ -228,6 +259,15 @@ func startProgressBatchWorker() {
 					processProgressBatch(batch)
 					batch = batch[:0] // Reset batch
 				}
+
+			case done := <-progressFlushQueue:
+				// Write everything queued so far
+				for len(progressUpdateQueue) > 0 {
+					batch = append(batch, <-progressUpdateQueue)
+				}
+				processProgressBatch(batch)
+				batch = batch[:0]
+				close(done)
 			}
 		}
 	}()
@@ -308,6 +348,15 @@ func startStatusBatchWorker() {
 					processStatusBatch(batch)
 					batch = batch[:0] // Reset batch
 				}
+
+			case done := <-statusFlushQueue:
+				// Write everything queued so far
+				for len(statusUpdateQueue) > 0 {
+					batch = append(batch, <-statusUpdateQueue)
+				}
+				processStatusBatch(batch)
+				batch = batch[:0]
+				close(done)
 			}
 		}
 	}()
@@ -399,13 +448,101 @@ func createTables() error {
 			total_full INTEGER DEFAULT 0,
 			total_incremental INTEGER DEFAULT 0,
 			total_failed INTEGER DEFAULT 0,
-			mysql_restart_time INTEGER DEFAULT 0
+			mysql_restart_time INTEGER DEFAULT 0,
+			policy TEXT DEFAULT '',
+			job_type TEXT DEFAULT '',
+			databases TEXT DEFAULT '',
+			resumed_job_id TEXT DEFAULT ''
+		)`,
+		`CREATE TABLE IF NOT EXISTS users (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			username TEXT UNIQUE NOT NULL COLLATE NOCASE,
+			password_hash TEXT NOT NULL,
+			role TEXT NOT NULL,
+			disabled INTEGER NOT NULL DEFAULT 0,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			last_login_at DATETIME
+		)`,
+		`CREATE TABLE IF NOT EXISTS web_sessions (
+			id_hash TEXT PRIMARY KEY,
+			username TEXT NOT NULL COLLATE NOCASE,
+			csrf_token TEXT NOT NULL,
+			created_at INTEGER NOT NULL,
+			last_seen_at INTEGER NOT NULL,
+			rotated_at INTEGER NOT NULL,
+			superseded_at INTEGER NOT NULL DEFAULT 0,
+			client_ip TEXT DEFAULT '',
+			user_agent TEXT DEFAULT ''
+		)`,
+		`CREATE TABLE IF NOT EXISTS api_tokens (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL,
+			kind TEXT NOT NULL,
+			username TEXT NOT NULL DEFAULT '',
+			created_by TEXT NOT NULL DEFAULT '',
+			token_hash TEXT UNIQUE NOT NULL,
+			prefix TEXT NOT NULL,
+			scopes TEXT NOT NULL DEFAULT '',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			expires_at DATETIME,
+			last_used_at DATETIME,
+			last_used_ip TEXT DEFAULT '',
+			revoked_at DATETIME
+		)`,
+		`CREATE TABLE IF NOT EXISTS audit_log (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			actor TEXT NOT NULL,
+			actor_ip TEXT NOT NULL DEFAULT '',
+			action TEXT NOT NULL,
+			target TEXT NOT NULL DEFAULT '',
+			outcome TEXT NOT NULL,
+			before_value TEXT NOT NULL DEFAULT '',
+			after_value TEXT NOT NULL DEFAULT '',
+			details TEXT NOT NULL DEFAULT ''
+		)`,
+		// The audit log is append-only, even for code with database access
+		`CREATE TRIGGER IF NOT EXISTS audit_log_no_update BEFORE UPDATE ON audit_log
+		BEGIN
+			SELECT RAISE(ABORT, 'audit_log is append-only');
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS audit_log_no_delete BEFORE DELETE ON audit_log
+		BEGIN
+			SELECT RAISE(ABORT, 'audit_log is append-only');
+		END`,
+		`CREATE TABLE IF NOT EXISTS config_history (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			author TEXT NOT NULL,
+			source TEXT NOT NULL,
+			rollback_of INTEGER NOT NULL DEFAULT 0,
+			changed_fields TEXT NOT NULL DEFAULT '',
+			config TEXT NOT NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS schedule_state (
+			policy TEXT PRIMARY KEY,
+			schedule TEXT NOT NULL,
+			last_scheduled_at TEXT NOT NULL,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE TABLE IF NOT EXISTS schedule_history (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			policy TEXT NOT NULL,
+			schedule TEXT NOT NULL,
+			missed_count INTEGER NOT NULL,
+			first_missed_at TEXT NOT NULL,
+			last_missed_at TEXT NOT NULL,
+			action TEXT NOT NULL,
+			message TEXT,
+			detected_at DATETIME DEFAULT CURRENT_TIMESTAMP
 		)`,
 	}
 
 	//backup_mode (auto, full, incremental)
 	//backup_type (auto-full, auto-inc, force-full, force-inc)
 	//status (running, done, failed, cancelled, optimizing)
+	//action (run_once, skip, alert)
+	//role (admin, operator, viewer)
 
 	for _, query := range queries {
 		if _, err := db.Exec(query); err != nil {
@@ -413,6 +550,64 @@ func createTables() error {
 		}
 	}
 
+	// Columns added after the initial release
+	columnMigrations := []struct {
+		table      string
+		column     string
+		definition string
+	}{
+		{"backup_summary", "policy", "TEXT DEFAULT ''"},
+		{"backup_summary", "job_type", "TEXT DEFAULT ''"},
+		{"backup_summary", "databases", "TEXT DEFAULT ''"},
+		{"backup_summary", "resumed_job_id", "TEXT DEFAULT ''"},
+		{"backup_summary", "requested_by", "TEXT DEFAULT ''"},
+		{"users", "totp_secret", "TEXT DEFAULT ''"},
+		{"users", "totp_enabled", "INTEGER NOT NULL DEFAULT 0"},
+		{"users", "totp_last_step", "INTEGER NOT NULL DEFAULT 0"},
+		{"users", "recovery_codes", "TEXT DEFAULT ''"},
+		{"users", "auth_source", "TEXT NOT NULL DEFAULT 'local'"},
+	}
+
+	for _, migration := range columnMigrations {
+		if err := addColumnIfMissing(migration.table, migration.column, migration.definition); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// addColumnIfMissing adds a column to an existing table when it is not present yet
+func addColumnIfMissing(table, column, definition string) error {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return fmt.Errorf("failed to inspect table %s: %v", table, err)
+	}
+
+	exists := false
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, columnType string
+		var defaultValue sql.NullString
+		if err := rows.Scan(&cid, &name, &columnType, &notNull, &defaultValue, &pk); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to inspect table %s: %v", table, err)
+		}
+		if name == column {
+			exists = true
+		}
+	}
+	rows.Close()
+
+	if exists {
+		return nil
+	}
+
+	if _, err := db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition)); err != nil {
+		return fmt.Errorf("failed to add column %s.%s: %v", table, column, err)
+	}
+
+	LogInfo("Added column %s to table %s", column, table)
 	return nil
 }
 
@@ -548,6 +743,21 @@ func CompleteBackupJob(jobID, databaseName string, success bool, actualSizeKB in
 	}, fmt.Sprintf("CompleteBackupJob(%s/%s)", jobID, databaseName), 3)
 }
 
+// CancelBackupJob marks the backup of one database in a job as cancelled
+func CancelBackupJob(jobID, databaseName, errorMessage string) error {
+	query := `UPDATE backup_jobs 
+		SET status = 'cancelled', completed_at = CURRENT_TIMESTAMP, error_message = ?
+		WHERE job_id = ? AND database_name = ?`
+
+	return executeWithRetry(func() error {
+		_, err := db.Exec(query, errorMessage, jobID, databaseName)
+		if err == nil {
+			go broadcastJobsUpdate()
+		}
+		return err
+	}, fmt.Sprintf("CancelBackupJob(%s/%s)", jobID, databaseName), 3)
+}
+
 // GetRunningJobs returns all currently running backup jobs
 func GetRunningJobs() ([]map[string]interface{}, error) {
 	query := `SELECT id, job_id, database_name, backup_type, status, progress, 
@@ -738,12 +948,12 @@ func GetBackupJobs() ([]map[string]interface{}, error) {
 }
 
 // Backup Summary Functions
-func CreateBackupSummary(jobID, backupMode string, totalDBCount int) error {
-	query := `INSERT INTO backup_summary (job_id, backup_mode, total_db_count, state)
-		VALUES (?, ?, ?, 'running')`
+func CreateBackupSummary(jobID, backupMode, jobType, policy, requestedBy string, databases []string) error {
+	query := `INSERT INTO backup_summary (job_id, backup_mode, job_type, policy, requested_by, databases, total_db_count, state)
+		VALUES (?, ?, ?, ?, ?, ?, ?, 'running')`
 
 	return executeWithRetry(func() error {
-		_, err := db.Exec(query, jobID, backupMode, totalDBCount)
+		_, err := db.Exec(query, jobID, backupMode, jobType, policy, requestedBy, strings.Join(databases, ","), len(databases))
 		return err
 	}, fmt.Sprintf("CreateBackupSummary(%s)", jobID), 5)
 }
@@ -780,14 +990,8 @@ func CompleteBackupSummary(jobID string, cfg *Config) error {
 		return err
 	}
 
-	// Send Slack notification if configured
-	go func() {
-		if cfg != nil && cfg.Notification.SlackWebhookURL != "" {
-			if err := SendSlackNotificationFromSummary(cfg.Notification.SlackWebhookURL, jobID); err != nil {
-				LogError("Failed to send Slack notification for job %s: %v", jobID, err)
-			}
-		}
-	}()
+	// Send notifications to the configured channels
+	go SendBackupNotifications(cfg, jobID)
 
 	return nil
 }
@@ -801,9 +1005,115 @@ func CancelBackupSummary(jobID string) error {
 	return err
 }
 
+// MarkInterruptedBackups marks database backups and summaries still running from
+// a previous run of the service as interrupted. Returns the interrupted job IDs
+// and the number of database backups updated.
+func MarkInterruptedBackups() ([]string, int, error) {
+	rows, err := db.Query(`SELECT job_id FROM backup_summary WHERE state = 'running'`)
+	if err != nil {
+		return nil, 0, err
+	}
+	var jobIDs []string
+	for rows.Next() {
+		var jobID string
+		if err := rows.Scan(&jobID); err != nil {
+			rows.Close()
+			return nil, 0, err
+		}
+		jobIDs = append(jobIDs, jobID)
+	}
+	rows.Close()
+
+	var updated int64
+	err = executeWithRetry(func() error {
+		result, err := db.Exec(`UPDATE backup_jobs 
+			SET status = 'interrupted', completed_at = CURRENT_TIMESTAMP, error_message = 'Interrupted by service restart'
+			WHERE status IN ('running', 'optimizing')`)
+		if err != nil {
+			return err
+		}
+		updated, err = result.RowsAffected()
+		return err
+	}, "MarkInterruptedBackups(jobs)", 3)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	err = executeWithRetry(func() error {
+		_, err := db.Exec(`UPDATE backup_summary 
+			SET state = 'interrupted', completed_at = CURRENT_TIMESTAMP
+			WHERE state = 'running'`)
+		return err
+	}, "MarkInterruptedBackups(summaries)", 3)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return jobIDs, int(updated), nil
+}
+
+// GetUnfinishedDatabases returns the type (full or incremental) of a job and
+// the databases of it that have no successful backup
+func GetUnfinishedDatabases(jobID string) (string, []string, error) {
+	var jobType, databaseList string
+	err := db.QueryRow(`SELECT job_type, databases FROM backup_summary WHERE job_id = ?`, jobID).Scan(&jobType, &databaseList)
+	if err != nil {
+		return "", nil, err
+	}
+
+	rows, err := db.Query(`SELECT database_name, backup_type, status FROM backup_jobs WHERE job_id = ?`, jobID)
+	if err != nil {
+		return "", nil, err
+	}
+	defer rows.Close()
+
+	var databases []string
+	if databaseList != "" {
+		databases = strings.Split(databaseList, ",")
+	}
+	done := make(map[string]bool)
+	for rows.Next() {
+		var databaseName, backupType, status string
+		if err := rows.Scan(&databaseName, &backupType, &status); err != nil {
+			return "", nil, err
+		}
+
+		// Summaries created before the database list was stored
+		if databaseList == "" {
+			databases = append(databases, databaseName)
+		}
+		if jobType == "" {
+			jobType = "full"
+			if strings.HasSuffix(backupType, "-inc") {
+				jobType = "incremental"
+			}
+		}
+		if status == "done" {
+			done[databaseName] = true
+		}
+	}
+
+	var unfinished []string
+	for _, databaseName := range databases {
+		if !done[databaseName] {
+			unfinished = append(unfinished, databaseName)
+		}
+	}
+
+	return jobType, unfinished, nil
+}
+
+// SetResumedJobID records the job that resumed an interrupted job
+func SetResumedJobID(jobID, resumedJobID string) error {
+	return executeWithRetry(func() error {
+		_, err := db.Exec(`UPDATE backup_summary SET resumed_job_id = ? WHERE job_id = ?`, resumedJobID, jobID)
+		return err
+	}, fmt.Sprintf("SetResumedJobID(%s)", jobID), 3)
+}
+
 func GetBackupSummaries() ([]map[string]interface{}, error) {
 	query := `SELECT job_id, total_db_count, created_at, state, completed_at, 
-		total_size_kb, total_disk_size, backup_mode, total_full, total_incremental, total_failed, mysql_restart_time
+		total_size_kb, total_disk_size, backup_mode, total_full, total_incremental, total_failed, mysql_restart_time, policy, resumed_job_id, requested_by
 		FROM backup_summary 
 		ORDER BY created_at DESC 
 		LIMIT 20`
@@ -816,12 +1126,12 @@ func GetBackupSummaries() ([]map[string]interface{}, error) {
 
 	var summaries []map[string]interface{}
 	for rows.Next() {
-		var jobID, createdAt, state, backupMode string
+		var jobID, createdAt, state, backupMode, policy, resumedJobID, requestedBy string
 		var totalDBCount, totalSizeKB, totalDiskSizeKB, totalFull, totalIncremental, totalFailed, mysqlRestartTime int
 		var completedAt sql.NullString // Use sql.NullString for nullable column
 
 		err := rows.Scan(&jobID, &totalDBCount, &createdAt, &state, &completedAt,
-			&totalSizeKB, &totalDiskSizeKB, &backupMode, &totalFull, &totalIncremental, &totalFailed, &mysqlRestartTime)
+			&totalSizeKB, &totalDiskSizeKB, &backupMode, &totalFull, &totalIncremental, &totalFailed, &mysqlRestartTime, &policy, &resumedJobID, &requestedBy)
 		if err != nil {
 			return nil, err
 		}
@@ -845,6 +1155,9 @@ func GetBackupSummaries() ([]map[string]interface{}, error) {
 			"total_incremental":  totalIncremental,
 			"total_failed":       totalFailed,
 			"mysql_restart_time": mysqlRestartTime,
+			"policy":             policy,
+			"resumed_job_id":     resumedJobID,
+			"requested_by":       requestedBy,
 		}
 
 		summaries = append(summaries, summary)
@@ -856,16 +1169,16 @@ func GetBackupSummaries() ([]map[string]interface{}, error) {
 // GetBackupSummaryByJobID gets a specific backup summary by job ID
 func GetBackupSummaryByJobID(jobID string) (map[string]interface{}, error) {
 	query := `SELECT job_id, total_db_count, created_at, state, completed_at, 
-		total_size_kb, total_disk_size, backup_mode, total_full, total_incremental, total_failed, mysql_restart_time
+		total_size_kb, total_disk_size, backup_mode, total_full, total_incremental, total_failed, mysql_restart_time, policy, resumed_job_id, requested_by, databases
 		FROM backup_summary 
 		WHERE job_id = ?`
 
-	var jobIDResult, createdAt, state, backupMode string
+	var jobIDResult, createdAt, state, backupMode, policy, resumedJobID, requestedBy, databases string
 	var totalDBCount, totalSizeKB, totalDiskSizeKB, totalFull, totalIncremental, totalFailed, mysqlRestartTime int
 	var completedAt sql.NullString
 
 	err := db.QueryRow(query, jobID).Scan(&jobIDResult, &totalDBCount, &createdAt, &state, &completedAt,
-		&totalSizeKB, &totalDiskSizeKB, &backupMode, &totalFull, &totalIncremental, &totalFailed, &mysqlRestartTime)
+		&totalSizeKB, &totalDiskSizeKB, &backupMode, &totalFull, &totalIncremental, &totalFailed, &mysqlRestartTime, &policy, &resumedJobID, &requestedBy, &databases)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil // Not found
@@ -892,6 +1205,10 @@ func GetBackupSummaryByJobID(jobID string) (map[string]interface{}, error) {
 		"total_incremental":  totalIncremental,
 		"total_failed":       totalFailed,
 		"mysql_restart_time": mysqlRestartTime,
+		"policy":             policy,
+		"resumed_job_id":     resumedJobID,
+		"requested_by":       requestedBy,
+		"databases":          databases,
 	}
 
 	return summary, nil
@@ -901,7 +1218,7 @@ func GetBackupSummaryByJobID(jobID string) (map[string]interface{}, error) {
 func GetRunningJobsWithSummary() (map[string]interface{}, error) {
 	// Get running summaries (state = 'running') AND recent completed summaries
 	summaryQuery := `SELECT job_id, total_db_count, created_at, state, completed_at, 
-		total_size_kb, total_disk_size, backup_mode, total_full, total_incremental, total_failed, mysql_restart_time
+		total_size_kb, total_disk_size, backup_mode, total_full, total_incremental, total_failed, mysql_restart_time, policy, resumed_job_id, requested_by
 		FROM backup_summary 
 		WHERE state = 'running' OR (state = 'completed' AND completed_at >= datetime('now', '-1 day'))
 		ORDER BY 
@@ -925,12 +1242,12 @@ func GetRunningJobsWithSummary() (map[string]interface{}, error) {
 
 	var summaries []map[string]interface{}
 	for summaryRows.Next() {
-		var jobID, createdAt, state, backupMode string
+		var jobID, createdAt, state, backupMode, policy, resumedJobID, requestedBy string
 		var totalDBCount, totalSizeKB, totalDiskSizeKB, totalFull, totalIncremental, totalFailed, mysqlRestartTime int
 		var completedAt sql.NullString // Use sql.NullString for nullable column
 
 		err := summaryRows.Scan(&jobID, &totalDBCount, &createdAt, &state, &completedAt,
-			&totalSizeKB, &totalDiskSizeKB, &backupMode, &totalFull, &totalIncremental, &totalFailed, &mysqlRestartTime)
+			&totalSizeKB, &totalDiskSizeKB, &backupMode, &totalFull, &totalIncremental, &totalFailed, &mysqlRestartTime, &policy, &resumedJobID, &requestedBy)
 		if err != nil {
 			return nil, err
 		}
@@ -954,6 +1271,9 @@ func GetRunningJobsWithSummary() (map[string]interface{}, error) {
 			"total_incremental":  totalIncremental,
 			"total_failed":       totalFailed,
 			"mysql_restart_time": mysqlRestartTime,
+			"policy":             policy,
+			"resumed_job_id":     resumedJobID,
+			"requested_by":       requestedBy,
 		}
 
 		summaries = append(summaries, summary)
@@ -1078,7 +1398,7 @@ func GetRecentActivityWithPagination(page, limit int) (map[string]interface{}, e
 
 	// Get all summaries (not just recent ones) with pagination
 	summaryQuery := `SELECT job_id, total_db_count, created_at, state, completed_at, 
-		total_size_kb, total_disk_size, backup_mode, total_full, total_incremental, total_failed, mysql_restart_time
+		total_size_kb, total_disk_size, backup_mode, total_full, total_incremental, total_failed, mysql_restart_time, policy, resumed_job_id, requested_by
 		FROM backup_summary 
 		ORDER BY 
 			CASE 
@@ -1101,12 +1421,12 @@ func GetRecentActivityWithPagination(page, limit int) (map[string]interface{}, e
 
 	var summaries []map[string]interface{}
 	for summaryRows.Next() {
-		var jobID, createdAt, state, backupMode string
+		var jobID, createdAt, state, backupMode, policy, resumedJobID, requestedBy string
 		var totalDBCount, totalSizeKB, totalDiskSizeKB, totalFull, totalIncremental, totalFailed, mysqlRestartTime int
 		var completedAt sql.NullString
 
 		err := summaryRows.Scan(&jobID, &totalDBCount, &createdAt, &state, &completedAt,
-			&totalSizeKB, &totalDiskSizeKB, &backupMode, &totalFull, &totalIncremental, &totalFailed, &mysqlRestartTime)
+			&totalSizeKB, &totalDiskSizeKB, &backupMode, &totalFull, &totalIncremental, &totalFailed, &mysqlRestartTime, &policy, &resumedJobID, &requestedBy)
 		if err != nil {
 			return nil, err
 		}
@@ -1130,6 +1450,9 @@ func GetRecentActivityWithPagination(page, limit int) (map[string]interface{}, e
 			"total_incremental":  totalIncremental,
 			"total_failed":       totalFailed,
 			"mysql_restart_time": mysqlRestartTime,
+			"policy":             policy,
+			"resumed_job_id":     resumedJobID,
+			"requested_by":       requestedBy,
 		}
 
 		summaries = append(summaries, summary)
@@ -1161,6 +1484,600 @@ func GetRecentActivityWithPagination(page, limit int) (map[string]interface{}, e
 	}, nil
 }
 
+// Schedule State Functions
+
+// GetScheduleLastRun returns the last scheduled run time recorded for a policy.
+// A zero time means the policy has not been tracked yet.
+func GetScheduleLastRun(policy string) (time.Time, error) {
+	var lastScheduledAt string
+	err := db.QueryRow(`SELECT last_scheduled_at FROM schedule_state WHERE policy = ?`, policy).Scan(&lastScheduledAt)
+	if err == sql.ErrNoRows {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	lastRun, err := time.Parse(time.RFC3339, lastScheduledAt)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid last scheduled time %q for policy %s: %v", lastScheduledAt, policy, err)
+	}
+
+	return lastRun, nil
+}
+
+// SaveScheduleLastRun records the last scheduled run time of a policy
+func SaveScheduleLastRun(policy, schedule string, scheduledAt time.Time) error {
+	query := `INSERT INTO schedule_state (policy, schedule, last_scheduled_at, updated_at)
+		VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(policy) DO UPDATE SET
+			schedule = excluded.schedule,
+			last_scheduled_at = excluded.last_scheduled_at,
+			updated_at = CURRENT_TIMESTAMP`
+
+	return executeWithRetry(func() error {
+		_, err := db.Exec(query, policy, schedule, scheduledAt.Format(time.RFC3339))
+		return err
+	}, fmt.Sprintf("SaveScheduleLastRun(%s)", policy), 3)
+}
+
+// RecordMissedRuns stores how missed scheduled runs of a policy were handled
+func RecordMissedRuns(policy, schedule string, missedCount int, firstMissed, lastMissed time.Time, action, message string) error {
+	query := `INSERT INTO schedule_history (policy, schedule, missed_count, first_missed_at, last_missed_at, action, message)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`
+
+	return executeWithRetry(func() error {
+		_, err := db.Exec(query, policy, schedule, missedCount,
+			firstMissed.Format(time.RFC3339), lastMissed.Format(time.RFC3339), action, message)
+		return err
+	}, fmt.Sprintf("RecordMissedRuns(%s)", policy), 3)
+}
+
+// GetMissedRunHistory returns the most recent missed run decisions
+func GetMissedRunHistory(limit int) ([]map[string]interface{}, error) {
+	query := `SELECT id, policy, schedule, missed_count, first_missed_at, last_missed_at, action, message, detected_at
+		FROM schedule_history
+		ORDER BY id DESC
+		LIMIT ?`
+
+	rows, err := db.Query(query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := []map[string]interface{}{}
+	for rows.Next() {
+		var id, missedCount int
+		var policy, schedule, firstMissedAt, lastMissedAt, action, detectedAt string
+		var message sql.NullString
+
+		if err := rows.Scan(&id, &policy, &schedule, &missedCount, &firstMissedAt, &lastMissedAt, &action, &message, &detectedAt); err != nil {
+			return nil, err
+		}
+
+		history = append(history, map[string]interface{}{
+			"id":              id,
+			"policy":          policy,
+			"schedule":        schedule,
+			"missed_count":    missedCount,
+			"first_missed_at": firstMissedAt,
+			"last_missed_at":  lastMissedAt,
+			"action":          action,
+			"message":         message.String,
+			"detected_at":     detectedAt,
+		})
+	}
+
+	return history, nil
+}
+
+// User Functions
+
+// Columns read by scanUser
+const userColumns = `id, username, password_hash, role, disabled, created_at, last_login_at,
+	COALESCE(totp_secret, ''), totp_enabled, totp_last_step, COALESCE(recovery_codes, ''), auth_source`
+
+// scanUser reads a users row selected with userColumns
+func scanUser(row interface{ Scan(...interface{}) error }) (*User, error) {
+	var user User
+	var disabled, totpEnabled int
+	var lastLoginAt sql.NullString
+	var recoveryCodes string
+	if err := row.Scan(&user.ID, &user.Username, &user.PasswordHash, &user.Role, &disabled, &user.CreatedAt, &lastLoginAt,
+		&user.TOTPSecret, &totpEnabled, &user.TOTPLastStep, &recoveryCodes, &user.AuthSource); err != nil {
+		return nil, err
+	}
+	user.Disabled = disabled != 0
+	user.LastLoginAt = lastLoginAt.String
+	user.TOTPEnabled = totpEnabled != 0
+	if recoveryCodes != "" {
+		user.RecoveryCodes = strings.Split(recoveryCodes, ",")
+	}
+	user.RecoveryCodesLeft = len(user.RecoveryCodes)
+	return &user, nil
+}
+
+// GetUsers returns all web users ordered by username
+func GetUsers() ([]User, error) {
+	rows, err := db.Query(`SELECT ` + userColumns + ` FROM users ORDER BY username`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []User{}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, *user)
+	}
+
+	return users, rows.Err()
+}
+
+// GetUser returns a web user by name (case-insensitive), or nil when it does not exist
+func GetUser(username string) (*User, error) {
+	user, err := scanUser(db.QueryRow(`SELECT `+userColumns+` FROM users WHERE username = ?`, username))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return user, err
+}
+
+// CountUsers returns the number of web users
+func CountUsers() (int, error) {
+	var count int
+	err := db.QueryRow(`SELECT COUNT(*) FROM users`).Scan(&count)
+	return count, err
+}
+
+// CountActiveAdmins returns the number of enabled admin users
+func CountActiveAdmins() (int, error) {
+	var count int
+	err := db.QueryRow(`SELECT COUNT(*) FROM users WHERE role = ? AND disabled = 0`, RoleAdmin).Scan(&count)
+	return count, err
+}
+
+// CreateUser adds a web user with an already hashed password
+func CreateUser(username, passwordHash, role string) error {
+	return executeWithRetry(func() error {
+		_, err := db.Exec(`INSERT INTO users (username, password_hash, role) VALUES (?, ?, ?)`, username, passwordHash, role)
+		return err
+	}, fmt.Sprintf("CreateUser(%s)", username), 3)
+}
+
+// CreateSSOUser adds a web user that logs in through an identity provider.
+// It has no local password.
+func CreateSSOUser(username, role, source string) error {
+	return executeWithRetry(func() error {
+		_, err := db.Exec(`INSERT INTO users (username, password_hash, role, auth_source) VALUES (?, '', ?, ?)`, username, role, source)
+		return err
+	}, fmt.Sprintf("CreateSSOUser(%s)", username), 3)
+}
+
+// UpdateUser changes the role and disabled state of a web user
+func UpdateUser(username, role string, disabled bool) error {
+	return executeWithRetry(func() error {
+		_, err := db.Exec(`UPDATE users SET role = ?, disabled = ? WHERE username = ?`, role, disabled, username)
+		return err
+	}, fmt.Sprintf("UpdateUser(%s)", username), 3)
+}
+
+// SetUserPassword replaces the password hash of a web user
+func SetUserPassword(username, passwordHash string) error {
+	return executeWithRetry(func() error {
+		_, err := db.Exec(`UPDATE users SET password_hash = ? WHERE username = ?`, passwordHash, username)
+		return err
+	}, fmt.Sprintf("SetUserPassword(%s)", username), 3)
+}
+
+// DeleteUser removes a web user
+func DeleteUser(username string) error {
+	return executeWithRetry(func() error {
+		_, err := db.Exec(`DELETE FROM users WHERE username = ?`, username)
+		return err
+	}, fmt.Sprintf("DeleteUser(%s)", username), 3)
+}
+
+// RecordUserLogin stores the time of a user's last successful login
+func RecordUserLogin(username string) error {
+	return executeWithRetry(func() error {
+		_, err := db.Exec(`UPDATE users SET last_login_at = CURRENT_TIMESTAMP WHERE username = ?`, username)
+		return err
+	}, fmt.Sprintf("RecordUserLogin(%s)", username), 3)
+}
+
+// Web Session Functions
+// Session times are stored as Unix seconds
+
+// GetWebSession returns the session with the given ID hash, or nil when it does not exist
+func GetWebSession(idHash string) (*WebSession, error) {
+	var session WebSession
+	var createdAt, lastSeenAt, rotatedAt, supersededAt int64
+	err := db.QueryRow(`SELECT id_hash, username, csrf_token, created_at, last_seen_at, rotated_at, superseded_at,
+			client_ip, user_agent
+		FROM web_sessions WHERE id_hash = ?`, idHash).Scan(&session.IDHash, &session.Username, &session.CSRFToken,
+		&createdAt, &lastSeenAt, &rotatedAt, &supersededAt, &session.ClientIP, &session.UserAgent)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	session.CreatedAt = time.Unix(createdAt, 0)
+	session.LastSeenAt = time.Unix(lastSeenAt, 0)
+	session.RotatedAt = time.Unix(rotatedAt, 0)
+	if supersededAt > 0 {
+		session.SupersededAt = time.Unix(supersededAt, 0)
+	}
+	return &session, nil
+}
+
+// CreateWebSession stores a new browser session
+func CreateWebSession(session *WebSession) error {
+	return executeWithRetry(func() error {
+		_, err := db.Exec(`INSERT INTO web_sessions (id_hash, username, csrf_token, created_at, last_seen_at, rotated_at,
+				client_ip, user_agent)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			session.IDHash, session.Username, session.CSRFToken, session.CreatedAt.Unix(), session.LastSeenAt.Unix(),
+			session.RotatedAt.Unix(), session.ClientIP, session.UserAgent)
+		return err
+	}, fmt.Sprintf("CreateWebSession(%s)", session.Username), 3)
+}
+
+// RotateWebSession copies a session to a new ID hash and marks the old row
+// superseded, in one transaction
+func RotateWebSession(oldIDHash, newIDHash string, now time.Time) error {
+	return executeWithRetry(func() error {
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		result, err := tx.Exec(`INSERT INTO web_sessions (id_hash, username, csrf_token, created_at, last_seen_at, rotated_at,
+				client_ip, user_agent)
+			SELECT ?, username, csrf_token, created_at, ?, ?, client_ip, user_agent
+			FROM web_sessions WHERE id_hash = ? AND superseded_at = 0`, newIDHash, now.Unix(), now.Unix(), oldIDHash)
+		if err != nil {
+			return err
+		}
+		if rows, _ := result.RowsAffected(); rows == 0 {
+			return fmt.Errorf("session no longer exists or was already rotated")
+		}
+		if _, err := tx.Exec(`UPDATE web_sessions SET superseded_at = ? WHERE id_hash = ?`, now.Unix(), oldIDHash); err != nil {
+			return err
+		}
+		return tx.Commit()
+	}, "RotateWebSession", 3)
+}
+
+// TouchWebSession records activity on a session
+func TouchWebSession(idHash string, now time.Time) error {
+	return executeWithRetry(func() error {
+		_, err := db.Exec(`UPDATE web_sessions SET last_seen_at = ? WHERE id_hash = ?`, now.Unix(), idHash)
+		return err
+	}, "TouchWebSession", 3)
+}
+
+// DeleteWebSession removes a browser session
+func DeleteWebSession(idHash string) error {
+	return executeWithRetry(func() error {
+		_, err := db.Exec(`DELETE FROM web_sessions WHERE id_hash = ?`, idHash)
+		return err
+	}, "DeleteWebSession", 3)
+}
+
+// DeleteUserWebSessions removes all browser sessions of a user
+func DeleteUserWebSessions(username string) error {
+	return executeWithRetry(func() error {
+		_, err := db.Exec(`DELETE FROM web_sessions WHERE username = ?`, username)
+		return err
+	}, fmt.Sprintf("DeleteUserWebSessions(%s)", username), 3)
+}
+
+// PurgeExpiredWebSessions removes idle, too old and superseded sessions
+func PurgeExpiredWebSessions(now time.Time) error {
+	return executeWithRetry(func() error {
+		_, err := db.Exec(`DELETE FROM web_sessions
+			WHERE last_seen_at < ? OR created_at < ? OR (superseded_at > 0 AND superseded_at < ?)`,
+			now.Add(-sessionTimeout).Unix(), now.Add(-sessionMaxLifetime).Unix(), now.Add(-sessionRotateGrace).Unix())
+		return err
+	}, "PurgeExpiredWebSessions", 3)
+}
+
+// API Token Functions
+
+// Columns read by scanAPIToken
+const apiTokenColumns = `id, name, kind, username, created_by, token_hash, prefix, scopes, created_at,
+	expires_at, last_used_at, last_used_ip, revoked_at`
+
+// scanAPIToken reads an api_tokens row selected with apiTokenColumns
+func scanAPIToken(row interface{ Scan(...interface{}) error }) (*APIToken, error) {
+	var token APIToken
+	var scopes string
+	var expiresAt, lastUsedAt, lastUsedIP, revokedAt sql.NullString
+	if err := row.Scan(&token.ID, &token.Name, &token.Kind, &token.Username, &token.CreatedBy, &token.TokenHash,
+		&token.Prefix, &scopes, &token.CreatedAt, &expiresAt, &lastUsedAt, &lastUsedIP, &revokedAt); err != nil {
+		return nil, err
+	}
+	token.Scopes = parseTokenScopes(scopes)
+	token.ExpiresAt = expiresAt.String
+	token.LastUsedAt = lastUsedAt.String
+	token.LastUsedIP = lastUsedIP.String
+	token.RevokedAt = revokedAt.String
+	return &token, nil
+}
+
+// GetAPITokens returns API tokens, newest first. An empty username returns
+// the tokens of every user and all service tokens.
+func GetAPITokens(username string) ([]APIToken, error) {
+	query := `SELECT ` + apiTokenColumns + ` FROM api_tokens`
+	var args []interface{}
+	if username != "" {
+		query += ` WHERE kind = ? AND username = ?`
+		args = append(args, TokenKindPersonal, username)
+	}
+	query += ` ORDER BY id DESC`
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []APIToken{}
+	for rows.Next() {
+		token, err := scanAPIToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, *token)
+	}
+
+	return tokens, rows.Err()
+}
+
+// GetAPIToken returns an API token by id, or nil when it does not exist
+func GetAPIToken(id int) (*APIToken, error) {
+	token, err := scanAPIToken(db.QueryRow(`SELECT `+apiTokenColumns+` FROM api_tokens WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return token, err
+}
+
+// GetAPITokenByHash returns the API token with the given SHA-256 hash, or nil
+// when it does not exist
+func GetAPITokenByHash(tokenHash string) (*APIToken, error) {
+	token, err := scanAPIToken(db.QueryRow(`SELECT `+apiTokenColumns+` FROM api_tokens WHERE token_hash = ?`, tokenHash))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return token, err
+}
+
+// CreateAPIToken stores a new API token and returns its id. Only the hash of
+// the secret is stored; expiresAt is empty for tokens that never expire.
+func CreateAPIToken(token *APIToken, expiresAt string) (int, error) {
+	var id int64
+	err := executeWithRetry(func() error {
+		var expires interface{}
+		if expiresAt != "" {
+			expires = expiresAt
+		}
+		result, err := db.Exec(`INSERT INTO api_tokens (name, kind, username, created_by, token_hash, prefix, scopes, expires_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			token.Name, token.Kind, token.Username, token.CreatedBy, token.TokenHash, token.Prefix,
+			strings.Join(token.Scopes, ","), expires)
+		if err != nil {
+			return err
+		}
+		id, err = result.LastInsertId()
+		return err
+	}, fmt.Sprintf("CreateAPIToken(%s)", token.Name), 3)
+	return int(id), err
+}
+
+// RecordAPITokenUse stores the time and client address of a token's last use
+func RecordAPITokenUse(id int, clientIP string) error {
+	return executeWithRetry(func() error {
+		_, err := db.Exec(`UPDATE api_tokens SET last_used_at = CURRENT_TIMESTAMP, last_used_ip = ? WHERE id = ?`, clientIP, id)
+		return err
+	}, fmt.Sprintf("RecordAPITokenUse(%d)", id), 3)
+}
+
+// RevokeAPIToken marks an API token as revoked
+func RevokeAPIToken(id int) error {
+	return executeWithRetry(func() error {
+		_, err := db.Exec(`UPDATE api_tokens SET revoked_at = CURRENT_TIMESTAMP WHERE id = ? AND revoked_at IS NULL`, id)
+		return err
+	}, fmt.Sprintf("RevokeAPIToken(%d)", id), 3)
+}
+
+// RevokeUserAPITokens revokes all personal tokens of a user
+func RevokeUserAPITokens(username string) error {
+	return executeWithRetry(func() error {
+		_, err := db.Exec(`UPDATE api_tokens SET revoked_at = CURRENT_TIMESTAMP
+			WHERE kind = ? AND username = ? AND revoked_at IS NULL`, TokenKindPersonal, username)
+		return err
+	}, fmt.Sprintf("RevokeUserAPITokens(%s)", username), 3)
+}
+
+// SetUserTOTPSecret stores a new, not yet confirmed, TOTP secret. 2FA stays
+// off until EnableUserTOTP.
+func SetUserTOTPSecret(username, secret string) error {
+	return executeWithRetry(func() error {
+		_, err := db.Exec(`UPDATE users SET totp_secret = ?, totp_enabled = 0, totp_last_step = 0, recovery_codes = ''
+			WHERE username = ?`, secret, username)
+		return err
+	}, fmt.Sprintf("SetUserTOTPSecret(%s)", username), 3)
+}
+
+// EnableUserTOTP turns on 2FA with the stored secret and the given recovery code hashes
+func EnableUserTOTP(username string, recoveryCodeHashes []string, step int64) error {
+	return executeWithRetry(func() error {
+		_, err := db.Exec(`UPDATE users SET totp_enabled = 1, totp_last_step = ?, recovery_codes = ? WHERE username = ?`,
+			step, strings.Join(recoveryCodeHashes, ","), username)
+		return err
+	}, fmt.Sprintf("EnableUserTOTP(%s)", username), 3)
+}
+
+// DisableUserTOTP turns off 2FA and removes the secret and recovery codes
+func DisableUserTOTP(username string) error {
+	return executeWithRetry(func() error {
+		_, err := db.Exec(`UPDATE users SET totp_secret = '', totp_enabled = 0, totp_last_step = 0, recovery_codes = ''
+			WHERE username = ?`, username)
+		return err
+	}, fmt.Sprintf("DisableUserTOTP(%s)", username), 3)
+}
+
+// SetUserRecoveryCodes replaces the recovery code hashes of a user
+func SetUserRecoveryCodes(username string, recoveryCodeHashes []string) error {
+	return executeWithRetry(func() error {
+		_, err := db.Exec(`UPDATE users SET recovery_codes = ? WHERE username = ?`, strings.Join(recoveryCodeHashes, ","), username)
+		return err
+	}, fmt.Sprintf("SetUserRecoveryCodes(%s)", username), 3)
+}
+
+// RecordTOTPStep stores the time step of an accepted TOTP code. It returns
+// false when that step or a later one was already used, so a code cannot be
+// replayed.
+func RecordTOTPStep(username string, step int64) (bool, error) {
+	var updated int64
+	err := executeWithRetry(func() error {
+		result, err := db.Exec(`UPDATE users SET totp_last_step = ? WHERE username = ? AND totp_last_step < ?`, step, username, step)
+		if err != nil {
+			return err
+		}
+		updated, err = result.RowsAffected()
+		return err
+	}, fmt.Sprintf("RecordTOTPStep(%s)", username), 3)
+	return updated > 0, err
+}
+
+// ConsumeRecoveryCode removes a recovery code hash from a user. It returns
+// false when the code is not (or no longer) one of theirs.
+func ConsumeRecoveryCode(username, codeHash string) (bool, error) {
+	var consumed bool
+	err := executeWithRetry(func() error {
+		var current string
+		if err := db.QueryRow(`SELECT COALESCE(recovery_codes, '') FROM users WHERE username = ?`, username).Scan(&current); err != nil {
+			return err
+		}
+
+		var remaining []string
+		found := false
+		for _, hash := range strings.Split(current, ",") {
+			if hash == codeHash && !found {
+				found = true
+				continue
+			}
+			if hash != "" {
+				remaining = append(remaining, hash)
+			}
+		}
+		if !found {
+			consumed = false
+			return nil
+		}
+
+		// Only update when nobody used a code in between
+		result, err := db.Exec(`UPDATE users SET recovery_codes = ? WHERE username = ? AND recovery_codes = ?`,
+			strings.Join(remaining, ","), username, current)
+		if err != nil {
+			return err
+		}
+		rows, err := result.RowsAffected()
+		consumed = rows > 0
+		return err
+	}, fmt.Sprintf("ConsumeRecoveryCode(%s)", username), 3)
+	return consumed, err
+}
+
+// GetLastSuccessfulBackups returns, per database and backup kind (full or
+// incremental), the Unix time of the last successful backup
+func GetLastSuccessfulBackups() ([]map[string]interface{}, error) {
+	query := `SELECT database_name,
+			CASE WHEN backup_type LIKE '%inc' THEN 'incremental' ELSE 'full' END AS kind,
+			CAST(strftime('%s', MAX(completed_at)) AS INTEGER)
+		FROM backup_jobs
+		WHERE status = 'done' AND completed_at IS NOT NULL
+		GROUP BY database_name, kind
+		ORDER BY database_name, kind`
+
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var backups []map[string]interface{}
+	for rows.Next() {
+		var databaseName, kind string
+		var completedAt sql.NullInt64
+		if err := rows.Scan(&databaseName, &kind, &completedAt); err != nil {
+			return nil, err
+		}
+		if !completedAt.Valid {
+			continue
+		}
+
+		backups = append(backups, map[string]interface{}{
+			"database_name": databaseName,
+			"backup_type":   kind,
+			"completed_at":  completedAt.Int64,
+		})
+	}
+
+	return backups, nil
+}
+
+// GetDatabaseFreshness returns, per database in the backup history, the time
+// and type of the last successful backup together with the status and error
+// of the most recent attempt
+func GetDatabaseFreshness() ([]map[string]interface{}, error) {
+	query := `SELECT l.database_name, l.status, COALESCE(l.error_message, ''),
+			(SELECT CAST(strftime('%s', MAX(d.completed_at)) AS INTEGER) FROM backup_jobs d
+				WHERE d.database_name = l.database_name AND d.status = 'done'),
+			(SELECT d.backup_type FROM backup_jobs d
+				WHERE d.database_name = l.database_name AND d.status = 'done'
+				ORDER BY d.completed_at DESC LIMIT 1)
+		FROM backup_jobs l
+		WHERE l.id IN (SELECT MAX(id) FROM backup_jobs GROUP BY database_name)
+		ORDER BY l.database_name`
+
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var freshness []map[string]interface{}
+	for rows.Next() {
+		var databaseName, lastStatus, lastError string
+		var lastSuccess sql.NullInt64
+		var lastSuccessType sql.NullString
+		if err := rows.Scan(&databaseName, &lastStatus, &lastError, &lastSuccess, &lastSuccessType); err != nil {
+			return nil, err
+		}
+
+		freshness = append(freshness, map[string]interface{}{
+			"database_name":     databaseName,
+			"last_status":       lastStatus,
+			"last_error":        lastError,
+			"last_success":      lastSuccess.Int64, // 0 when the database was never backed up
+			"last_success_type": lastSuccessType.String,
+		})
+	}
+
+	return freshness, rows.Err()
+}
+
 func DeleteBackupJob(jobID string) error {
 	query := `DELETE FROM backup_jobs WHERE job_id = ?`
 	_, err := db.Exec(query, jobID)
@@ -1975,10 +2892,22 @@ func GetBackupTimelineData(days int) (map[string]interface{}, error) {
 	return result, nil
 }
 
+var lastJobID int64
+var jobIDMutex sync.Mutex
+
 // Helper function to generate job ID based on current timestamp
 func GenerateJobID() string {
-	now := time.Now()
-	return fmt.Sprintf("%d", now.Unix()) // Unix timestamp format
+	jobIDMutex.Lock()
+	defer jobIDMutex.Unlock()
+
+	// Unix timestamp format, bumped by one when several jobs start within the same second
+	id := time.Now().Unix()
+	if id <= lastJobID {
+		id = lastJobID + 1
+	}
+	lastJobID = id
+
+	return fmt.Sprintf("%d", id)
 }
 
 // GetDatabaseMetrics returns current database operation metrics
@@ -2031,3 +2960,274 @@ func ResetDatabaseMetrics() {
 
 	LogInfo("Database metrics reset")
 }
+
+// Audit Log Functions
+
+// InsertAuditEntry appends an entry to the audit log
+func InsertAuditEntry(entry *AuditEntry) error {
+	return executeWithRetry(func() error {
+		_, err := db.Exec(`INSERT INTO audit_log (actor, actor_ip, action, target, outcome, before_value, after_value, details)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			entry.Actor, entry.ActorIP, entry.Action, entry.Target, entry.Outcome, entry.Before, entry.After, entry.Details)
+		return err
+	}, fmt.Sprintf("InsertAuditEntry(%s)", entry.Action), 3)
+}
+
+// GetAuditEntries returns the entries matching a filter, newest first, and
+// the number of matching entries
+func GetAuditEntries(filter AuditFilter) ([]AuditEntry, int, error) {
+	var conditions []string
+	var args []interface{}
+	if filter.Actor != "" {
+		conditions = append(conditions, `actor LIKE ?`)
+		args = append(args, "%"+filter.Actor+"%")
+	}
+	if filter.Action != "" {
+		// "backup" matches every backup.* action
+		conditions = append(conditions, `(action = ? OR action LIKE ?)`)
+		args = append(args, filter.Action, filter.Action+".%")
+	}
+	if filter.Target != "" {
+		conditions = append(conditions, `target LIKE ?`)
+		args = append(args, "%"+filter.Target+"%")
+	}
+	if filter.Outcome != "" {
+		conditions = append(conditions, `outcome = ?`)
+		args = append(args, filter.Outcome)
+	}
+	if !filter.From.IsZero() {
+		conditions = append(conditions, `created_at >= ?`)
+		args = append(args, filter.From.UTC().Format("2006-01-02 15:04:05"))
+	}
+	if !filter.To.IsZero() {
+		conditions = append(conditions, `created_at < ?`)
+		args = append(args, filter.To.UTC().Format("2006-01-02 15:04:05"))
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM audit_log`+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := db.Query(`SELECT id, created_at, actor, actor_ip, action, target, outcome, before_value, after_value, details
+		FROM audit_log`+where+` ORDER BY id DESC LIMIT ? OFFSET ?`, append(args, filter.Limit, filter.Offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	entries := []AuditEntry{}
+	for rows.Next() {
+		var entry AuditEntry
+		if err := rows.Scan(&entry.ID, &entry.CreatedAt, &entry.Actor, &entry.ActorIP, &entry.Action, &entry.Target,
+			&entry.Outcome, &entry.Before, &entry.After, &entry.Details); err != nil {
+			return nil, 0, err
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, total, rows.Err()
+}
+
+// backupRunColumns are the backup_summary columns read into a BackupRun
+const backupRunColumns = `job_id, state, backup_mode, job_type, policy, requested_by, databases, total_db_count,
+	created_at, completed_at, total_size_kb, total_disk_size, total_full, total_incremental, total_failed, resumed_job_id`
+
+// scanBackupRun reads a backup_summary row selected with backupRunColumns
+func scanBackupRun(row interface{ Scan(...interface{}) error }) (*BackupRun, error) {
+	var run BackupRun
+	var databases string
+	var completedAt sql.NullString
+	if err := row.Scan(&run.JobID, &run.State, &run.Mode, &run.Type, &run.Policy, &run.RequestedBy, &databases,
+		&run.DatabaseCount, &run.CreatedAt, &completedAt, &run.SizeKB, &run.DiskSizeKB, &run.FullCount,
+		&run.IncrementalCount, &run.FailedCount, &run.ResumedJobID); err != nil {
+		return nil, err
+	}
+	run.Databases = []string{}
+	if databases != "" {
+		run.Databases = strings.Split(databases, ",")
+	}
+	run.CompletedAt = completedAt.String
+	return &run, nil
+}
+
+// GetBackupRuns returns a page of backup runs, newest first, and the total
+// number of runs matching the filter
+func GetBackupRuns(filter BackupRunFilter) ([]BackupRun, int, error) {
+	var conditions []string
+	var args []interface{}
+	if filter.State != "" {
+		conditions = append(conditions, `state = ?`)
+		args = append(args, filter.State)
+	}
+	if filter.Policy != "" {
+		conditions = append(conditions, `policy = ?`)
+		args = append(args, filter.Policy)
+	}
+	if filter.RequestedBy != "" {
+		conditions = append(conditions, `requested_by = ?`)
+		args = append(args, filter.RequestedBy)
+	}
+	if filter.Database != "" {
+		conditions = append(conditions, `(',' || databases || ',') LIKE ?`)
+		args = append(args, "%,"+filter.Database+",%")
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM backup_summary`+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := db.Query(`SELECT `+backupRunColumns+` FROM backup_summary`+where+
+		` ORDER BY created_at DESC, id DESC LIMIT ? OFFSET ?`, append(args, filter.Limit, filter.Offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	runs := []BackupRun{}
+	for rows.Next() {
+		run, err := scanBackupRun(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		runs = append(runs, *run)
+	}
+
+	return runs, total, rows.Err()
+}
+
+// GetBackupRun returns a backup run with the result of every database, or
+// nil when the job does not exist
+func GetBackupRun(jobID string) (*BackupRun, error) {
+	run, err := scanBackupRun(db.QueryRow(`SELECT `+backupRunColumns+` FROM backup_summary WHERE job_id = ?`, jobID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(`SELECT database_name, backup_type, status, progress, started_at, completed_at,
+		actual_size_kb, backup_file_path, error_message
+		FROM backup_jobs WHERE job_id = ? ORDER BY id`, jobID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	run.Results = []BackupResult{}
+	for rows.Next() {
+		var result BackupResult
+		var progress, sizeKB sql.NullInt64
+		var startedAt, completedAt, filePath, errorMessage sql.NullString
+		if err := rows.Scan(&result.Database, &result.Type, &result.Status, &progress, &startedAt, &completedAt,
+			&sizeKB, &filePath, &errorMessage); err != nil {
+			return nil, err
+		}
+		result.Progress = int(progress.Int64)
+		result.StartedAt = startedAt.String
+		result.CompletedAt = completedAt.String
+		result.SizeKB = int(sizeKB.Int64)
+		result.FilePath = filePath.String
+		result.Error = errorMessage.String
+		run.Results = append(run.Results, result)
+	}
+
+	return run, rows.Err()
+}
+
+// InsertConfigSnapshot appends a configuration snapshot and returns its ID
+func InsertConfigSnapshot(snapshot *ConfigSnapshot) (int64, error) {
+	var id int64
+	err := executeWithRetry(func() error {
+		result, err := db.Exec(`INSERT INTO config_history (author, source, rollback_of, changed_fields, config)
+			VALUES (?, ?, ?, ?, ?)`,
+			snapshot.Author, snapshot.Source, snapshot.RollbackOf, strings.Join(snapshot.ChangedFields, ","), snapshot.Config)
+		if err != nil {
+			return err
+		}
+		id, err = result.LastInsertId()
+		return err
+	}, fmt.Sprintf("InsertConfigSnapshot(%s)", snapshot.Source), 3)
+	return id, err
+}
+
+// configSnapshotColumns are the config_history columns read into a ConfigSnapshot
+const configSnapshotColumns = `id, created_at, author, source, rollback_of, changed_fields, config`
+
+// scanConfigSnapshot reads a config_history row selected with configSnapshotColumns
+func scanConfigSnapshot(row interface{ Scan(...interface{}) error }) (*ConfigSnapshot, error) {
+	var snapshot ConfigSnapshot
+	var changedFields string
+	if err := row.Scan(&snapshot.ID, &snapshot.CreatedAt, &snapshot.Author, &snapshot.Source, &snapshot.RollbackOf,
+		&changedFields, &snapshot.Config); err != nil {
+		return nil, err
+	}
+	snapshot.ChangedFields = []string{}
+	if changedFields != "" {
+		snapshot.ChangedFields = strings.Split(changedFields, ",")
+	}
+	return &snapshot, nil
+}
+
+// GetConfigSnapshots returns a page of configuration snapshots, newest
+// first, and the total number of snapshots
+func GetConfigSnapshots(limit, offset int) ([]ConfigSnapshot, int, error) {
+	var total int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM config_history`).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := db.Query(`SELECT `+configSnapshotColumns+` FROM config_history ORDER BY id DESC LIMIT ? OFFSET ?`, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	snapshots := []ConfigSnapshot{}
+	for rows.Next() {
+		snapshot, err := scanConfigSnapshot(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		snapshots = append(snapshots, *snapshot)
+	}
+
+	return snapshots, total, rows.Err()
+}
+
+// GetConfigSnapshot returns a configuration snapshot, or nil when it does not exist
+func GetConfigSnapshot(id int64) (*ConfigSnapshot, error) {
+	snapshot, err := scanConfigSnapshot(db.QueryRow(`SELECT `+configSnapshotColumns+` FROM config_history WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return snapshot, err
+}
+
+// GetPreviousConfigSnapshot returns the snapshot taken before the snapshot
+// with the given ID, the latest one for 0, or nil when there is none
+func GetPreviousConfigSnapshot(id int64) (*ConfigSnapshot, error) {
+	query := `SELECT ` + configSnapshotColumns + ` FROM config_history ORDER BY id DESC LIMIT 1`
+	args := []interface{}{}
+	if id > 0 {
+		query = `SELECT ` + configSnapshotColumns + ` FROM config_history WHERE id < ? ORDER BY id DESC LIMIT 1`
+		args = append(args, id)
+	}
+	snapshot, err := scanConfigSnapshot(db.QueryRow(query, args...))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return snapshot, err
+}
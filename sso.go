@@ -0,0 +1,209 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Values of web.local_login
+const (
+	LocalLoginAll    = "all"
+	LocalLoginAdmins = "admins"
+)
+
+// errInvalidLogin is returned for a wrong username or password. Other
+// errors mean the identity provider could not be asked.
+var errInvalidLogin = errors.New("invalid username or password")
+
+// ssoEnabled reports whether an identity provider is configured
+func ssoEnabled(config *Config) bool {
+	return config.Web.OIDC.Enabled || config.Web.LDAP.Enabled
+}
+
+// mapGroupsToRole returns the highest role mapped to any of the groups, or
+// defaultRole when none is mapped. Group names compare case-insensitively.
+func mapGroupsToRole(groups []string, groupRoles map[string]string, defaultRole string) string {
+	role := ""
+	for _, group := range groups {
+		for mapped, mappedRole := range groupRoles {
+			if strings.EqualFold(strings.TrimSpace(group), strings.TrimSpace(mapped)) && roleLevels[mappedRole] > roleLevels[role] {
+				role = mappedRole
+			}
+		}
+	}
+	if role == "" {
+		return defaultRole
+	}
+	return role
+}
+
+// provisionSSOUser creates or updates the account of a user authenticated by
+// an identity provider. The role follows the group mapping on every login.
+// Names taken by local accounts, or by another provider, are refused so a
+// provider can never log in as the break-glass admin.
+func provisionSSOUser(username, source, role string) (*User, error) {
+	if !usernamePattern.MatchString(username) {
+		return nil, fmt.Errorf("username %q from %s is not a valid account name", username, source)
+	}
+	if role == "" {
+		return nil, fmt.Errorf("%s is in no group mapped to a role", username)
+	}
+
+	user, err := GetUser(username)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up user %s: %v", username, err)
+	}
+
+	if user == nil {
+		if err := CreateSSOUser(username, role, source); err != nil {
+			return nil, fmt.Errorf("failed to create user %s: %v", username, err)
+		}
+		LogInfo("👤 [USERS] Created %s user %s (%s)", source, username, role)
+		return GetUser(username)
+	}
+
+	if user.AuthSource != source {
+		return nil, fmt.Errorf("account %s already exists as a %s account", user.Username, user.AuthSource)
+	}
+	if user.Disabled {
+		return nil, fmt.Errorf("account %s is disabled", user.Username)
+	}
+	if user.Role != role {
+		if err := UpdateUser(user.Username, role, false); err != nil {
+			return nil, fmt.Errorf("failed to update role of %s: %v", user.Username, err)
+		}
+		LogInfo("👤 [USERS] Role of %s user %s changed from %s to %s by group mapping", source, user.Username, user.Role, role)
+		user.Role = role
+	}
+	return user, nil
+}
+
+// authenticatePassword checks a login form. Local accounts use their password
+// hash, other names are tried against LDAP when it is enabled. With
+// local_login set to admins and SSO enabled, only local admins may use a
+// local password.
+func authenticatePassword(config *Config, username, password string) (*User, error) {
+	user, err := GetUser(username)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up user %s: %v", username, err)
+	}
+
+	if user != nil && user.IsLocal() {
+		if user.Disabled || !checkPassword(password, user.PasswordHash) {
+			return nil, errInvalidLogin
+		}
+		if ssoEnabled(config) && config.Web.LocalLogin == LocalLoginAdmins && user.Role != RoleAdmin {
+			LogWarn("🔒 [AUTH] Refused local login of %s: only admins may log in locally while SSO is enabled", user.Username)
+			return nil, errInvalidLogin
+		}
+		return user, nil
+	}
+
+	if !config.Web.LDAP.Enabled || (user != nil && user.AuthSource != AuthSourceLDAP) {
+		return nil, errInvalidLogin
+	}
+
+	groups, err := ldapAuthenticate(&config.Web.LDAP, username, password)
+	if err != nil {
+		return nil, err
+	}
+	role := mapGroupsToRole(groups, config.Web.LDAP.GroupRoles, config.Web.LDAP.DefaultRole)
+	user, err = provisionSSOUser(username, AuthSourceLDAP, role)
+	if err != nil {
+		LogWarn("🔒 [AUTH] Refused LDAP login of %s: %v", username, err)
+		return nil, errInvalidLogin
+	}
+	return user, nil
+}
+
+// validateGroupRoles checks that every mapped role exists
+func validateGroupRoles(groupRoles map[string]string, defaultRole string) error {
+	for group, role := range groupRoles {
+		if strings.TrimSpace(group) == "" {
+			return fmt.Errorf("group name must not be empty")
+		}
+		if _, ok := roleLevels[role]; !ok {
+			return fmt.Errorf("invalid role %q for group %s", role, group)
+		}
+	}
+	if _, ok := roleLevels[defaultRole]; defaultRole != "" && !ok {
+		return fmt.Errorf("invalid default role %q", defaultRole)
+	}
+	return nil
+}
+
+// parseGroupRoles parses "group = role" lines from a form field. The last
+// "=" separates the role, so group DNs can be used.
+func parseGroupRoles(value string) (map[string]string, error) {
+	groupRoles := make(map[string]string)
+	for _, line := range strings.Split(value, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		separator := strings.LastIndex(line, "=")
+		if separator <= 0 {
+			return nil, fmt.Errorf("invalid line %q, expected group = role", line)
+		}
+		groupRoles[strings.TrimSpace(line[:separator])] = strings.TrimSpace(line[separator+1:])
+	}
+	return groupRoles, nil
+}
+
+// validateSSOConfig checks the OIDC, LDAP and local login settings
+func validateSSOConfig(web *WebConfig) error {
+	switch web.LocalLogin {
+	case "", LocalLoginAll, LocalLoginAdmins:
+	default:
+		return fmt.Errorf("local_login must be %s or %s", LocalLoginAll, LocalLoginAdmins)
+	}
+
+	if oidc := &web.OIDC; oidc.Enabled {
+		issuer, err := url.Parse(oidc.IssuerURL)
+		if err != nil || issuer.Host == "" || (issuer.Scheme != "https" && issuer.Scheme != "http") {
+			return fmt.Errorf("OIDC issuer URL must be an absolute http(s) URL")
+		}
+		if oidc.ClientID == "" {
+			return fmt.Errorf("OIDC client ID is required")
+		}
+		if oidc.RedirectURL != "" {
+			if redirect, err := url.Parse(oidc.RedirectURL); err != nil || redirect.Host == "" {
+				return fmt.Errorf("OIDC redirect URL must be an absolute URL")
+			}
+		}
+		if err := validateGroupRoles(oidc.GroupRoles, oidc.DefaultRole); err != nil {
+			return fmt.Errorf("OIDC: %v", err)
+		}
+	}
+
+	if ldap := &web.LDAP; ldap.Enabled {
+		server, err := url.Parse(ldap.URL)
+		if err != nil || server.Host == "" || (server.Scheme != "ldap" && server.Scheme != "ldaps") {
+			return fmt.Errorf("LDAP URL must look like ldap://host:389 or ldaps://host:636")
+		}
+		if ldap.StartTLS && server.Scheme == "ldaps" {
+			return fmt.Errorf("LDAP StartTLS cannot be combined with ldaps://")
+		}
+		if ldap.UserBaseDN == "" {
+			return fmt.Errorf("LDAP user base DN is required")
+		}
+		if _, err := parseLDAPFilter(ldapUserFilter(ldap, "user")); err != nil {
+			return fmt.Errorf("LDAP user filter: %v", err)
+		}
+		if !strings.Contains(ldap.UserFilter, "{username}") && ldap.UserFilter != "" {
+			return fmt.Errorf("LDAP user filter must contain {username}")
+		}
+		if ldap.GroupBaseDN != "" {
+			if _, err := parseLDAPFilter(ldapGroupFilter(ldap, "cn=user", "user")); err != nil {
+				return fmt.Errorf("LDAP group filter: %v", err)
+			}
+		}
+		if err := validateGroupRoles(ldap.GroupRoles, ldap.DefaultRole); err != nil {
+			return fmt.Errorf("LDAP: %v", err)
+		}
+	}
+
+	return nil
+}
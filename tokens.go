@@ -0,0 +1,428 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// API token kinds
+const (
+	TokenKindPersonal = "personal" // Acts as its owner, limited by the owner's role
+	TokenKindService  = "service"  // Not tied to a user, limited only by its scopes
+)
+
+// API token scopes
+const (
+	ScopeBackupRead    = "backup:read"    // Status, history, logs and health
+	ScopeBackupRun     = "backup:run"     // Start, stop, cancel, retry and resume backups and optimization
+	ScopeRestoreRun    = "restore:run"    // Download backup files to restore them
+	ScopeSettingsWrite = "settings:write" // Settings, users, tokens and deleting backups
+)
+
+// apiTokenScopes lists the valid scopes in display order
+var apiTokenScopes = []string{ScopeBackupRead, ScopeBackupRun, ScopeRestoreRun, ScopeSettingsWrite}
+
+// scopeRoles is the role a user needs to hold a personal token with the scope
+var scopeRoles = map[string]string{
+	ScopeBackupRead:    RoleViewer,
+	ScopeBackupRun:     RoleOperator,
+	ScopeRestoreRun:    RoleOperator,
+	ScopeSettingsWrite: RoleAdmin,
+}
+
+// endpointScopes overrides the scope derived from the endpoint's role.
+// Account and token management need settings:write so a leaked read-only
+// token cannot mint new tokens or change the owner's password or 2FA.
+var endpointScopes = map[string]string{
+	"/api/me/password":               ScopeSettingsWrite,
+	"/api/me/2fa/setup":              ScopeSettingsWrite,
+	"/api/me/2fa/enable":             ScopeSettingsWrite,
+	"/api/me/2fa/disable":            ScopeSettingsWrite,
+	"/api/me/2fa/recovery-codes":     ScopeSettingsWrite,
+	"/api/tokens/create":             ScopeSettingsWrite,
+	"/api/tokens/revoke":             ScopeSettingsWrite,
+	"/api/backup/download/":          ScopeRestoreRun,
+	"/api/backup/download-file":      ScopeRestoreRun,
+	"/api/backup/download-group-zip": ScopeRestoreRun,
+}
+
+// Prefix of generated token secrets, so leaked tokens are easy to recognise
+const apiTokenPrefix = "mbt_"
+
+// Longest accepted expiry for new tokens, in days
+const maxTokenExpiryDays = 3650
+
+// APIToken is a bearer token stored in the api_tokens table
+type APIToken struct {
+	ID         int      `json:"id"`
+	Name       string   `json:"name"`
+	Kind       string   `json:"kind"`
+	Username   string   `json:"username"` // Owner of a personal token
+	CreatedBy  string   `json:"created_by"`
+	TokenHash  string   `json:"-"`
+	Prefix     string   `json:"prefix"`
+	Scopes     []string `json:"scopes"`
+	CreatedAt  string   `json:"created_at"`
+	ExpiresAt  string   `json:"expires_at"`
+	LastUsedAt string   `json:"last_used_at"`
+	LastUsedIP string   `json:"last_used_ip"`
+	RevokedAt  string   `json:"revoked_at"`
+}
+
+// HasScope reports whether the token was granted the scope
+func (t *APIToken) HasScope(scope string) bool {
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// IsExpired reports whether the token's expiry time has passed
+func (t *APIToken) IsExpired() bool {
+	if t.ExpiresAt == "" {
+		return false
+	}
+	// The SQLite driver returns DATETIME columns as RFC 3339
+	expiresAt, err := time.Parse(time.RFC3339, t.ExpiresAt)
+	if err != nil {
+		expiresAt, err = time.Parse("2006-01-02 15:04:05", t.ExpiresAt)
+	}
+	return err != nil || time.Now().UTC().After(expiresAt)
+}
+
+// getEndpointScope returns the scope a token needs for a route pattern,
+// derived from the endpoint's minimum role unless overridden
+func getEndpointScope(pattern string) string {
+	if scope, ok := endpointScopes[pattern]; ok {
+		return scope
+	}
+	switch getEndpointRole(pattern) {
+	case RoleViewer:
+		return ScopeBackupRead
+	case RoleOperator:
+		return ScopeBackupRun
+	default:
+		return ScopeSettingsWrite
+	}
+}
+
+// parseTokenScopes splits a comma separated scope list, dropping blanks
+func parseTokenScopes(value string) []string {
+	scopes := []string{}
+	for _, scope := range strings.Split(value, ",") {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes
+}
+
+// validateTokenScopes checks that every scope is known and, when role is
+// set, that the role may hold it
+func validateTokenScopes(scopes []string, role string) error {
+	if len(scopes) == 0 {
+		return fmt.Errorf("select at least one scope")
+	}
+	for _, scope := range scopes {
+		requiredRole, ok := scopeRoles[scope]
+		if !ok {
+			return fmt.Errorf("unknown scope %q", scope)
+		}
+		if role != "" && roleLevels[role] < roleLevels[requiredRole] {
+			return fmt.Errorf("scope %s requires the %s role", scope, requiredRole)
+		}
+	}
+	return nil
+}
+
+// serviceTokenRole returns the role a service token acts with: the highest
+// role needed by any of its scopes
+func serviceTokenRole(scopes []string) string {
+	role := RoleViewer
+	for _, scope := range scopes {
+		if requiredRole := scopeRoles[scope]; roleLevels[requiredRole] > roleLevels[role] {
+			role = requiredRole
+		}
+	}
+	return role
+}
+
+// generateAPIToken returns a new random token secret
+func generateAPIToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return apiTokenPrefix + hex.EncodeToString(buf), nil
+}
+
+// bearerToken returns the token from an Authorization: Bearer header
+func bearerToken(r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+	if len(header) < 7 || !strings.EqualFold(header[:7], "Bearer ") {
+		return "", false
+	}
+	token := strings.TrimSpace(header[7:])
+	return token, token != ""
+}
+
+// clientIP returns the remote address of a request without the port
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// authenticateAPIToken resolves a bearer token to the token and the user it
+// acts as. Personal tokens act as their owner; service tokens act as a
+// synthetic user named after the token.
+func authenticateAPIToken(secret, remoteIP string) (*APIToken, *User, error) {
+	token, err := GetAPITokenByHash(hashToken(secret))
+	if err != nil {
+		LogError("❌ [AUTH] Failed to look up API token: %v", err)
+		return nil, nil, fmt.Errorf("failed to look up token")
+	}
+	if token == nil {
+		return nil, nil, fmt.Errorf("invalid token")
+	}
+	if token.RevokedAt != "" {
+		return nil, nil, fmt.Errorf("token %s has been revoked", token.Name)
+	}
+	if token.IsExpired() {
+		return nil, nil, fmt.Errorf("token %s has expired", token.Name)
+	}
+
+	var user *User
+	if token.Kind == TokenKindPersonal {
+		user, err = GetUser(token.Username)
+		if err != nil || user == nil || user.Disabled {
+			return nil, nil, fmt.Errorf("owner of token %s is not an active user", token.Name)
+		}
+	} else {
+		user = &User{Username: "token:" + token.Name, Role: serviceTokenRole(token.Scopes)}
+	}
+
+	if err := RecordAPITokenUse(token.ID, remoteIP); err != nil {
+		LogWarn("⚠️ [AUTH] Failed to record use of API token %s: %v", token.Name, err)
+	}
+	return token, user, nil
+}
+
+// requireToken authenticates a bearer token request and checks both the
+// endpoint's role and its scope. It returns nil after writing the error.
+func requireToken(w http.ResponseWriter, r *http.Request, secret string) *http.Request {
+	token, user, err := authenticateAPIToken(secret, clientIP(r))
+	if err != nil {
+		LogWarn("🔒 [AUTH] Rejected API token for %s %s from %s: %v", r.Method, r.URL.Path, clientIP(r), err)
+		w.Header().Set("WWW-Authenticate", `Bearer realm="mariadb-backup-tool"`)
+		if isAPIv1Request(r) {
+			writeAPIError(w, http.StatusUnauthorized, APIErrUnauthorized, "Unauthorized: "+err.Error())
+			return nil
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   "Unauthorized: " + err.Error(),
+		})
+		return nil
+	}
+
+	requiredRole := getEndpointRole(r.Pattern)
+	if !user.HasRole(requiredRole) {
+		denyAccess(w, r, user, requiredRole)
+		return nil
+	}
+
+	scope := getEndpointScope(r.Pattern)
+	if !token.HasScope(scope) {
+		LogWarn("🔒 [AUTH] Token %s denied %s %s, requires scope %s", token.Name, r.Method, r.URL.Path, scope)
+		if isAPIv1Request(r) {
+			writeAPIError(w, http.StatusForbidden, APIErrForbidden, fmt.Sprintf("Permission denied: requires the %s scope", scope))
+			return nil
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   fmt.Sprintf("Permission denied: requires the %s scope", scope),
+		})
+		return nil
+	}
+
+	return withCurrentUser(r, user)
+}
+
+// canManageToken reports whether the user may see and revoke the token
+func canManageToken(user *User, token *APIToken) bool {
+	if user.HasRole(RoleAdmin) {
+		return true
+	}
+	return token.Kind == TokenKindPersonal && strings.EqualFold(token.Username, user.Username)
+}
+
+// handleListAPITokens returns the caller's personal tokens, or all tokens for admins
+func handleListAPITokens(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user := currentUser(r)
+	username := user.Username
+	if user.HasRole(RoleAdmin) && r.URL.Query().Get("mine") != "1" {
+		username = ""
+	}
+
+	tokens, err := GetAPITokens(username)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   "Failed to get API tokens: " + err.Error(),
+		})
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"tokens":  tokens,
+		"scopes":  apiTokenScopes,
+	})
+}
+
+// handleCreateAPIToken creates a personal token for the caller or, for
+// admins, a service token. The secret is returned once and only its hash is stored.
+func handleCreateAPIToken(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user := currentUser(r)
+	name := strings.TrimSpace(r.FormValue("name"))
+	kind := r.FormValue("kind")
+	if kind == "" {
+		kind = TokenKindPersonal
+	}
+	scopes := parseTokenScopes(strings.Join(r.Form["scopes"], ","))
+
+	var validationErr error
+	expiresDays, err := strconv.Atoi(strings.TrimSpace(r.FormValue("expires_days")))
+	switch {
+	case name == "" || len(name) > 64:
+		validationErr = fmt.Errorf("token name is required (up to 64 characters)")
+	case err != nil || expiresDays < 0 || expiresDays > maxTokenExpiryDays:
+		validationErr = fmt.Errorf("expiry must be between 0 (never) and %d days", maxTokenExpiryDays)
+	case kind == TokenKindPersonal:
+		validationErr = validateTokenScopes(scopes, user.Role)
+	case kind == TokenKindService:
+		if !user.HasRole(RoleAdmin) {
+			validationErr = fmt.Errorf("only admins can create service tokens")
+		} else {
+			validationErr = validateTokenScopes(scopes, "")
+		}
+	default:
+		validationErr = fmt.Errorf("invalid token type %q (personal or service)", kind)
+	}
+	if validationErr != nil {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   validationErr.Error(),
+		})
+		return
+	}
+
+	secret, err := generateAPIToken()
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   "Failed to generate token: " + err.Error(),
+		})
+		return
+	}
+
+	token := &APIToken{
+		Name:      name,
+		Kind:      kind,
+		CreatedBy: user.Username,
+		TokenHash: hashToken(secret),
+		Prefix:    secret[:len(apiTokenPrefix)+8],
+		Scopes:    scopes,
+	}
+	if kind == TokenKindPersonal {
+		token.Username = user.Username
+	}
+	expiresAt, expiresLabel := "", "never"
+	if expiresDays > 0 {
+		expiresAt = time.Now().UTC().AddDate(0, 0, expiresDays).Format("2006-01-02 15:04:05")
+		expiresLabel = expiresAt + " UTC"
+	}
+
+	if _, err := CreateAPIToken(token, expiresAt); err != nil {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   "Failed to create token: " + err.Error(),
+		})
+		return
+	}
+
+	LogInfo("🔑 [TOKENS] %s created %s token %s (scopes: %s, expires: %s)",
+		user.Username, kind, name, strings.Join(scopes, ","), expiresLabel)
+	recordAudit(r, AuditTokenCreate, name, nil, map[string]interface{}{"kind": kind, "scopes": scopes, "expires": expiresLabel})
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": fmt.Sprintf("Token %s created. Copy it now, it is not shown again.", name),
+		"token":   secret,
+	})
+}
+
+// handleRevokeAPIToken revokes one of the caller's tokens, or any token for admins
+func handleRevokeAPIToken(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user := currentUser(r)
+	id, _ := strconv.Atoi(r.FormValue("id"))
+	token, err := GetAPIToken(id)
+	if err != nil || token == nil || !canManageToken(user, token) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   "Token not found",
+		})
+		return
+	}
+
+	if err := RevokeAPIToken(token.ID); err != nil {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   "Failed to revoke token: " + err.Error(),
+		})
+		return
+	}
+
+	LogInfo("🔑 [TOKENS] %s revoked %s token %s", user.Username, token.Kind, token.Name)
+	recordAudit(r, AuditTokenRevoke, token.Name, map[string]interface{}{"kind": token.Kind, "owner": token.Username}, nil)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": fmt.Sprintf("Token %s revoked", token.Name),
+	})
+}
@@ -0,0 +1,494 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TOTP parameters (RFC 6238), the defaults every authenticator app supports
+const (
+	totpPeriod = 30 // Seconds per time step
+	totpDigits = 6
+	totpSkew   = 1 // Steps accepted before and after the current one, for clock drift
+	totpIssuer = "MariaDB Backup Tool"
+)
+
+// Number of recovery codes issued when 2FA is enabled or the codes are regenerated
+const recoveryCodeCount = 10
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// generateTOTPSecret returns a new random 160-bit secret, base32 encoded
+func generateTOTPSecret() (string, error) {
+	buf := make([]byte, 20)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return totpEncoding.EncodeToString(buf), nil
+}
+
+// totpCode returns the code of a secret for a time step (HOTP, RFC 4226)
+func totpCode(secret string, step int64) (string, error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", fmt.Errorf("invalid TOTP secret: %v", err)
+	}
+
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	modulus := uint32(1)
+	for i := 0; i < totpDigits; i++ {
+		modulus *= 10
+	}
+	return fmt.Sprintf("%0*d", totpDigits, value%modulus), nil
+}
+
+// verifyTOTP checks a code against the steps around now and returns the
+// matching step
+func verifyTOTP(secret, code string, now time.Time) (int64, bool) {
+	if len(code) != totpDigits {
+		return 0, false
+	}
+	current := now.Unix() / totpPeriod
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		expected, err := totpCode(secret, step)
+		if err == nil && hmac.Equal([]byte(expected), []byte(code)) {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// totpURI returns the otpauth:// URI authenticator apps enroll from
+func totpURI(username, secret string) string {
+	label := url.PathEscape(totpIssuer + ":" + username)
+	return fmt.Sprintf("otpauth://totp/%s?secret=%s&issuer=%s&algorithm=SHA1&digits=%d&period=%d",
+		label, secret, url.PathEscape(totpIssuer), totpDigits, totpPeriod)
+}
+
+// normalizeRecoveryCode lowercases a recovery code and drops dashes and spaces
+func normalizeRecoveryCode(code string) string {
+	return strings.NewReplacer("-", "", " ", "").Replace(strings.ToLower(code))
+}
+
+// generateRecoveryCodes returns new one-time recovery codes and their hashes
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		buf := make([]byte, 5)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, nil, err
+		}
+		code := hex.EncodeToString(buf)
+		codes = append(codes, code[:5]+"-"+code[5:])
+		hashes = append(hashes, hashToken(code))
+	}
+	return codes, hashes, nil
+}
+
+// verifySecondFactor accepts a current TOTP code or an unused recovery code
+// of a user with 2FA enabled. Each TOTP code and recovery code works once.
+func verifySecondFactor(user *User, code string) (string, bool) {
+	code = strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+	if code == "" || !user.TOTPEnabled {
+		return "", false
+	}
+
+	if step, ok := verifyTOTP(user.TOTPSecret, code, time.Now()); ok {
+		fresh, err := RecordTOTPStep(user.Username, step)
+		if err != nil {
+			LogError("❌ [AUTH] Failed to record TOTP use of %s: %v", user.Username, err)
+			return "", false
+		}
+		return "authenticator code", fresh
+	}
+
+	consumed, err := ConsumeRecoveryCode(user.Username, hashToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		LogError("❌ [AUTH] Failed to check recovery code of %s: %v", user.Username, err)
+		return "", false
+	}
+	return "recovery code", consumed
+}
+
+// Pending logins whose password was correct and that still need the second
+// factor. They live in memory only and expire quickly.
+const (
+	twoFactorChallengeTTL    = 5 * time.Minute
+	twoFactorMaxAttempts     = 5
+	twoFactorChallengeCookie = "login_challenge"
+)
+
+type twoFactorChallenge struct {
+	Username string
+	Expires  time.Time
+	Attempts int
+}
+
+var (
+	twoFactorChallenges      = make(map[string]*twoFactorChallenge)
+	twoFactorChallengesMutex sync.Mutex
+)
+
+// startTwoFactorChallenge remembers a password-verified login and sets the
+// cookie that ties the second step to it
+func startTwoFactorChallenge(w http.ResponseWriter, r *http.Request, username string) error {
+	id, err := generateSecureToken(32)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	twoFactorChallengesMutex.Lock()
+	for key, challenge := range twoFactorChallenges {
+		if now.After(challenge.Expires) {
+			delete(twoFactorChallenges, key)
+		}
+	}
+	twoFactorChallenges[hashToken(id)] = &twoFactorChallenge{Username: username, Expires: now.Add(twoFactorChallengeTTL)}
+	twoFactorChallengesMutex.Unlock()
+
+	setTwoFactorCookie(w, r, id, int(twoFactorChallengeTTL.Seconds()))
+	return nil
+}
+
+func setTwoFactorCookie(w http.ResponseWriter, r *http.Request, value string, maxAge int) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     twoFactorChallengeCookie,
+		Value:    value,
+		Path:     "/login",
+		HttpOnly: true,
+		Secure:   isSecureRequest(r),
+		SameSite: http.SameSiteStrictMode,
+		MaxAge:   maxAge,
+	})
+}
+
+// lookupTwoFactorChallenge returns the pending login of the request and
+// counts the attempt. Challenges are dropped after twoFactorMaxAttempts.
+func lookupTwoFactorChallenge(r *http.Request) (string, *twoFactorChallenge) {
+	cookie, err := r.Cookie(twoFactorChallengeCookie)
+	if err != nil {
+		return "", nil
+	}
+	key := hashToken(cookie.Value)
+
+	twoFactorChallengesMutex.Lock()
+	defer twoFactorChallengesMutex.Unlock()
+
+	challenge := twoFactorChallenges[key]
+	if challenge == nil || time.Now().After(challenge.Expires) || challenge.Attempts >= twoFactorMaxAttempts {
+		delete(twoFactorChallenges, key)
+		return "", nil
+	}
+	challenge.Attempts++
+	return key, challenge
+}
+
+// endTwoFactorChallenge removes a pending login and its cookie
+func endTwoFactorChallenge(w http.ResponseWriter, r *http.Request, key string) {
+	twoFactorChallengesMutex.Lock()
+	delete(twoFactorChallenges, key)
+	twoFactorChallengesMutex.Unlock()
+	setTwoFactorCookie(w, r, "", -1)
+}
+
+// handleTwoFactorLogin checks the code of a pending login and starts the
+// session. Wrong codes count as failed logins for the lockout.
+func handleTwoFactorLogin(w http.ResponseWriter, r *http.Request, ip string) {
+	key, challenge := lookupTwoFactorChallenge(r)
+	if challenge == nil {
+		setTwoFactorCookie(w, r, "", -1)
+		renderLoginPage(w, "Your login has expired, sign in again", false)
+		return
+	}
+
+	if remaining := loginLimiter.lockedFor(ip, challenge.Username); remaining > 0 {
+		LogWarn("🔒 [AUTH] Refused login of %s from %s: locked out", challenge.Username, ip)
+		endTwoFactorChallenge(w, r, key)
+		w.WriteHeader(http.StatusTooManyRequests)
+		renderLoginPage(w, formatLockout(remaining), false)
+		return
+	}
+
+	user, err := GetUser(challenge.Username)
+	if err != nil || user == nil || user.Disabled {
+		endTwoFactorChallenge(w, r, key)
+		renderLoginPage(w, "Invalid username or password", false)
+		return
+	}
+
+	method, ok := verifySecondFactor(user, r.FormValue("otp"))
+	if !ok {
+		recordFailedLogin(r, user.Username, "invalid second factor")
+		renderLoginPage(w, "Invalid authenticator or recovery code", true)
+		return
+	}
+
+	endTwoFactorChallenge(w, r, key)
+	if method == "recovery code" {
+		LogWarn("🔐 [AUTH] %s used a recovery code, %d left", user.Username, user.RecoveryCodesLeft-1)
+	}
+	completeLogin(w, r, user, ip)
+}
+
+// handleTwoFactorSetup creates a new, not yet active, TOTP secret for the
+// current user and returns it with the enrollment QR code
+func handleTwoFactorSetup(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user := currentUser(r)
+	if !user.IsLocal() {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   fmt.Sprintf("Two-factor authentication of %s accounts is handled by the identity provider", user.AuthSource),
+		})
+		return
+	}
+	if !checkPassword(r.FormValue("current_password"), user.PasswordHash) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   "Current password is incorrect",
+		})
+		return
+	}
+	if user.TOTPEnabled {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   "Two-factor authentication is already enabled, disable it first to enroll a new device",
+		})
+		return
+	}
+
+	secret, err := generateTOTPSecret()
+	if err == nil {
+		err = SetUserTOTPSecret(user.Username, secret)
+	}
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   "Failed to create TOTP secret: " + err.Error(),
+		})
+		return
+	}
+
+	uri := totpURI(user.Username, secret)
+	qrSVG := ""
+	if qr, err := encodeQRCode(uri); err == nil {
+		qrSVG = qr.SVG()
+	} else {
+		LogWarn("⚠️ [USERS] Failed to render 2FA QR code for %s: %v", user.Username, err)
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"secret":  secret,
+		"uri":     uri,
+		"qr_svg":  qrSVG,
+	})
+}
+
+// handleTwoFactorEnable confirms enrollment with a code from the app,
+// turns 2FA on and returns the recovery codes once
+func handleTwoFactorEnable(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user := currentUser(r)
+	if user.TOTPEnabled || user.TOTPSecret == "" {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   "Start the two-factor setup first",
+		})
+		return
+	}
+
+	step, ok := verifyTOTP(user.TOTPSecret, strings.TrimSpace(r.FormValue("code")), time.Now())
+	if !ok {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   "Invalid code, check the time on your device and try again",
+		})
+		return
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err == nil {
+		err = EnableUserTOTP(user.Username, hashes, step)
+	}
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   "Failed to enable two-factor authentication: " + err.Error(),
+		})
+		return
+	}
+
+	LogInfo("🔐 [USERS] %s enabled two-factor authentication", user.Username)
+	recordAudit(r, AuditUser2FAEnable, user.Username, nil, nil)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":        true,
+		"message":        "Two-factor authentication enabled",
+		"recovery_codes": codes,
+	})
+}
+
+// handleTwoFactorDisable turns 2FA off after checking the password and a
+// current code or recovery code
+func handleTwoFactorDisable(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user := currentUser(r)
+	if !user.IsLocal() {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   fmt.Sprintf("Two-factor authentication of %s accounts is handled by the identity provider", user.AuthSource),
+		})
+		return
+	}
+	if !checkPassword(r.FormValue("current_password"), user.PasswordHash) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   "Current password is incorrect",
+		})
+		return
+	}
+	if user.TOTPEnabled {
+		if _, ok := verifySecondFactor(user, r.FormValue("code")); !ok {
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"success": false,
+				"error":   "Invalid authenticator or recovery code",
+			})
+			return
+		}
+	}
+
+	if err := DisableUserTOTP(user.Username); err != nil {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   "Failed to disable two-factor authentication: " + err.Error(),
+		})
+		return
+	}
+
+	LogInfo("🔐 [USERS] %s disabled two-factor authentication", user.Username)
+	recordAudit(r, AuditUser2FADisable, user.Username, nil, nil)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Two-factor authentication disabled",
+	})
+}
+
+// handleTwoFactorRecoveryCodes replaces the recovery codes of the current user
+func handleTwoFactorRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user := currentUser(r)
+	if !checkPassword(r.FormValue("current_password"), user.PasswordHash) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   "Current password is incorrect",
+		})
+		return
+	}
+	if !user.TOTPEnabled {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   "Two-factor authentication is not enabled",
+		})
+		return
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err == nil {
+		err = SetUserRecoveryCodes(user.Username, hashes)
+	}
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   "Failed to create recovery codes: " + err.Error(),
+		})
+		return
+	}
+
+	LogInfo("🔐 [USERS] %s generated new recovery codes", user.Username)
+	recordAudit(r, AuditUser2FARecovery, user.Username, nil, nil)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":        true,
+		"message":        "New recovery codes created, the old ones no longer work",
+		"recovery_codes": codes,
+	})
+}
+
+// handleResetUserTwoFactor lets an admin turn off 2FA of a user who lost their device
+func handleResetUserTwoFactor(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	admin := currentUser(r)
+	username := strings.TrimSpace(r.FormValue("username"))
+	user, err := GetUser(username)
+	if err != nil || user == nil {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   fmt.Sprintf("User %s not found", username),
+		})
+		return
+	}
+
+	if err := DisableUserTOTP(user.Username); err != nil {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   "Failed to reset two-factor authentication: " + err.Error(),
+		})
+		return
+	}
+
+	LogInfo("🔐 [USERS] %s reset two-factor authentication of %s", admin.Username, user.Username)
+	recordAudit(r, AuditUserReset2FA, user.Username, nil, nil)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": fmt.Sprintf("Two-factor authentication of %s reset", user.Username),
+	})
+}
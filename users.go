@@ -0,0 +1,504 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Web user roles, in increasing order of privilege
+const (
+	RoleViewer   = "viewer"   // Read-only access to the dashboard, history and logs
+	RoleOperator = "operator" // Viewer plus running, cancelling and downloading backups
+	RoleAdmin    = "admin"    // Everything, including settings and user management
+)
+
+var roleLevels = map[string]int{
+	RoleViewer:   1,
+	RoleOperator: 2,
+	RoleAdmin:    3,
+}
+
+// Where an account authenticates
+const (
+	AuthSourceLocal = "local" // Password hash in the users table
+	AuthSourceOIDC  = "oidc"
+	AuthSourceLDAP  = "ldap"
+)
+
+// Shortest password accepted for new or changed passwords
+const minPasswordLength = 8
+
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.@-]{0,63}$`)
+
+// User is a web interface account stored in the users table
+type User struct {
+	ID           int    `json:"id"`
+	Username     string `json:"username"`
+	PasswordHash string `json:"-"`
+	Role         string `json:"role"`
+	Disabled     bool   `json:"disabled"`
+	CreatedAt    string `json:"created_at"`
+	LastLoginAt  string `json:"last_login_at"`
+
+	TOTPSecret        string   `json:"-"`
+	TOTPEnabled       bool     `json:"totp_enabled"`
+	TOTPLastStep      int64    `json:"-"`
+	RecoveryCodes     []string `json:"-"` // SHA-256 hashes of the unused recovery codes
+	RecoveryCodesLeft int      `json:"recovery_codes_left"`
+
+	AuthSource string `json:"auth_source"` // local, or the identity provider that created the account
+}
+
+// IsLocal reports whether the user logs in with a password stored here
+func (u *User) IsLocal() bool {
+	return u.AuthSource == "" || u.AuthSource == AuthSourceLocal
+}
+
+// HasRole reports whether the user's role grants at least the given role
+func (u *User) HasRole(role string) bool {
+	return roleLevels[u.Role] >= roleLevels[role]
+}
+
+// endpointRoles is the minimum role per route pattern. Routes not listed
+// require admin, so new endpoints are locked down until classified.
+var endpointRoles = map[string]string{
+	"/dashboard":                     RoleViewer,
+	"/backup":                        RoleViewer,
+	"/users":                         RoleViewer, // Own account, the user list needs admin
+	"/api/me":                        RoleViewer,
+	"/api/me/password":               RoleViewer,
+	"/api/me/2fa/setup":              RoleViewer,
+	"/api/me/2fa/enable":             RoleViewer,
+	"/api/me/2fa/disable":            RoleViewer,
+	"/api/me/2fa/recovery-codes":     RoleViewer,
+	"/api/tokens":                    RoleViewer, // Own personal tokens, all tokens for admins
+	"/api/tokens/create":             RoleViewer,
+	"/api/tokens/revoke":             RoleViewer,
+	"/api/schedule/info":             RoleViewer,
+	"/api/schedule/status":           RoleViewer,
+	"/api/schedule/missed":           RoleViewer,
+	"/api/test-results":              RoleViewer,
+	"/api/system-metrics":            RoleViewer,
+	"/api/system-info":               RoleViewer,
+	"/api/databases":                 RoleViewer,
+	"/api/optimize/status":           RoleViewer,
+	"/api/backup/running":            RoleViewer,
+	"/api/backup/queue":              RoleViewer,
+	"/api/backup/health":             RoleViewer,
+	"/api/backup/recent-activity":    RoleViewer,
+	"/api/backup/jobs":               RoleViewer,
+	"/api/backup/history":            RoleViewer,
+	"/api/backup/database-groups/":   RoleViewer,
+	"/api/backup/timeline":           RoleViewer,
+	"/api/logging/status":            RoleViewer,
+	"/api/logs/stream":               RoleViewer,
+	"/api/logs/debug":                RoleViewer,
+	"/ws/jobs":                       RoleViewer,
+	"/ws/system":                     RoleViewer,
+	"/ws/logs":                       RoleViewer,
+	"/api/backup/start":              RoleOperator,
+	"/api/backup/stop":               RoleOperator,
+	"/api/backup/cancel":             RoleOperator,
+	"/api/backup/retry":              RoleOperator,
+	"/api/backup/resume":             RoleOperator,
+	"/api/optimize/start":            RoleOperator,
+	"/api/optimize/stop":             RoleOperator,
+	"/api/backup/download/":          RoleOperator,
+	"/api/backup/download-file":      RoleOperator,
+	"/api/backup/download-group-zip": RoleOperator,
+}
+
+// getEndpointRole returns the minimum role for a route pattern, admin when unlisted
+func getEndpointRole(pattern string) string {
+	if role, ok := endpointRoles[pattern]; ok {
+		return role
+	}
+	return RoleAdmin
+}
+
+type contextKey string
+
+const currentUserKey contextKey = "user"
+
+// currentUser returns the user authenticated by requireAuth, or nil
+func currentUser(r *http.Request) *User {
+	user, _ := r.Context().Value(currentUserKey).(*User)
+	return user
+}
+
+// currentUsername returns the name of the authenticated user for RequestedBy
+// fields, falling back to web_ui for requests without a user
+func currentUsername(r *http.Request) string {
+	if user := currentUser(r); user != nil {
+		return user.Username
+	}
+	return "web_ui"
+}
+
+// withCurrentUser attaches the authenticated user to the request context
+func withCurrentUser(r *http.Request, user *User) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), currentUserKey, user))
+}
+
+// denyAccess rejects a request from a user whose role is too low
+func denyAccess(w http.ResponseWriter, r *http.Request, user *User, requiredRole string) {
+	LogWarn("🔒 [AUTH] %s (%s) denied %s %s, requires %s", user.Username, user.Role, r.Method, r.URL.Path, requiredRole)
+
+	if isAPIv1Request(r) {
+		writeAPIError(w, http.StatusForbidden, APIErrForbidden, fmt.Sprintf("Permission denied: requires the %s role", requiredRole))
+		return
+	}
+	if strings.HasPrefix(r.URL.Path, "/api/") || strings.HasPrefix(r.URL.Path, "/ws/") {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   fmt.Sprintf("Permission denied: requires the %s role", requiredRole),
+		})
+		return
+	}
+
+	http.Error(w, fmt.Sprintf("Forbidden: requires the %s role", requiredRole), http.StatusForbidden)
+}
+
+// EnsureInitialAdmin seeds the users table with auth_user/auth_pass_hash from
+// the config as an admin when no user exists yet
+func EnsureInitialAdmin(config *Config) error {
+	count, err := CountUsers()
+	if err != nil {
+		return fmt.Errorf("failed to count users: %v", err)
+	}
+	if count > 0 {
+		return nil
+	}
+
+	username := config.Web.AuthUser
+	if username == "" {
+		username = "admin"
+	}
+	if err := CreateUser(username, config.Web.AuthPassHash, RoleAdmin); err != nil {
+		return fmt.Errorf("failed to create initial admin %s: %v", username, err)
+	}
+
+	LogInfo("👤 [USERS] Created initial admin %s from config", username)
+	return nil
+}
+
+// validateUserInput checks a username, role and (when set) a new password
+func validateUserInput(username, role, password string) error {
+	if !usernamePattern.MatchString(username) {
+		return fmt.Errorf("invalid username %q: use up to 64 letters, digits, '.', '_', '-' or '@'", username)
+	}
+	if _, ok := roleLevels[role]; !ok {
+		return fmt.Errorf("invalid role %q (admin, operator or viewer)", role)
+	}
+	if password != "" && len(password) < minPasswordLength {
+		return fmt.Errorf("password must be at least %d characters", minPasswordLength)
+	}
+	return nil
+}
+
+// hashPassword hashes a web user password with bcrypt
+func hashPassword(password string) (string, error) {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hashedPassword), nil
+}
+
+// isLastActiveAdmin reports whether the user is the only enabled admin left
+func isLastActiveAdmin(user *User) (bool, error) {
+	if user.Role != RoleAdmin || user.Disabled {
+		return false, nil
+	}
+	count, err := CountActiveAdmins()
+	if err != nil {
+		return false, err
+	}
+	return count <= 1, nil
+}
+
+// handleUsers renders the account page, with user management for admins
+func handleUsers(w http.ResponseWriter, r *http.Request) {
+	renderTemplate(w, "users.html", map[string]interface{}{
+		"Title": "Users - MariaDB Backup Tool",
+	})
+}
+
+// handleCurrentUser returns the logged in user
+func handleCurrentUser(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"user":    currentUser(r),
+	})
+}
+
+// handleChangeOwnPassword lets any user change their own password
+func handleChangeOwnPassword(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user := currentUser(r)
+	if !user.IsLocal() {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   fmt.Sprintf("The password of %s accounts is managed by the identity provider", user.AuthSource),
+		})
+		return
+	}
+	if !checkPassword(r.FormValue("current_password"), user.PasswordHash) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   "Current password is incorrect",
+		})
+		return
+	}
+
+	newPassword := r.FormValue("new_password")
+	if len(newPassword) < minPasswordLength {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   fmt.Sprintf("Password must be at least %d characters", minPasswordLength),
+		})
+		return
+	}
+
+	passwordHash, err := hashPassword(newPassword)
+	if err == nil {
+		err = SetUserPassword(user.Username, passwordHash)
+	}
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   "Failed to change password: " + err.Error(),
+		})
+		return
+	}
+
+	LogInfo("👤 [USERS] %s changed their password", user.Username)
+	recordAudit(r, AuditUserPassword, user.Username, nil, nil)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Password changed successfully",
+	})
+}
+
+// handleListUsers returns all web users
+func handleListUsers(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	users, err := GetUsers()
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   "Failed to get users: " + err.Error(),
+		})
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"users":   users,
+	})
+}
+
+// handleSaveUser creates a user, or updates the role, disabled state and
+// (when given) the password of an existing one
+func handleSaveUser(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	username := strings.TrimSpace(r.FormValue("username"))
+	role := r.FormValue("role")
+	password := r.FormValue("password")
+	disabled := r.FormValue("disabled") == "on"
+
+	if err := validateUserInput(username, role, password); err != nil {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	existing, err := GetUser(username)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   "Failed to look up user: " + err.Error(),
+		})
+		return
+	}
+
+	admin := currentUser(r)
+	if existing == nil {
+		if password == "" {
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"success": false,
+				"error":   "Password is required for new users",
+			})
+			return
+		}
+		passwordHash, err := hashPassword(password)
+		if err == nil {
+			err = CreateUser(username, passwordHash, role)
+		}
+		if err == nil && disabled {
+			err = UpdateUser(username, role, true)
+		}
+		if err != nil {
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"success": false,
+				"error":   "Failed to create user: " + err.Error(),
+			})
+			return
+		}
+
+		LogInfo("👤 [USERS] %s created user %s (%s)", admin.Username, username, role)
+		recordAudit(r, AuditUserCreate, username, nil, map[string]interface{}{"role": role, "disabled": disabled})
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"message": fmt.Sprintf("User %s created", username),
+		})
+		return
+	}
+
+	if password != "" && !existing.IsLocal() {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   fmt.Sprintf("%s is a %s account, its password is managed by the identity provider", existing.Username, existing.AuthSource),
+		})
+		return
+	}
+
+	if role != RoleAdmin || disabled {
+		lastAdmin, err := isLastActiveAdmin(existing)
+		if err != nil {
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"success": false,
+				"error":   "Failed to count admins: " + err.Error(),
+			})
+			return
+		}
+		if lastAdmin {
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"success": false,
+				"error":   "Cannot demote or disable the last admin",
+			})
+			return
+		}
+	}
+
+	err = UpdateUser(existing.Username, role, disabled)
+	if err == nil && password != "" {
+		var passwordHash string
+		passwordHash, err = hashPassword(password)
+		if err == nil {
+			err = SetUserPassword(existing.Username, passwordHash)
+		}
+	}
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   "Failed to update user: " + err.Error(),
+		})
+		return
+	}
+
+	if disabled || password != "" {
+		endUserSessions(existing.Username)
+	}
+
+	LogInfo("👤 [USERS] %s updated user %s (role: %s, disabled: %v, password changed: %v)",
+		admin.Username, existing.Username, role, disabled, password != "")
+	recordAudit(r, AuditUserUpdate, existing.Username,
+		map[string]interface{}{"role": existing.Role, "disabled": existing.Disabled},
+		map[string]interface{}{"role": role, "disabled": disabled, "password_changed": password != ""})
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": fmt.Sprintf("User %s updated", existing.Username),
+	})
+}
+
+// handleDeleteUser removes a user and ends their sessions
+func handleDeleteUser(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	admin := currentUser(r)
+	username := strings.TrimSpace(r.FormValue("username"))
+	if strings.EqualFold(username, admin.Username) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   "You cannot delete your own account",
+		})
+		return
+	}
+
+	user, err := GetUser(username)
+	if err != nil || user == nil {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   fmt.Sprintf("User %s not found", username),
+		})
+		return
+	}
+
+	lastAdmin, err := isLastActiveAdmin(user)
+	if err == nil && lastAdmin {
+		err = fmt.Errorf("cannot delete the last admin")
+	}
+	if err == nil {
+		err = DeleteUser(user.Username)
+	}
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   "Failed to delete user: " + err.Error(),
+		})
+		return
+	}
+
+	endUserSessions(user.Username)
+	if err := RevokeUserAPITokens(user.Username); err != nil {
+		LogWarn("⚠️ [USERS] Failed to revoke API tokens of %s: %v", user.Username, err)
+	}
+
+	LogInfo("👤 [USERS] %s deleted user %s", admin.Username, user.Username)
+	recordAudit(r, AuditUserDelete, user.Username, map[string]interface{}{"role": user.Role, "auth_source": user.AuthSource}, nil)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": fmt.Sprintf("User %s deleted", user.Username),
+	})
+}
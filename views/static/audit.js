@@ -0,0 +1,98 @@
+// MariaDB Backup Tool - Audit Log
+
+const AUDIT_PAGE_SIZE = 50;
+
+let auditEntries = [];
+let auditTotal = 0;
+
+function initAudit() {
+    document.getElementById('audit-filter-form').addEventListener('submit', function(event) {
+        event.preventDefault();
+        loadAudit(false);
+    });
+    document.getElementById('audit-clear-btn').addEventListener('click', function() {
+        document.getElementById('audit-filter-form').reset();
+        loadAudit(false);
+    });
+    document.getElementById('audit-more-btn').addEventListener('click', function() {
+        loadAudit(true);
+    });
+    document.getElementById('audit-export-csv-btn').addEventListener('click', function() {
+        exportAudit('csv');
+    });
+    document.getElementById('audit-export-json-btn').addEventListener('click', function() {
+        exportAudit('json');
+    });
+
+    loadAudit(false);
+}
+
+function auditFilterParams() {
+    const params = new URLSearchParams();
+    ['actor', 'action', 'target', 'outcome', 'from', 'to'].forEach(name => {
+        const value = document.getElementById(`audit_${name}`).value.trim();
+        if (value) params.set(name, value);
+    });
+    return params;
+}
+
+function loadAudit(append) {
+    const tbody = document.getElementById('audit-tbody');
+    const params = auditFilterParams();
+    params.set('limit', AUDIT_PAGE_SIZE);
+    params.set('offset', append ? auditEntries.length : 0);
+
+    fetch(`/api/audit?${params}`)
+        .then(response => response.json())
+        .then(data => {
+            if (!data.success) {
+                tbody.innerHTML = `<tr><td colspan="7" class="text-center text-error">Failed to load audit log: ${escapeHtml(data.error || '')}</td></tr>`;
+                return;
+            }
+            auditEntries = append ? auditEntries.concat(data.entries || []) : (data.entries || []);
+            auditTotal = data.total || 0;
+            displayAudit();
+        })
+        .catch(error => {
+            console.error('Error loading audit log:', error);
+            tbody.innerHTML = '<tr><td colspan="7" class="text-center text-error">Failed to load audit log</td></tr>';
+        });
+}
+
+function displayAudit() {
+    const tbody = document.getElementById('audit-tbody');
+    document.getElementById('audit-count').textContent = `(${auditEntries.length} of ${auditTotal})`;
+    document.getElementById('audit-more-btn').style.display = auditEntries.length < auditTotal ? '' : 'none';
+
+    if (auditEntries.length === 0) {
+        tbody.innerHTML = '<tr><td colspan="7" class="text-center text-muted">No audit entries</td></tr>';
+        return;
+    }
+
+    tbody.innerHTML = auditEntries.map(entry => `
+        <tr>
+            <td>${escapeHtml(formatDateTime(entry.created_at))}</td>
+            <td>${escapeHtml(entry.actor)}</td>
+            <td>${escapeHtml(entry.actor_ip || '')}</td>
+            <td><code>${escapeHtml(entry.action)}</code></td>
+            <td>${escapeHtml(entry.target || '')}</td>
+            <td><span class="status-badge ${entry.outcome === 'success' ? 'success' : 'error'}">${escapeHtml(entry.outcome)}</span></td>
+            <td>${formatAuditChanges(entry)}</td>
+        </tr>
+    `).join('');
+}
+
+// formatAuditChanges shows the before and after values, and any details
+function formatAuditChanges(entry) {
+    const parts = [];
+    if (entry.before) parts.push(`<div><small class="text-muted">Before:</small> <code>${escapeHtml(entry.before)}</code></div>`);
+    if (entry.after) parts.push(`<div><small class="text-muted">After:</small> <code>${escapeHtml(entry.after)}</code></div>`);
+    if (entry.details) parts.push(`<div><small class="text-muted">${escapeHtml(entry.details)}</small></div>`);
+    return parts.join('');
+}
+
+function exportAudit(format) {
+    const params = auditFilterParams();
+    params.set('format', format);
+    window.location.href = `/api/audit/export?${params}`;
+}
@@ -592,6 +592,26 @@ function stopOptimizeStatusCheck() {
 
 window.jobGroupStates = new Map();
 
+// Show jobs waiting in the job queue
+function displayQueuedJobs(queue) {
+    const container = document.getElementById('queued-jobs');
+    if (!container) return;
+
+    const pending = (queue && queue.pending) || [];
+    if (pending.length === 0) {
+        container.style.display = 'none';
+        container.innerHTML = '';
+        return;
+    }
+
+    container.style.display = 'block';
+    container.innerHTML = '<strong>⏸️ Queued (' + pending.length + '):</strong><br>' + pending.map(job =>
+        escapeHtml(job.job_id) + ' - ' + escapeHtml(job.type) + ', ' + job.databases.length + ' database(s)' +
+        (job.policy ? ', policy ' + escapeHtml(job.policy) : '') + ', requested by ' + escapeHtml(job.requested_by) +
+        ` <button class="btn btn-secondary btn-sm" onclick="cancelBackup('${escapeHtml(job.job_id)}')">✖ Cancel</button>`
+    ).join('<br>');
+}
+
 function displayRunningJobs(data) {
     const container = document.getElementById('running-jobs');
     if (!container) return;
@@ -1246,8 +1266,37 @@ function createProgressBar(progress) {
 }
 
 function getRunningJobActionButtons(job) {
-    // No action buttons for running jobs - they are managed centrally
-    return '';
+    if (job.status !== 'running' && job.status !== 'optimizing') {
+        return '';
+    }
+    return `<button class="btn btn-secondary btn-sm" onclick="cancelBackup('${escapeHtml(job.job_id)}', '${escapeHtml(job.database_name)}')">✖ Cancel</button>`;
+}
+
+// Cancel a whole backup job or, when dbName is given, one database of it
+function cancelBackup(jobId, dbName) {
+    const target = dbName ? `backup of ${dbName}` : `job ${jobId}`;
+    if (!confirm(`Cancel ${target}? The partial backup file will be removed.`)) {
+        return;
+    }
+
+    const params = new URLSearchParams({ job_id: jobId });
+    if (dbName) {
+        params.append('db', dbName);
+    }
+
+    fetch('/api/backup/cancel?' + params.toString(), { method: 'POST' })
+    .then(response => response.json())
+    .then(data => {
+        if (data.success) {
+            showToast(data.message || 'Backup cancelled', 'success');
+        } else {
+            showToast('Failed to cancel backup: ' + (data.error || 'Unknown error'), 'error');
+        }
+    })
+    .catch(error => {
+        console.error('Error cancelling backup:', error);
+        showToast('Error cancelling backup', 'error');
+    });
 }
 
 function updateJobSummary(data) {
@@ -1,5 +1,24 @@
 // MariaDB Backup Tool - Common JavaScript Functions
 
+// getCSRFToken returns the session's CSRF token from its cookie
+function getCSRFToken() {
+    const match = document.cookie.match(/(?:^|;\s*)csrf_token=([^;]*)/);
+    return match ? decodeURIComponent(match[1]) : '';
+}
+
+// Send the CSRF token with every state-changing request. The server rejects
+// POSTs from a logged in browser without it.
+const originalFetch = window.fetch.bind(window);
+window.fetch = function(resource, options = {}) {
+    const method = (options.method || 'GET').toUpperCase();
+    if (method !== 'GET' && method !== 'HEAD') {
+        const headers = new Headers(options.headers || {});
+        headers.set('X-CSRF-Token', getCSRFToken());
+        options = Object.assign({}, options, { headers: headers });
+    }
+    return originalFetch(resource, options);
+};
+
 document.addEventListener('DOMContentLoaded', function() {
     const navIcon = document.querySelector('.nav-icon');
     const navTitle = document.querySelector('.nav-brand h1');
@@ -17,8 +36,56 @@ document.addEventListener('DOMContentLoaded', function() {
             window.open('https://github.com/nhattuanbl/mariadb-backup-tool', '_blank');
         });
     }
+
+    // Plain form posts carry the CSRF token as a hidden field
+    document.querySelectorAll('form[method="POST"], form[method="post"]').forEach(form => {
+        if (form.querySelector('input[name="csrf_token"]')) return;
+        const input = document.createElement('input');
+        input.type = 'hidden';
+        input.name = 'csrf_token';
+        input.value = getCSRFToken();
+        form.appendChild(input);
+    });
+
+    if (document.querySelector('.nav-menu')) {
+        loadCurrentUser();
+    }
 });
 
+// Role levels, matching roleLevels in users.go
+const roleLevels = { viewer: 1, operator: 2, admin: 3 };
+let currentUser = null;
+
+// loadCurrentUser shows the logged in user and hides controls their role cannot use.
+// The server enforces the same permissions, this only tidies up the UI.
+function loadCurrentUser() {
+    fetch('/api/me')
+        .then(response => response.json())
+        .then(data => {
+            if (!data.success || !data.user) return;
+            currentUser = data.user;
+
+            const logoutLink = document.querySelector('.nav-link.logout');
+            if (logoutLink) {
+                logoutLink.textContent = `Logout (${currentUser.username})`;
+                logoutLink.title = `Signed in as ${currentUser.username} (${currentUser.role})`;
+            }
+            applyRoleVisibility(document);
+        })
+        .catch(error => console.error('Error loading current user:', error));
+}
+
+// applyRoleVisibility hides elements whose data-role is above the current user's role
+function applyRoleVisibility(root) {
+    if (!currentUser) return;
+    const level = roleLevels[currentUser.role] || 0;
+    root.querySelectorAll('[data-role]').forEach(element => {
+        if ((roleLevels[element.dataset.role] || 0) > level) {
+            element.classList.add('role-hidden');
+        }
+    });
+}
+
 function showToast(message, type = 'info', duration = null) {
     // Get or create toast container
     let container = document.getElementById('toast-container');
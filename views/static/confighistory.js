@@ -0,0 +1,163 @@
+// MariaDB Backup Tool - Configuration History
+
+const configHistoryPageSize = 20;
+let configSnapshots = [];
+let configHistoryOffset = 0;
+let configHistoryTotal = 0;
+
+function initConfigHistory() {
+    const section = document.getElementById('config-history');
+    if (!section) return;
+
+    document.getElementById('config-history-prev').addEventListener('click', () => {
+        loadConfigHistory(Math.max(configHistoryOffset - configHistoryPageSize, 0));
+    });
+    document.getElementById('config-history-next').addEventListener('click', () => {
+        loadConfigHistory(configHistoryOffset + configHistoryPageSize);
+    });
+    loadConfigHistory();
+}
+
+function loadConfigHistory(offset = 0) {
+    const tbody = document.getElementById('config-history-tbody');
+    if (!tbody) return;
+
+    fetch(`/api/settings/history?limit=${configHistoryPageSize}&offset=${offset}`)
+        .then(response => response.json())
+        .then(data => {
+            if (!data.success) {
+                tbody.innerHTML = `<tr><td colspan="6" class="text-center text-error">Failed to load configuration history: ${escapeHtml(data.error || '')}</td></tr>`;
+                return;
+            }
+            configSnapshots = data.snapshots || [];
+            configHistoryOffset = data.offset || 0;
+            configHistoryTotal = data.total || 0;
+            displayConfigHistory();
+        })
+        .catch(error => {
+            console.error('Error loading configuration history:', error);
+            tbody.innerHTML = '<tr><td colspan="6" class="text-center text-error">Error loading configuration history</td></tr>';
+        });
+}
+
+function configSourceLabel(snapshot) {
+    switch (snapshot.source) {
+        case 'save': return 'Saved';
+        case 'reset': return 'Reset to defaults';
+        case 'rollback': return `Rollback to #${snapshot.rollback_of}`;
+        case 'file': return 'Config file';
+        default: return snapshot.source;
+    }
+}
+
+function displayConfigHistory() {
+    const tbody = document.getElementById('config-history-tbody');
+
+    const pages = Math.max(Math.ceil(configHistoryTotal / configHistoryPageSize), 1);
+    const page = Math.floor(configHistoryOffset / configHistoryPageSize) + 1;
+    document.getElementById('config-history-page').textContent = `Page ${page} of ${pages}`;
+    document.getElementById('config-history-prev').disabled = configHistoryOffset === 0;
+    document.getElementById('config-history-next').disabled = configHistoryOffset + configHistoryPageSize >= configHistoryTotal;
+
+    if (configSnapshots.length === 0) {
+        tbody.innerHTML = '<tr><td colspan="6" class="text-center text-muted">No saved configurations yet</td></tr>';
+        return;
+    }
+
+    tbody.innerHTML = configSnapshots.map((snapshot, index) => {
+        const fields = snapshot.changed_fields || [];
+        let changes = '<span class="text-muted">No changes</span>';
+        if (fields.length > 0) {
+            const shown = fields.slice(0, 3).map(field => `<code>${escapeHtml(field)}</code>`).join(' ');
+            changes = fields.length > 3 ? `${shown} <small class="text-muted">+${fields.length - 3} more</small>` : shown;
+        }
+        const latest = configHistoryOffset === 0 && index === 0;
+        return `
+            <tr>
+                <td>#${snapshot.id}</td>
+                <td>${formatDateTime(snapshot.created_at)}</td>
+                <td>${escapeHtml(snapshot.author)}</td>
+                <td>${escapeHtml(configSourceLabel(snapshot))}${latest ? ' <span class="status-badge success">Current</span>' : ''}</td>
+                <td>${changes}</td>
+                <td>
+                    <button type="button" class="btn btn-sm btn-secondary" onclick="showConfigDiff(${snapshot.id}, 'previous')">Diff</button>
+                    ${latest ? '' : `<button type="button" class="btn btn-sm btn-secondary" onclick="showConfigDiff(${snapshot.id}, 'current')">Compare</button>
+                    <button type="button" class="btn btn-sm btn-danger" onclick="rollbackConfig(${snapshot.id})">Rollback</button>`}
+                </td>
+            </tr>
+        `;
+    }).join('');
+}
+
+function formatConfigValue(value) {
+    if (value === undefined || value === null) {
+        return '<span class="text-muted">(none)</span>';
+    }
+    return `<code>${escapeHtml(JSON.stringify(value))}</code>`;
+}
+
+function showConfigDiff(id, compare) {
+    const panel = document.getElementById('config-diff');
+    const title = document.getElementById('config-diff-title');
+    const tbody = document.getElementById('config-diff-tbody');
+
+    fetch(`/api/settings/history/diff?id=${id}&compare=${compare}`)
+        .then(response => response.json())
+        .then(data => {
+            if (!data.success) {
+                showToast('Failed to load configuration diff: ' + data.error, 'error');
+                return;
+            }
+
+            title.textContent = compare === 'current'
+                ? `Rolling back to #${id} changes`
+                : `Changes in #${id}`;
+            const changes = data.changes || [];
+            if (changes.length === 0) {
+                tbody.innerHTML = '<tr><td colspan="3" class="text-center text-muted">No field changes</td></tr>';
+            } else {
+                tbody.innerHTML = changes.map(change => `
+                    <tr>
+                        <td><code>${escapeHtml(change.field)}</code></td>
+                        <td>${formatConfigValue(change.before)}</td>
+                        <td>${formatConfigValue(change.after)}</td>
+                    </tr>
+                `).join('');
+            }
+            panel.style.display = 'block';
+            panel.scrollIntoView({ behavior: 'smooth', block: 'nearest' });
+        })
+        .catch(error => {
+            console.error('Error loading configuration diff:', error);
+            showToast('Error loading configuration diff', 'error');
+        });
+}
+
+function rollbackConfig(id) {
+    if (!confirm(`Roll back the configuration to #${id}? The scheduler is reloaded with the restored settings.`)) {
+        return;
+    }
+
+    const formData = new FormData();
+    formData.append('id', id);
+
+    fetch('/api/settings/history/rollback', {
+        method: 'POST',
+        body: formData
+    })
+    .then(response => response.json())
+    .then(data => {
+        if (data.success) {
+            showToast(data.message, 'success');
+            document.getElementById('config-diff').style.display = 'none';
+            loadSettings();
+            loadConfigHistory();
+        } else {
+            showToast('Failed to roll back configuration: ' + data.error, 'error');
+        }
+    })
+    .catch(error => {
+        console.error('Error rolling back configuration:', error);
+        showToast('Error rolling back configuration', 'error');
+    });
+}
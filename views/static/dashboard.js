@@ -35,6 +35,9 @@ function loadDashboardMetrics() {
     
     // Load machine disk usage
     loadMachineDiskUsage();
+
+    // Load per-database RPO health
+    loadBackupHealth();
 }
 
 function loadDatabaseCount() {
@@ -128,8 +131,6 @@ function loadBackupSchedule() {
         .then(data => {
             if (data.success && data.schedule) {
                 const schedule = data.schedule;
-                const intervalHours = schedule.interval_hours || 24;
-                const startTime = schedule.start_time || "09:00";
                 const defaultMode = schedule.default_mode || "auto";
                 const lastBackupTime = schedule.last_backup_time;
                 const nextBackupTime = schedule.next_backup_time;
@@ -151,7 +152,13 @@ function loadBackupSchedule() {
                     document.getElementById('next-run-time').textContent = 'Disabled';
                 } else if (nextBackupTime && nextBackupTime !== 'Disabled') {
                     const nextRunTime = formatNextRunTime(nextBackupTime);
-                    document.getElementById('next-run-time').textContent = nextRunTime;
+                    const nextRunElement = document.getElementById('next-run-time');
+                    nextRunElement.textContent = nextRunTime;
+                    // Show the cron expression and upcoming runs on hover
+                    nextRunElement.title = (schedule.policies || [])
+                        .filter(policy => policy.enabled)
+                        .map(policy => policy.name + ' (' + policy.schedule + ', ' + policy.mode + '):\n  ' + (policy.next_runs || []).join('\n  '))
+                        .join('\n');
                 } else {
                     document.getElementById('next-run-time').textContent = 'Error calculating';
                 }
@@ -181,8 +188,8 @@ function loadSchedulerStatus() {
                 const statusText = document.getElementById('scheduler-status-text');
                 
                 if (indicator && statusText) {
-                    // Check if scheduler is disabled (interval = 0)
-                    if (status.config && status.config.interval_hours === 0) {
+                    // Check if scheduler is disabled (no policy has a schedule)
+                    if (status.config && status.config.active_policies === 0) {
                         indicator.className = 'status-indicator stopped';
                         statusText.textContent = 'Disabled';
                     } else if (status.running) {
@@ -385,6 +392,73 @@ function loadMachineDiskUsage() {
         });
 }
 
+const backupHealthBadges = {
+    breached: ['error', 'Breached'],
+    never: ['error', 'Never'],
+    warning: ['warning', 'At Risk'],
+    ok: ['success', 'OK'],
+    no_rpo: ['unknown', 'No RPO']
+};
+
+function loadBackupHealth() {
+    const tbody = document.getElementById('backup-health-tbody');
+    if (!tbody) return;
+
+    fetch('/api/backup/health')
+        .then(response => response.json())
+        .then(data => {
+            if (!data.success) {
+                tbody.innerHTML = `<tr><td colspan="6" class="text-center text-error">Failed to load backup health: ${escapeHtml(data.error || '')}</td></tr>`;
+                return;
+            }
+            displayBackupHealth(data.databases || [], data.summary || {});
+        })
+        .catch(error => {
+            console.error('Error loading backup health:', error);
+            tbody.innerHTML = '<tr><td colspan="6" class="text-center text-error">Error loading backup health</td></tr>';
+        });
+}
+
+function displayBackupHealth(databases, summary) {
+    const tbody = document.getElementById('backup-health-tbody');
+    const summaryElement = document.getElementById('backup-health-summary');
+
+    if (summaryElement) {
+        const breached = (summary.breached || 0) + (summary.never || 0);
+        summaryElement.textContent = `${breached} breached · ${summary.warning || 0} at risk · ${summary.ok || 0} ok`;
+    }
+
+    if (databases.length === 0) {
+        tbody.innerHTML = '<tr><td colspan="6" class="text-center text-muted">No databases selected for backup</td></tr>';
+        return;
+    }
+
+    tbody.innerHTML = databases.map(entry => {
+        const [badgeClass, badgeText] = backupHealthBadges[entry.status] || ['unknown', entry.status];
+        const lastSuccess = entry.last_success
+            ? `${escapeHtml(entry.last_success)} <span class="text-muted">(${escapeHtml(entry.last_success_type)})</span>`
+            : '<span class="text-muted">Never</span>';
+        const age = entry.last_success ? formatDurationFromMs(entry.age_seconds * 1000) : '-';
+        const rpo = entry.rpo_hours > 0
+            ? `${entry.rpo_hours}h <span class="text-muted">(${escapeHtml(entry.rpo_source)})</span>`
+            : '<span class="text-muted">-</span>';
+        const lastAttempt = entry.last_status
+            ? escapeHtml(entry.last_status) + (entry.last_error ? `<span class="backup-health-error" title="${escapeHtml(entry.last_error)}">${escapeHtml(entry.last_error)}</span>` : '')
+            : '<span class="text-muted">-</span>';
+
+        return `
+            <tr>
+                <td>${escapeHtml(entry.database)}</td>
+                <td><span class="status-badge ${badgeClass}">${badgeText}</span></td>
+                <td>${lastSuccess}</td>
+                <td>${age}</td>
+                <td>${rpo}</td>
+                <td>${lastAttempt}</td>
+            </tr>
+        `;
+    }).join('');
+}
+
 let recentActivityPagination = {
     currentPage: 1,
     totalPages: 1,
@@ -452,6 +526,7 @@ function displayRecentActivity(data) {
     sortedSummaries.forEach(summary => { // Remove hardcoded slice(0, 5)
         const isRunning = summary.state === 'running';
         const isCompleted = summary.state === 'completed';
+        const isInterrupted = summary.state === 'interrupted';
         
         let statusText = '';
         let statusClass = '';
@@ -495,6 +570,10 @@ function displayRecentActivity(data) {
             } else {
                 timeInfo = `Completed: ${formatTime(summary.created_at)}`;
             }
+        } else if (isInterrupted) {
+            statusText = summary.resumed_job_id ? `Interrupted (resumed as #${summary.resumed_job_id})` : 'Interrupted';
+            statusClass = 'completed-warning';
+            timeInfo = `Interrupted: ${formatTime(summary.completed_at || summary.created_at)}`;
         } else {
             statusText = 'Unknown';
             statusClass = 'unknown';
@@ -533,7 +612,7 @@ function displayRecentActivity(data) {
                         <span class="activity-job-id">#${summary.job_id}</span>
                         <span class="activity-timestamp">${formattedTimestamp}</span>
                         <span class="activity-status">${statusText}</span>
-                        <span class="activity-mode-icon" title="${summary.backup_mode}">${modeIcon}</span>
+                        <span class="activity-mode-icon" title="${summary.backup_mode}${summary.policy ? ' (policy: ' + summary.policy + ')' : ''}${summary.requested_by ? ' by ' + escapeHtml(summary.requested_by) : ''}">${modeIcon}</span>
                     </div>
                     <div class="activity-time">${timeInfo}</div>
                 </div>
@@ -544,7 +623,8 @@ function displayRecentActivity(data) {
                         <span class="summary-stat failed clickable-stat" onclick="event.stopPropagation(); navigateToBackupWithFilter('${summary.job_id}', 'failed')">❌ ${summary.total_failed} failed</span>
                         ${pendingCount > 0 ? `<span class="summary-stat warning">⏸️ ${pendingCount} pending</span>` : ''}
                         <span class="summary-stat success">📈 ${successRate}% success rate</span>
-                        ${summary.total_failed > 0 && summary.state === 'completed' ? `<button class="btn btn-sm btn-warning retry-btn" onclick="event.stopPropagation(); retryFailedBackups('${summary.job_id}')" title="Retry failed databases">🔄 Retry</button>` : ''}
+                        ${summary.total_failed > 0 && summary.state === 'completed' ? `<button class="btn btn-sm btn-warning retry-btn" data-role="operator" onclick="event.stopPropagation(); retryFailedBackups('${summary.job_id}')" title="Retry failed databases">🔄 Retry</button>` : ''}
+                        ${isInterrupted && !summary.resumed_job_id ? `<button class="btn btn-sm btn-warning resume-btn" data-role="operator" onclick="event.stopPropagation(); resumeInterruptedBackup('${summary.job_id}')" title="Back up the databases that did not finish">🔁 Resume</button>` : ''}
                     </div>
                     <div class="summary-size">
                         <span class="size-label">Total Size:</span>
@@ -560,6 +640,7 @@ function displayRecentActivity(data) {
     });
     
     recentActivityElement.innerHTML = activityHTML;
+    applyRoleVisibility(recentActivityElement);
 }
 
 function formatJobIdTimestamp(jobId) {
@@ -747,6 +828,51 @@ function clearBackupHistory() {
     });
 }
 
+function resumeInterruptedBackup(jobId) {
+    if (!confirm('Resume this interrupted backup? A new job will back up the databases that did not finish.')) {
+        return;
+    }
+
+    const resumeButtons = document.querySelectorAll(`.resume-btn[onclick*="'${jobId}'"]`);
+    resumeButtons.forEach(btn => {
+        btn.disabled = true;
+        btn.innerHTML = '⏳ Resuming...';
+    });
+
+    fetch('/api/backup/resume', {
+        method: 'POST',
+        headers: {
+            'Content-Type': 'application/json',
+        },
+        body: JSON.stringify({
+            job_id: jobId
+        })
+    })
+    .then(response => response.json())
+    .then(data => {
+        if (data.success) {
+            showToast(data.message || 'Backup resumed', 'success');
+            setTimeout(() => {
+                loadRecentActivity(recentActivityPagination.currentPage);
+            }, 2000);
+        } else {
+            showToast('Failed to resume backup: ' + (data.error || 'Unknown error'), 'error');
+            resumeButtons.forEach(btn => {
+                btn.disabled = false;
+                btn.innerHTML = '🔁 Resume';
+            });
+        }
+    })
+    .catch(error => {
+        console.error('Error resuming backup:', error);
+        showToast('Error resuming backup', 'error');
+        resumeButtons.forEach(btn => {
+            btn.disabled = false;
+            btn.innerHTML = '🔁 Resume';
+        });
+    });
+}
+
 function retryFailedBackups(jobId) {
     // Show confirmation dialog
     if (!confirm('Are you sure you want to retry all failed databases for this backup? This will update the existing backup record.')) {
@@ -390,13 +390,13 @@ function displayBackupGroups(groups, databaseName, pagination, totalGroups) {
                     </div>
                     <div class="group-controls">
                         <span class="backup-filename" title="${backupPath}">${fileName}</span>
-                        <button class="btn btn-sm btn-primary download-btn" data-file-path="${backupPath}" data-file-name="${fileName}" title="Download backup file">
+                        <button class="btn btn-sm btn-primary download-btn" data-role="operator" data-file-path="${backupPath}" data-file-name="${fileName}" title="Download backup file">
                             ⬇️ Download
                         </button>
-                        <button class="btn btn-sm btn-success zip-btn" data-group-index="${index}" title="Download full backup with all incremental backups as ZIP">
+                        <button class="btn btn-sm btn-success zip-btn" data-role="operator" data-group-index="${index}" title="Download full backup with all incremental backups as ZIP">
                             📦 ZIP
                         </button>
-                        <button class="btn btn-sm btn-danger delete-btn" data-group-index="${index}" title="Delete full backup and all incremental backups">
+                        <button class="btn btn-sm btn-danger delete-btn" data-role="admin" data-group-index="${index}" title="Delete full backup and all incremental backups">
                             🗑️ Delete
                         </button>
                         <span class="group-toggle" id="toggle-${index}">▼</span>
@@ -430,7 +430,7 @@ function displayBackupGroups(groups, databaseName, pagination, totalGroups) {
                             </div>
                             <div class="backup-controls">
                                 <span class="backup-filename" title="${incBackupPath}">${incFileName}</span>
-                                <button class="btn btn-sm btn-primary download-btn" data-file-path="${incBackupPath}" data-file-name="${incFileName}" title="Download backup file">
+                                <button class="btn btn-sm btn-primary download-btn" data-role="operator" data-file-path="${incBackupPath}" data-file-name="${incFileName}" title="Download backup file">
                                     ⬇️ Download
                                 </button>
                             </div>
@@ -455,6 +455,7 @@ function displayBackupGroups(groups, databaseName, pagination, totalGroups) {
     html += `</div>`;
     
     content.innerHTML = html;
+    applyRoleVisibility(content);
     
     // Add event listeners for download buttons
     addDownloadButtonListeners();
@@ -7,6 +7,10 @@ function loadSettings() {
         .then(data => {
             if (data.success) {
                 populateForm(data.config);
+                showFieldErrors(data.fields || {});
+                if (data.fields) {
+                    showToast('The config file has invalid settings, correct the highlighted fields and save', 'error');
+                }
                 
                 // Update test results if available
                 if (data.test_results) {
@@ -110,6 +114,10 @@ function populateForm(config) {
     if (dbUsernameElement) dbUsernameElement.value = config.database.username || '';
     if (dbPasswordElement) dbPasswordElement.value = config.database.password || '';
     if (dbSocketElement) dbSocketElement.value = config.database.socket || '';
+    ['ssl_mode', 'ssl_ca', 'ssl_cert', 'ssl_key'].forEach(field => {
+        const element = document.getElementById('db_' + field);
+        if (element) element.value = config.database[field] || '';
+    });
     // binlog_path is now read-only and populated from database, not from config
     if (binaryDumpElement) binaryDumpElement.value = config.database.binary_dump || '';
     if (binaryCheckElement) binaryCheckElement.value = config.database.binary_check || '';
@@ -120,8 +128,7 @@ function populateForm(config) {
     const retentionBackupsElement = document.getElementById('retention_backups');
     const parallelElement = document.getElementById('parallel');
     const fullBackupIntervalElement = document.getElementById('full_backup_interval');
-    const backupIntervalHoursElement = document.getElementById('backup_interval_hours');
-    const backupStartTimeElement = document.getElementById('backup_start_time');
+    const backupScheduleElement = document.getElementById('backup_schedule');
     const compressionLevelElement = document.getElementById('compression_level');
     const niceLevelElement = document.getElementById('nice_level');
     const defaultBackupModeElement = document.getElementById('default_backup_mode');
@@ -133,17 +140,45 @@ function populateForm(config) {
     const mariadbCheckOptionsElement = document.getElementById('mariadb_check_options');
     const mariadbBinlogOptionsElement = document.getElementById('mariadb_binlog_options');
     const ignoreDbsElement = document.getElementById('ignore_dbs');
+    const includeDbsElement = document.getElementById('include_dbs');
 
     if (backupDirElement) backupDirElement.value = config.backup.backup_dir || '';
     if (retentionBackupsElement) retentionBackupsElement.value = config.backup.retention_backups || '';
     if (parallelElement) parallelElement.value = config.backup.parallel || '';
+    const maxConcurrentJobsElement = document.getElementById('max_concurrent_jobs');
+    if (maxConcurrentJobsElement) maxConcurrentJobsElement.value = config.backup.max_concurrent_jobs || 1;
+    const scheduleConflictElement = document.getElementById('schedule_conflict');
+    if (scheduleConflictElement) scheduleConflictElement.value = config.backup.schedule_conflict || 'skip';
     if (fullBackupIntervalElement) fullBackupIntervalElement.value = config.backup.full_backup_interval || '';
-    if (backupIntervalHoursElement) backupIntervalHoursElement.value = config.backup.backup_interval_hours || '';
-    if (backupStartTimeElement) backupStartTimeElement.value = config.backup.backup_start_time || '';
+    if (backupScheduleElement) backupScheduleElement.value = config.backup.backup_schedule || '';
     if (compressionLevelElement) compressionLevelElement.value = config.backup.compression_level || '';
     if (niceLevelElement) niceLevelElement.value = config.backup.nice_level || '';
     if (defaultBackupModeElement) defaultBackupModeElement.value = config.backup.default_backup_mode || '';
+    const missedRunPolicyElement = document.getElementById('missed_run_policy');
+    if (missedRunPolicyElement) missedRunPolicyElement.value = config.backup.missed_run_policy || 'run_once';
     if (optimizeTablesElement) optimizeTablesElement.checked = config.backup.optimize_tables || false;
+    const gfs = config.backup.gfs_retention || {};
+    ['daily', 'weekly', 'monthly', 'yearly'].forEach(period => {
+        const element = document.getElementById('gfs_' + period);
+        if (element) element.value = gfs[period] || 0;
+    });
+    const gfsEnabledElement = document.getElementById('gfs_enabled');
+    if (gfsEnabledElement) gfsEnabledElement.checked = gfs.enabled || false;
+    ['keep_last_fulls', 'max_database_backup_mb', 'max_total_backup_mb', 'min_free_space_mb', 'compression_ratio', 'shutdown_timeout', 'rpo_hours'].forEach(field => {
+        const element = document.getElementById(field);
+        if (element) element.value = config.backup[field] || 0;
+    });
+    const databaseRPOElement = document.getElementById('database_rpo');
+    if (databaseRPOElement) {
+        const databaseRPO = config.backup.database_rpo || {};
+        databaseRPOElement.value = Object.keys(databaseRPO).sort().map(pattern => `${pattern} = ${databaseRPO[pattern]}`).join('\n');
+    }
+    const lowSpaceActionElement = document.getElementById('low_space_action');
+    if (lowSpaceActionElement) lowSpaceActionElement.value = config.backup.low_space_action || 'refuse';
+    const orphanTempActionElement = document.getElementById('orphan_temp_action');
+    if (orphanTempActionElement) orphanTempActionElement.value = config.backup.orphan_temp_action || 'delete';
+    const resumeInterruptedElement = document.getElementById('resume_interrupted');
+    if (resumeInterruptedElement) resumeInterruptedElement.checked = config.backup.resume_interrupted || false;
     if (maxMemoryThresholdElement) maxMemoryThresholdElement.value = config.backup.max_memory_threshold || '';
     if (maxMemoryPerProcessElement) maxMemoryPerProcessElement.value = config.backup.max_memory_per_process || '';
     if (createTableInfoElement) createTableInfoElement.checked = config.backup.create_table_info || false;
@@ -151,19 +186,20 @@ function populateForm(config) {
     if (mariadbCheckOptionsElement) mariadbCheckOptionsElement.value = config.backup.mariadb_check_options || '';
     if (mariadbBinlogOptionsElement) mariadbBinlogOptionsElement.value = config.backup.mariadb_binlog_options || '';
     if (ignoreDbsElement) ignoreDbsElement.value = (config.backup.ignore_dbs || []).join('\n');
+    if (includeDbsElement) includeDbsElement.value = (config.backup.include_dbs || []).join('\n');
 
     // Web settings
     const webPortElement = document.getElementById('web_port');
-    const authUserElement = document.getElementById('auth_user');
     const sslEnabledElement = document.getElementById('ssl_enabled');
     const sslCertFileElement = document.getElementById('ssl_cert_file');
     const sslKeyFileElement = document.getElementById('ssl_key_file');
 
     if (webPortElement) webPortElement.value = config.web.port || '';
-    if (authUserElement) authUserElement.value = config.web.auth_user || '';
     if (sslEnabledElement) sslEnabledElement.checked = config.web.ssl_enabled || false;
     if (sslCertFileElement) sslCertFileElement.value = config.web.ssl_cert_file || '';
     if (sslKeyFileElement) sslKeyFileElement.value = config.web.ssl_key_file || '';
+    const encryptSecretsElement = document.getElementById('encrypt_secrets');
+    if (encryptSecretsElement) encryptSecretsElement.checked = (config.secrets || {}).encrypt_at_rest || false;
 
     // Logging settings
     const logDirElement = document.getElementById('log_dir');
@@ -176,6 +212,49 @@ function populateForm(config) {
     const slackWebhookElement = document.getElementById('slack_webhook');
     if (slackWebhookElement) slackWebhookElement.value = config.notification.slack_webhook_url || '';
 
+    const webhook = config.notification.webhook || {};
+    const email = config.notification.email || {};
+    const teams = config.notification.teams || {};
+    const discord = config.notification.discord || {};
+    const telegram = config.notification.telegram || {};
+    const notificationValues = {
+        webhook_enabled: webhook.enabled || false,
+        webhook_url: webhook.url || '',
+        webhook_headers: Object.entries(webhook.headers || {}).map(([name, value]) => name + ': ' + value).join('\n'),
+        webhook_body_template: webhook.body_template || '',
+        email_enabled: email.enabled || false,
+        email_smtp_host: email.smtp_host || '',
+        email_smtp_port: email.smtp_port || '',
+        email_tls_mode: email.tls_mode || 'starttls',
+        email_username: email.username || '',
+        email_password: email.password || '',
+        email_from: email.from || '',
+        email_to: (email.to || []).join(', '),
+        quiet_hours: config.notification.quiet_hours || '',
+        dedup_minutes: config.notification.dedup_minutes || '',
+        digest_time: config.notification.digest_time || '',
+        teams_enabled: teams.enabled || false,
+        teams_webhook_url: teams.webhook_url || '',
+        discord_enabled: discord.enabled || false,
+        discord_webhook_url: discord.webhook_url || '',
+        telegram_enabled: telegram.enabled || false,
+        telegram_bot_token: telegram.bot_token || '',
+        telegram_chat_id: telegram.chat_id || ''
+    };
+    Object.entries(notificationValues).forEach(([id, value]) => {
+        const element = document.getElementById(id);
+        if (!element) return;
+        if (element.type === 'checkbox') {
+            element.checked = value;
+        } else {
+            element.value = value;
+        }
+    });
+
+    // Backup policies
+    renderPolicies(config.policies || []);
+    renderNotificationRules(config.notification.rules || []);
+
     // Update mysqldump options after populating form
     updateMysqldumpOptions();
 }
@@ -199,6 +278,10 @@ function saveSettings() {
     if (dbUsernameElement) formData.append('db_username', dbUsernameElement.value);
     if (dbPasswordElement) formData.append('db_password', dbPasswordElement.value);
     if (dbSocketElement) formData.append('db_socket', dbSocketElement.value);
+    ['db_ssl_mode', 'db_ssl_ca', 'db_ssl_cert', 'db_ssl_key'].forEach(id => {
+        const element = document.getElementById(id);
+        if (element) formData.append(id, element.value);
+    });
     // binlog_path is now read-only and not saved to config
     if (binaryDumpElement) formData.append('binary_dump', binaryDumpElement.value);
     if (binaryCheckElement) formData.append('binary_check', binaryCheckElement.value);
@@ -209,8 +292,7 @@ function saveSettings() {
     const retentionBackupsElement = document.getElementById('retention_backups');
     const parallelElement = document.getElementById('parallel');
     const fullBackupIntervalElement = document.getElementById('full_backup_interval');
-    const backupIntervalHoursElement = document.getElementById('backup_interval_hours');
-    const backupStartTimeElement = document.getElementById('backup_start_time');
+    const backupScheduleElement = document.getElementById('backup_schedule');
     const compressionLevelElement = document.getElementById('compression_level');
     const niceLevelElement = document.getElementById('nice_level');
     const defaultBackupModeElement = document.getElementById('default_backup_mode');
@@ -222,17 +304,30 @@ function saveSettings() {
     const mariadbCheckOptionsElement = document.getElementById('mariadb_check_options');
     const mariadbBinlogOptionsElement = document.getElementById('mariadb_binlog_options');
     const ignoreDbsElement = document.getElementById('ignore_dbs');
+    const includeDbsElement = document.getElementById('include_dbs');
 
     if (backupDirElement) formData.append('backup_dir', backupDirElement.value);
     if (retentionBackupsElement) formData.append('retention_backups', retentionBackupsElement.value);
     if (parallelElement) formData.append('parallel', parallelElement.value);
+    const maxConcurrentJobsElement = document.getElementById('max_concurrent_jobs');
+    if (maxConcurrentJobsElement) formData.append('max_concurrent_jobs', maxConcurrentJobsElement.value);
+    const scheduleConflictElement = document.getElementById('schedule_conflict');
+    if (scheduleConflictElement) formData.append('schedule_conflict', scheduleConflictElement.value);
     if (fullBackupIntervalElement) formData.append('full_backup_interval', fullBackupIntervalElement.value);
-    if (backupIntervalHoursElement) formData.append('backup_interval_hours', backupIntervalHoursElement.value);
-    if (backupStartTimeElement) formData.append('backup_start_time', backupStartTimeElement.value);
+    if (backupScheduleElement) formData.append('backup_schedule', backupScheduleElement.value);
     if (compressionLevelElement) formData.append('compression_level', compressionLevelElement.value);
     if (niceLevelElement) formData.append('nice_level', niceLevelElement.value);
     if (defaultBackupModeElement) formData.append('default_backup_mode', defaultBackupModeElement.value);
+    const missedRunPolicyElement = document.getElementById('missed_run_policy');
+    if (missedRunPolicyElement) formData.append('missed_run_policy', missedRunPolicyElement.value);
     if (optimizeTablesElement) formData.append('optimize_tables', optimizeTablesElement.checked ? 'on' : '');
+    appendGFSRetention(formData);
+    ['min_free_space_mb', 'compression_ratio', 'low_space_action', 'orphan_temp_action', 'shutdown_timeout', 'rpo_hours', 'database_rpo'].forEach(field => {
+        const element = document.getElementById(field);
+        if (element) formData.append(field, element.value);
+    });
+    const resumeInterruptedElement = document.getElementById('resume_interrupted');
+    if (resumeInterruptedElement) formData.append('resume_interrupted', resumeInterruptedElement.checked ? 'on' : '');
     if (maxMemoryThresholdElement) formData.append('max_memory_threshold', maxMemoryThresholdElement.value);
     if (maxMemoryPerProcessElement) formData.append('max_memory_per_process', maxMemoryPerProcessElement.value);
     if (createTableInfoElement) formData.append('create_table_info', createTableInfoElement.checked ? 'on' : '');
@@ -240,21 +335,41 @@ function saveSettings() {
     if (mariadbCheckOptionsElement) formData.append('mariadb_check_options', mariadbCheckOptionsElement.value);
     if (mariadbBinlogOptionsElement) formData.append('mariadb_binlog_options', mariadbBinlogOptionsElement.value);
     if (ignoreDbsElement) formData.append('ignore_dbs', ignoreDbsElement.value);
+    if (includeDbsElement) formData.append('include_dbs', includeDbsElement.value);
 
     // Web settings
     const webPortElement = document.getElementById('web_port');
-    const authUserElement = document.getElementById('auth_user');
-    const newPasswordElement = document.getElementById('new_password');
     const sslEnabledElement = document.getElementById('ssl_enabled');
     const sslCertFileElement = document.getElementById('ssl_cert_file');
     const sslKeyFileElement = document.getElementById('ssl_key_file');
 
     if (webPortElement) formData.append('web_port', webPortElement.value);
-    if (authUserElement) formData.append('auth_user', authUserElement.value);
-    if (newPasswordElement) formData.append('new_password', newPasswordElement.value);
     if (sslEnabledElement) formData.append('ssl_enabled', sslEnabledElement.checked ? 'on' : '');
     if (sslCertFileElement) formData.append('ssl_cert_file', sslCertFileElement.value);
     if (sslKeyFileElement) formData.append('ssl_key_file', sslKeyFileElement.value);
+    const metricsEnabledElement = document.getElementById('metrics_enabled');
+    if (metricsEnabledElement) formData.append('metrics_enabled', metricsEnabledElement.checked ? 'on' : '');
+    const metricsTokenElement = document.getElementById('metrics_token');
+    if (metricsTokenElement) formData.append('metrics_token', metricsTokenElement.value);
+    const metricsTokenClearElement = document.getElementById('metrics_token_clear');
+    if (metricsTokenClearElement) formData.append('metrics_token_clear', metricsTokenClearElement.checked ? 'on' : '');
+    const encryptSecretsElement = document.getElementById('encrypt_secrets');
+    if (encryptSecretsElement) formData.append('encrypt_secrets', encryptSecretsElement.checked ? 'on' : '');
+
+    // Single sign-on settings
+    ['local_login', 'oidc_enabled', 'oidc_issuer_url', 'oidc_client_id', 'oidc_client_secret', 'oidc_redirect_url',
+     'oidc_scopes', 'oidc_username_claim', 'oidc_groups_claim', 'oidc_group_roles', 'oidc_default_role',
+     'ldap_enabled', 'ldap_url', 'ldap_start_tls', 'ldap_insecure_skip_verify', 'ldap_bind_dn', 'ldap_bind_password',
+     'ldap_user_base_dn', 'ldap_user_filter', 'ldap_group_base_dn', 'ldap_group_filter', 'ldap_group_attribute',
+     'ldap_group_roles', 'ldap_default_role'].forEach(id => {
+        const element = document.getElementById(id);
+        if (!element) return;
+        if (element.type === 'checkbox') {
+            formData.append(id, element.checked ? 'on' : '');
+        } else {
+            formData.append(id, element.value);
+        }
+    });
 
     // Logging settings
     const logDirElement = document.getElementById('log_dir');
@@ -266,6 +381,23 @@ function saveSettings() {
     // Notification settings
     const slackWebhookElement = document.getElementById('slack_webhook');
     if (slackWebhookElement) formData.append('slack_webhook', slackWebhookElement.value);
+    ['webhook_enabled', 'webhook_url', 'webhook_headers', 'webhook_body_template',
+     'email_enabled', 'email_smtp_host', 'email_smtp_port', 'email_tls_mode', 'email_username', 'email_password', 'email_from', 'email_to',
+     'teams_enabled', 'teams_webhook_url', 'discord_enabled', 'discord_webhook_url',
+     'telegram_enabled', 'telegram_bot_token', 'telegram_chat_id',
+     'quiet_hours', 'dedup_minutes', 'digest_time'].forEach(id => {
+        const element = document.getElementById(id);
+        if (!element) return;
+        if (element.type === 'checkbox') {
+            formData.append(id, element.checked ? 'on' : '');
+        } else {
+            formData.append(id, element.value);
+        }
+    });
+
+    // Backup policies
+    if (document.getElementById('policies-list')) formData.append('policies', JSON.stringify(collectPolicies()));
+    if (document.getElementById('notification-rules-list')) formData.append('notification_rules', JSON.stringify(collectNotificationRules()));
 
     fetch('/api/settings/save', {
         method: 'POST',
@@ -273,13 +405,10 @@ function saveSettings() {
     })
     .then(response => response.json())
     .then(data => {
+        showFieldErrors(data.fields || {});
         if (data.success) {
             showToast('Settings saved successfully!', 'success');
-            // Clear password field
-            const newPasswordElement = document.getElementById('new_password');
-            if (newPasswordElement) {
-                newPasswordElement.value = '';
-            }
+            if (typeof loadConfigHistory === 'function') loadConfigHistory();
         } else {
             showToast('Failed to save settings: ' + data.error, 'error');
         }
@@ -290,6 +419,28 @@ function saveSettings() {
     });
 }
 
+// Show validation errors next to the form inputs, keyed by input id.
+// Errors of whole sections (e.g. policies) are only part of the toast.
+function showFieldErrors(fields) {
+    document.querySelectorAll('.field-error').forEach(element => element.remove());
+    document.querySelectorAll('.input-invalid').forEach(element => element.classList.remove('input-invalid'));
+
+    let firstInvalid = null;
+    Object.keys(fields).forEach(id => {
+        const element = document.getElementById(id);
+        if (!element) return;
+        element.classList.add('input-invalid');
+        const message = document.createElement('small');
+        message.className = 'form-help field-error';
+        message.textContent = fields[id];
+        (element.closest('.form-group') || element.parentNode).appendChild(message);
+        if (!firstInvalid) firstInvalid = element;
+    });
+    if (firstInvalid) {
+        firstInvalid.scrollIntoView({ behavior: 'smooth', block: 'center' });
+    }
+}
+
 function resetSettings() {
     fetch('/api/settings/reset', {
         method: 'POST'
@@ -299,6 +450,7 @@ function resetSettings() {
         if (data.success) {
             showToast('Settings reset to default!', 'success');
             loadSettings();
+            if (typeof loadConfigHistory === 'function') loadConfigHistory();
         } else {
             showToast('Failed to reset settings: ' + data.error, 'error');
         }
@@ -377,6 +529,480 @@ function updateMysqldumpOptions() {
     }
 }
 
+// Backup policies editor
+function renderPolicies(policies) {
+    const list = document.getElementById('policies-list');
+    if (!list) {
+        return;
+    }
+
+    list.innerHTML = '';
+    policies.forEach(policy => addPolicy(policy));
+
+    if (policies.length === 0) {
+        list.innerHTML = '<small class="form-help policies-empty">No named policies. The default schedule covers all selected databases.</small>';
+    }
+}
+
+function addPolicy(policy) {
+    const list = document.getElementById('policies-list');
+    if (!list) {
+        return;
+    }
+
+    const empty = list.querySelector('.policies-empty');
+    if (empty) {
+        empty.remove();
+    }
+
+    policy = policy || { name: '', enabled: true, schedule: '', mode: 'auto', include_dbs: [], ignore_dbs: [] };
+
+    const card = document.createElement('div');
+    card.className = 'policy-card';
+    card.style.cssText = 'border: 1px solid #dee2e6; border-radius: 6px; padding: 12px; margin-bottom: 12px;';
+
+    const compression = policy.compression_level === undefined || policy.compression_level === null ? '' : policy.compression_level;
+    const modeOption = (value, label) => '<option value="' + value + '"' + (policy.mode === value ? ' selected' : '') + '>' + label + '</option>';
+
+    card.innerHTML = `
+        <div style="display: flex; gap: 20px; align-items: flex-end;">
+            <div class="form-group" style="flex: 1;">
+                <label>Name</label>
+                <input type="text" class="policy-name" value="${escapeHtml(policy.name || '')}" placeholder="critical-dbs">
+            </div>
+            <div class="form-group" style="flex: 1;">
+                <label>Schedule (Cron)</label>
+                <input type="text" class="policy-schedule" value="${escapeHtml(policy.schedule || '')}" placeholder="0 * * * *">
+            </div>
+            <div class="form-group" style="flex: 1;">
+                <label>Mode</label>
+                <select class="policy-mode">
+                    ${modeOption('auto', 'Auto (Smart Decision)')}
+                    ${modeOption('full', 'Full Backup')}
+                    ${modeOption('incremental', 'Incremental Backup')}
+                </select>
+            </div>
+            <div class="form-group">
+                <label class="checkbox-label">
+                    <input type="checkbox" class="policy-enabled" ${policy.enabled ? 'checked' : ''}>
+                    <span class="checkmark"></span>
+                    Enabled
+                </label>
+            </div>
+        </div>
+        <div style="display: flex; gap: 20px;">
+            <div class="form-group" style="flex: 1;">
+                <label>Include Databases</label>
+                <textarea class="policy-include-dbs" rows="3" placeholder="tenant_*">${escapeHtml((policy.include_dbs || []).join('\n'))}</textarea>
+            </div>
+            <div class="form-group" style="flex: 1;">
+                <label>Ignore Databases</label>
+                <textarea class="policy-ignore-dbs" rows="3" placeholder="tenant_demo">${escapeHtml((policy.ignore_dbs || []).join('\n'))}</textarea>
+            </div>
+        </div>
+        <div style="display: flex; gap: 20px; align-items: flex-end;">
+            <div class="form-group" style="flex: 1;">
+                <label>Retention (Days)</label>
+                <input type="number" class="policy-retention" min="0" max="3650" value="${policy.retention_days || ''}" placeholder="Global">
+            </div>
+            <div class="form-group" style="flex: 1;">
+                <label>Full Backup Interval (Days)</label>
+                <input type="number" class="policy-full-interval" min="0" max="365" value="${policy.full_backup_interval || ''}" placeholder="Global">
+            </div>
+            <div class="form-group" style="flex: 1;">
+                <label>Compression Level</label>
+                <input type="number" class="policy-compression" min="0" max="9" value="${compression}" placeholder="Global">
+            </div>
+            <div class="form-group" style="flex: 1;">
+                <label>RPO (Hours)</label>
+                <input type="number" class="policy-rpo" min="0" value="${policy.rpo_hours || ''}" placeholder="Global">
+            </div>
+        </div>
+        <div style="display: flex; gap: 20px; align-items: flex-end;">
+            <div class="form-group" style="flex: 1;">
+                <label>Slack Webhook URL</label>
+                <input type="text" class="policy-slack" value="${escapeHtml(policy.slack_webhook_url || '')}" placeholder="Global">
+            </div>
+            <div class="form-group">
+                <button type="button" class="btn btn-secondary policy-remove">Remove</button>
+            </div>
+        </div>
+    `;
+
+    card.querySelector('.policy-remove').addEventListener('click', function() {
+        if (confirm('Remove this backup policy?')) {
+            card.remove();
+        }
+    });
+
+    list.appendChild(card);
+}
+
+function collectPolicies() {
+    const policies = [];
+    const splitLines = value => value.split('\n').map(line => line.trim()).filter(line => line !== '');
+
+    document.querySelectorAll('#policies-list .policy-card').forEach(card => {
+        const policy = {
+            name: card.querySelector('.policy-name').value.trim(),
+            enabled: card.querySelector('.policy-enabled').checked,
+            schedule: card.querySelector('.policy-schedule').value.trim(),
+            mode: card.querySelector('.policy-mode').value,
+            include_dbs: splitLines(card.querySelector('.policy-include-dbs').value),
+            ignore_dbs: splitLines(card.querySelector('.policy-ignore-dbs').value),
+            retention_days: parseInt(card.querySelector('.policy-retention').value, 10) || 0,
+            full_backup_interval: parseInt(card.querySelector('.policy-full-interval').value, 10) || 0,
+            rpo_hours: parseInt(card.querySelector('.policy-rpo').value, 10) || 0,
+            slack_webhook_url: card.querySelector('.policy-slack').value.trim()
+        };
+
+        const compression = card.querySelector('.policy-compression').value;
+        if (compression !== '') {
+            policy.compression_level = parseInt(compression, 10);
+        }
+
+        policies.push(policy);
+    });
+
+    return policies;
+}
+
+// Notification rules editor
+const notificationEventTypes = [
+    ['job_started', 'Job started'],
+    ['job_succeeded', 'Job succeeded'],
+    ['job_failed', 'Job failed'],
+    ['job_cancelled', 'Job cancelled'],
+    ['rpo_breached', 'RPO breached'],
+    ['retention_deleted', 'Retention deletions'],
+    ['verification_failed', 'Verification failed'],
+    ['disk_space_warning', 'Disk space'],
+    ['mysql_restart', 'MySQL restart'],
+    ['missed_run', 'Missed runs']
+];
+const notificationChannels = [
+    ['slack', 'Slack'],
+    ['webhook', 'Webhook'],
+    ['email', 'Email'],
+    ['teams', 'Teams'],
+    ['discord', 'Discord'],
+    ['telegram', 'Telegram']
+];
+
+function renderNotificationRules(rules) {
+    const list = document.getElementById('notification-rules-list');
+    if (!list) {
+        return;
+    }
+
+    list.innerHTML = '';
+    rules.forEach(rule => addNotificationRule(rule));
+
+    if (rules.length === 0) {
+        list.innerHTML = '<small class="form-help rules-empty">No rules. The default rules apply.</small>';
+    }
+}
+
+function addNotificationRule(rule) {
+    const list = document.getElementById('notification-rules-list');
+    if (!list) {
+        return;
+    }
+
+    const empty = list.querySelector('.rules-empty');
+    if (empty) {
+        empty.remove();
+    }
+
+    rule = rule || { name: '', events: [], min_severity: 'info', databases: [], channels: [], digest: false };
+
+    const card = document.createElement('div');
+    card.className = 'notification-rule-card';
+    card.style.cssText = 'border: 1px solid #dee2e6; border-radius: 6px; padding: 12px; margin-bottom: 12px;';
+
+    const checkboxes = (options, selected, className) => options.map(([value, label]) =>
+        '<label class="checkbox-label" style="margin-right: 12px;"><input type="checkbox" class="' + className + '" value="' + value + '"' +
+        ((selected || []).includes(value) ? ' checked' : '') + '><span class="checkmark"></span>' + label + '</label>').join('');
+    const severityOption = (value, label) => '<option value="' + value + '"' + ((rule.min_severity || 'info') === value ? ' selected' : '') + '>' + label + '</option>';
+
+    card.innerHTML = `
+        <div style="display: flex; gap: 20px; align-items: flex-end;">
+            <div class="form-group" style="flex: 1;">
+                <label>Name</label>
+                <input type="text" class="rule-name" value="${escapeHtml(rule.name || '')}" placeholder="dba-failures">
+            </div>
+            <div class="form-group" style="flex: 1;">
+                <label>Minimum Severity</label>
+                <select class="rule-severity">
+                    ${severityOption('info', 'Info')}
+                    ${severityOption('warning', 'Warning')}
+                    ${severityOption('critical', 'Critical')}
+                </select>
+            </div>
+            <div class="form-group">
+                <label class="checkbox-label">
+                    <input type="checkbox" class="rule-digest" ${rule.digest ? 'checked' : ''}>
+                    <span class="checkmark"></span>
+                    Daily digest
+                </label>
+            </div>
+        </div>
+        <div class="form-group">
+            <label>Events (none = all)</label>
+            <div style="display: flex; flex-wrap: wrap;">${checkboxes(notificationEventTypes, rule.events, 'rule-event')}</div>
+        </div>
+        <div class="form-group">
+            <label>Channels (none = all configured)</label>
+            <div style="display: flex; flex-wrap: wrap;">${checkboxes(notificationChannels, rule.channels, 'rule-channel')}</div>
+        </div>
+        <div style="display: flex; gap: 20px; align-items: flex-end;">
+            <div class="form-group" style="flex: 1;">
+                <label>Databases (none = all)</label>
+                <textarea class="rule-databases" rows="2" placeholder="billing_*">${escapeHtml((rule.databases || []).join('\n'))}</textarea>
+            </div>
+            <div class="form-group">
+                <button type="button" class="btn btn-secondary rule-remove">Remove</button>
+            </div>
+        </div>
+    `;
+
+    card.querySelector('.rule-remove').addEventListener('click', function() {
+        if (confirm('Remove this notification rule?')) {
+            card.remove();
+        }
+    });
+
+    list.appendChild(card);
+}
+
+function collectNotificationRules() {
+    const rules = [];
+    const splitLines = value => value.split('\n').map(line => line.trim()).filter(line => line !== '');
+    const checkedValues = (card, className) => Array.from(card.querySelectorAll('.' + className + ':checked')).map(input => input.value);
+
+    document.querySelectorAll('#notification-rules-list .notification-rule-card').forEach(card => {
+        rules.push({
+            name: card.querySelector('.rule-name').value.trim(),
+            events: checkedValues(card, 'rule-event'),
+            min_severity: card.querySelector('.rule-severity').value,
+            databases: splitLines(card.querySelector('.rule-databases').value),
+            channels: checkedValues(card, 'rule-channel'),
+            digest: card.querySelector('.rule-digest').checked
+        });
+    });
+
+    return rules;
+}
+
+// Add the GFS retention and retention limit fields to a form
+function appendGFSRetention(formData) {
+    const gfsEnabledElement = document.getElementById('gfs_enabled');
+    if (!gfsEnabledElement) {
+        return;
+    }
+
+    formData.append('gfs_enabled', gfsEnabledElement.checked ? 'on' : 'off');
+    ['daily', 'weekly', 'monthly', 'yearly'].forEach(period => {
+        const element = document.getElementById('gfs_' + period);
+        if (element) formData.append('gfs_' + period, element.value);
+    });
+    ['keep_last_fulls', 'max_database_backup_mb', 'max_total_backup_mb'].forEach(field => {
+        const element = document.getElementById(field);
+        if (element) formData.append(field, element.value);
+    });
+}
+
+// Preview which backup groups the current retention settings would keep or prune
+function previewRetention() {
+    const previewElement = document.getElementById('retention-preview');
+    if (!previewElement) {
+        return;
+    }
+
+    const formData = new FormData();
+    appendGFSRetention(formData);
+
+    previewElement.innerHTML = '<span style="color: #666;">Loading preview...</span>';
+
+    fetch('/api/backup/retention/preview', {
+        method: 'POST',
+        body: formData
+    })
+        .then(response => response.json())
+        .then(data => {
+            if (!data.success) {
+                previewElement.innerHTML = '<span style="color: #dc3545;">' + escapeHtml(data.error || 'Failed to preview retention') + '</span>';
+                return;
+            }
+
+            const databases = data.databases || [];
+            if (databases.length === 0) {
+                previewElement.innerHTML = '<span style="color: #666;">No backups found</span>';
+                return;
+            }
+
+            previewElement.innerHTML = databases.map(db => {
+                const groups = db.groups.map(group => {
+                    const label = escapeHtml(group.timestamp) + ' (+' + group.incremental_count + ' inc)';
+                    if (group.keep) {
+                        return '<span style="color: #28a745;">keep</span> ' + label + ' - ' + escapeHtml(group.reasons.join(', '));
+                    }
+                    return '<span style="color: #dc3545;">prune</span> ' + label;
+                }).join('<br>');
+
+                return '<strong>' + escapeHtml(db.database) + '</strong>: ' + db.kept + ' kept, ' + db.pruned +
+                    ' pruned (' + formatBytes(db.pruned_size) + ')<br>' + groups;
+            }).join('<br>');
+        })
+        .catch(error => {
+            console.error('Error previewing retention:', error);
+            previewElement.innerHTML = '<span style="color: #dc3545;">Error loading preview</span>';
+        });
+}
+
+// Show how recent missed scheduled runs were handled
+function loadMissedRuns() {
+    const element = document.getElementById('missed-runs');
+    if (!element) {
+        return;
+    }
+
+    fetch('/api/schedule/missed?limit=10')
+        .then(response => response.json())
+        .then(data => {
+            if (!data.success) {
+                element.innerHTML = '<span style="color: #dc3545;">' + escapeHtml(data.error || 'Failed to load missed runs') + '</span>';
+                return;
+            }
+
+            const history = data.history || [];
+            if (history.length === 0) {
+                element.innerHTML = '<span style="color: #666;">No missed runs recorded</span>';
+                return;
+            }
+
+            element.innerHTML = '<strong>Recent missed runs:</strong><br>' + history.map(entry =>
+                escapeHtml(entry.detected_at) + ' - ' + escapeHtml(entry.policy) + ': ' +
+                entry.missed_count + ' missed, ' + escapeHtml(entry.message || entry.action)
+            ).join('<br>');
+        })
+        .catch(error => {
+            console.error('Error loading missed runs:', error);
+            element.innerHTML = '<span style="color: #dc3545;">Error loading missed runs</span>';
+        });
+}
+
+// Debounce timer for the schedule preview
+let schedulePreviewTimer = null;
+
+function scheduleCronPreview() {
+    if (schedulePreviewTimer) {
+        clearTimeout(schedulePreviewTimer);
+    }
+    schedulePreviewTimer = setTimeout(previewSchedule, 500);
+}
+
+function previewSchedule() {
+    const previewElement = document.getElementById('schedule-preview');
+    const scheduleElement = document.getElementById('backup_schedule');
+
+    if (!previewElement || !scheduleElement) {
+        return;
+    }
+
+    const params = new URLSearchParams({ schedule: scheduleElement.value.trim(), count: 5 });
+
+    fetch('/api/schedule/info?' + params.toString())
+        .then(response => response.json())
+        .then(data => {
+            if (!data.success) {
+                previewElement.innerHTML = '<span style="color: #dc3545;">' + escapeHtml(data.error || 'Invalid schedule') + '</span>';
+                return;
+            }
+
+            if (!data.schedule.schedule) {
+                previewElement.innerHTML = '<span style="color: #666;">Default schedule disabled</span>';
+                return;
+            }
+
+            const runs = data.schedule.next_runs || [];
+            if (runs.length === 0) {
+                previewElement.innerHTML = '<span style="color: #dc3545;">Schedule never runs</span>';
+                return;
+            }
+
+            previewElement.innerHTML = '<strong>Next runs:</strong><br>' + runs.map(run => escapeHtml(run)).join('<br>');
+        })
+        .catch(error => {
+            console.error('Error previewing schedule:', error);
+            previewElement.innerHTML = '<span style="color: #dc3545;">Error loading preview</span>';
+        });
+}
+
+// Debounce timer for the database pattern preview
+let databasePreviewTimer = null;
+
+function scheduleDatabasePreview() {
+    if (databasePreviewTimer) {
+        clearTimeout(databasePreviewTimer);
+    }
+    databasePreviewTimer = setTimeout(previewDatabases, 500);
+}
+
+function previewDatabases() {
+    const previewElement = document.getElementById('database-preview');
+    const includeDbsElement = document.getElementById('include_dbs');
+    const ignoreDbsElement = document.getElementById('ignore_dbs');
+
+    if (!previewElement || !includeDbsElement || !ignoreDbsElement) {
+        return;
+    }
+
+    const formData = new FormData();
+    formData.append('include_dbs', includeDbsElement.value);
+    formData.append('ignore_dbs', ignoreDbsElement.value);
+
+    fetch('/api/databases/preview', {
+        method: 'POST',
+        body: formData
+    })
+    .then(response => response.json())
+    .then(data => {
+        if (!data.success) {
+            previewElement.innerHTML = '<span style="color: #dc3545;">' + escapeHtml(data.error || 'Preview unavailable') + '</span>';
+            return;
+        }
+
+        if (data.databases.length === 0) {
+            previewElement.innerHTML = '<span style="color: #666;">No databases found</span>';
+            return;
+        }
+
+        const header = '<div><strong>' + data.selected_count + ' of ' + data.total_count + ' databases selected</strong>' +
+            (data.include_only ? ' (include-only mode)' : '') + '</div>';
+
+        const items = data.databases.map(db => {
+            let color = '#28a745';
+            let label = '✓';
+            if (db.status === 'ignored') {
+                color = '#dc3545';
+                label = '✗';
+            } else if (db.status === 'not_included') {
+                color = '#999';
+                label = '–';
+            }
+            const reason = db.pattern && db.pattern !== db.name ? ' <span style="color: #666;">(' + escapeHtml(db.pattern) + ')</span>' : '';
+            return '<div style="color: ' + color + ';">' + label + ' ' + escapeHtml(db.name) + reason + '</div>';
+        });
+
+        previewElement.innerHTML = header + items.join('');
+    })
+    .catch(error => {
+        console.error('Error previewing databases:', error);
+        previewElement.innerHTML = '<span style="color: #dc3545;">Error loading preview</span>';
+    });
+}
+
 function testConnection() {
     const btn = document.getElementById('testConnectionBtn');
     const icon = btn.querySelector('.test-icon');
@@ -409,7 +1035,10 @@ function testConnection() {
             icon.textContent = '✅';
             text.textContent = 'Connected';
             section.classList.add('success');
-            showToast('MySQL connection test successful!', 'success');
+            const tls = data.tls && data.tls.encrypted
+                ? 'TLS: ' + data.tls.version + ' ' + data.tls.cipher
+                : 'not encrypted';
+            showToast('MySQL connection test successful! (' + tls + ')', 'success');
             return true;
         } else {
             // Error state
@@ -665,6 +1294,52 @@ function restartService() {
     });
 }
 
+// Send a test notification through one channel using the saved settings
+function testNotification(btn) {
+    const channel = btn.dataset.channel;
+    const icon = btn.querySelector('.test-icon');
+    const text = btn.querySelector('.test-text');
+
+    btn.disabled = true;
+    btn.classList.remove('success', 'error');
+    icon.textContent = '⏳';
+    text.textContent = 'Sending...';
+
+    fetch('/api/notification/test', {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/x-www-form-urlencoded' },
+        body: new URLSearchParams({ channel: channel })
+    })
+    .then(response => response.json())
+    .then(data => {
+        if (data.success) {
+            btn.classList.add('success');
+            icon.textContent = '✅';
+            text.textContent = 'Sent';
+            showToast('Test ' + channel + ' notification sent', 'success');
+        } else {
+            btn.classList.add('error');
+            icon.textContent = '❌';
+            text.textContent = 'Failed';
+            showToast('Test ' + channel + ' notification failed: ' + data.error, 'error');
+        }
+    })
+    .catch(error => {
+        console.error('Error sending test notification:', error);
+        btn.classList.add('error');
+        icon.textContent = '❌';
+        text.textContent = 'Error';
+        showToast('Error sending test notification', 'error');
+    })
+    .finally(() => {
+        btn.disabled = false;
+        setTimeout(() => {
+            icon.textContent = '📨';
+            text.textContent = 'Send Test';
+        }, 3000);
+    });
+}
+
 // Setup event listeners when DOM is ready
 document.addEventListener('DOMContentLoaded', function() {
     // Binary detection button
@@ -674,6 +1349,13 @@ document.addEventListener('DOMContentLoaded', function() {
             detectBinaryPaths();
         });
     }
+
+    // Notification test buttons
+    document.querySelectorAll('.notification-test-btn').forEach(btn => {
+        btn.addEventListener('click', function() {
+            testNotification(btn);
+        });
+    });
 });
 
 
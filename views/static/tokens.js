@@ -0,0 +1,146 @@
+// MariaDB Backup Tool - API Tokens
+
+let apiTokens = [];
+
+function initApiTokens() {
+    const section = document.getElementById('api-tokens');
+    if (!section) return;
+
+    document.getElementById('token-create-btn').addEventListener('click', createApiToken);
+    loadApiTokens();
+}
+
+function apiTokensMine() {
+    return document.getElementById('api-tokens').dataset.mine === '1';
+}
+
+function loadApiTokens() {
+    const tbody = document.getElementById('tokens-tbody');
+    const colspan = apiTokensMine() ? 7 : 8;
+
+    fetch('/api/tokens' + (apiTokensMine() ? '?mine=1' : ''))
+        .then(response => response.json())
+        .then(data => {
+            if (!data.success) {
+                tbody.innerHTML = `<tr><td colspan="${colspan}" class="text-center text-error">Failed to load tokens: ${escapeHtml(data.error || '')}</td></tr>`;
+                return;
+            }
+            apiTokens = data.tokens || [];
+            renderTokenScopes(data.scopes || []);
+            displayApiTokens();
+        })
+        .catch(error => {
+            console.error('Error loading API tokens:', error);
+            tbody.innerHTML = `<tr><td colspan="${colspan}" class="text-center text-error">Error loading tokens</td></tr>`;
+        });
+}
+
+function renderTokenScopes(scopes) {
+    const container = document.getElementById('token-scopes');
+    if (container.children.length > 0) return;
+
+    container.innerHTML = scopes.map(scope => `
+        <label class="checkbox-label">
+            <input type="checkbox" class="token-scope" value="${escapeHtml(scope)}" ${scope === 'backup:read' ? 'checked' : ''}>
+            <span class="checkmark"></span>
+            ${escapeHtml(scope)}
+        </label>
+    `).join('');
+}
+
+function tokenStatus(token) {
+    if (token.revoked_at) return { label: 'Revoked', badge: 'error' };
+    if (token.expires_at && new Date(token.expires_at) < new Date()) {
+        return { label: 'Expired', badge: 'warning' };
+    }
+    return { label: 'Active', badge: 'success' };
+}
+
+function displayApiTokens() {
+    const tbody = document.getElementById('tokens-tbody');
+    const mine = apiTokensMine();
+
+    if (apiTokens.length === 0) {
+        tbody.innerHTML = `<tr><td colspan="${mine ? 7 : 8}" class="text-center text-muted">No API tokens</td></tr>`;
+        return;
+    }
+
+    tbody.innerHTML = apiTokens.map((token, index) => {
+        const status = tokenStatus(token);
+        const lastUsed = token.last_used_at
+            ? `${formatDateTime(token.last_used_at)}<br><small class="text-muted">${escapeHtml(token.last_used_ip || '')}</small>`
+            : '<span class="text-muted">Never</span>';
+        return `
+            <tr>
+                <td>${escapeHtml(token.name)}<br><small class="text-muted"><code>${escapeHtml(token.prefix)}…</code></small></td>
+                <td>${escapeHtml(token.kind)}</td>
+                ${mine ? '' : `<td>${escapeHtml(token.username || token.created_by)}</td>`}
+                <td>${(token.scopes || []).map(scope => `<code>${escapeHtml(scope)}</code>`).join(' ')}</td>
+                <td>${token.expires_at ? formatDateTime(token.expires_at) : '<span class="text-muted">Never</span>'}</td>
+                <td>${lastUsed}</td>
+                <td><span class="status-badge ${status.badge}">${status.label}</span></td>
+                <td>${token.revoked_at ? '' : `<button type="button" class="btn btn-sm btn-danger" onclick="revokeApiToken(${index})">Revoke</button>`}</td>
+            </tr>
+        `;
+    }).join('');
+}
+
+function createApiToken() {
+    const formData = new FormData();
+    formData.append('name', document.getElementById('token_name').value.trim());
+    const kind = document.getElementById('token_kind');
+    formData.append('kind', kind ? kind.value : 'personal');
+    formData.append('expires_days', document.getElementById('token_expires_days').value || '0');
+    document.querySelectorAll('#token-scopes .token-scope:checked').forEach(input => {
+        formData.append('scopes', input.value);
+    });
+
+    fetch('/api/tokens/create', {
+        method: 'POST',
+        body: formData
+    })
+    .then(response => response.json())
+    .then(data => {
+        if (data.success) {
+            showToast(data.message, 'success');
+            document.getElementById('token_value').value = data.token;
+            document.getElementById('token-created').style.display = 'block';
+            document.getElementById('token_name').value = '';
+            loadApiTokens();
+        } else {
+            showToast('Failed to create token: ' + data.error, 'error');
+        }
+    })
+    .catch(error => {
+        console.error('Error creating API token:', error);
+        showToast('Error creating token', 'error');
+    });
+}
+
+function revokeApiToken(index) {
+    const token = apiTokens[index];
+    if (!confirm(`Revoke token ${token.name}? Clients using it are rejected immediately.`)) {
+        return;
+    }
+
+    const formData = new FormData();
+    formData.append('id', token.id);
+
+    fetch('/api/tokens/revoke', {
+        method: 'POST',
+        body: formData
+    })
+    .then(response => response.json())
+    .then(data => {
+        if (data.success) {
+            showToast(data.message, 'success');
+            loadApiTokens();
+        } else {
+            showToast('Failed to revoke token: ' + data.error, 'error');
+        }
+    })
+    .catch(error => {
+        console.error('Error revoking API token:', error);
+        showToast('Error revoking token', 'error');
+    });
+}
@@ -0,0 +1,330 @@
+// MariaDB Backup Tool - User Management
+
+let users = [];
+
+function initUsers() {
+    fetch('/api/me')
+        .then(response => response.json())
+        .then(data => {
+            if (!data.success || !data.user) return;
+            const info = document.getElementById('my-account-info');
+            if (info) {
+                info.textContent = `Signed in as ${data.user.username} (${data.user.role})`;
+            }
+            if (isSSOUser(data.user)) {
+                if (info) info.textContent += ` through ${data.user.auth_source.toUpperCase()}. Your password and second factor are managed by the identity provider.`;
+                document.getElementById('password-form').style.display = 'none';
+                document.getElementById('twofa-section').style.display = 'none';
+            } else {
+                renderTwoFactorState(data.user);
+            }
+            if (data.user.role === 'admin') {
+                loadUsers();
+            }
+        })
+        .catch(error => console.error('Error loading current user:', error));
+
+    document.getElementById('password-form').addEventListener('submit', function(event) {
+        event.preventDefault();
+        changeOwnPassword(this);
+    });
+    document.getElementById('user-form').addEventListener('submit', function(event) {
+        event.preventDefault();
+        saveUser();
+    });
+    document.getElementById('user-cancel-btn').addEventListener('click', resetUserForm);
+    document.getElementById('twofa-setup-btn').addEventListener('click', setupTwoFactor);
+    document.getElementById('twofa-enable-btn').addEventListener('click', enableTwoFactor);
+    document.getElementById('twofa-codes-btn').addEventListener('click', regenerateRecoveryCodes);
+    document.getElementById('twofa-disable-btn').addEventListener('click', disableTwoFactor);
+}
+
+function isSSOUser(user) {
+    return user.auth_source && user.auth_source !== 'local';
+}
+
+function loadUsers() {
+    const tbody = document.getElementById('users-tbody');
+
+    fetch('/api/users')
+        .then(response => response.json())
+        .then(data => {
+            if (!data.success) {
+                tbody.innerHTML = `<tr><td colspan="7" class="text-center text-error">Failed to load users: ${escapeHtml(data.error || '')}</td></tr>`;
+                return;
+            }
+            users = data.users || [];
+            displayUsers();
+        })
+        .catch(error => {
+            console.error('Error loading users:', error);
+            tbody.innerHTML = '<tr><td colspan="7" class="text-center text-error">Error loading users</td></tr>';
+        });
+}
+
+function displayUsers() {
+    const tbody = document.getElementById('users-tbody');
+
+    if (users.length === 0) {
+        tbody.innerHTML = '<tr><td colspan="7" class="text-center text-muted">No users</td></tr>';
+        return;
+    }
+
+    tbody.innerHTML = users.map((user, index) => `
+        <tr>
+            <td>${escapeHtml(user.username)}${isSSOUser(user) ? ` <small class="text-muted">${escapeHtml(user.auth_source.toUpperCase())}</small>` : ''}</td>
+            <td>${escapeHtml(user.role)}</td>
+            <td><span class="status-badge ${user.disabled ? 'error' : 'success'}">${user.disabled ? 'Disabled' : 'Active'}</span></td>
+            <td>${user.totp_enabled ? 'On' : '<span class="text-muted">Off</span>'}</td>
+            <td>${user.last_login_at ? escapeHtml(user.last_login_at) : '<span class="text-muted">Never</span>'}</td>
+            <td>${escapeHtml(user.created_at || '')}</td>
+            <td>
+                <button type="button" class="btn btn-sm btn-secondary" onclick="editUser(${index})">Edit</button>
+                ${user.totp_enabled ? `<button type="button" class="btn btn-sm btn-secondary" onclick="resetUserTwoFactor(${index})">Reset 2FA</button>` : ''}
+                <button type="button" class="btn btn-sm btn-danger" onclick="deleteUser(${index})">Delete</button>
+            </td>
+        </tr>
+    `).join('');
+}
+
+function editUser(index) {
+    const user = users[index];
+    document.getElementById('user-form-title').textContent = `✏️ Edit User ${user.username}`;
+    document.getElementById('user_username').value = user.username;
+    document.getElementById('user_username').readOnly = true;
+    document.getElementById('user_role').value = user.role;
+    document.getElementById('user_password').value = '';
+    document.getElementById('user_disabled').checked = user.disabled;
+    document.getElementById('user-form').scrollIntoView({ behavior: 'smooth' });
+}
+
+function resetUserForm() {
+    document.getElementById('user-form').reset();
+    document.getElementById('user_username').readOnly = false;
+    document.getElementById('user-form-title').textContent = '➕ Add User';
+}
+
+function saveUser() {
+    const formData = new FormData();
+    formData.append('username', document.getElementById('user_username').value.trim());
+    formData.append('role', document.getElementById('user_role').value);
+    formData.append('password', document.getElementById('user_password').value);
+    formData.append('disabled', document.getElementById('user_disabled').checked ? 'on' : '');
+
+    fetch('/api/users/save', {
+        method: 'POST',
+        body: formData
+    })
+    .then(response => response.json())
+    .then(data => {
+        if (data.success) {
+            showToast(data.message, 'success');
+            resetUserForm();
+            loadUsers();
+        } else {
+            showToast('Failed to save user: ' + data.error, 'error');
+        }
+    })
+    .catch(error => {
+        console.error('Error saving user:', error);
+        showToast('Error saving user', 'error');
+    });
+}
+
+function deleteUser(index) {
+    const user = users[index];
+    if (!confirm(`Delete user ${user.username}? They are logged out immediately.`)) {
+        return;
+    }
+
+    const formData = new FormData();
+    formData.append('username', user.username);
+
+    fetch('/api/users/delete', {
+        method: 'POST',
+        body: formData
+    })
+    .then(response => response.json())
+    .then(data => {
+        if (data.success) {
+            showToast(data.message, 'success');
+            loadUsers();
+        } else {
+            showToast('Failed to delete user: ' + data.error, 'error');
+        }
+    })
+    .catch(error => {
+        console.error('Error deleting user:', error);
+        showToast('Error deleting user', 'error');
+    });
+}
+
+function changeOwnPassword(form) {
+    fetch('/api/me/password', {
+        method: 'POST',
+        body: new FormData(form)
+    })
+    .then(response => response.json())
+    .then(data => {
+        if (data.success) {
+            showToast(data.message, 'success');
+            form.reset();
+        } else {
+            showToast('Failed to change password: ' + data.error, 'error');
+        }
+    })
+    .catch(error => {
+        console.error('Error changing password:', error);
+        showToast('Error changing password', 'error');
+    });
+}
+
+function renderTwoFactorState(user) {
+    const status = document.getElementById('twofa-status');
+    if (user.totp_enabled) {
+        status.textContent = `Enabled. ${user.recovery_codes_left} recovery code(s) left.`;
+    } else {
+        status.textContent = 'Disabled. Add an authenticator app so a stolen password alone is not enough to log in.';
+    }
+
+    document.getElementById('twofa-setup-btn').style.display = user.totp_enabled ? 'none' : '';
+    document.getElementById('twofa-enable-btn').style.display = 'none';
+    document.getElementById('twofa-codes-btn').style.display = user.totp_enabled ? '' : 'none';
+    document.getElementById('twofa-disable-btn').style.display = user.totp_enabled ? '' : 'none';
+    document.getElementById('twofa-code-group').style.display = user.totp_enabled ? '' : 'none';
+    document.getElementById('twofa-enroll').style.display = 'none';
+}
+
+function postTwoFactor(url, fields) {
+    const formData = new FormData();
+    Object.keys(fields).forEach(key => formData.append(key, fields[key]));
+    return fetch(url, {
+        method: 'POST',
+        body: formData
+    }).then(response => response.json());
+}
+
+function reloadTwoFactorState() {
+    document.getElementById('twofa_password').value = '';
+    document.getElementById('twofa_code').value = '';
+    fetch('/api/me')
+        .then(response => response.json())
+        .then(data => {
+            if (data.success && data.user) renderTwoFactorState(data.user);
+        });
+}
+
+function showRecoveryCodes(codes) {
+    document.getElementById('twofa-recovery-codes').textContent = codes.join('\n');
+    document.getElementById('twofa-recovery').style.display = 'block';
+}
+
+function setupTwoFactor() {
+    postTwoFactor('/api/me/2fa/setup', {
+        current_password: document.getElementById('twofa_password').value
+    })
+    .then(data => {
+        if (!data.success) {
+            showToast('Failed to set up 2FA: ' + data.error, 'error');
+            return;
+        }
+        document.getElementById('twofa-qr').innerHTML = data.qr_svg;
+        document.getElementById('twofa-secret').textContent = data.secret;
+        document.getElementById('twofa-enroll').style.display = 'block';
+        document.getElementById('twofa-code-group').style.display = '';
+        document.getElementById('twofa-setup-btn').style.display = 'none';
+        document.getElementById('twofa-enable-btn').style.display = '';
+        document.getElementById('twofa_code').focus();
+    })
+    .catch(error => {
+        console.error('Error setting up 2FA:', error);
+        showToast('Error setting up 2FA', 'error');
+    });
+}
+
+function enableTwoFactor() {
+    postTwoFactor('/api/me/2fa/enable', {
+        code: document.getElementById('twofa_code').value.trim()
+    })
+    .then(data => {
+        if (!data.success) {
+            showToast('Failed to enable 2FA: ' + data.error, 'error');
+            return;
+        }
+        showToast(data.message, 'success');
+        showRecoveryCodes(data.recovery_codes);
+        reloadTwoFactorState();
+    })
+    .catch(error => {
+        console.error('Error enabling 2FA:', error);
+        showToast('Error enabling 2FA', 'error');
+    });
+}
+
+function regenerateRecoveryCodes() {
+    if (!confirm('Create new recovery codes? The current ones stop working.')) {
+        return;
+    }
+
+    postTwoFactor('/api/me/2fa/recovery-codes', {
+        current_password: document.getElementById('twofa_password').value
+    })
+    .then(data => {
+        if (!data.success) {
+            showToast('Failed to create recovery codes: ' + data.error, 'error');
+            return;
+        }
+        showToast(data.message, 'success');
+        showRecoveryCodes(data.recovery_codes);
+        reloadTwoFactorState();
+    })
+    .catch(error => {
+        console.error('Error creating recovery codes:', error);
+        showToast('Error creating recovery codes', 'error');
+    });
+}
+
+function disableTwoFactor() {
+    if (!confirm('Disable two-factor authentication? Your password alone will be enough to log in.')) {
+        return;
+    }
+
+    postTwoFactor('/api/me/2fa/disable', {
+        current_password: document.getElementById('twofa_password').value,
+        code: document.getElementById('twofa_code').value.trim()
+    })
+    .then(data => {
+        if (!data.success) {
+            showToast('Failed to disable 2FA: ' + data.error, 'error');
+            return;
+        }
+        showToast(data.message, 'success');
+        document.getElementById('twofa-recovery').style.display = 'none';
+        reloadTwoFactorState();
+    })
+    .catch(error => {
+        console.error('Error disabling 2FA:', error);
+        showToast('Error disabling 2FA', 'error');
+    });
+}
+
+function resetUserTwoFactor(index) {
+    const user = users[index];
+    if (!confirm(`Turn off two-factor authentication of ${user.username}? They can log in with their password alone until they enroll again.`)) {
+        return;
+    }
+
+    postTwoFactor('/api/users/reset-2fa', { username: user.username })
+    .then(data => {
+        if (data.success) {
+            showToast(data.message, 'success');
+            loadUsers();
+        } else {
+            showToast('Failed to reset 2FA: ' + data.error, 'error');
+        }
+    })
+    .catch(error => {
+        console.error('Error resetting 2FA:', error);
+        showToast('Error resetting 2FA', 'error');
+    });
+}
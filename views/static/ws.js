@@ -79,6 +79,9 @@ function connectJobsWebSocket() {
                 if (typeof updateJobSummary === 'function') {
                     updateJobSummary(message.data || []);
                 }
+                if (typeof displayQueuedJobs === 'function') {
+                    displayQueuedJobs(message.data ? message.data.queue : null);
+                }
             }
         } catch (error) {
             console.error('Error parsing jobs WebSocket message:', error);
@@ -6,6 +6,7 @@ import (
 	"database/sql"
 	"embed"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
 	"io"
@@ -15,6 +16,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -52,10 +54,6 @@ var testState = struct {
 	ButtonsEnabled:    false,
 }
 
-var sessions = make(map[string]time.Time)
-var sessionsMutex sync.RWMutex
-var sessionTimeout = 24 * time.Hour
-
 func init() {
 	// Load HTML templates from embedded filesystem
 	var err error
@@ -130,17 +128,42 @@ func setupRoutes(config *Config) {
 
 	http.HandleFunc("/", handleLogin)
 	http.HandleFunc("/login", handleLogin)
+	http.HandleFunc(oidcLoginPath, handleOIDCLogin)
+	http.HandleFunc(oidcCallbackPath, handleOIDCCallback)
+	http.HandleFunc("/metrics", handleMetrics) // Own optional bearer token, no session login
 	http.HandleFunc("/dashboard", requireAuth(handleDashboard))
 	http.HandleFunc("/backup", requireAuth(handleBackup))
 	http.HandleFunc("/settings", requireAuth(handleSettings))
+	http.HandleFunc("/users", requireAuth(handleUsers))
+	http.HandleFunc("/audit", requireAuth(handleAudit))
 	http.HandleFunc("/logout", handleLogout)
 
+	http.HandleFunc("/api/me", requireAuth(handleCurrentUser))
+	http.HandleFunc("/api/me/password", requireAuth(handleChangeOwnPassword))
+	http.HandleFunc("/api/me/2fa/setup", requireAuth(handleTwoFactorSetup))
+	http.HandleFunc("/api/me/2fa/enable", requireAuth(handleTwoFactorEnable))
+	http.HandleFunc("/api/me/2fa/disable", requireAuth(handleTwoFactorDisable))
+	http.HandleFunc("/api/me/2fa/recovery-codes", requireAuth(handleTwoFactorRecoveryCodes))
+	http.HandleFunc("/api/users", requireAuth(handleListUsers))
+	http.HandleFunc("/api/users/save", requireAuth(handleSaveUser))
+	http.HandleFunc("/api/users/delete", requireAuth(handleDeleteUser))
+	http.HandleFunc("/api/users/reset-2fa", requireAuth(handleResetUserTwoFactor))
+	http.HandleFunc("/api/tokens", requireAuth(handleListAPITokens))
+	http.HandleFunc("/api/tokens/create", requireAuth(handleCreateAPIToken))
+	http.HandleFunc("/api/tokens/revoke", requireAuth(handleRevokeAPIToken))
+	http.HandleFunc("/api/audit", requireAuth(handleListAudit))
+	http.HandleFunc("/api/audit/export", requireAuth(handleExportAudit))
 	http.HandleFunc("/api/settings/load", requireAuth(handleLoadSettings))
 	http.HandleFunc("/api/settings/save", requireAuth(handleSaveSettings))
 	http.HandleFunc("/api/settings/reset", requireAuth(handleResetSettings))
+	http.HandleFunc("/api/settings/history", requireAuth(handleConfigHistory))
+	http.HandleFunc("/api/settings/history/diff", requireAuth(handleConfigHistoryDiff))
+	http.HandleFunc("/api/settings/history/rollback", requireAuth(handleConfigRollback))
 	http.HandleFunc("/api/schedule/info", requireAuth(handleScheduleInfo))
 	http.HandleFunc("/api/schedule/status", requireAuth(handleScheduleStatus))
+	http.HandleFunc("/api/schedule/missed", requireAuth(handleMissedRuns))
 	http.HandleFunc("/api/test-connection", requireAuth(handleTestConnection))
+	http.HandleFunc("/api/notification/test", requireAuth(handleTestNotification))
 	http.HandleFunc("/api/validate-binary", requireAuth(handleValidateBinary))
 	http.HandleFunc("/api/detect-binary", requireAuth(handleDetectBinary))
 	http.HandleFunc("/api/test-results", requireAuth(handleGetTestResults))
@@ -148,13 +171,18 @@ func setupRoutes(config *Config) {
 	http.HandleFunc("/api/system-info", requireAuth(handleGetSystemInfo))
 	http.HandleFunc("/api/service/restart", requireAuth(handleRestartService))
 	http.HandleFunc("/api/databases", requireValidTests(requireAuth(handleGetDatabases)))
+	http.HandleFunc("/api/databases/preview", requireAuth(handlePreviewDatabases))
 	http.HandleFunc("/api/backup/start", requireValidTests(requireAuth(handleStartBackup)))
 	http.HandleFunc("/api/backup/stop", requireAuth(handleStopBackups))
+	http.HandleFunc("/api/backup/cancel", requireAuth(handleCancelBackup))
 	http.HandleFunc("/api/optimize/start", requireValidTests(requireAuth(handleStartOptimize)))
 	http.HandleFunc("/api/optimize/stop", requireAuth(handleStopOptimize))
 	http.HandleFunc("/api/optimize/status", requireAuth(handleOptimizeStatus))
 	http.HandleFunc("/api/backup/running", requireAuth(handleGetRunningJobs))
+	http.HandleFunc("/api/backup/queue", requireAuth(handleJobQueue))
+	http.HandleFunc("/api/backup/retention/preview", requireAuth(handleRetentionPreview))
 	http.HandleFunc("/api/backup/recent-activity", requireAuth(handleGetRecentActivity))
+	http.HandleFunc("/api/backup/health", requireAuth(handleBackupHealth))
 	http.HandleFunc("/api/backup/jobs", requireAuth(handleGetBackupJobs))
 	http.HandleFunc("/api/backup/history", requireAuth(handleGetBackupHistory))
 	http.HandleFunc("/api/backup/database-groups/", requireAuth(handleGetDatabaseBackupGroups))
@@ -163,6 +191,7 @@ func setupRoutes(config *Config) {
 	http.HandleFunc("/api/backup/download-group-zip", requireAuth(handleDownloadBackupGroupZip))
 	http.HandleFunc("/api/backup/delete-group", requireAuth(handleDeleteBackupGroup))
 	http.HandleFunc("/api/backup/retry", requireAuth(handleRetryBackup))
+	http.HandleFunc("/api/backup/resume", requireAuth(handleResumeBackup))
 	http.HandleFunc("/api/logging/status", requireAuth(handleLoggingStatus))
 	http.HandleFunc("/api/logs/stream", requireAuth(handleLogStream))
 	http.HandleFunc("/api/logs/delete", requireAuth(handleDeleteLogFile))
@@ -173,34 +202,69 @@ func setupRoutes(config *Config) {
 	http.HandleFunc("/ws/system", requireAuth(handleSystemWebSocket))
 	http.HandleFunc("/ws/logs", requireAuth(handleLogsWebSocket))
 
+	// Versioned REST API
+	setupAPIv1Routes()
+
 	LogInfo("Routes configured successfully")
 }
 
 func requireAuth(handler func(http.ResponseWriter, *http.Request)) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		cookie, err := r.Cookie("session_id")
+		// API clients authenticate with a bearer token instead of a session
+		if secret, ok := bearerToken(r); ok {
+			if r = requireToken(w, r, secret); r != nil {
+				handler(w, r)
+			}
+			return
+		}
+
+		cookie, err := r.Cookie(sessionCookieName)
 		if err != nil {
-			http.Redirect(w, r, "/login", http.StatusFound)
+			redirectToLogin(w, r)
 			return
 		}
 
-		sessionsMutex.RLock()
-		sessionTime, exists := sessions[cookie.Value]
-		sessionsMutex.RUnlock()
+		session := lookupSession(cookie.Value)
+		if session == nil {
+			redirectToLogin(w, r)
+			return
+		}
 
-		if !exists || time.Since(sessionTime) > sessionTimeout {
-			sessionsMutex.Lock()
-			delete(sessions, cookie.Value)
-			sessionsMutex.Unlock()
-			http.Redirect(w, r, "/login", http.StatusFound)
+		// Look the user up on every request so role changes and disabling take effect immediately
+		user, err := GetUser(session.Username)
+		if err != nil || user == nil || user.Disabled {
+			endSession(cookie.Value)
+			redirectToLogin(w, r)
 			return
 		}
 
-		sessionsMutex.Lock()
-		sessions[cookie.Value] = time.Now()
-		sessionsMutex.Unlock()
-		handler(w, r)
+		// Browsers attach the session cookie to cross-site requests too, so
+		// anything that can change state must carry the session's CSRF token
+		if !isSafeMethod(r.Method) && !checkCSRF(r, session) {
+			denyCSRF(w, r, user)
+			return
+		}
+
+		// Check the endpoint's minimum role
+		requiredRole := getEndpointRole(r.Pattern)
+		if !user.HasRole(requiredRole) {
+			denyAccess(w, r, user, requiredRole)
+			return
+		}
+
+		refreshSession(w, r, session)
+		handler(w, withCurrentUser(r, user))
+	}
+}
+
+// redirectToLogin sends an unauthenticated browser to the login page. The
+// versioned API answers with 401 instead.
+func redirectToLogin(w http.ResponseWriter, r *http.Request) {
+	if isAPIv1Request(r) {
+		writeAPIError(w, http.StatusUnauthorized, APIErrUnauthorized, "Authentication required: send a bearer token or log in")
+		return
 	}
+	http.Redirect(w, r, "/login", http.StatusFound)
 }
 
 func requireValidTests(handler func(http.ResponseWriter, *http.Request)) http.HandlerFunc {
@@ -226,55 +290,105 @@ func requireValidTests(handler func(http.ResponseWriter, *http.Request)) http.Ha
 
 func handleLogin(w http.ResponseWriter, r *http.Request) {
 	if r.Method == "GET" {
-		renderTemplate(w, "login.html", map[string]interface{}{
-			"Title":   "Login - MariaDB Backup Tool",
-			"Version": Version,
-		})
+		renderLoginPage(w, "", false)
 		return
 	}
 
 	if r.Method == "POST" {
+		ip := clientIP(r)
+
+		// Second step of a login with two-factor authentication
+		if r.FormValue("step") == "2fa" {
+			handleTwoFactorLogin(w, r, ip)
+			return
+		}
+
 		username := r.FormValue("username")
 		password := r.FormValue("password")
 
-		// Load current config
+		// Locked out IPs and usernames are refused before the password is checked
+		if remaining := loginLimiter.lockedFor(ip, username); remaining > 0 {
+			LogWarn("🔒 [AUTH] Refused login of %s from %s: locked out", username, ip)
+			w.WriteHeader(http.StatusTooManyRequests)
+			renderLoginPage(w, formatLockout(remaining), false)
+			return
+		}
+
 		config, err := loadConfig("config.json")
 		if err != nil {
-			http.Error(w, "Failed to load config", http.StatusInternalServerError)
+			http.Error(w, "Failed to load configuration", http.StatusInternalServerError)
 			return
 		}
 
-		// Validate credentials
-		if username == config.Web.AuthUser && checkPassword(password, config.Web.AuthPassHash) {
-			// Create session
-			sessionID := generateSessionID()
-			sessionsMutex.Lock()
-			sessions[sessionID] = time.Now()
-			sessionsMutex.Unlock()
-
-			// Set session cookie
-			cookie := &http.Cookie{
-				Name:     "session_id",
-				Value:    sessionID,
-				Path:     "/",
-				HttpOnly: true,
-				MaxAge:   int(sessionTimeout.Seconds()),
-			}
-			http.SetCookie(w, cookie)
-
-			http.Redirect(w, r, "/dashboard", http.StatusFound)
+		// Validate credentials, locally or against LDAP
+		user, err := authenticatePassword(config, username, password)
+		if err == nil {
+			beginLogin(w, r, user, ip)
+			return
+		}
+		if err != errInvalidLogin {
+			LogError("❌ [AUTH] Login of %s from %s failed: %v", username, ip, err)
+			w.WriteHeader(http.StatusBadGateway)
+			renderLoginPage(w, "Login failed, the directory server is unavailable", false)
 			return
 		}
 
 		// Invalid credentials
-		renderTemplate(w, "login.html", map[string]interface{}{
-			"Title":   "Login - MariaDB Backup Tool",
-			"Error":   "Invalid username or password",
-			"Version": Version,
-		})
+		recordFailedLogin(r, username, "invalid username or password")
+		renderLoginPage(w, "Invalid username or password", false)
 	}
 }
 
+// beginLogin continues a login whose first factor is verified, asking for
+// the second factor when the user has one
+func beginLogin(w http.ResponseWriter, r *http.Request, user *User, ip string) {
+	if user.TOTPEnabled {
+		if err := startTwoFactorChallenge(w, r, user.Username); err != nil {
+			LogError("❌ [AUTH] Failed to start two-factor login for %s: %v", user.Username, err)
+			http.Error(w, "Failed to start login", http.StatusInternalServerError)
+			return
+		}
+		renderLoginPage(w, "", true)
+		return
+	}
+
+	completeLogin(w, r, user, ip)
+}
+
+// renderLoginPage shows the login form, or the code form of the second step
+func renderLoginPage(w http.ResponseWriter, errorMessage string, twoFactor bool) {
+	config, _ := loadConfig("config.json")
+	renderTemplate(w, "login.html", map[string]interface{}{
+		"Title":     "Login - MariaDB Backup Tool",
+		"Error":     errorMessage,
+		"TwoFactor": twoFactor,
+		"OIDC":      config != nil && config.Web.OIDC.Enabled,
+		"Version":   Version,
+	})
+}
+
+// completeLogin starts the session of a fully authenticated user
+func completeLogin(w http.ResponseWriter, r *http.Request, user *User, ip string) {
+	if err := startSession(w, r, user.Username); err != nil {
+		LogError("❌ [AUTH] Failed to create session for %s: %v", user.Username, err)
+		http.Error(w, "Failed to create session", http.StatusInternalServerError)
+		return
+	}
+	loginLimiter.recordSuccess(user.Username)
+
+	if err := RecordUserLogin(user.Username); err != nil {
+		LogWarn("Failed to record login of %s: %v", user.Username, err)
+	}
+	LogInfo("👤 [AUTH] %s logged in (%s) from %s", user.Username, user.Role, ip)
+	details := "source: " + user.AuthSource
+	if user.TOTPEnabled {
+		details += ", two-factor"
+	}
+	recordAuditEntry(r, AuditEntry{Actor: user.Username, Action: AuditLogin, Target: user.Username, Details: details})
+
+	http.Redirect(w, r, "/dashboard", http.StatusFound)
+}
+
 func handleDashboard(w http.ResponseWriter, r *http.Request) {
 	renderTemplate(w, "dashboard.html", map[string]interface{}{
 		"Title": "Dashboard - MariaDB Backup Tool",
@@ -291,7 +405,7 @@ func handleSettings(w http.ResponseWriter, r *http.Request) {
 	if r.Method == "GET" {
 		// Load current config
 		config, err := loadConfig("config.json")
-		if err != nil {
+		if err != nil && !isConfigValidationError(err) {
 			http.Error(w, "Failed to load config", http.StatusInternalServerError)
 			return
 		}
@@ -304,7 +418,7 @@ func handleSettings(w http.ResponseWriter, r *http.Request) {
 
 		renderTemplate(w, "settings.html", map[string]interface{}{
 			"Title":      "Settings - MariaDB Backup Tool",
-			"Config":     config,
+			"Config":     redactedConfig(config),
 			"ConfigPath": configPath,
 		})
 		return
@@ -320,7 +434,7 @@ func handleLoadSettings(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	config, err := loadConfig("config.json")
-	if err != nil {
+	if err != nil && !isConfigValidationError(err) {
 		json.NewEncoder(w).Encode(map[string]interface{}{
 			"success": false,
 			"error":   "Failed to load config: " + err.Error(),
@@ -328,10 +442,10 @@ func handleLoadSettings(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Include test results in the response
+	// Include test results in the response, secrets never leave the server
 	response := map[string]interface{}{
 		"success": true,
-		"config":  config,
+		"config":  redactedConfig(config),
 		"test_results": map[string]interface{}{
 			"connection_status":  testState.ConnectionStatus,
 			"connection_message": testState.ConnectionMessage,
@@ -344,6 +458,14 @@ func handleLoadSettings(w http.ResponseWriter, r *http.Request) {
 		},
 	}
 
+	// An invalid config file is shown with its errors so it can be fixed here
+	var configErrs ConfigErrors
+	if errors.As(err, &configErrs) {
+		fields := map[string]string{}
+		addSettingsFieldErrors(fields, configErrs)
+		response["fields"] = fields
+	}
+
 	json.NewEncoder(w).Encode(response)
 }
 
@@ -365,26 +487,83 @@ func handleScheduleInfo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Allow previewing an unsaved schedule from the settings page
+	schedule := config.Backup.BackupSchedule
+	if r.URL.Query().Has("schedule") {
+		schedule = strings.TrimSpace(r.URL.Query().Get("schedule"))
+	}
+
+	// Number of upcoming runs to return
+	count := 5
+	if countStr := r.URL.Query().Get("count"); countStr != "" {
+		if c, err := strconv.Atoi(countStr); err == nil && c > 0 && c <= 50 {
+			count = c
+		}
+	}
+
 	// Get last backup time from database
 	lastBackupTime := getLastBackupTime()
 
-	// Calculate next backup time
-	nextBackupTime := calculateNextBackupTime(config.Backup.BackupStartTime, config.Backup.BackupIntervalHours)
+	// Calculate upcoming backup times
+	nextRuns, err := CalculateNextBackupTimes(schedule, count)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   "Invalid schedule: " + err.Error(),
+		})
+		return
+	}
+
+	nextRunStrs := []string{}
+	for _, t := range nextRuns {
+		nextRunStrs = append(nextRunStrs, t.Format("2006-01-02 15:04:05"))
+	}
+
+	// Upcoming runs of every policy; the next backup is the earliest of them
+	var nextRun time.Time
+	policies := []map[string]interface{}{}
+	for _, policy := range getBackupPolicies(config) {
+		if policy.Name == DefaultPolicyName {
+			policy.Schedule = schedule
+			policy.Enabled = schedule != ""
+		}
+
+		policyRuns := []string{}
+		if policy.Enabled {
+			times, _ := CalculateNextBackupTimes(policy.Schedule, count)
+			for _, t := range times {
+				policyRuns = append(policyRuns, t.Format("2006-01-02 15:04:05"))
+			}
+			if len(times) > 0 && (nextRun.IsZero() || times[0].Before(nextRun)) {
+				nextRun = times[0]
+			}
+		}
+		policies = append(policies, map[string]interface{}{
+			"name":      policy.Name,
+			"enabled":   policy.Enabled,
+			"schedule":  policy.Schedule,
+			"mode":      policy.Mode,
+			"next_runs": policyRuns,
+		})
+	}
 
 	// Check if scheduler is disabled
-	isSchedulerDisabled := config.Backup.BackupIntervalHours == 0
-	if isSchedulerDisabled {
-		nextBackupTime = "Disabled"
+	isSchedulerDisabled := nextRun.IsZero()
+	nextBackupTime := "Disabled"
+	if !isSchedulerDisabled {
+		nextBackupTime = nextRun.Format("2006-01-02 15:04:05")
 	}
 
 	scheduleInfo := map[string]interface{}{
-		"interval_hours":       config.Backup.BackupIntervalHours,
-		"start_time":           config.Backup.BackupStartTime,
+		"policies":             policies,
+		"schedule":             schedule,
 		"default_mode":         config.Backup.DefaultBackupMode,
 		"last_backup_time":     lastBackupTime,
 		"next_backup_time":     nextBackupTime,
+		"next_runs":            nextRunStrs,
 		"full_backup_interval": config.Backup.FullBackupInterval,
 		"is_disabled":          isSchedulerDisabled,
+		"missed_run_policy":    getMissedRunPolicy(config),
 	}
 
 	json.NewEncoder(w).Encode(map[string]interface{}{
@@ -410,6 +589,243 @@ func handleScheduleStatus(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// handleMissedRuns API endpoint to get how missed scheduled runs were handled
+func handleMissedRuns(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	limit := 20
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 200 {
+			limit = l
+		}
+	}
+
+	history, err := GetMissedRunHistory(limit)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   "Failed to get missed run history: " + err.Error(),
+		})
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"history": history,
+	})
+}
+
+// handleBackupHealth returns the RPO health of every backed up database
+func handleBackupHealth(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	config, err := loadConfig("config.json")
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   "Failed to load config: " + err.Error(),
+		})
+		return
+	}
+
+	health, err := GetBackupHealth(config)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	summary := map[string]int{
+		RPOStatusOK:          0,
+		RPOStatusWarning:     0,
+		RPOStatusBreached:    0,
+		RPOStatusNever:       0,
+		RPOStatusUnmonitored: 0,
+	}
+	for _, entry := range health {
+		summary[entry.Status]++
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":   true,
+		"databases": health,
+		"summary":   summary,
+	})
+}
+
+// parseGFSRetentionForm reads the gfs_* fields of a settings form or preview request
+func parseGFSRetentionForm(r *http.Request) (GFSRetention, error) {
+	gfs := GFSRetention{
+		Enabled: r.FormValue("gfs_enabled") == "on" || r.FormValue("gfs_enabled") == "true",
+	}
+
+	fields := []struct {
+		name  string
+		value *int
+	}{
+		{"gfs_daily", &gfs.Daily},
+		{"gfs_weekly", &gfs.Weekly},
+		{"gfs_monthly", &gfs.Monthly},
+		{"gfs_yearly", &gfs.Yearly},
+	}
+	for _, field := range fields {
+		value := strings.TrimSpace(r.FormValue(field.name))
+		if value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return gfs, fmt.Errorf("%s must be a non-negative number", field.name)
+		}
+		*field.value = n
+	}
+
+	if gfs.Enabled && gfs.Daily+gfs.Weekly+gfs.Monthly+gfs.Yearly == 0 {
+		return gfs, fmt.Errorf("at least one of daily, weekly, monthly or yearly must be greater than 0")
+	}
+
+	return gfs, nil
+}
+
+// handleRetentionPreview API endpoint to show which backup groups retention would keep or prune.
+// Without gfs_* parameters the saved settings are previewed.
+func handleRetentionPreview(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != "GET" && r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	config, err := loadConfig("config.json")
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   "Failed to load config: " + err.Error(),
+		})
+		return
+	}
+
+	// Unsaved count and size limits from the settings page
+	if value := r.FormValue("keep_last_fulls"); value != "" {
+		config.Backup.KeepLastFulls, _ = strconv.Atoi(value)
+	}
+	if value := r.FormValue("max_database_backup_mb"); value != "" {
+		config.Backup.MaxDatabaseBackupMB, _ = strconv.Atoi(value)
+	}
+	if value := r.FormValue("max_total_backup_mb"); value != "" {
+		config.Backup.MaxTotalBackupMB, _ = strconv.Atoi(value)
+	}
+
+	gfs := config.Backup.GFSRetention
+	if r.FormValue("gfs_enabled") != "" {
+		gfs, err = parseGFSRetentionForm(r)
+		if err != nil {
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"success": false,
+				"error":   "Invalid GFS retention: " + err.Error(),
+			})
+			return
+		}
+	}
+
+	databases, err := PreviewRetention(config, gfs, r.FormValue("database"))
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	mode := "age"
+	if gfs.Enabled {
+		mode = "gfs"
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":   true,
+		"mode":      mode,
+		"gfs":       gfs,
+		"databases": databases,
+	})
+}
+
+// settingsFormFields maps config field paths to the settings form inputs
+// that are not named after the last part of the path
+var settingsFormFields = map[string]string{
+	"database.host":            "db_host",
+	"database.port":            "db_port",
+	"database.socket":          "db_socket",
+	"database.ssl_mode":        "db_ssl_mode",
+	"database.ssl_ca":          "db_ssl_ca",
+	"database.ssl_cert":        "db_ssl_cert",
+	"database.ssl_key":         "db_ssl_key",
+	"backup.gfs_retention":     "gfs_daily",
+	"backup.backup_start_time": "backup_schedule",
+	"web.port":                 "web_port",
+	"logging.retention_logs":   "log_retention_days",
+}
+
+// settingsFormField returns the settings form input of a config field path,
+// e.g. parallel for backup.parallel. Sections such as policies stay as is.
+func settingsFormField(path string) string {
+	if id, ok := settingsFormFields[path]; ok {
+		return id
+	}
+	return path[strings.LastIndex(path, ".")+1:]
+}
+
+// addSettingsFieldErrors adds config validation errors to fields by form
+// input, keeping errors already recorded for an input
+func addSettingsFieldErrors(fields map[string]string, errs ConfigErrors) {
+	for path, message := range errs {
+		if id := settingsFormField(path); fields[id] == "" {
+			fields[id] = message
+		}
+	}
+}
+
+// formInt reads a whole number form field, empty is 0. Other values are
+// recorded in fields.
+func formInt(r *http.Request, name string, fields map[string]string) int {
+	value := strings.TrimSpace(r.FormValue(name))
+	if value == "" {
+		return 0
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		fields[name] = "must be a whole number"
+	}
+	return n
+}
+
+// formFloat reads a number form field, empty is 0. Other values are recorded
+// in fields.
+func formFloat(r *http.Request, name string, fields map[string]string) float64 {
+	value := strings.TrimSpace(r.FormValue(name))
+	if value == "" {
+		return 0
+	}
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		fields[name] = "must be a number"
+	}
+	return n
+}
+
 // handleSaveSettings API endpoint to save settings
 func handleSaveSettings(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
@@ -419,91 +835,249 @@ func handleSaveSettings(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Parse form data
+	// The page shows stored secrets masked, a masked input keeps the stored value
+	storedConfig, err := loadConfig("config.json")
+	if err != nil && !isConfigValidationError(err) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   "Failed to load config: " + err.Error(),
+		})
+		return
+	}
+
+	// Parse form data, collecting errors by form field
+	fields := map[string]string{}
 	var config Config
+	config.Version = currentConfigVersion
+	config.secretRefs = storedConfig.secretRefs
+	config.Secrets.EncryptAtRest = r.FormValue("encrypt_secrets") == "on"
 	config.Database.Host = r.FormValue("db_host")
-	config.Database.Port, _ = strconv.Atoi(r.FormValue("db_port"))
+	config.Database.Port = formInt(r, "db_port", fields)
 	config.Database.Username = r.FormValue("db_username")
-	config.Database.Password = r.FormValue("db_password")
+	config.Database.Password = formSecret(r, "db_password", storedConfig.Database.Password)
 	config.Database.Socket = r.FormValue("db_socket")
+	config.Database.SSLMode = r.FormValue("db_ssl_mode")
+	config.Database.SSLCA = strings.TrimSpace(r.FormValue("db_ssl_ca"))
+	config.Database.SSLCert = strings.TrimSpace(r.FormValue("db_ssl_cert"))
+	config.Database.SSLKey = strings.TrimSpace(r.FormValue("db_ssl_key"))
 	// binlog_path is now read-only and populated from database, not from form
 	config.Database.BinaryDump = r.FormValue("binary_dump")
 	config.Database.BinaryCheck = r.FormValue("binary_check")
 	config.Database.BinaryBinLog = r.FormValue("binary_binlog")
 
 	config.Backup.BackupDir = r.FormValue("backup_dir")
-	config.Backup.RetentionBackups, _ = strconv.Atoi(r.FormValue("retention_backups"))
-	config.Backup.Parallel, _ = strconv.Atoi(r.FormValue("parallel"))
-	config.Backup.FullBackupInterval, _ = strconv.Atoi(r.FormValue("full_backup_interval"))
-	config.Backup.BackupIntervalHours, _ = strconv.Atoi(r.FormValue("backup_interval_hours"))
-	config.Backup.BackupStartTime = r.FormValue("backup_start_time")
-	config.Backup.CompressionLevel, _ = strconv.Atoi(r.FormValue("compression_level"))
-	config.Backup.NiceLevel, _ = strconv.Atoi(r.FormValue("nice_level"))
+	config.Backup.RetentionBackups = formInt(r, "retention_backups", fields)
+	gfsRetention, err := parseGFSRetentionForm(r)
+	if err != nil {
+		fields["gfs_daily"] = err.Error()
+	}
+	config.Backup.GFSRetention = gfsRetention
+	config.Backup.KeepLastFulls = formInt(r, "keep_last_fulls", fields)
+	config.Backup.MaxDatabaseBackupMB = formInt(r, "max_database_backup_mb", fields)
+	config.Backup.MaxTotalBackupMB = formInt(r, "max_total_backup_mb", fields)
+	config.Backup.CompressionRatio = formFloat(r, "compression_ratio", fields)
+	config.Backup.MinFreeSpaceMB = formInt(r, "min_free_space_mb", fields)
+	config.Backup.LowSpaceAction = r.FormValue("low_space_action")
+	if config.Backup.LowSpaceAction != LowSpacePrune {
+		config.Backup.LowSpaceAction = LowSpaceRefuse
+	}
+	config.Backup.OrphanTempAction = r.FormValue("orphan_temp_action")
+	if config.Backup.OrphanTempAction != OrphanTempQuarantine {
+		config.Backup.OrphanTempAction = OrphanTempDelete
+	}
+	config.Backup.ResumeInterrupted = r.FormValue("resume_interrupted") == "on"
+	config.Backup.RPOHours = formInt(r, "rpo_hours", fields)
+	databaseRPO, err := parseDatabaseRPO(r.FormValue("database_rpo"))
+	if err != nil {
+		fields["database_rpo"] = err.Error()
+	}
+	config.Backup.DatabaseRPO = databaseRPO
+	config.Backup.ShutdownTimeout = formInt(r, "shutdown_timeout", fields)
+	config.Backup.Parallel = formInt(r, "parallel", fields)
+	config.Backup.MaxConcurrentJobs = formInt(r, "max_concurrent_jobs", fields)
+	config.Backup.ScheduleConflict = r.FormValue("schedule_conflict")
+	if config.Backup.ScheduleConflict != JobConflictWait {
+		config.Backup.ScheduleConflict = JobConflictSkip
+	}
+	config.Backup.FullBackupInterval = formInt(r, "full_backup_interval", fields)
+	config.Backup.BackupSchedule = strings.TrimSpace(r.FormValue("backup_schedule"))
+	config.Backup.CompressionLevel = formInt(r, "compression_level", fields)
+	config.Backup.NiceLevel = formInt(r, "nice_level", fields)
 	config.Backup.DefaultBackupMode = r.FormValue("default_backup_mode")
+	config.Backup.MissedRunPolicy = r.FormValue("missed_run_policy")
+	if config.Backup.MissedRunPolicy == "" {
+		config.Backup.MissedRunPolicy = MissedRunOnce
+	}
 	config.Backup.OptimizeTables = r.FormValue("optimize_tables") == "on"
-	config.Backup.MaxMemoryThreshold, _ = strconv.Atoi(r.FormValue("max_memory_threshold"))
+	config.Backup.MaxMemoryThreshold = formInt(r, "max_memory_threshold", fields)
 	config.Backup.MaxMemoryPerProcess = r.FormValue("max_memory_per_process")
 	config.Backup.CreateTableInfo = r.FormValue("create_table_info") == "on"
 	config.Backup.MysqldumpOptions = r.FormValue("mysqldump_options")
 	config.Backup.MariadbCheckOptions = r.FormValue("mariadb_check_options")
 	config.Backup.MariadbBinlogOptions = r.FormValue("mariadb_binlog_options")
 
-	// Parse ignore databases
-	ignoreDbsStr := r.FormValue("ignore_dbs")
-	if ignoreDbsStr != "" {
-		config.Backup.IgnoreDbs = strings.Split(ignoreDbsStr, "\n")
-		// Clean up empty strings
-		var cleaned []string
-		for _, db := range config.Backup.IgnoreDbs {
-			if strings.TrimSpace(db) != "" {
-				cleaned = append(cleaned, strings.TrimSpace(db))
-			}
-		}
-		config.Backup.IgnoreDbs = cleaned
-	}
+	// Parse ignore/include database patterns
+	config.Backup.IgnoreDbs = parsePatternList(r.FormValue("ignore_dbs"))
+	config.Backup.IncludeDbs = parsePatternList(r.FormValue("include_dbs"))
 
-	config.Web.Port, _ = strconv.Atoi(r.FormValue("web_port"))
-	config.Web.AuthUser = r.FormValue("auth_user")
+	config.Web.Port = formInt(r, "web_port", fields)
 	config.Web.SSLEnabled = r.FormValue("ssl_enabled") == "on"
 	config.Web.SSLCertFile = r.FormValue("ssl_cert_file")
 	config.Web.SSLKeyFile = r.FormValue("ssl_key_file")
 
-	// Handle password change
-	newPassword := r.FormValue("new_password")
-	if newPassword != "" {
-		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
-		if err != nil {
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"success": false,
-				"error":   "Failed to hash password: " + err.Error(),
-			})
-			return
+	// Accounts are managed on the Users page, keep the initial admin credentials
+	existingConfig, _ := loadConfig("config.json")
+	if existingConfig != nil {
+		config.Web.AuthUser = existingConfig.Web.AuthUser
+		config.Web.AuthPassHash = existingConfig.Web.AuthPassHash
+	}
+
+	// Handle metrics token change, keeping the existing token unless a new one is set or it is removed
+	config.Web.MetricsEnabled = r.FormValue("metrics_enabled") == "on"
+	if metricsToken := r.FormValue("metrics_token"); metricsToken != "" {
+		config.Web.MetricsTokenHash = hashMetricsToken(metricsToken)
+	} else if r.FormValue("metrics_token_clear") != "on" {
+		existingConfig, _ := loadConfig("config.json")
+		if existingConfig != nil {
+			config.Web.MetricsTokenHash = existingConfig.Web.MetricsTokenHash
+		}
+	}
+
+	// Single sign-on
+	config.Web.LocalLogin = r.FormValue("local_login")
+	config.Web.OIDC.Enabled = r.FormValue("oidc_enabled") == "on"
+	config.Web.OIDC.IssuerURL = strings.TrimSpace(r.FormValue("oidc_issuer_url"))
+	config.Web.OIDC.ClientID = strings.TrimSpace(r.FormValue("oidc_client_id"))
+	config.Web.OIDC.ClientSecret = formSecret(r, "oidc_client_secret", storedConfig.Web.OIDC.ClientSecret)
+	config.Web.OIDC.RedirectURL = strings.TrimSpace(r.FormValue("oidc_redirect_url"))
+	config.Web.OIDC.Scopes = strings.Fields(r.FormValue("oidc_scopes"))
+	config.Web.OIDC.UsernameClaim = strings.TrimSpace(r.FormValue("oidc_username_claim"))
+	config.Web.OIDC.GroupsClaim = strings.TrimSpace(r.FormValue("oidc_groups_claim"))
+	config.Web.OIDC.DefaultRole = r.FormValue("oidc_default_role")
+	config.Web.LDAP.Enabled = r.FormValue("ldap_enabled") == "on"
+	config.Web.LDAP.URL = strings.TrimSpace(r.FormValue("ldap_url"))
+	config.Web.LDAP.StartTLS = r.FormValue("ldap_start_tls") == "on"
+	config.Web.LDAP.InsecureSkipVerify = r.FormValue("ldap_insecure_skip_verify") == "on"
+	config.Web.LDAP.BindDN = strings.TrimSpace(r.FormValue("ldap_bind_dn"))
+	config.Web.LDAP.BindPassword = formSecret(r, "ldap_bind_password", storedConfig.Web.LDAP.BindPassword)
+	config.Web.LDAP.UserBaseDN = strings.TrimSpace(r.FormValue("ldap_user_base_dn"))
+	config.Web.LDAP.UserFilter = strings.TrimSpace(r.FormValue("ldap_user_filter"))
+	config.Web.LDAP.GroupBaseDN = strings.TrimSpace(r.FormValue("ldap_group_base_dn"))
+	config.Web.LDAP.GroupFilter = strings.TrimSpace(r.FormValue("ldap_group_filter"))
+	config.Web.LDAP.GroupAttribute = strings.TrimSpace(r.FormValue("ldap_group_attribute"))
+	config.Web.LDAP.DefaultRole = r.FormValue("ldap_default_role")
+	if config.Web.OIDC.GroupRoles, err = parseGroupRoles(r.FormValue("oidc_group_roles")); err != nil {
+		fields["oidc_group_roles"] = err.Error()
+	}
+	if config.Web.LDAP.GroupRoles, err = parseGroupRoles(r.FormValue("ldap_group_roles")); err != nil {
+		fields["ldap_group_roles"] = err.Error()
+	}
+
+	config.Logging.LogDir = r.FormValue("log_dir")
+	config.Logging.RetentionLogs = formInt(r, "log_retention_days", fields)
+
+	config.Notification.SlackWebhookURL = strings.TrimSpace(formSecret(r, "slack_webhook", storedConfig.Notification.SlackWebhookURL))
+
+	config.Notification.Webhook.Enabled = r.FormValue("webhook_enabled") == "on"
+	config.Notification.Webhook.URL = strings.TrimSpace(formSecret(r, "webhook_url", storedConfig.Notification.Webhook.URL))
+	config.Notification.Webhook.BodyTemplate = r.FormValue("webhook_body_template")
+	webhookHeaders, err := parseWebhookHeaders(r.FormValue("webhook_headers"))
+	if err != nil {
+		fields["webhook_headers"] = err.Error()
+	}
+	config.Notification.Webhook.Headers = webhookHeaders
+
+	config.Notification.Email.Enabled = r.FormValue("email_enabled") == "on"
+	config.Notification.Email.SMTPHost = strings.TrimSpace(r.FormValue("email_smtp_host"))
+	config.Notification.Email.SMTPPort = formInt(r, "email_smtp_port", fields)
+	config.Notification.Email.Username = r.FormValue("email_username")
+	config.Notification.Email.Password = formSecret(r, "email_password", storedConfig.Notification.Email.Password)
+	config.Notification.Email.TLSMode = r.FormValue("email_tls_mode")
+	config.Notification.Email.From = strings.TrimSpace(r.FormValue("email_from"))
+	config.Notification.Email.To = parseEmailRecipients(r.FormValue("email_to"))
+
+	config.Notification.Teams.Enabled = r.FormValue("teams_enabled") == "on"
+	config.Notification.Teams.WebhookURL = strings.TrimSpace(formSecret(r, "teams_webhook_url", storedConfig.Notification.Teams.WebhookURL))
+
+	config.Notification.Discord.Enabled = r.FormValue("discord_enabled") == "on"
+	config.Notification.Discord.WebhookURL = strings.TrimSpace(formSecret(r, "discord_webhook_url", storedConfig.Notification.Discord.WebhookURL))
+
+	config.Notification.Telegram.Enabled = r.FormValue("telegram_enabled") == "on"
+	config.Notification.Telegram.BotToken = strings.TrimSpace(formSecret(r, "telegram_bot_token", storedConfig.Notification.Telegram.BotToken))
+	config.Notification.Telegram.ChatID = strings.TrimSpace(r.FormValue("telegram_chat_id"))
+
+	config.Notification.QuietHours = strings.TrimSpace(r.FormValue("quiet_hours"))
+	config.Notification.DedupMinutes = formInt(r, "dedup_minutes", fields)
+	config.Notification.DigestTime = strings.TrimSpace(r.FormValue("digest_time"))
+
+	// Parse notification rules (sent as a JSON array)
+	if rulesStr, ok := r.Form["notification_rules"]; ok {
+		if strings.TrimSpace(rulesStr[0]) != "" {
+			if err := json.Unmarshal([]byte(rulesStr[0]), &config.Notification.Rules); err != nil {
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"success": false,
+					"error":   "Invalid notification rules: " + err.Error(),
+				})
+				return
+			}
 		}
-		config.Web.AuthPassHash = string(hashedPassword)
 	} else {
-		// Keep existing password hash
+		// Keep existing rules when the form does not include them
 		existingConfig, _ := loadConfig("config.json")
 		if existingConfig != nil {
-			config.Web.AuthPassHash = existingConfig.Web.AuthPassHash
+			config.Notification.Rules = existingConfig.Notification.Rules
 		}
 	}
 
-	config.Logging.LogDir = r.FormValue("log_dir")
-	config.Logging.RetentionLogs, _ = strconv.Atoi(r.FormValue("log_retention_days"))
+	// Parse named backup policies (sent as a JSON array)
+	if policiesStr, ok := r.Form["policies"]; ok {
+		if strings.TrimSpace(policiesStr[0]) != "" {
+			if err := json.Unmarshal([]byte(policiesStr[0]), &config.Policies); err != nil {
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"success": false,
+					"error":   "Invalid backup policies: " + err.Error(),
+				})
+				return
+			}
+			keepPolicySecrets(config.Policies, storedConfig.Policies)
+		}
+	} else {
+		// Keep existing policies when the form does not include them
+		existingConfig, _ := loadConfig("config.json")
+		if existingConfig != nil {
+			config.Policies = existingConfig.Policies
+		}
+	}
 
-	config.Notification.SlackWebhookURL = r.FormValue("slack_webhook")
+	// Validate the whole config, parse errors of a field take precedence
+	addSettingsFieldErrors(fields, validateConfig(&config))
+	addSettingsFieldErrors(fields, checkConfigFiles(&config))
+	if len(fields) > 0 {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   "Invalid settings: " + ConfigErrors(fields).Error(),
+			"fields":  fields,
+		})
+		return
+	}
 
 	// Save config
+	previousConfig, _ := loadConfig("config.json")
 	if err := saveConfig(&config, "config.json"); err != nil {
+		recordAuditFailure(r, AuditSettingsSave, "config.json", err.Error())
 		json.NewEncoder(w).Encode(map[string]interface{}{
 			"success": false,
 			"error":   "Failed to save config: " + err.Error(),
 		})
 		return
 	}
+	changedBefore, changedAfter := auditConfigChanges(previousConfig, &config)
+	recordAudit(r, AuditSettingsSave, "config.json", changedBefore, changedAfter)
+	recordConfigSnapshot(&config, currentUsername(r), ConfigSourceSave, 0)
 
 	// Reload scheduler with new configuration
 	ReloadSchedulerConfig(&config)
+	ConfigureJobQueue(&config)
 	LogInfo("Settings saved and scheduler configuration reloaded")
 
 	json.NewEncoder(w).Encode(map[string]interface{}{
@@ -521,9 +1095,13 @@ func handleResetSettings(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Read the config being replaced before the defaults overwrite the file
+	previousConfig, _ := loadConfig("config.json")
+
 	// Create default config
 	defaultConfig, err := createDefaultConfig("config.json")
 	if err != nil {
+		recordAuditFailure(r, AuditSettingsReset, "config.json", err.Error())
 		json.NewEncoder(w).Encode(map[string]interface{}{
 			"success": false,
 			"error":   "Failed to create default config: " + err.Error(),
@@ -533,12 +1111,21 @@ func handleResetSettings(w http.ResponseWriter, r *http.Request) {
 
 	// Save default config
 	if err := saveConfig(defaultConfig, "config.json"); err != nil {
+		recordAuditFailure(r, AuditSettingsReset, "config.json", err.Error())
 		json.NewEncoder(w).Encode(map[string]interface{}{
 			"success": false,
 			"error":   "Failed to reset config: " + err.Error(),
 		})
 		return
 	}
+	changedBefore, changedAfter := auditConfigChanges(previousConfig, defaultConfig)
+	recordAudit(r, AuditSettingsReset, "config.json", changedBefore, changedAfter)
+	recordConfigSnapshot(defaultConfig, currentUsername(r), ConfigSourceReset, 0)
+
+	// Reload scheduler so the old schedule and policies stop running
+	ReloadSchedulerConfig(defaultConfig)
+	ConfigureJobQueue(defaultConfig)
+	LogInfo("Settings reset to default and scheduler configuration reloaded")
 
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"success": true,
@@ -548,22 +1135,16 @@ func handleResetSettings(w http.ResponseWriter, r *http.Request) {
 
 // handleLogout handles logout
 func handleLogout(w http.ResponseWriter, r *http.Request) {
-	// Get session cookie and remove from sessions map
-	if cookie, err := r.Cookie("session_id"); err == nil {
-		sessionsMutex.Lock()
-		delete(sessions, cookie.Value)
-		sessionsMutex.Unlock()
+	// Get session cookie and remove the session
+	if cookie, err := r.Cookie(sessionCookieName); err == nil {
+		if session := lookupSession(cookie.Value); session != nil {
+			recordAuditEntry(r, AuditEntry{Actor: session.Username, Action: AuditLogout, Target: session.Username})
+		}
+		endSession(cookie.Value)
 	}
 
-	// Clear session cookie
-	cookie := &http.Cookie{
-		Name:     "session_id",
-		Value:    "",
-		Path:     "/",
-		HttpOnly: true,
-		MaxAge:   -1,
-	}
-	http.SetCookie(w, cookie)
+	// Clear session and CSRF cookies
+	setSessionCookies(w, r, "", "", -1)
 
 	http.Redirect(w, r, "/login", http.StatusFound)
 }
@@ -588,11 +1169,6 @@ func checkPassword(password, hash string) bool {
 	return err == nil
 }
 
-// generateSessionID generates a random session ID
-func generateSessionID() string {
-	return fmt.Sprintf("%d", time.Now().UnixNano())
-}
-
 // handleTestConnection API endpoint to test MySQL connection
 func handleTestConnection(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
@@ -628,6 +1204,7 @@ func handleTestConnection(w http.ResponseWriter, r *http.Request) {
 			"success": true,
 			"message": result["message"],
 			"details": result["details"],
+			"tls":     result["tls"],
 		})
 	} else {
 		// Connection failed, disable buttons
@@ -644,7 +1221,14 @@ func buildMySQLDSN(config *Config) (string, error) {
 	// Add connection parameters to handle large result sets
 	// maxAllowedPacket: 64MB, readTimeout: 60s, writeTimeout: 60s, timeout: 60s
 	params := "maxAllowedPacket=67108864&readTimeout=60s&writeTimeout=60s&timeout=60s&charset=utf8mb4&parseTime=true&loc=Local"
-	
+
+	// Select the registered TLS config when TLS is on
+	tlsParams, err := databaseTLSParams(config)
+	if err != nil {
+		return "", fmt.Errorf("invalid database TLS settings: %v", err)
+	}
+	params += tlsParams
+
 	if config.Database.Port > 0 && config.Database.Host != "" {
 		// Use TCP connection when port is specified and host is not empty
 		return fmt.Sprintf("%s:%s@tcp(%s:%d)/?%s",
@@ -665,10 +1249,21 @@ func buildMySQLDSN(config *Config) (string, error) {
 	}
 }
 
-// buildMySQLConnectionArgs builds MySQL connection arguments for mysqldump/mysql commands
+// buildMySQLConnectionArgs builds MySQL connection arguments for mysqldump/mysql commands.
+// They must come first, --defaults-extra-file is only accepted as the first option.
 func buildMySQLConnectionArgs(config *Config) []string {
 	var args []string
 
+	// Pass the password in a private option file, not as -p on the command line
+	if config.Database.Password != "" {
+		optionFile, err := clientOptionFile(config.Database.Password)
+		if err != nil {
+			LogError("🔐 [SECRETS] Failed to write the client option file, connecting without password: %v", err)
+		} else {
+			args = append(args, "--defaults-extra-file="+optionFile)
+		}
+	}
+
 	if config.Database.Port > 0 && config.Database.Host != "" {
 		// Use TCP connection
 		args = append(args, "-h", config.Database.Host)
@@ -683,14 +1278,33 @@ func buildMySQLConnectionArgs(config *Config) []string {
 		args = append(args, "-u", config.Database.Username)
 	}
 
-	// Add password if specified
-	if config.Database.Password != "" {
-		args = append(args, "-p"+config.Database.Password)
-	}
+	// Add TLS options
+	args = append(args, buildDatabaseTLSArgs(config)...)
 
 	return args
 }
 
+// shellQuoteArgs quotes arguments for the sh -c command lines used on Linux,
+// so paths and names with spaces or shell metacharacters stay one argument
+func shellQuoteArgs(args []string) []string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = shellQuote(arg)
+	}
+	return quoted
+}
+
+// shellQuote wraps an argument in single quotes unless it only contains
+// characters the shell leaves alone
+func shellQuote(arg string) string {
+	if arg != "" && strings.IndexFunc(arg, func(c rune) bool {
+		return !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.ContainsRune("-_=./:,@+%", c))
+	}) < 0 {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
+
 // handleValidateBinary API endpoint to validate binary files and binlog settings
 func handleValidateBinary(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
@@ -837,6 +1451,39 @@ func handleGetDatabases(w http.ResponseWriter, r *http.Request) {
 
 // getDatabases queries MySQL for available databases
 func getDatabases(config *Config) ([]string, error) {
+	allDatabases, err := listServerDatabases(config)
+	if err != nil {
+		return nil, err
+	}
+
+	var databases []string
+	var systemCount int
+	var ignoredCount int
+	var notIncludedCount int
+
+	for _, dbName := range allDatabases {
+		// Filter out system databases and databases excluded by include/ignore patterns
+		status, _ := classifyDatabase(config.Backup.IncludeDbs, config.Backup.IgnoreDbs, dbName)
+		switch status {
+		case "system":
+			systemCount++
+		case "ignored":
+			ignoredCount++
+		case "not_included":
+			notIncludedCount++
+		default:
+			databases = append(databases, dbName)
+		}
+	}
+
+	LogInfo("Retrieved %d databases from MySQL: total=%d, system=%d, ignored=%d, not_included=%d, valid=%d",
+		len(databases), len(allDatabases), systemCount, ignoredCount, notIncludedCount, len(databases))
+
+	return databases, nil
+}
+
+// listServerDatabases returns every database name reported by SHOW DATABASES
+func listServerDatabases(config *Config) ([]string, error) {
 	// Build connection string
 	dsn, err := buildMySQLDSN(config)
 	if err != nil {
@@ -863,54 +1510,94 @@ func getDatabases(config *Config) ([]string, error) {
 	defer rows.Close()
 
 	var databases []string
-	var totalCount int
-	var systemCount int
-	var ignoredCount int
-	
 	for rows.Next() {
-		totalCount++
 		var dbName string
 		if err := rows.Scan(&dbName); err != nil {
 			LogWarn("Failed to scan database name: %v", err)
 			continue
 		}
-
-		// Filter out system databases
-		if dbName == "information_schema" ||
-			dbName == "performance_schema" ||
-			dbName == "mysql" ||
-			dbName == "sys" {
-			systemCount++
-			continue
-		}
-
-		// Filter out ignored databases from config
-		shouldIgnore := false
-		for _, ignoredDB := range config.Backup.IgnoreDbs {
-			if dbName == ignoredDB {
-				shouldIgnore = true
-				ignoredCount++
-				break
-			}
-		}
-
-		if !shouldIgnore {
-			databases = append(databases, dbName)
-		}
+		databases = append(databases, dbName)
 	}
-	
+
 	// Check for errors from iterating over rows
 	if err := rows.Err(); err != nil {
 		LogError("Error iterating over database results: %v", err)
 		return nil, err
 	}
 
-	LogInfo("Retrieved %d databases from MySQL: total=%d, system=%d, ignored=%d, valid=%d",
-		len(databases), totalCount, systemCount, ignoredCount, len(databases))
-
 	return databases, nil
 }
 
+// handlePreviewDatabases shows which databases the given include/ignore patterns select
+func handlePreviewDatabases(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	config, err := loadConfig("config.json")
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   "Failed to load config: " + err.Error(),
+		})
+		return
+	}
+
+	includeDbs := parsePatternList(r.FormValue("include_dbs"))
+	ignoreDbs := parsePatternList(r.FormValue("ignore_dbs"))
+
+	if err := validateDatabasePatterns(includeDbs); err != nil {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   "Include databases: " + err.Error(),
+		})
+		return
+	}
+	if err := validateDatabasePatterns(ignoreDbs); err != nil {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   "Ignore databases: " + err.Error(),
+		})
+		return
+	}
+
+	allDatabases, err := listServerDatabases(config)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   "Failed to get databases: " + err.Error(),
+		})
+		return
+	}
+
+	databases := []map[string]interface{}{}
+	selectedCount := 0
+	for _, dbName := range allDatabases {
+		status, pattern := classifyDatabase(includeDbs, ignoreDbs, dbName)
+		if status == "system" {
+			continue
+		}
+		if status == "included" {
+			selectedCount++
+		}
+		databases = append(databases, map[string]interface{}{
+			"name":    dbName,
+			"status":  status,
+			"pattern": pattern,
+		})
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":        true,
+		"include_only":   len(includeDbs) > 0,
+		"databases":      databases,
+		"selected_count": selectedCount,
+		"total_count":    len(databases),
+	})
+}
+
 // getSystemMetrics gets current CPU and memory usage
 func getSystemMetrics() map[string]interface{} {
 	// Get CPU usage
@@ -1386,10 +2073,25 @@ func testMySQLConnection(config *Config) map[string]interface{} {
 		return result
 	}
 
+	// Report the negotiated TLS version and cipher
+	tlsVersion, tlsCipher, err := queryConnectionTLS(db)
+	if err != nil {
+		LogWarn("Failed to query connection TLS status: %v", err)
+	}
+	result["tls"] = map[string]interface{}{
+		"encrypted": tlsCipher != "",
+		"version":   tlsVersion,
+		"cipher":    tlsCipher,
+	}
+	tlsDetails := "not encrypted"
+	if tlsCipher != "" {
+		tlsDetails = fmt.Sprintf("%s %s", tlsVersion, tlsCipher)
+	}
+
 	result["status"] = "success"
 	if config.Database.Port > 0 && config.Database.Host != "" {
 		result["message"] = "MySQL connection successful (TCP)"
-		result["details"] = fmt.Sprintf("Connected via TCP to %s:%d - MySQL version: %s", config.Database.Host, config.Database.Port, version)
+		result["details"] = fmt.Sprintf("Connected via TCP to %s:%d - MySQL version: %s - TLS: %s", config.Database.Host, config.Database.Port, version, tlsDetails)
 	} else {
 		result["message"] = "MySQL connection successful (Unix Socket)"
 		result["details"] = fmt.Sprintf("Connected via Unix socket %s - MySQL version: %s", config.Database.Socket, version)
@@ -1411,6 +2113,8 @@ func handleGetRunningJobs(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	runningData["queue"] = GetJobQueueStatus()
+
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"success": true,
 		"data":    runningData,
@@ -1676,6 +2380,8 @@ func handleDownloadBackupFile(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", fileName))
 	w.Header().Set("Content-Type", "application/octet-stream")
 
+	recordAudit(r, AuditBackupDownload, filePath, nil, nil)
+
 	// Serve the file
 	http.ServeFile(w, r, filePath)
 }
@@ -1737,6 +2443,7 @@ func handleDownloadBackupGroupZip(w http.ResponseWriter, r *http.Request) {
 
 	// Create ZIP file
 	zipFileName := fmt.Sprintf("%s_backup_group_%s.zip", requestData.DatabaseName, time.Now().Format("20060102_150405"))
+	recordAudit(r, AuditBackupDownload, requestData.FullBackupPath, nil, map[string]interface{}{"zip": zipFileName, "files": allPaths})
 
 	// Set headers for ZIP download
 	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", zipFileName))
@@ -1841,6 +2548,14 @@ func handleDeleteBackupGroup(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if len(errors) > 0 {
+		recordAuditEntry(r, AuditEntry{
+			Action:  AuditBackupDelete,
+			Target:  requestData.FullBackupPath,
+			Outcome: AuditOutcomeFailure,
+			Before:  auditValue(allPaths),
+			After:   auditValue(map[string]interface{}{"deleted": deletedFilePaths}),
+			Details: strings.Join(errors, "; "),
+		})
 		json.NewEncoder(w).Encode(map[string]interface{}{
 			"success":       false,
 			"error":         "Some files could not be deleted",
@@ -1851,6 +2566,7 @@ func handleDeleteBackupGroup(w http.ResponseWriter, r *http.Request) {
 	}
 
 	LogInfo("Successfully deleted %d backup files for database %s", deletedFiles, requestData.DatabaseName)
+	recordAudit(r, AuditBackupDelete, requestData.FullBackupPath, allPaths, nil)
 
 	// Create deletion log for UI-initiated deletion
 	if len(deletedFilePaths) > 0 {
@@ -1910,6 +2626,7 @@ func handleStartBackup(w http.ResponseWriter, r *http.Request) {
 	var requestData struct {
 		BackupMode string   `json:"backup_mode"`
 		Databases  []string `json:"databases"`
+		OnConflict string   `json:"on_conflict"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil {
@@ -1933,11 +2650,19 @@ func handleStartBackup(w http.ResponseWriter, r *http.Request) {
 		requestData.BackupMode = "auto"
 	}
 
+	if requestData.OnConflict != "" && requestData.OnConflict != JobConflictWait && requestData.OnConflict != JobConflictSkip {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   "Invalid on_conflict value: " + requestData.OnConflict + " (wait or skip)",
+		})
+		return
+	}
+
 	// Generate job ID using timestamp format
 	jobID := GenerateJobID()
 
-	LogInfo("Manual backup request received - JobID: %s, Mode: %s, Databases: %v",
-		jobID, requestData.BackupMode, requestData.Databases)
+	LogInfo("Manual backup request received - JobID: %s, Mode: %s, Databases: %v, RequestedBy: %s",
+		jobID, requestData.BackupMode, requestData.Databases, currentUsername(r))
 
 	// Route to appropriate backup function based on mode
 	switch requestData.BackupMode {
@@ -1947,10 +2672,12 @@ func handleStartBackup(w http.ResponseWriter, r *http.Request) {
 			JobID:       jobID,
 			Databases:   requestData.Databases,
 			BackupMode:  requestData.BackupMode,
-			RequestedBy: "web_ui",
+			RequestedBy: currentUsername(r),
+			OnConflict:  requestData.OnConflict,
 		}
 
 		response := StartFullBackup(backupRequest)
+		auditBackupStart(r, response.JobID, requestData.BackupMode, requestData.Databases, response.Success, response.Message)
 
 		if response.Success {
 			json.NewEncoder(w).Encode(map[string]interface{}{
@@ -1974,10 +2701,12 @@ func handleStartBackup(w http.ResponseWriter, r *http.Request) {
 			JobID:       jobID,
 			Databases:   requestData.Databases,
 			BackupMode:  requestData.BackupMode,
-			RequestedBy: "web_ui",
+			RequestedBy: currentUsername(r),
+			OnConflict:  requestData.OnConflict,
 		}
 
 		response := StartIncBackup(backupRequest)
+		auditBackupStart(r, response.JobID, requestData.BackupMode, requestData.Databases, response.Success, response.Message)
 
 		if response.Success {
 			json.NewEncoder(w).Encode(map[string]interface{}{
@@ -2031,7 +2760,8 @@ func handleStartBackup(w http.ResponseWriter, r *http.Request) {
 				JobID:       fullJobID,
 				Databases:   fullBackupDBs,
 				BackupMode:  "auto", // Use "auto" mode, backup-full.go will convert to "auto-full" type
-				RequestedBy: "web_ui",
+				RequestedBy: currentUsername(r),
+				OnConflict:  requestData.OnConflict,
 			}
 
 			go StartFullBackup(fullRequest)
@@ -2045,13 +2775,16 @@ func handleStartBackup(w http.ResponseWriter, r *http.Request) {
 				JobID:       incJobID,
 				Databases:   incBackupDBs,
 				BackupMode:  "auto", // Use "auto" mode, backup-inc.go will convert to "auto-inc" type
-				RequestedBy: "web_ui",
+				RequestedBy: currentUsername(r),
+				OnConflict:  requestData.OnConflict,
 			}
 
 			go StartIncBackup(incRequest)
 			LogInfo("Started incremental backup for %d databases (JobID: %s)", len(incBackupDBs), incJobID)
 		}
 
+		auditBackupStart(r, jobID, "auto", requestData.Databases, true, fmt.Sprintf("%d full, %d incremental", len(fullBackupDBs), len(incBackupDBs)))
+
 		// Return response
 		json.NewEncoder(w).Encode(map[string]interface{}{
 			"success": true,
@@ -2076,6 +2809,20 @@ func handleStartBackup(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// auditBackupStart records a manual backup request
+func auditBackupStart(r *http.Request, jobID, mode string, databases []string, success bool, message string) {
+	entry := AuditEntry{
+		Action:  AuditBackupStart,
+		Target:  strings.Join(databases, ", "),
+		After:   auditValue(map[string]interface{}{"job_id": jobID, "mode": mode}),
+		Details: message,
+	}
+	if !success {
+		entry.Outcome = AuditOutcomeFailure
+	}
+	recordAuditEntry(r, entry)
+}
+
 // handleStartOptimize handles the optimize request from web UI
 func handleStartOptimize(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
@@ -2117,13 +2864,14 @@ func handleStartOptimize(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	LogInfo("Optimize request received - Databases: %v", requestData.Databases)
+	LogInfo("Optimize request received - Databases: %v, RequestedBy: %s", requestData.Databases, currentUsername(r))
 
 	// Reset global abort flag when starting new optimization
 	ResetGlobalOptimizeAbort()
 
 	// Start optimization in background
 	go optimizeDatabases(config, requestData.Databases)
+	recordAudit(r, AuditOptimizeStart, strings.Join(requestData.Databases, ", "), nil, nil)
 
 	// Return success response immediately
 	json.NewEncoder(w).Encode(map[string]interface{}{
@@ -2260,6 +3008,7 @@ func handleStopOptimize(w http.ResponseWriter, r *http.Request) {
 
 	// Signal global abort to stop all optimization processes
 	SignalGlobalOptimizeAbort()
+	recordAudit(r, AuditOptimizeStop, "all databases", nil, nil)
 
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"success": true,
@@ -2267,6 +3016,73 @@ func handleStopOptimize(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// handleJobQueue API endpoint to get pending and running jobs of the job queue
+func handleJobQueue(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"queue":   GetJobQueueStatus(),
+	})
+}
+
+// handleResumeBackup queues the unfinished databases of a job interrupted by a restart
+func handleResumeBackup(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var requestData struct {
+		JobID string `json:"job_id"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   "Invalid request format: " + err.Error(),
+		})
+		return
+	}
+
+	if requestData.JobID == "" {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   "job_id is required",
+		})
+		return
+	}
+
+	LogInfo("Resume backup request received - JobID: %s, RequestedBy: %s", requestData.JobID, currentUsername(r))
+
+	newJobID, err := ResumeInterruptedBackup(requestData.JobID)
+	if err != nil {
+		LogError("Failed to resume backup job %s: %v", requestData.JobID, err)
+		recordAuditFailure(r, AuditBackupResume, requestData.JobID, err.Error())
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	recordAudit(r, AuditBackupResume, requestData.JobID, nil, map[string]interface{}{"resumed_job_id": newJobID})
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":        true,
+		"message":        fmt.Sprintf("Resumed as job %s", newJobID),
+		"job_id":         requestData.JobID,
+		"resumed_job_id": newJobID,
+	})
+}
+
 // handleRetryBackup handles retrying failed databases for a completed backup job
 func handleRetryBackup(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
@@ -2298,12 +3114,13 @@ func handleRetryBackup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	LogInfo("Retry backup request received - JobID: %s", requestData.JobID)
+	LogInfo("Retry backup request received - JobID: %s, RequestedBy: %s", requestData.JobID, currentUsername(r))
 
 	// Call retry function
 	err := RetryFailedBackups(requestData.JobID)
 	if err != nil {
 		LogError("Failed to retry backup for job %s: %v", requestData.JobID, err)
+		recordAuditFailure(r, AuditBackupRetry, requestData.JobID, err.Error())
 		json.NewEncoder(w).Encode(map[string]interface{}{
 			"success": false,
 			"error":   err.Error(),
@@ -2311,6 +3128,8 @@ func handleRetryBackup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	recordAudit(r, AuditBackupRetry, requestData.JobID, nil, nil)
+
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"success": true,
 		"message": "Retry started successfully",
@@ -2327,11 +3146,18 @@ func handleStopBackups(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	LogInfo("Stop backup request received")
+	LogInfo("Stop backup request received - RequestedBy: %s", currentUsername(r))
 
 	// Signal global abort to stop all backup processes including queued ones
 	SignalGlobalBackupAbort()
 
+	// Kill running dump/binlog processes instead of waiting for them to finish
+	killedCount := CancelAllBackups()
+
+	// Drop jobs that are still waiting in the job queue
+	removedCount := CancelPendingJobs()
+	recordAudit(r, AuditBackupStop, "all jobs", nil, map[string]interface{}{"killed_processes": killedCount, "removed_queued": removedCount})
+
 	// Get all active jobs (running, optimizing, etc.) from SQLite
 	activeJobs, err := GetActiveJobs()
 	if err != nil {
@@ -2343,9 +3169,15 @@ func handleStopBackups(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if len(activeJobs) == 0 {
+		message := "No active backups to stop"
+		if removedCount > 0 {
+			message = fmt.Sprintf("Removed %d queued backup jobs", removedCount)
+		}
 		json.NewEncoder(w).Encode(map[string]interface{}{
-			"success": true,
-			"message": "No active backups to stop",
+			"success":       true,
+			"message":       message,
+			"removed_count": removedCount,
+			"killed_count":  killedCount,
 		})
 		return
 	}
@@ -2385,10 +3217,86 @@ func handleStopBackups(w http.ResponseWriter, r *http.Request) {
 		"success":       true,
 		"message":       fmt.Sprintf("Stopped %d active backup jobs", stoppedCount),
 		"stopped_count": stoppedCount,
+		"removed_count": removedCount,
+		"killed_count":  killedCount,
 		"total_active":  len(activeJobs),
 	})
 }
 
+// handleCancelBackup cancels one job, or one database of a job, without
+// touching other jobs. Running processes are killed and their partial files removed.
+func handleCancelBackup(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	jobID := strings.TrimSpace(r.FormValue("job_id"))
+	dbName := strings.TrimSpace(r.FormValue("db"))
+	if jobID == "" {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   "job_id is required",
+		})
+		return
+	}
+
+	job := GetQueuedJob(jobID)
+	if job == nil {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   fmt.Sprintf("Job %s is not queued or running", jobID),
+		})
+		return
+	}
+
+	if dbName != "" && len(job.Databases) > 0 && !slices.Contains(job.Databases, dbName) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   fmt.Sprintf("Job %s does not back up database %s", jobID, dbName),
+		})
+		return
+	}
+
+	message, killedCount := cancelQueuedJob(r, job, dbName)
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":      true,
+		"message":      message,
+		"killed_count": killedCount,
+	})
+}
+
+// cancelQueuedJob cancels a queued or running job, or one database of it when
+// dbName is set. Returns a message for the user and the number of killed processes.
+func cancelQueuedJob(r *http.Request, job *QueuedJob, dbName string) (string, int) {
+	jobID := job.JobID
+	LogInfo("Cancel backup request received - JobID: %s, Database: %s, RequestedBy: %s", jobID, dbName, currentUsername(r))
+	target := jobID
+	if dbName != "" {
+		target = jobID + "/" + dbName
+	}
+	recordAudit(r, AuditBackupCancel, target, nil, nil)
+
+	// A whole job that has not started yet is just dropped from the queue
+	if dbName == "" && job.State == "pending" && CancelPendingJob(jobID) {
+		return fmt.Sprintf("Removed queued job %s", jobID), 0
+	}
+
+	killedCount := CancelBackup(jobID, dbName)
+
+	message := fmt.Sprintf("Cancelled job %s", jobID)
+	if dbName != "" {
+		message = fmt.Sprintf("Cancelled backup of %s in job %s", dbName, jobID)
+	}
+	if killedCount > 0 {
+		message += fmt.Sprintf(" (%d process(es) killed)", killedCount)
+	}
+	return message, killedCount
+}
+
 // determineBackupType determines whether a database needs full or incremental backup
 func determineBackupType(dbName string, config *Config) string {
 	// Check if full backup exists and is within the full backup interval
@@ -2664,6 +3572,8 @@ func handleDeleteLogFile(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	recordAudit(r, AuditLogDelete, logFileName, nil, nil)
+
 	// Check if this is the current day's log file
 	currentDate := time.Now().Format("2006-01-02")
 	isCurrentDay := requestData.Date == currentDate
@@ -2964,6 +3874,7 @@ func handleClearBackupHistory(w http.ResponseWriter, r *http.Request) {
 	}
 
 	LogInfo("Successfully cleared all backup history")
+	recordAudit(r, AuditHistoryClear, "backup history", nil, nil)
 
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"success": true,
@@ -3041,6 +3952,7 @@ func handleDownloadBackup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	defer file.Close()
+	recordAudit(r, AuditBackupDownload, backupFilePath, nil, map[string]interface{}{"job_id": path})
 
 	// Copy file to response
 	_, err = io.Copy(w, file)
@@ -3071,12 +3983,6 @@ func getLastBackupTime() string {
 	return ""
 }
 
-// calculateNextBackupTime calculates the next scheduled backup time based on start time and interval
-// This function now uses the shared calculation function from scheduler.go
-func calculateNextBackupTime(startTime string, intervalHours int) string {
-	return CalculateNextBackupTime(startTime, intervalHours)
-}
-
 // Global abort mechanism for backup processes
 var globalBackupAbortFlag bool
 var globalBackupAbortMutex sync.RWMutex
@@ -3399,6 +4305,9 @@ func handleRestartService(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Recorded first, the restart may stop this process before it returns
+	recordAudit(r, AuditServiceRestart, "mariadb-backup-tool", nil, nil)
+
 	// Execute service restart command
 	cmd := exec.Command("service", "mariadb-backup-tool", "restart")
 	output, err := cmd.CombinedOutput()
@@ -3420,3 +4329,46 @@ func handleRestartService(w http.ResponseWriter, r *http.Request) {
 		"output":  string(output),
 	})
 }
+
+func handleTestNotification(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	channel := strings.TrimSpace(r.FormValue("channel"))
+	if !slices.Contains(notifierNames, channel) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   "Unknown notification channel: " + channel,
+		})
+		return
+	}
+
+	// Test the saved settings, like the connection test
+	config, err := loadConfig("config.json")
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   "Failed to load config: " + err.Error(),
+		})
+		return
+	}
+
+	if err := SendTestNotification(config.Notification, channel); err != nil {
+		LogWarn("Test %s notification failed: %v", channel, err)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	LogInfo("📢 Test %s notification sent", channel)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Test notification sent",
+	})
+}
@@ -10,9 +10,7 @@ import (
 )
 
 var upgrader = websocket.Upgrader{
-	CheckOrigin: func(r *http.Request) bool {
-		return true
-	},
+	CheckOrigin: checkSameOrigin,
 }
 
 var jobsConnections = make(map[*websocket.Conn]*sync.Mutex)
@@ -46,6 +44,7 @@ func handleJobsWebSocket(w http.ResponseWriter, r *http.Request) {
 			"completed_jobs": 0,
 		}
 	}
+	runningData["queue"] = GetJobQueueStatus()
 
 	conn.WriteJSON(map[string]interface{}{
 		"type": "jobs_update",
@@ -83,6 +82,7 @@ func broadcastJobsUpdate() {
 			"completed_jobs": 0,
 		}
 	}
+	runningData["queue"] = GetJobQueueStatus()
 
 	message := map[string]interface{}{
 		"type": "jobs_update",
@@ -371,3 +371,29 @@ func getLogLevelColor(level string) string {
 		return "info"
 	}
 }
+
+// CloseAllWebSockets sends a going-away close frame to every WebSocket client and closes the connections
+func CloseAllWebSockets() {
+	closeMessage := websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down")
+
+	closeConnections := func(connections map[*websocket.Conn]*sync.Mutex, connMutex *sync.RWMutex) int {
+		connMutex.Lock()
+		defer connMutex.Unlock()
+
+		count := len(connections)
+		for conn, mutex := range connections {
+			mutex.Lock()
+			conn.WriteControl(websocket.CloseMessage, closeMessage, time.Now().Add(time.Second))
+			conn.Close()
+			mutex.Unlock()
+			delete(connections, conn)
+		}
+		return count
+	}
+
+	count := closeConnections(jobsConnections, &jobsMutex)
+	count += closeConnections(systemConnections, &systemMutex)
+	count += closeConnections(logsConnections, &logsMutex)
+
+	LogDebug("Closed %d WebSocket connections", count)
+}